@@ -30,6 +30,7 @@ import (
 	"github.com/yungbote/slotter/backend/services/database/internal/server/websocket"
 	"github.com/yungbote/slotter/backend/services/database/internal/services"
 	"github.com/yungbote/slotter/backend/services/database/internal/services/avatar"
+	"github.com/yungbote/slotter/backend/services/database/internal/services/label"
 	"github.com/yungbote/slotter/backend/services/database/internal/services/s3"
 )
 
@@ -77,10 +78,33 @@ func main() {
 		&models.Company{},
 		&models.Warehouse{},
 		&models.Location{},
+		&models.LocationAlias{},
 		&models.TransactionRecord{},
 		&models.TransactionFile{},
 		&models.Item{},
 		&models.UserAction{},
+		&models.Zone{},
+		&models.ZoneViolation{},
+		&models.Job{},
+		&models.LabelTemplate{},
+		&models.LabelBatch{},
+		&models.ClassificationRun{},
+		&models.ItemClassification{},
+		&models.ForecastRun{},
+		&models.ItemForecast{},
+		&models.SlotPlan{},
+		&models.SlotPlanEntry{},
+		&models.SlottingScenario{},
+		&models.MoveTask{},
+		&models.StockFlag{},
+		&models.SlowStockSetting{},
+		&models.SlotAssignment{},
+		&models.ActivityRollup{},
+		&models.LaborStandard{},
+		&models.TransactionException{},
+		&models.ErgonomicModel{},
+		&models.ReplenishmentSetting{},
+		&models.ReplenishmentTask{},
 	); err != nil {
 		log.Fatalf("failed to auto-migrate: %v", err)
 	}
@@ -112,10 +136,42 @@ func main() {
 	companyRepo := repos.NewCRepo(db)
 	warehouseRepo := repos.NewWRepo(db)
 	locationRepo := repos.NewLRepo(db)
+	locationAliasRepo := repos.NewLARepo(db)
+	slotAssignmentRepo := repos.NewSlotAssignmentRepo(db)
 	transactionRecordRepo := repos.NewTRRepo(db)
 	transactionFileRepo := repos.NewTFRepo(db)
 	userActionRepo := repos.NewUARepo(db) // optional, but included for completeness
 	itemRepo := repos.NewIRepo(db)
+	jobRepo := repos.NewJRepo(db)
+	analyticsRepo := repos.NewAnalyticsRepo(db)
+	classificationRepo := repos.NewClassificationRepo(db)
+	forecastRepo := repos.NewForecastRepo(db)
+	slotPlanRepo := repos.NewSlotPlanRepo(db)
+	moveTaskRepo := repos.NewMoveTaskRepo(db)
+	scenarioRepo := repos.NewScenarioRepo(db)
+	stockFlagRepo := repos.NewStockFlagRepo(db)
+	rollupRepo := repos.NewRollupRepo(db)
+	laborStandardRepo := repos.NewLaborStandardRepo(db)
+	exceptionRepo := repos.NewExceptionRepo(db)
+	ergonomicRepo := repos.NewErgonomicRepo(db)
+	replenishmentRepo := repos.NewReplenishmentRepo(db)
+	zoneRepo := repos.NewZRepo(db)
+	zoneViolationRepo := repos.NewZVRepo(db)
+	labelTemplateRepo := repos.NewLTRepo(db)
+	labelBatchRepo := repos.NewLBRepo(db)
+
+	// Item links made before slot assignments existed get an open assignment.
+	if n, err := slotAssignmentRepo.Backfill(); err != nil {
+		log.Fatalf("failed to backfill slot assignments: %v", err)
+	} else if n > 0 {
+		log.Printf("Backfilled %d slot assignments.", n)
+	}
+	// Warehouses imported before activity rollups existed get rolled up once.
+	if n, err := rollupRepo.BuildMissing(); err != nil {
+		log.Fatalf("failed to build activity rollups: %v", err)
+	} else if n > 0 {
+		log.Printf("Built %d activity rollups.", n)
+	}
 
 	// -------------------------------------------------------------------------
 	// 5. Initialize Services
@@ -150,11 +206,30 @@ func main() {
 	userSvc := services.NewUSvc(userRepo /* pass more if needed... */)
 	companySvc := services.NewCSvc(companyRepo)
 	warehouseSvc := services.NewWSvc(warehouseRepo)
-	locationSvc := services.NewLSvc(locationRepo)
+	locationSvc := services.NewLSvc(locationRepo, locationAliasRepo, slotAssignmentRepo)
 	tfSvc := services.NewTFSvc(transactionFileRepo)
 	trSvc := services.NewTRSvc(transactionRecordRepo)
 	itemSvc := services.NewISvc(itemRepo)
+	zoneSvc := services.NewZSvc(zoneRepo, zoneViolationRepo)
+	labelTemplateSvc := services.NewLTSvc(labelTemplateRepo)
+	labelBatchSvc := services.NewLBSvc(labelBatchRepo)
+	jobSvc := services.NewJSvc(jobRepo, pub)
+	analyticsSvc := services.NewAnalyticsSvc(analyticsRepo)
+	forecastSvc := services.NewForecastSvc(forecastRepo, analyticsRepo)
+	classificationSvc := services.NewClassificationSvc(classificationRepo, analyticsRepo, forecastSvc)
+	ergonomicSvc := services.NewErgonomicSvc(ergonomicRepo, analyticsRepo, locationRepo, itemRepo, zoneRepo)
+	putawaySvc := services.NewPutawaySvc(analyticsRepo, locationRepo, itemRepo, zoneRepo)
+	replenishmentSvc := services.NewReplenishmentSvc(replenishmentRepo, analyticsRepo, locationRepo, slotAssignmentRepo)
+	capacitySvc := services.NewCapacitySvc(locationRepo, zoneRepo, slotAssignmentRepo, analyticsRepo, forecastSvc)
+	slottingSvc := services.NewSlottingSvc(slotPlanRepo, moveTaskRepo, warehouseRepo, locationRepo, itemRepo, zoneRepo, analyticsSvc, forecastSvc, ergonomicSvc)
+	scenarioSvc := services.NewScenarioSvc(scenarioRepo, slottingSvc, analyticsSvc)
+	simulationSvc := services.NewSimulationSvc(analyticsRepo, slotPlanRepo, warehouseRepo, locationRepo)
+	slowStockSvc := services.NewSlowStockSvc(stockFlagRepo, analyticsRepo, locationRepo, itemRepo)
+	rollupSvc := services.NewRollupSvc(rollupRepo)
+	productivitySvc := services.NewProductivitySvc(laborStandardRepo, analyticsRepo, zoneRepo)
+	exceptionSvc := services.NewExceptionSvc(exceptionRepo, analyticsRepo)
 	avatarSvc := avatar.NewAvatarService(s3Svc)
+	labelSvc := label.NewLabelService(s3Svc)
 
 	// If you have an OAuth config for Google:
 	oauthCfg := auth.OAuthConfig{
@@ -168,7 +243,7 @@ func main() {
 	}
 
 	// Parser Service
-	parserSvc := parser.NewParserService(locationSvc, itemSvc, trSvc, tfSvc, warehouseSvc)
+	parserSvc := parser.NewParserService(locationSvc, itemSvc, trSvc, tfSvc, warehouseSvc, zoneSvc, pub)
 
 	// Build the App Service
 	appSvc := services.NewAppSvc(
@@ -179,8 +254,27 @@ func main() {
 		tfSvc,
 		trSvc,
 		itemSvc,
+		zoneSvc,
+		labelTemplateSvc,
+		labelBatchSvc,
+		jobSvc,
+		analyticsSvc,
+		classificationSvc,
+		forecastSvc,
+		slottingSvc,
+		scenarioSvc,
+		simulationSvc,
+		slowStockSvc,
+		rollupSvc,
+		productivitySvc,
+		exceptionSvc,
+		ergonomicSvc,
+		putawaySvc,
+		replenishmentSvc,
+		capacitySvc,
 		avatarSvc,
 		s3Svc,
+		labelSvc,
 		tokenSvc,
 		refreshTokenSvc,
 		oauthSvc,
@@ -242,12 +336,34 @@ func main() {
 		protected.PUT("/warehouse/:warehouse_id/name", appHandler.UpdateWarehouseName)
 		protected.DELETE("/warehouse/:warehouse_id", appHandler.DeleteWarehouse)
 		protected.GET("/warehouses", appHandler.ListWarehouses)
+		protected.POST("/warehouse/:warehouse_id/clone", appHandler.CloneWarehouse)
+		protected.PUT("/warehouse/:warehouse_id/depot", appHandler.UpdateWarehouseDepot)
+
+		// job endpoints
+		protected.GET("/job/:job_id", appHandler.GetJob)
+		protected.GET("/jobs", appHandler.ListJobs)
 
 		// location endpoints
 		protected.POST("/warehouse/:warehouse_id/location", appHandler.CreateLocation)
 		protected.GET("/location/:location_id", appHandler.GetLocationByID)
 		protected.DELETE("/location/:location_id", appHandler.DeleteLocation)
 		protected.GET("/locations", appHandler.ListLocations)
+		protected.PUT("/location/:location_id/path", appHandler.MoveLocation)
+		protected.POST("/location/:location_id/merge", appHandler.MergeLocations)
+		protected.GET("/location/:location_id/aliases", appHandler.ListLocationAliases)
+		protected.PUT("/location/:location_id/status", appHandler.UpdateLocationStatus)
+		protected.POST("/location/:location_id/item/:item_id", appHandler.LinkItemToLocation)
+		protected.DELETE("/location/:location_id/item/:item_id", appHandler.UnlinkItemFromLocation)
+		protected.PUT("/location/:location_id/slot", appHandler.UpdateLocationSlot)
+		protected.PUT("/warehouse/:warehouse_id/slots", appHandler.UpdateWarehouseSlots)
+
+		// zone endpoints
+		protected.POST("/warehouse/:warehouse_id/zone", appHandler.CreateZone)
+		protected.PUT("/zone/:zone_id", appHandler.UpdateZone)
+		protected.DELETE("/zone/:zone_id", appHandler.DeleteZone)
+		protected.GET("/warehouse/:warehouse_id/zones", appHandler.ListZones)
+		protected.POST("/warehouse/:warehouse_id/zone-violations/recheck", appHandler.RecheckZoneViolations)
+		protected.GET("/zone-violations", appHandler.ListZoneViolations)
 
 		// transaction file endpoints
 		protected.POST("/warehouse/:warehouse_id/transaction-file/upload", appHandler.UploadTransactionFile)
@@ -277,6 +393,84 @@ func main() {
 
 		// item endpoints
 		protected.GET("/items", appHandler.ListItems)
+		protected.PUT("/item/:item_id/attributes", appHandler.UpdateItemAttributes)
+
+		// label endpoints
+		protected.POST("/label-template", appHandler.CreateLabelTemplate)
+		protected.PUT("/label-template/:template_id", appHandler.UpdateLabelTemplate)
+		protected.DELETE("/label-template/:template_id", appHandler.DeleteLabelTemplate)
+		protected.GET("/label-templates", appHandler.ListLabelTemplates)
+		protected.POST("/warehouse/:warehouse_id/labels/locations", appHandler.GenerateLocationLabels)
+		protected.POST("/labels/items", appHandler.GenerateItemLabels)
+		protected.GET("/label-batch/:batch_id", appHandler.GetLabelBatch)
+		protected.GET("/label-batches", appHandler.ListLabelBatches)
+
+		// ANALYTICS
+		protected.GET("/warehouse/:warehouse_id/analytics/velocity", appHandler.GetItemVelocity)
+		protected.GET("/warehouse/:warehouse_id/analytics/affinity", appHandler.GetItemAffinity)
+		protected.GET("/warehouse/:warehouse_id/analytics/order-profile", appHandler.GetOrderProfile)
+		protected.GET("/warehouse/:warehouse_id/slow-stock", appHandler.GetSlowStock)
+		protected.POST("/warehouse/:warehouse_id/slow-stock/scan", appHandler.ScanSlowStock)
+		protected.GET("/warehouse/:warehouse_id/heatmap", appHandler.GetHeatmap)
+		protected.GET("/warehouse/:warehouse_id/rollups", appHandler.GetRollupStatus)
+		protected.POST("/warehouse/:warehouse_id/rollups/rebuild", appHandler.RebuildRollups)
+		protected.GET("/warehouse/:warehouse_id/productivity", appHandler.GetProductivity)
+		protected.GET("/warehouse/:warehouse_id/labor-standards", appHandler.ListLaborStandards)
+		protected.PUT("/warehouse/:warehouse_id/labor-standard", appHandler.SetLaborStandard)
+		protected.DELETE("/warehouse/:warehouse_id/labor-standard/:transaction_type", appHandler.DeleteLaborStandard)
+		protected.POST("/warehouse/:warehouse_id/exceptions/scan", appHandler.ScanTransactionExceptions)
+		protected.GET("/transaction-exceptions", appHandler.ListTransactionExceptions)
+		protected.PUT("/warehouse/:warehouse_id/exceptions/acknowledge", appHandler.AcknowledgeTransactionExceptions)
+		protected.GET("/warehouse/:warehouse_id/ergonomic-model", appHandler.GetErgonomicModel)
+		protected.PUT("/warehouse/:warehouse_id/ergonomic-model", appHandler.SetErgonomicModel)
+		protected.DELETE("/warehouse/:warehouse_id/ergonomic-model", appHandler.ResetErgonomicModel)
+		protected.GET("/warehouse/:warehouse_id/ergonomics", appHandler.GetErgonomics)
+		protected.GET("/warehouse/:warehouse_id/putaway-suggestions", appHandler.SuggestPutaway)
+		protected.GET("/transaction-record/:record_id/putaway-suggestions", appHandler.SuggestRecordPutaway)
+		protected.GET("/warehouse/:warehouse_id/replenishment-settings", appHandler.ListReplenishmentSettings)
+		protected.PUT("/warehouse/:warehouse_id/replenishment-setting", appHandler.SetReplenishmentSetting)
+		protected.DELETE("/warehouse/:warehouse_id/replenishment-setting/:location_id/:item_id", appHandler.DeleteReplenishmentSetting)
+		protected.GET("/warehouse/:warehouse_id/replenishment-status", appHandler.GetReplenishmentStatus)
+		protected.POST("/warehouse/:warehouse_id/replenishment/evaluate", appHandler.EvaluateReplenishment)
+		protected.GET("/replenishment-queue", appHandler.ListReplenishmentQueue)
+		protected.GET("/replenishment-task/:task_id", appHandler.GetReplenishmentTask)
+		protected.PUT("/replenishment-task/:task_id/complete", appHandler.CompleteReplenishmentTask)
+		protected.PUT("/replenishment-task/:task_id/cancel", appHandler.CancelReplenishmentTask)
+		protected.GET("/warehouse/:warehouse_id/capacity", appHandler.GetCapacity)
+		protected.POST("/warehouse/:warehouse_id/classification", appHandler.RunClassification)
+		protected.GET("/classifications", appHandler.ListClassificationRuns)
+		protected.GET("/classification/:run_id", appHandler.GetClassificationRun)
+		protected.DELETE("/classification/:run_id", appHandler.DeleteClassificationRun)
+		protected.GET("/classification/:run_id/items", appHandler.ListItemClassifications)
+		protected.GET("/classification/:run_id/changes", appHandler.CompareClassificationRuns)
+		protected.POST("/warehouse/:warehouse_id/forecast", appHandler.RunForecast)
+		protected.GET("/forecasts", appHandler.ListForecastRuns)
+		protected.GET("/forecast/:run_id", appHandler.GetForecastRun)
+		protected.DELETE("/forecast/:run_id", appHandler.DeleteForecastRun)
+		protected.GET("/forecast/:run_id/items", appHandler.ListItemForecasts)
+
+		// SLOTTING
+		protected.POST("/warehouse/:warehouse_id/slot-plan", appHandler.RecommendSlotPlan)
+		protected.GET("/slot-plans", appHandler.ListSlotPlans)
+		protected.GET("/slot-plan/:plan_id", appHandler.GetSlotPlan)
+		protected.DELETE("/slot-plan/:plan_id", appHandler.DeleteSlotPlan)
+		protected.GET("/slot-plan/:plan_id/entries", appHandler.ListSlotPlanEntries)
+		protected.GET("/slot-plan/:plan_id/moves", appHandler.GetSlotPlanMoves)
+		protected.POST("/slot-plan/:plan_id/moves/accept", appHandler.AcceptSlotPlanMoves)
+		protected.GET("/move-tasks", appHandler.ListMoveTasks)
+		protected.GET("/move-task/:task_id", appHandler.GetMoveTask)
+		protected.PUT("/move-task/:task_id/confirm", appHandler.ConfirmMoveTask)
+		protected.PUT("/move-task/:task_id/cancel", appHandler.CancelMoveTask)
+		protected.GET("/slot-plan/:plan_id/replay", appHandler.ReplaySlotPlan)
+		protected.GET("/warehouse/:warehouse_id/pick-face-sizing", appHandler.GetPickFaceSizing)
+		protected.POST("/warehouse/:warehouse_id/scenario", appHandler.CreateScenario)
+		protected.GET("/scenarios", appHandler.ListScenarios)
+		protected.GET("/scenarios/compare", appHandler.CompareScenarios)
+		protected.GET("/scenario/:scenario_id", appHandler.GetScenario)
+		protected.DELETE("/scenario/:scenario_id", appHandler.DeleteScenario)
+		protected.POST("/scenario/:scenario_id/clone", appHandler.CloneScenario)
+		protected.GET("/warehouse/:warehouse_id/slot-map", appHandler.GetSlotMap)
+		protected.GET("/warehouse/:warehouse_id/slot-assignments", appHandler.ListSlotAssignments)
 	}
 
 	// -------------------------------------------------------------------------
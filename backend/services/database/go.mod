@@ -16,6 +16,7 @@ require (
 	github.com/joho/godotenv v1.5.1
 	github.com/xuri/excelize/v2 v2.9.0
 	golang.org/x/crypto v0.35.0
+	golang.org/x/image v0.18.0
 	golang.org/x/oauth2 v0.27.0
 	gorm.io/datatypes v1.2.5
 	gorm.io/driver/postgres v1.5.11
@@ -0,0 +1,23 @@
+package constants
+
+const (
+  ABCBasisLines = "lines"
+  ABCBasisUnits = "units"
+  ABCBasisCube  = "cube" // units x item cube, i.e. cube movement
+)
+
+var ABCBases = map[string]bool{
+  ABCBasisLines: true,
+  ABCBasisUnits: true,
+  ABCBasisCube:  true,
+}
+
+const (
+  ABCClassA = "A"
+  ABCClassB = "B"
+  ABCClassC = "C"
+
+  XYZClassX = "X"
+  XYZClassY = "Y"
+  XYZClassZ = "Z"
+)
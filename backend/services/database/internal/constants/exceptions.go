@@ -0,0 +1,22 @@
+package constants
+
+// Transaction exception types raised by the exceptions scan.
+const (
+  ExceptionOverCompleted   = "over_completed"        // completed quantity above the transaction quantity
+  ExceptionNonPositiveQty  = "non_positive_quantity" // transaction quantity <= 0 or completed quantity < 0
+  ExceptionFutureDate      = "future_date"
+  ExceptionBeforeWarehouse = "before_warehouse" // completed before the warehouse's go-live date
+  ExceptionClosedLocation  = "closed_location"  // pick from a blocked or decommissioned location
+  ExceptionNonPickFace     = "non_pick_face"    // pick from a reserve or overflow slot
+  ExceptionVolumeOutlier   = "volume_outlier"   // the item's daily units are a z-score outlier
+)
+
+var ExceptionTypes = map[string]bool{
+  ExceptionOverCompleted:   true,
+  ExceptionNonPositiveQty:  true,
+  ExceptionFutureDate:      true,
+  ExceptionBeforeWarehouse: true,
+  ExceptionClosedLocation:  true,
+  ExceptionNonPickFace:     true,
+  ExceptionVolumeOutlier:   true,
+}
@@ -0,0 +1,25 @@
+package constants
+
+const (
+  ForecastGranularityDay  = "day"
+  ForecastGranularityWeek = "week"
+)
+
+var ForecastGranularities = map[string]bool{
+  ForecastGranularityDay:  true,
+  ForecastGranularityWeek: true,
+}
+
+const (
+  ForecastMethodHoltWinters = "holt_winters" // level, trend and additive season
+  ForecastMethodHolt        = "holt"         // level and trend, too little history for a season
+  ForecastMethodSimple      = "simple"       // level only
+  ForecastMethodNone        = "none"         // no demand in the history
+)
+
+var ForecastMethods = map[string]bool{
+  ForecastMethodHoltWinters: true,
+  ForecastMethodHolt:        true,
+  ForecastMethodSimple:      true,
+  ForecastMethodNone:        true,
+}
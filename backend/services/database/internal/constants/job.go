@@ -0,0 +1,21 @@
+package constants
+
+const (
+  JobStatusQueued    = "queued"
+  JobStatusRunning   = "running"
+  JobStatusSucceeded = "succeeded"
+  JobStatusFailed    = "failed"
+)
+
+var JobStatuses = map[string]bool{
+  JobStatusQueued:    true,
+  JobStatusRunning:   true,
+  JobStatusSucceeded: true,
+  JobStatusFailed:    true,
+}
+
+const (
+  JobTypeWarehouseClone = "warehouse_clone"
+  JobTypeRollupRefresh  = "rollup_refresh"
+  JobTypeRollupRebuild  = "rollup_rebuild"
+)
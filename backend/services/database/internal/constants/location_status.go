@@ -0,0 +1,22 @@
+package constants
+
+const (
+  LocationStatusActive          = "active"
+  LocationStatusBlocked         = "blocked"
+  LocationStatusReserved        = "reserved"
+  LocationStatusDecommissioned  = "decommissioned"
+)
+
+var LocationStatuses = map[string]bool{
+  LocationStatusActive:         true,
+  LocationStatusBlocked:        true,
+  LocationStatusReserved:       true,
+  LocationStatusDecommissioned: true,
+}
+
+// LocationStatusesClosed are statuses where no stock movement is expected;
+// imported transactions landing on them get flagged.
+var LocationStatusesClosed = map[string]bool{
+  LocationStatusBlocked:        true,
+  LocationStatusDecommissioned: true,
+}
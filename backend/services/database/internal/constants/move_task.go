@@ -0,0 +1,20 @@
+package constants
+
+const (
+  MoveTaskStatusOpen      = "open"
+  MoveTaskStatusDone      = "done"
+  MoveTaskStatusCancelled = "cancelled"
+)
+
+var MoveTaskStatuses = map[string]bool{
+  MoveTaskStatusOpen:      true,
+  MoveTaskStatusDone:      true,
+  MoveTaskStatusCancelled: true,
+}
+
+// Move kinds of a re-slot move list group.
+const (
+  MoveKindMove  = "move"  // into an empty slot
+  MoveKindSwap  = "swap"  // two items trade slots
+  MoveKindChain = "chain" // each item moves into the slot the next one vacates
+)
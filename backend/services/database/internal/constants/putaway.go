@@ -0,0 +1,30 @@
+package constants
+
+// Putaway candidate kinds, best first.
+const (
+  PutawayPickFace     = "pick_face"     // a pick slot of the item
+  PutawayItemReserve  = "item_reserve"  // a reserve or overflow slot already holding the item
+  PutawayEmptyReserve = "empty_reserve" // a reserve or overflow slot holding nothing
+)
+
+// Reasons a location was left out of the putaway candidates.
+const (
+  PutawaySkipInactive = "inactive"     // location status is not active
+  PutawaySkipZoneRule = "zone_rule"    // the item breaks a rule of a zone covering it
+  PutawaySkipNoFit    = "does_not_fit" // item dimensions or weight exceed the slot
+  PutawaySkipFull     = "full"         // no room left for the item
+  PutawaySkipOccupied = "occupied"     // holds other items
+)
+
+// PutawayInboundTypes and PutawayOutboundTypes are the transaction type
+// categories that put stock into and take it out of a location when putaway
+// estimates what a location holds.
+var PutawayInboundTypes = map[string]bool{
+  TransactionTypeReceive:   true,
+  TransactionTypePutaway:   true,
+  TransactionTypeReplenish: true,
+}
+
+var PutawayOutboundTypes = map[string]bool{
+  TransactionTypePick: true,
+}
@@ -0,0 +1,30 @@
+package constants
+
+// Replenishment methods: how the min and max levels of a pick face are set.
+const (
+  ReplenishMinMax       = "min_max"        // fixed unit levels
+  ReplenishDaysOfSupply = "days_of_supply" // levels in days of the face's recent picks
+)
+
+var ReplenishMethods = map[string]bool{
+  ReplenishMinMax:       true,
+  ReplenishDaysOfSupply: true,
+}
+
+const (
+  ReplenishTaskOpen      = "open"
+  ReplenishTaskDone      = "done"
+  ReplenishTaskCancelled = "cancelled"
+)
+
+var ReplenishTaskStatuses = map[string]bool{
+  ReplenishTaskOpen:      true,
+  ReplenishTaskDone:      true,
+  ReplenishTaskCancelled: true,
+}
+
+// Sources of a pick face on-hand estimate.
+const (
+  OnHandTransactions = "transactions" // inbound less picked units recorded at the slot
+  OnHandProjected    = "projected"    // level after the last replenishment less the picks since
+)
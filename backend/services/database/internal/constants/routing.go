@@ -0,0 +1,17 @@
+package constants
+
+// Picker routing policies for travel simulation in a single block layout
+// with a front and a back cross aisle.
+const (
+  RoutingSShape     = "s_shape"     // traverse every aisle with picks
+  RoutingReturn     = "return"      // enter and leave each aisle from the front
+  RoutingLargestGap = "largest_gap" // skip the largest gap of middle aisles
+  RoutingOptimal    = "optimal"
+)
+
+var RoutingPolicies = map[string]bool{
+  RoutingSShape:     true,
+  RoutingReturn:     true,
+  RoutingLargestGap: true,
+  RoutingOptimal:    true,
+}
@@ -0,0 +1,74 @@
+package constants
+
+const (
+  SlotRolePick     = "pick"
+  SlotRoleReserve  = "reserve"
+  SlotRoleOverflow = "overflow"
+)
+
+var SlotRoles = map[string]bool{
+  SlotRolePick:     true,
+  SlotRoleReserve:  true,
+  SlotRoleOverflow: true,
+}
+
+// Golden zone: slot floor between waist and shoulder height.
+const (
+  GoldenZoneMinCm = 76.0
+  GoldenZoneMaxCm = 152.0
+)
+
+// Ergonomic height bands of a slot floor, bounded by the knee, waist and
+// shoulder heights of the warehouse ergonomic model.
+const (
+  ErgoBandBelowKnee     = "below_knee"
+  ErgoBandKneeToWaist   = "knee_to_waist"
+  ErgoBandGolden        = "golden"
+  ErgoBandAboveShoulder = "above_shoulder"
+  ErgoBandUnknown       = "unknown" // neither a height nor a Level=<n> segment
+)
+
+// ErgoBandsAwkward are the bands ergonomic reports count as awkward picks.
+var ErgoBandsAwkward = map[string]bool{
+  ErgoBandBelowKnee:     true,
+  ErgoBandAboveShoulder: true,
+}
+
+// Pick-face sizing verdicts.
+const (
+  SizingUnder     = "under"     // days of supply below the minimum
+  SizingOK        = "ok"
+  SizingOver      = "over"      // days of supply above the maximum, or no demand
+  SizingUnknown   = "unknown"   // slot or item dimensions missing
+  SizingUnslotted = "unslotted" // demand but no active pick slot
+)
+
+var SizingStatuses = map[string]bool{
+  SizingUnder:     true,
+  SizingOK:        true,
+  SizingOver:      true,
+  SizingUnknown:   true,
+  SizingUnslotted: true,
+}
+
+// Slot assignment types: what an item's assignment to a location is for.
+const (
+  AssignmentPrimary  = "primary"  // the item's pick face
+  AssignmentOverflow = "overflow" // extra pick face next to the primary one
+  AssignmentReserve  = "reserve"  // bulk storage that replenishes the pick face
+)
+
+var AssignmentTypes = map[string]bool{
+  AssignmentPrimary:  true,
+  AssignmentOverflow: true,
+  AssignmentReserve:  true,
+}
+
+// Slot assignment sources: the workflow that opened or closed an assignment.
+const (
+  AssignmentSourceImport   = "import"
+  AssignmentSourceManual   = "manual"
+  AssignmentSourceMoveTask = "move_task"
+  AssignmentSourceMerge    = "merge"
+  AssignmentSourceBackfill = "backfill" // links that predate assignment history
+)
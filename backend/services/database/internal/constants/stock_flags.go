@@ -0,0 +1,11 @@
+package constants
+
+const (
+  StockFlagDead = "dead" // no pick in the dead-days window
+  StockFlagSlow = "slow" // picks fell sharply against the prior window
+)
+
+var StockFlagStatuses = map[string]bool{
+  StockFlagDead: true,
+  StockFlagSlow: true,
+}
@@ -0,0 +1,45 @@
+package constants
+
+import "strings"
+
+// Transaction types arrive as free text from client files ("Pick", "PUT AWAY",
+// "Replen"...). Analytics group them into these categories.
+const (
+  TransactionTypePick      = "pick"
+  TransactionTypePutaway   = "putaway"
+  TransactionTypeReplenish = "replenish"
+  TransactionTypeReceive   = "receive"
+  TransactionTypeAdjust    = "adjust"
+  TransactionTypeOther     = "other"
+)
+
+var TransactionTypes = map[string]bool{
+  TransactionTypePick:      true,
+  TransactionTypePutaway:   true,
+  TransactionTypeReplenish: true,
+  TransactionTypeReceive:   true,
+  TransactionTypeAdjust:    true,
+  TransactionTypeOther:     true,
+}
+
+// NormalizeTransactionType maps a raw transaction_type value onto one of the
+// TransactionTypes categories.
+func NormalizeTransactionType(raw string) string {
+  t := strings.ToLower(raw)
+  t = strings.NewReplacer(" ", "", "-", "", "_", "").Replace(t)
+  switch {
+  case t == "":
+    return TransactionTypeOther
+  case strings.Contains(t, "pick"):
+    return TransactionTypePick
+  case strings.Contains(t, "putaway") || t == "put" || strings.HasPrefix(t, "stow"):
+    return TransactionTypePutaway
+  case strings.HasPrefix(t, "repl"):
+    return TransactionTypeReplenish
+  case strings.HasPrefix(t, "adj") || strings.Contains(t, "count"):
+    return TransactionTypeAdjust
+  case strings.HasPrefix(t, "receiv") || strings.HasPrefix(t, "receipt") || strings.HasPrefix(t, "recv") || strings.HasPrefix(t, "inbound"):
+    return TransactionTypeReceive
+  }
+  return TransactionTypeOther
+}
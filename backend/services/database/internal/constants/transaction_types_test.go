@@ -0,0 +1,35 @@
+package constants
+
+import "testing"
+
+func TestNormalizeTransactionType(t *testing.T) {
+  tests := []struct {
+    raw  string
+    want string
+  }{
+    {"", TransactionTypeOther},
+    {"Pick", TransactionTypePick},
+    {"PICK-FULL CASE", TransactionTypePick},
+    {"PUT AWAY", TransactionTypePutaway},
+    {"stow", TransactionTypePutaway},
+    {"Replen", TransactionTypeReplenish},
+    {"Receive", TransactionTypeReceive},
+    {"Receiving", TransactionTypeReceive},
+    {"Receipt", TransactionTypeReceive},
+    {"RECV", TransactionTypeReceive},
+    {"inbound", TransactionTypeReceive},
+    {"Adjustment", TransactionTypeAdjust},
+    {"Cycle Count", TransactionTypeAdjust},
+    {"recount", TransactionTypeAdjust},
+    {"Re-Count", TransactionTypeAdjust},
+    {"record", TransactionTypeOther},
+    {"transfer", TransactionTypeOther},
+  }
+  for _, tt := range tests {
+    t.Run(tt.raw, func(t *testing.T) {
+      if got := NormalizeTransactionType(tt.raw); got != tt.want {
+        t.Errorf("NormalizeTransactionType(%q) = %q, want %q", tt.raw, got, tt.want)
+      }
+    })
+  }
+}
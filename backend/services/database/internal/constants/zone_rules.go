@@ -0,0 +1,25 @@
+package constants
+
+const (
+  TemperatureClassAmbient = "ambient"
+  TemperatureClassChilled = "chilled"
+  TemperatureClassFrozen  = "frozen"
+)
+
+var TemperatureClasses = map[string]bool{
+  TemperatureClassAmbient: true,
+  TemperatureClassChilled: true,
+  TemperatureClassFrozen:  true,
+}
+
+const (
+  ZoneRuleTemperature = "temperature"
+  ZoneRuleHazmat      = "hazmat"
+  ZoneRuleUnitWeight  = "unit_weight"
+  ZoneRuleItemCube    = "item_cube"
+)
+
+const (
+  ViolationSourceLink   = "link"
+  ViolationSourceImport = "import"
+)
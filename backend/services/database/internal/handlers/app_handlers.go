@@ -1,16 +1,20 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	// Internal
+	"github.com/yungbote/slotter/backend/services/database/internal/models"
 	"github.com/yungbote/slotter/backend/services/database/internal/repos"
 	"github.com/yungbote/slotter/backend/services/database/internal/services"
+	"github.com/yungbote/slotter/backend/services/database/internal/services/export"
 )
 
 // AppHandler holds a reference to your core AppSvc.
@@ -44,12 +48,35 @@ func (h *AppHandler) RegisterRoutes(rg *gin.RouterGroup) {
 	rg.PUT("/warehouse/:warehouse_id/name", h.UpdateWarehouseName)
 	rg.DELETE("/warehouse/:warehouse_id", h.DeleteWarehouse)
 	rg.GET("/warehouses", h.ListWarehouses)
+	rg.POST("/warehouse/:warehouse_id/clone", h.CloneWarehouse)
+	rg.PUT("/warehouse/:warehouse_id/depot", h.UpdateWarehouseDepot)
+	rg.PUT("/warehouse/:warehouse_id/go-live", h.UpdateWarehouseGoLive)
+
+	// JOB
+	rg.GET("/job/:job_id", h.GetJob)
+	rg.GET("/jobs", h.ListJobs)
 
 	// LOCATION
 	rg.POST("/warehouse/:warehouse_id/location", h.CreateLocation)
 	rg.GET("/location/:location_id", h.GetLocationByID)
 	rg.DELETE("/location/:location_id", h.DeleteLocation)
 	rg.GET("/locations", h.ListLocations)
+	rg.PUT("/location/:location_id/path", h.MoveLocation)
+	rg.POST("/location/:location_id/merge", h.MergeLocations)
+	rg.GET("/location/:location_id/aliases", h.ListLocationAliases)
+	rg.PUT("/location/:location_id/status", h.UpdateLocationStatus)
+	rg.POST("/location/:location_id/item/:item_id", h.LinkItemToLocation)
+	rg.DELETE("/location/:location_id/item/:item_id", h.UnlinkItemFromLocation)
+	rg.PUT("/location/:location_id/slot", h.UpdateLocationSlot)
+	rg.PUT("/warehouse/:warehouse_id/slots", h.UpdateWarehouseSlots)
+
+	// ZONE
+	rg.POST("/warehouse/:warehouse_id/zone", h.CreateZone)
+	rg.PUT("/zone/:zone_id", h.UpdateZone)
+	rg.DELETE("/zone/:zone_id", h.DeleteZone)
+	rg.GET("/warehouse/:warehouse_id/zones", h.ListZones)
+	rg.POST("/warehouse/:warehouse_id/zone-violations/recheck", h.RecheckZoneViolations)
+	rg.GET("/zone-violations", h.ListZoneViolations)
 
 	// TRANSACTION FILE
 	rg.POST("/warehouse/:warehouse_id/transaction-file/upload", h.UploadTransactionFile)
@@ -79,6 +106,87 @@ func (h *AppHandler) RegisterRoutes(rg *gin.RouterGroup) {
 
 	// ITEM
 	rg.GET("/items", h.ListItems)
+	rg.PUT("/item/:item_id/attributes", h.UpdateItemAttributes)
+
+	// LABELS
+	rg.POST("/label-template", h.CreateLabelTemplate)
+	rg.PUT("/label-template/:template_id", h.UpdateLabelTemplate)
+	rg.DELETE("/label-template/:template_id", h.DeleteLabelTemplate)
+	rg.GET("/label-templates", h.ListLabelTemplates)
+	rg.POST("/warehouse/:warehouse_id/labels/locations", h.GenerateLocationLabels)
+	rg.POST("/labels/items", h.GenerateItemLabels)
+	rg.GET("/label-batch/:batch_id", h.GetLabelBatch)
+	rg.GET("/label-batches", h.ListLabelBatches)
+
+	// ANALYTICS
+	rg.GET("/warehouse/:warehouse_id/analytics/velocity", h.GetItemVelocity)
+	rg.GET("/warehouse/:warehouse_id/analytics/affinity", h.GetItemAffinity)
+	rg.GET("/warehouse/:warehouse_id/analytics/order-profile", h.GetOrderProfile)
+	rg.GET("/warehouse/:warehouse_id/slow-stock", h.GetSlowStock)
+	rg.POST("/warehouse/:warehouse_id/slow-stock/scan", h.ScanSlowStock)
+	rg.GET("/warehouse/:warehouse_id/slow-stock-setting", h.GetSlowStockSetting)
+	rg.PUT("/warehouse/:warehouse_id/slow-stock-setting", h.SetSlowStockSetting)
+	rg.DELETE("/warehouse/:warehouse_id/slow-stock-setting", h.ResetSlowStockSetting)
+	rg.GET("/warehouse/:warehouse_id/heatmap", h.GetHeatmap)
+	rg.GET("/warehouse/:warehouse_id/rollups", h.GetRollupStatus)
+	rg.POST("/warehouse/:warehouse_id/rollups/rebuild", h.RebuildRollups)
+	rg.GET("/warehouse/:warehouse_id/productivity", h.GetProductivity)
+	rg.GET("/warehouse/:warehouse_id/labor-standards", h.ListLaborStandards)
+	rg.PUT("/warehouse/:warehouse_id/labor-standard", h.SetLaborStandard)
+	rg.DELETE("/warehouse/:warehouse_id/labor-standard/:transaction_type", h.DeleteLaborStandard)
+	rg.POST("/warehouse/:warehouse_id/exceptions/scan", h.ScanTransactionExceptions)
+	rg.GET("/transaction-exceptions", h.ListTransactionExceptions)
+	rg.PUT("/warehouse/:warehouse_id/exceptions/acknowledge", h.AcknowledgeTransactionExceptions)
+	rg.GET("/warehouse/:warehouse_id/ergonomic-model", h.GetErgonomicModel)
+	rg.PUT("/warehouse/:warehouse_id/ergonomic-model", h.SetErgonomicModel)
+	rg.DELETE("/warehouse/:warehouse_id/ergonomic-model", h.ResetErgonomicModel)
+	rg.GET("/warehouse/:warehouse_id/ergonomics", h.GetErgonomics)
+	rg.GET("/warehouse/:warehouse_id/putaway-suggestions", h.SuggestPutaway)
+	rg.GET("/transaction-record/:record_id/putaway-suggestions", h.SuggestRecordPutaway)
+	rg.GET("/warehouse/:warehouse_id/replenishment-settings", h.ListReplenishmentSettings)
+	rg.PUT("/warehouse/:warehouse_id/replenishment-setting", h.SetReplenishmentSetting)
+	rg.DELETE("/warehouse/:warehouse_id/replenishment-setting/:location_id/:item_id", h.DeleteReplenishmentSetting)
+	rg.GET("/warehouse/:warehouse_id/replenishment-status", h.GetReplenishmentStatus)
+	rg.POST("/warehouse/:warehouse_id/replenishment/evaluate", h.EvaluateReplenishment)
+	rg.GET("/replenishment-queue", h.ListReplenishmentQueue)
+	rg.GET("/replenishment-task/:task_id", h.GetReplenishmentTask)
+	rg.PUT("/replenishment-task/:task_id/complete", h.CompleteReplenishmentTask)
+	rg.PUT("/replenishment-task/:task_id/cancel", h.CancelReplenishmentTask)
+	rg.GET("/warehouse/:warehouse_id/capacity", h.GetCapacity)
+	rg.POST("/warehouse/:warehouse_id/classification", h.RunClassification)
+	rg.GET("/classifications", h.ListClassificationRuns)
+	rg.GET("/classification/:run_id", h.GetClassificationRun)
+	rg.DELETE("/classification/:run_id", h.DeleteClassificationRun)
+	rg.GET("/classification/:run_id/items", h.ListItemClassifications)
+	rg.GET("/classification/:run_id/changes", h.CompareClassificationRuns)
+	rg.POST("/warehouse/:warehouse_id/forecast", h.RunForecast)
+	rg.GET("/forecasts", h.ListForecastRuns)
+	rg.GET("/forecast/:run_id", h.GetForecastRun)
+	rg.DELETE("/forecast/:run_id", h.DeleteForecastRun)
+	rg.GET("/forecast/:run_id/items", h.ListItemForecasts)
+
+	// SLOTTING
+	rg.POST("/warehouse/:warehouse_id/slot-plan", h.RecommendSlotPlan)
+	rg.GET("/slot-plans", h.ListSlotPlans)
+	rg.GET("/slot-plan/:plan_id", h.GetSlotPlan)
+	rg.DELETE("/slot-plan/:plan_id", h.DeleteSlotPlan)
+	rg.GET("/slot-plan/:plan_id/entries", h.ListSlotPlanEntries)
+	rg.GET("/slot-plan/:plan_id/moves", h.GetSlotPlanMoves)
+	rg.POST("/slot-plan/:plan_id/moves/accept", h.AcceptSlotPlanMoves)
+	rg.GET("/move-tasks", h.ListMoveTasks)
+	rg.GET("/move-task/:task_id", h.GetMoveTask)
+	rg.PUT("/move-task/:task_id/confirm", h.ConfirmMoveTask)
+	rg.PUT("/move-task/:task_id/cancel", h.CancelMoveTask)
+	rg.GET("/slot-plan/:plan_id/replay", h.ReplaySlotPlan)
+	rg.GET("/warehouse/:warehouse_id/pick-face-sizing", h.GetPickFaceSizing)
+	rg.POST("/warehouse/:warehouse_id/scenario", h.CreateScenario)
+	rg.GET("/scenarios", h.ListScenarios)
+	rg.GET("/scenarios/compare", h.CompareScenarios)
+	rg.GET("/scenario/:scenario_id", h.GetScenario)
+	rg.DELETE("/scenario/:scenario_id", h.DeleteScenario)
+	rg.POST("/scenario/:scenario_id/clone", h.CloneScenario)
+	rg.GET("/warehouse/:warehouse_id/slot-map", h.GetSlotMap)
+	rg.GET("/warehouse/:warehouse_id/slot-assignments", h.ListSlotAssignments)
 }
 
 // ---------------------------------------------------------------------------
@@ -334,7 +442,6 @@ func (h *AppHandler) CreateWarehouse(c *gin.Context) {
 
 // GetWarehouseByID handles GET /warehouse/:warehouse_id
 func (h *AppHandler) GetWarehouseByID(c *gin.Context) {
-	// user ID might or might not be needed
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -349,12 +456,11 @@ func (h *AppHandler) GetWarehouseByID(c *gin.Context) {
 		return
 	}
 
-	warehouse, err := h.appSvc.GetWarehouseByID(c.Request.Context(), warehouseID)
+	warehouse, err := h.appSvc.GetWarehouseByID(c.Request.Context(), userID, warehouseID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
-	// Could check ownership if needed. 
 	c.JSON(http.StatusOK, warehouse)
 }
 
@@ -435,12 +541,8 @@ func (h *AppHandler) ListWarehouses(c *gin.Context) {
 	c.JSON(http.StatusOK, warehouses)
 }
 
-// ---------------------------------------------------------------------------
-// LOCATION Handlers
-// ---------------------------------------------------------------------------
-
-// CreateLocation handles POST /warehouse/:warehouse_id/location
-func (h *AppHandler) CreateLocation(c *gin.Context) {
+// CloneWarehouse handles POST /warehouse/:warehouse_id/clone
+func (h *AppHandler) CloneWarehouse(c *gin.Context) {
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -456,8 +558,8 @@ func (h *AppHandler) CreateLocation(c *gin.Context) {
 	}
 
 	type reqBody struct {
-		LocationPath     string `json:"location_path"`
-		LocationNamePath string `json:"location_name_path"`
+		Name         string `json:"name"`
+		IncludeItems bool   `json:"include_items"`
 	}
 	var body reqBody
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -465,86 +567,20 @@ func (h *AppHandler) CreateLocation(c *gin.Context) {
 		return
 	}
 
-	location, err := h.appSvc.CreateLocation(c.Request.Context(), userID, warehouseID, body.LocationPath, body.LocationNamePath)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-	c.JSON(http.StatusOK, location)
-}
-
-// GetLocationByID handles GET /location/:location_id
-func (h *AppHandler) GetLocationByID(c *gin.Context) {
-	userIDVal, ok := c.Get("user_id")
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-	userID := userIDVal.(uuid.UUID)
-
-	locationIDStr := c.Param("location_id")
-	locationID, err := uuid.Parse(locationIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
-		return
-	}
-
-	loc, err := h.appSvc.GetLocationByID(c.Request.Context(), userID, locationID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		return
-	}
-	c.JSON(http.StatusOK, loc)
-}
-
-// DeleteLocation handles DELETE /location/:location_id
-func (h *AppHandler) DeleteLocation(c *gin.Context) {
-	userIDVal, ok := c.Get("user_id")
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-	userID := userIDVal.(uuid.UUID)
-
-	locationIDStr := c.Param("location_id")
-	locationID, err := uuid.Parse(locationIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
-		return
-	}
-
-	if err := h.appSvc.DeleteLocation(c.Request.Context(), userID, locationID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"message": "location deleted"})
-}
-
-// ListLocations handles GET /locations
-func (h *AppHandler) ListLocations(c *gin.Context) {
-	userIDVal, ok := c.Get("user_id")
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-	userID := userIDVal.(uuid.UUID)
-
-	var f repos.LocationFilter
-	// Optionally parse query params for filtering
-	locations, err := h.appSvc.ListLocations(c.Request.Context(), userID, f)
+	job, err := h.appSvc.CloneWarehouse(c.Request.Context(), userID, warehouseID, body.Name, body.IncludeItems)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, locations)
+	c.JSON(http.StatusAccepted, job)
 }
 
 // ---------------------------------------------------------------------------
-// TRANSACTION FILE Handlers
+// JOB Handlers
 // ---------------------------------------------------------------------------
 
-// UploadTransactionFile handles POST /warehouse/:warehouse_id/transaction-file/upload
-func (h *AppHandler) UploadTransactionFile(c *gin.Context) {
+// UpdateWarehouseDepot handles PUT /warehouse/:warehouse_id/depot
+func (h *AppHandler) UpdateWarehouseDepot(c *gin.Context) {
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -559,38 +595,26 @@ func (h *AppHandler) UploadTransactionFile(c *gin.Context) {
 		return
 	}
 
-	fileHeader, err := c.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing file"})
-		return
-	}
-
-	fileName := fileHeader.Filename
-	fileData, err := fileHeader.Open()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open file"})
-		return
+	type reqBody struct {
+		X float64 `json:"x"`
+		Y float64 `json:"y"`
 	}
-	defer fileData.Close()
-
-	buf := make([]byte, fileHeader.Size)
-	_, err = fileData.Read(buf)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read file data"})
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		return
 	}
-
-	tf, err := h.appSvc.UploadTransactionFile(c.Request.Context(), userID, warehouseID, fileName, buf)
-	if err != nil {
+	if err := h.appSvc.UpdateWarehouseDepot(c.Request.Context(), userID, warehouseID, body.X, body.Y); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	c.JSON(http.StatusOK, tf)
+	c.JSON(http.StatusOK, gin.H{"message": "depot updated"})
 }
 
-// UpdateTransactionFileName handles PUT /transaction-file/:file_id/name
-func (h *AppHandler) UpdateTransactionFileName(c *gin.Context) {
+// UpdateWarehouseGoLive handles PUT /warehouse/:warehouse_id/go-live. Records
+// completed before go_live_date are raised as exceptions; an empty date
+// clears it so any history imports cleanly.
+func (h *AppHandler) UpdateWarehouseGoLive(c *gin.Context) {
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -598,32 +622,39 @@ func (h *AppHandler) UpdateTransactionFileName(c *gin.Context) {
 	}
 	userID := userIDVal.(uuid.UUID)
 
-	fileIDStr := c.Param("file_id")
-	fileID, err := uuid.Parse(fileIDStr)
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file_id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
 		return
 	}
 
 	type reqBody struct {
-		NewName string `json:"new_name"`
+		GoLiveDate string `json:"go_live_date"`
 	}
 	var body reqBody
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		return
 	}
-
-	err = h.appSvc.UpdateTransactionFileName(c.Request.Context(), userID, fileID, body.NewName)
+	day, err := parseDay("go_live_date", body.GoLiveDate)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "transaction file name updated"})
+	var goLive *time.Time
+	if !day.IsZero() {
+		goLive = &day
+	}
+	if err := h.appSvc.UpdateWarehouseGoLive(c.Request.Context(), userID, warehouseID, goLive); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "go-live date updated"})
 }
 
-// DeleteTransactionFile handles DELETE /transaction-file/:file_id
-func (h *AppHandler) DeleteTransactionFile(c *gin.Context) {
+// GetJob handles GET /job/:job_id
+func (h *AppHandler) GetJob(c *gin.Context) {
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -631,23 +662,23 @@ func (h *AppHandler) DeleteTransactionFile(c *gin.Context) {
 	}
 	userID := userIDVal.(uuid.UUID)
 
-	fileIDStr := c.Param("file_id")
-	fileID, err := uuid.Parse(fileIDStr)
+	jobIDStr := c.Param("job_id")
+	jobID, err := uuid.Parse(jobIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file_id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job_id"})
 		return
 	}
 
-	err = h.appSvc.DeleteTransactionFile(c.Request.Context(), userID, fileID)
+	job, err := h.appSvc.GetJob(c.Request.Context(), userID, jobID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "transaction file deleted"})
+	c.JSON(http.StatusOK, job)
 }
 
-// ListTransactionFiles handles GET /transaction-files
-func (h *AppHandler) ListTransactionFiles(c *gin.Context) {
+// ListJobs handles GET /jobs
+func (h *AppHandler) ListJobs(c *gin.Context) {
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -655,22 +686,25 @@ func (h *AppHandler) ListTransactionFiles(c *gin.Context) {
 	}
 	userID := userIDVal.(uuid.UUID)
 
-	var f repos.TransactionFileFilter
-	// parse any query params if needed
-	tfiles, err := h.appSvc.ListTransactionFiles(c.Request.Context(), userID, f)
+	var f repos.JobFilter
+	f.Type = c.Query("type")
+	f.Status = c.Query("status")
+	f.SortField = c.Query("sort")
+	f.SortDir = c.Query("dir")
+	jobs, err := h.appSvc.ListJobs(c.Request.Context(), userID, f)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, tfiles)
+	c.JSON(http.StatusOK, jobs)
 }
 
 // ---------------------------------------------------------------------------
-// TRANSACTION RECORD Handlers
+// LOCATION Handlers
 // ---------------------------------------------------------------------------
 
-// CreateTransactionRecord handles POST /warehouse/:warehouse_id/transaction-record
-func (h *AppHandler) CreateTransactionRecord(c *gin.Context) {
+// CreateLocation handles POST /warehouse/:warehouse_id/location
+func (h *AppHandler) CreateLocation(c *gin.Context) {
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -686,54 +720,25 @@ func (h *AppHandler) CreateTransactionRecord(c *gin.Context) {
 	}
 
 	type reqBody struct {
-		TransactionType  string `json:"transaction_type"`
-		OrderName        string `json:"order_name"`
-		Description      string `json:"description"`
-		TransactionQ     int64  `json:"transaction_quantity"`
-		CompletedQ       int64  `json:"completed_quantity"`
-		CompletedDateStr string `json:"completed_date"` // parse to time
 		LocationPath     string `json:"location_path"`
 		LocationNamePath string `json:"location_name_path"`
-		ItemName         string `json:"item_name"`
 	}
-
 	var body reqBody
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		return
 	}
 
-	var completedDate time.Time
-	if body.CompletedDateStr != "" {
-		cd, parseErr := time.Parse("2006-01-02", body.CompletedDateStr)
-		if parseErr == nil {
-			completedDate = cd
-		}
-	}
-
-	err = h.appSvc.CreateTransactionRecord(
-		c.Request.Context(),
-		userID,
-		warehouseID,
-		body.TransactionType,
-		body.OrderName,
-		body.Description,
-		body.TransactionQ,
-		body.CompletedQ,
-		completedDate,
-		body.LocationPath,
-		body.LocationNamePath,
-		body.ItemName,
-	)
+	location, err := h.appSvc.CreateLocation(c.Request.Context(), userID, warehouseID, body.LocationPath, body.LocationNamePath)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "transaction record created"})
+	c.JSON(http.StatusOK, location)
 }
 
-// GetTransactionRecordByID handles GET /transaction-record/:record_id
-func (h *AppHandler) GetTransactionRecordByID(c *gin.Context) {
+// GetLocationByID handles GET /location/:location_id
+func (h *AppHandler) GetLocationByID(c *gin.Context) {
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -741,23 +746,23 @@ func (h *AppHandler) GetTransactionRecordByID(c *gin.Context) {
 	}
 	userID := userIDVal.(uuid.UUID)
 
-	recordIDStr := c.Param("record_id")
-	recordID, err := uuid.Parse(recordIDStr)
+	locationIDStr := c.Param("location_id")
+	locationID, err := uuid.Parse(locationIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
 		return
 	}
 
-	rec, err := h.appSvc.GetTransactionRecordByID(c.Request.Context(), userID, recordID)
+	loc, err := h.appSvc.GetLocationByID(c.Request.Context(), userID, locationID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, rec)
+	c.JSON(http.StatusOK, loc)
 }
 
-// UpdateTransactionRecordOrderName handles PUT /transaction-record/:record_id/order-name
-func (h *AppHandler) UpdateTransactionRecordOrderName(c *gin.Context) {
+// DeleteLocation handles DELETE /location/:location_id
+func (h *AppHandler) DeleteLocation(c *gin.Context) {
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -765,32 +770,42 @@ func (h *AppHandler) UpdateTransactionRecordOrderName(c *gin.Context) {
 	}
 	userID := userIDVal.(uuid.UUID)
 
-	recordIDStr := c.Param("record_id")
-	recordID, err := uuid.Parse(recordIDStr)
+	locationIDStr := c.Param("location_id")
+	locationID, err := uuid.Parse(locationIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
 		return
 	}
 
-	type reqBody struct {
-		NewOrderName string `json:"new_order_name"`
+	if err := h.appSvc.DeleteLocation(c.Request.Context(), userID, locationID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	var body reqBody
-	if err := c.ShouldBindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+	c.JSON(http.StatusOK, gin.H{"message": "location deleted"})
+}
+
+// ListLocations handles GET /locations
+func (h *AppHandler) ListLocations(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
+	userID := userIDVal.(uuid.UUID)
 
-	err = h.appSvc.UpdateTransactionRecordOrderName(c.Request.Context(), userID, recordID, body.NewOrderName)
+	var f repos.LocationFilter
+	// Optionally parse query params for filtering
+	f.Status = c.Query("status")
+	locations, err := h.appSvc.ListLocations(c.Request.Context(), userID, f)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "transaction record order_name updated"})
+	c.JSON(http.StatusOK, locations)
 }
 
-// UpdateTransactionRecordDescription handles PUT /transaction-record/:record_id/description
-func (h *AppHandler) UpdateTransactionRecordDescription(c *gin.Context) {
+// MoveLocation handles PUT /location/:location_id/path
+func (h *AppHandler) MoveLocation(c *gin.Context) {
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -798,15 +813,16 @@ func (h *AppHandler) UpdateTransactionRecordDescription(c *gin.Context) {
 	}
 	userID := userIDVal.(uuid.UUID)
 
-	recordIDStr := c.Param("record_id")
-	recordID, err := uuid.Parse(recordIDStr)
+	locationIDStr := c.Param("location_id")
+	locationID, err := uuid.Parse(locationIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
 		return
 	}
 
 	type reqBody struct {
-		NewDescription string `json:"new_description"`
+		NewLocationPath     string `json:"new_location_path"`
+		NewLocationNamePath string `json:"new_location_name_path"`
 	}
 	var body reqBody
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -814,16 +830,16 @@ func (h *AppHandler) UpdateTransactionRecordDescription(c *gin.Context) {
 		return
 	}
 
-	err = h.appSvc.UpdateTransactionRecordDescription(c.Request.Context(), userID, recordID, body.NewDescription)
+	loc, err := h.appSvc.MoveLocation(c.Request.Context(), userID, locationID, body.NewLocationPath, body.NewLocationNamePath)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "transaction record description updated"})
+	c.JSON(http.StatusOK, loc)
 }
 
-// UpdateTransactionRecordTransactionQuantity handles PUT /transaction-record/:record_id/transaction-quantity
-func (h *AppHandler) UpdateTransactionRecordTransactionQuantity(c *gin.Context) {
+// MergeLocations handles POST /location/:location_id/merge
+func (h *AppHandler) MergeLocations(c *gin.Context) {
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -831,32 +847,37 @@ func (h *AppHandler) UpdateTransactionRecordTransactionQuantity(c *gin.Context)
 	}
 	userID := userIDVal.(uuid.UUID)
 
-	recordIDStr := c.Param("record_id")
-	recordID, err := uuid.Parse(recordIDStr)
+	locationIDStr := c.Param("location_id")
+	locationID, err := uuid.Parse(locationIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
 		return
 	}
 
 	type reqBody struct {
-		NewTQuantity int64 `json:"new_transaction_quantity"`
+		TargetLocationID string `json:"target_location_id"`
 	}
 	var body reqBody
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		return
 	}
+	targetID, err := uuid.Parse(body.TargetLocationID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid target_location_id"})
+		return
+	}
 
-	err = h.appSvc.UpdateTransactionRecordTransactionQuantity(c.Request.Context(), userID, recordID, body.NewTQuantity)
+	loc, err := h.appSvc.MergeLocations(c.Request.Context(), userID, locationID, targetID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "transaction record transaction_quantity updated"})
+	c.JSON(http.StatusOK, loc)
 }
 
-// UpdateTransactionRecordCompletedQuantity handles PUT /transaction-record/:record_id/completed-quantity
-func (h *AppHandler) UpdateTransactionRecordCompletedQuantity(c *gin.Context) {
+// ListLocationAliases handles GET /location/:location_id/aliases
+func (h *AppHandler) ListLocationAliases(c *gin.Context) {
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -864,32 +885,23 @@ func (h *AppHandler) UpdateTransactionRecordCompletedQuantity(c *gin.Context) {
 	}
 	userID := userIDVal.(uuid.UUID)
 
-	recordIDStr := c.Param("record_id")
-	recordID, err := uuid.Parse(recordIDStr)
+	locationIDStr := c.Param("location_id")
+	locationID, err := uuid.Parse(locationIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
-		return
-	}
-
-	type reqBody struct {
-		NewCQuantity int64 `json:"new_completed_quantity"`
-	}
-	var body reqBody
-	if err := c.ShouldBindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
 		return
 	}
 
-	err = h.appSvc.UpdateTransactionRecordCompletedQuantity(c.Request.Context(), userID, recordID, body.NewCQuantity)
+	aliases, err := h.appSvc.ListLocationAliases(c.Request.Context(), userID, locationID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "transaction record completed_quantity updated"})
+	c.JSON(http.StatusOK, aliases)
 }
 
-// UpdateTransactionRecordCompletedDate handles PUT /transaction-record/:record_id/completed-date
-func (h *AppHandler) UpdateTransactionRecordCompletedDate(c *gin.Context) {
+// UpdateLocationStatus handles PUT /location/:location_id/status
+func (h *AppHandler) UpdateLocationStatus(c *gin.Context) {
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -897,15 +909,16 @@ func (h *AppHandler) UpdateTransactionRecordCompletedDate(c *gin.Context) {
 	}
 	userID := userIDVal.(uuid.UUID)
 
-	recordIDStr := c.Param("record_id")
-	recordID, err := uuid.Parse(recordIDStr)
+	locationIDStr := c.Param("location_id")
+	locationID, err := uuid.Parse(locationIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
 		return
 	}
 
 	type reqBody struct {
-		NewDateStr string `json:"new_completed_date"`
+		Status string `json:"status"`
+		Reason string `json:"reason"`
 	}
 	var body reqBody
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -913,27 +926,16 @@ func (h *AppHandler) UpdateTransactionRecordCompletedDate(c *gin.Context) {
 		return
 	}
 
-	var newDate time.Time
-	if body.NewDateStr != "" {
-		parsed, parseErr := time.Parse("2006-01-02", body.NewDateStr)
-		if parseErr == nil {
-			newDate = parsed
-		} else {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format (YYYY-MM-DD)"})
-			return
-		}
-	}
-
-	err = h.appSvc.UpdateTransactionRecordCompletedDate(c.Request.Context(), userID, recordID, newDate)
+	loc, err := h.appSvc.UpdateLocationStatus(c.Request.Context(), userID, locationID, body.Status, body.Reason)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "transaction record completed_date updated"})
+	c.JSON(http.StatusOK, loc)
 }
 
-// UpdateTransactionRecordTransactionType handles PUT /transaction-record/:record_id/transaction-type
-func (h *AppHandler) UpdateTransactionRecordTransactionType(c *gin.Context) {
+// LinkItemToLocation handles POST /location/:location_id/item/:item_id
+func (h *AppHandler) LinkItemToLocation(c *gin.Context) {
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -941,32 +943,36 @@ func (h *AppHandler) UpdateTransactionRecordTransactionType(c *gin.Context) {
 	}
 	userID := userIDVal.(uuid.UUID)
 
-	recordIDStr := c.Param("record_id")
-	recordID, err := uuid.Parse(recordIDStr)
+	locationIDStr := c.Param("location_id")
+	locationID, err := uuid.Parse(locationIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
 		return
 	}
-
-	type reqBody struct {
-		NewType string `json:"new_transaction_type"`
+	itemIDStr := c.Param("item_id")
+	itemID, err := uuid.Parse(itemIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid item_id"})
+		return
 	}
-	var body reqBody
-	if err := c.ShouldBindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+
+	// optional ?type=primary|overflow|reserve&effective_from=YYYY-MM-DD
+	effectiveFrom, err := parseDay("effective_from", c.Query("effective_from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	err = h.appSvc.UpdateTransactionRecordTransactionType(c.Request.Context(), userID, recordID, body.NewType)
+	violations, err := h.appSvc.LinkItemToLocation(c.Request.Context(), userID, locationID, itemID, c.Query("type"), effectiveFrom)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "transaction record transaction_type updated"})
+	c.JSON(http.StatusOK, gin.H{"violations": violations})
 }
 
-// ListTransactionRecords handles GET /transaction-records
-func (h *AppHandler) ListTransactionRecords(c *gin.Context) {
+// UnlinkItemFromLocation handles DELETE /location/:location_id/item/:item_id?effective_to=YYYY-MM-DD
+func (h *AppHandler) UnlinkItemFromLocation(c *gin.Context) {
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -974,210 +980,283 @@ func (h *AppHandler) ListTransactionRecords(c *gin.Context) {
 	}
 	userID := userIDVal.(uuid.UUID)
 
-	var f repos.TransactionRecordFilter
-	// parse query params if needed (e.g. f.StartDate, f.EndDate)
-	recs, err := h.appSvc.ListTransactionRecords(c.Request.Context(), userID, f)
+	locationID, err := uuid.Parse(c.Param("location_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
+		return
+	}
+	itemID, err := uuid.Parse(c.Param("item_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid item_id"})
+		return
+	}
+	effectiveTo, err := parseDay("effective_to", c.Query("effective_to"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, recs)
+
+	if err := h.appSvc.UnlinkItemFromLocation(c.Request.Context(), userID, locationID, itemID, effectiveTo); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "item unlinked"})
 }
 
-// ---------------------------------------------------------------------------
-// USER Handlers
-// ---------------------------------------------------------------------------
+type slotAttributesBody struct {
+	LocationPath  string  `json:"location_path"`
+	SlotRole      string  `json:"slot_role"`
+	SlotType      string  `json:"slot_type"`
+	Aisle         string  `json:"aisle"`
+	CoordX        float64 `json:"coord_x"`
+	CoordY        float64 `json:"coord_y"`
+	LevelHeightCm float64 `json:"level_height_cm"`
+	SlotWidthCm   float64 `json:"slot_width_cm"`
+	SlotDepthCm   float64 `json:"slot_depth_cm"`
+	SlotHeightCm  float64 `json:"slot_height_cm"`
+	MaxWeightKg   float64 `json:"max_weight_kg"`
+}
 
-// UpdateUserAvatar handles PUT /user/:user_id/avatar
-func (h *AppHandler) UpdateUserAvatar(c *gin.Context) {
-	// "user_id" in the route is the user being updated, but the context user might be the same or an admin
-	requestingUserIDVal, ok := c.Get("user_id")
+func (b slotAttributesBody) toModel() models.Location {
+	return models.Location{
+		LocationPath:  b.LocationPath,
+		SlotRole:      b.SlotRole,
+		SlotType:      b.SlotType,
+		Aisle:         b.Aisle,
+		CoordX:        b.CoordX,
+		CoordY:        b.CoordY,
+		LevelHeightCm: b.LevelHeightCm,
+		SlotWidthCm:   b.SlotWidthCm,
+		SlotDepthCm:   b.SlotDepthCm,
+		SlotHeightCm:  b.SlotHeightCm,
+		MaxWeightKg:   b.MaxWeightKg,
+	}
+}
+
+// UpdateLocationSlot handles PUT /location/:location_id/slot
+func (h *AppHandler) UpdateLocationSlot(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
-	requestingUserID := requestingUserIDVal.(uuid.UUID)
+	userID := userIDVal.(uuid.UUID)
 
-	paramUserIDStr := c.Param("user_id")
-	targetUserID, err := uuid.Parse(paramUserIDStr)
+	locationIDStr := c.Param("location_id")
+	locationID, err := uuid.Parse(locationIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
 		return
 	}
 
-	newAvatar, err := c.GetRawData()
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read avatar"})
+	var body slotAttributesBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		return
 	}
-
-	url, err := h.appSvc.UpdateUserAvatar(c.Request.Context(), targetUserID, newAvatar)
+	loc, err := h.appSvc.UpdateLocationSlot(c.Request.Context(), userID, locationID, body.toModel())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"avatar_url": url, "updated_for_user_id": targetUserID, "requested_by_user_id": requestingUserID})
+	c.JSON(http.StatusOK, loc)
 }
 
-// UpdateUserFirstName handles PUT /user/:user_id/first-name
-func (h *AppHandler) UpdateUserFirstName(c *gin.Context) {
-	requestingUserIDVal, ok := c.Get("user_id")
+// UpdateWarehouseSlots handles PUT /warehouse/:warehouse_id/slots
+func (h *AppHandler) UpdateWarehouseSlots(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
-	_ = requestingUserIDVal.(uuid.UUID)
+	userID := userIDVal.(uuid.UUID)
 
-	paramUserIDStr := c.Param("user_id")
-	targetUserID, err := uuid.Parse(paramUserIDStr)
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
 		return
 	}
 
 	type reqBody struct {
-		NewFirst string `json:"new_first_name"`
+		Slots []slotAttributesBody `json:"slots"`
 	}
 	var body reqBody
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		return
 	}
-
-	err = h.appSvc.UpdateUserFirstName(c.Request.Context(), targetUserID, body.NewFirst)
+	slots := make([]models.Location, 0, len(body.Slots))
+	for _, b := range body.Slots {
+		slots = append(slots, b.toModel())
+	}
+	updated, err := h.appSvc.UpdateWarehouseSlots(c.Request.Context(), userID, warehouseID, slots)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "updated": updated})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "user first_name updated"})
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
 }
 
-// UpdateUserLastName handles PUT /user/:user_id/last-name
-func (h *AppHandler) UpdateUserLastName(c *gin.Context) {
-	requestingUserIDVal, ok := c.Get("user_id")
+// ---------------------------------------------------------------------------
+// ZONE Handlers
+// ---------------------------------------------------------------------------
+
+type zoneBody struct {
+	Name                 string  `json:"name"`
+	PathPrefix           string  `json:"path_prefix"`
+	TemperatureClass     string  `json:"temperature_class"`
+	HazmatAllowed        bool    `json:"hazmat_allowed"`
+	MaxUnitWeightKg      float64 `json:"max_unit_weight_kg"`
+	WeightLimitFromLevel int     `json:"weight_limit_from_level"`
+	MaxItemCubeCm3       float64 `json:"max_item_cube_cm3"`
+}
+
+func (b zoneBody) toModel() models.Zone {
+	return models.Zone{
+		Name:                 b.Name,
+		PathPrefix:           b.PathPrefix,
+		TemperatureClass:     b.TemperatureClass,
+		HazmatAllowed:        b.HazmatAllowed,
+		MaxUnitWeightKg:      b.MaxUnitWeightKg,
+		WeightLimitFromLevel: b.WeightLimitFromLevel,
+		MaxItemCubeCm3:       b.MaxItemCubeCm3,
+	}
+}
+
+// CreateZone handles POST /warehouse/:warehouse_id/zone
+func (h *AppHandler) CreateZone(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
-	_ = requestingUserIDVal.(uuid.UUID)
+	userID := userIDVal.(uuid.UUID)
 
-	paramUserIDStr := c.Param("user_id")
-	targetUserID, err := uuid.Parse(paramUserIDStr)
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
 		return
 	}
 
-	type reqBody struct {
-		NewLast string `json:"new_last_name"`
-	}
-	var body reqBody
+	var body zoneBody
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		return
 	}
 
-	err = h.appSvc.UpdateUserLastName(c.Request.Context(), targetUserID, body.NewLast)
+	zone, err := h.appSvc.CreateZone(c.Request.Context(), userID, warehouseID, body.toModel())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "user last_name updated"})
+	c.JSON(http.StatusOK, zone)
 }
 
-// UpdateUserEmail handles PUT /user/:user_id/email
-func (h *AppHandler) UpdateUserEmail(c *gin.Context) {
-	requestingUserIDVal, ok := c.Get("user_id")
+// UpdateZone handles PUT /zone/:zone_id
+func (h *AppHandler) UpdateZone(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
-	_ = requestingUserIDVal.(uuid.UUID)
+	userID := userIDVal.(uuid.UUID)
 
-	paramUserIDStr := c.Param("user_id")
-	targetUserID, err := uuid.Parse(paramUserIDStr)
+	zoneIDStr := c.Param("zone_id")
+	zoneID, err := uuid.Parse(zoneIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid zone_id"})
 		return
 	}
 
-	type reqBody struct {
-		NewEmail string `json:"new_email"`
-	}
-	var body reqBody
+	var body zoneBody
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		return
 	}
 
-	err = h.appSvc.UpdateUserEmail(c.Request.Context(), targetUserID, body.NewEmail)
+	zone, err := h.appSvc.UpdateZone(c.Request.Context(), userID, zoneID, body.toModel())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "user email updated"})
+	c.JSON(http.StatusOK, zone)
 }
 
-// UpdateUserPassword handles PUT /user/:user_id/password
-func (h *AppHandler) UpdateUserPassword(c *gin.Context) {
-	requestingUserIDVal, ok := c.Get("user_id")
+// DeleteZone handles DELETE /zone/:zone_id
+func (h *AppHandler) DeleteZone(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
-	requestingUserID := requestingUserIDVal.(uuid.UUID)
+	userID := userIDVal.(uuid.UUID)
 
-	paramUserIDStr := c.Param("user_id")
-	targetUserID, err := uuid.Parse(paramUserIDStr)
+	zoneIDStr := c.Param("zone_id")
+	zoneID, err := uuid.Parse(zoneIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid zone_id"})
 		return
 	}
 
-	type reqBody struct {
-		NewPassword string `json:"new_password"`
+	if err := h.appSvc.DeleteZone(c.Request.Context(), userID, zoneID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	var body reqBody
-	if err := c.ShouldBindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+	c.JSON(http.StatusOK, gin.H{"message": "zone deleted"})
+}
+
+// ListZones handles GET /warehouse/:warehouse_id/zones
+func (h *AppHandler) ListZones(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
+	userID := userIDVal.(uuid.UUID)
 
-	// Usually you'd check requestingUserID == targetUserID or if an admin.
-	err = h.appSvc.UpdateUserPassword(c.Request.Context(), requestingUserID, targetUserID, body.NewPassword)
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	zones, err := h.appSvc.ListZones(c.Request.Context(), userID, warehouseID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "user password updated"})
+	c.JSON(http.StatusOK, zones)
 }
 
-// DeleteUser handles DELETE /user/:user_id
-func (h *AppHandler) DeleteUser(c *gin.Context) {
-	requestingUserIDVal, ok := c.Get("user_id")
+// RecheckZoneViolations handles POST /warehouse/:warehouse_id/zone-violations/recheck
+func (h *AppHandler) RecheckZoneViolations(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
-	requestingUserID := requestingUserIDVal.(uuid.UUID)
+	userID := userIDVal.(uuid.UUID)
 
-	paramUserIDStr := c.Param("user_id")
-	targetUserID, err := uuid.Parse(paramUserIDStr)
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
 		return
 	}
 
-	err = h.appSvc.DeleteUser(c.Request.Context(), requestingUserID, targetUserID)
+	count, err := h.appSvc.RecheckZoneViolations(c.Request.Context(), userID, warehouseID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "user deleted"})
+	c.JSON(http.StatusOK, gin.H{"violation_count": count})
 }
 
-// ListUsers handles GET /users
-func (h *AppHandler) ListUsers(c *gin.Context) {
+// ListZoneViolations handles GET /zone-violations
+func (h *AppHandler) ListZoneViolations(c *gin.Context) {
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -1185,22 +1264,46 @@ func (h *AppHandler) ListUsers(c *gin.Context) {
 	}
 	userID := userIDVal.(uuid.UUID)
 
-	var f repos.UserFilter
-	// parse optional query string e.g. f.EmailLike, f.Role, etc.
-	users, err := h.appSvc.ListUsers(c.Request.Context(), userID, f)
+	var f repos.ZoneViolationFilter
+	if v := c.Query("warehouse_id"); v != "" {
+		if id, err := uuid.Parse(v); err == nil {
+			f.WarehouseID = id
+		}
+	}
+	if v := c.Query("zone_id"); v != "" {
+		if id, err := uuid.Parse(v); err == nil {
+			f.ZoneID = id
+		}
+	}
+	if v := c.Query("location_id"); v != "" {
+		if id, err := uuid.Parse(v); err == nil {
+			f.LocationID = id
+		}
+	}
+	if v := c.Query("item_id"); v != "" {
+		if id, err := uuid.Parse(v); err == nil {
+			f.ItemID = id
+		}
+	}
+	f.Rule = c.Query("rule")
+	f.Source = c.Query("source")
+	f.IncludeResolved = c.Query("include_resolved") == "true"
+	f.SortField = c.Query("sort")
+	f.SortDir = c.Query("dir")
+	violations, err := h.appSvc.ListZoneViolations(c.Request.Context(), userID, f)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, users)
+	c.JSON(http.StatusOK, violations)
 }
 
 // ---------------------------------------------------------------------------
-// ITEM Handler
+// TRANSACTION FILE Handlers
 // ---------------------------------------------------------------------------
 
-// ListItems handles GET /items
-func (h *AppHandler) ListItems(c *gin.Context) {
+// UploadTransactionFile handles POST /warehouse/:warehouse_id/transaction-file/upload
+func (h *AppHandler) UploadTransactionFile(c *gin.Context) {
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -1208,13 +1311,3230 @@ func (h *AppHandler) ListItems(c *gin.Context) {
 	}
 	userID := userIDVal.(uuid.UUID)
 
-	var f repos.ItemFilter
-	// parse query params if needed
-	items, err := h.appSvc.ListItems(c.Request.Context(), userID, f)
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
 		return
 	}
-	c.JSON(http.StatusOK, items)
-}
 
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing file"})
+		return
+	}
+
+	fileName := fileHeader.Filename
+	fileData, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open file"})
+		return
+	}
+	defer fileData.Close()
+
+	buf := make([]byte, fileHeader.Size)
+	_, err = fileData.Read(buf)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read file data"})
+		return
+	}
+
+	tf, err := h.appSvc.UploadTransactionFile(c.Request.Context(), userID, warehouseID, fileName, buf)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tf)
+}
+
+// UpdateTransactionFileName handles PUT /transaction-file/:file_id/name
+func (h *AppHandler) UpdateTransactionFileName(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	fileIDStr := c.Param("file_id")
+	fileID, err := uuid.Parse(fileIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file_id"})
+		return
+	}
+
+	type reqBody struct {
+		NewName string `json:"new_name"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	err = h.appSvc.UpdateTransactionFileName(c.Request.Context(), userID, fileID, body.NewName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "transaction file name updated"})
+}
+
+// DeleteTransactionFile handles DELETE /transaction-file/:file_id
+func (h *AppHandler) DeleteTransactionFile(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	fileIDStr := c.Param("file_id")
+	fileID, err := uuid.Parse(fileIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file_id"})
+		return
+	}
+
+	err = h.appSvc.DeleteTransactionFile(c.Request.Context(), userID, fileID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "transaction file deleted"})
+}
+
+// ListTransactionFiles handles GET /transaction-files
+func (h *AppHandler) ListTransactionFiles(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	var f repos.TransactionFileFilter
+	// parse any query params if needed
+	tfiles, err := h.appSvc.ListTransactionFiles(c.Request.Context(), userID, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tfiles)
+}
+
+// ---------------------------------------------------------------------------
+// TRANSACTION RECORD Handlers
+// ---------------------------------------------------------------------------
+
+// CreateTransactionRecord handles POST /warehouse/:warehouse_id/transaction-record
+func (h *AppHandler) CreateTransactionRecord(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	type reqBody struct {
+		TransactionType  string `json:"transaction_type"`
+		OrderName        string `json:"order_name"`
+		Description      string `json:"description"`
+		TransactionQ     int64  `json:"transaction_quantity"`
+		CompletedQ       int64  `json:"completed_quantity"`
+		CompletedDateStr string `json:"completed_date"` // parse to time
+		LocationPath     string `json:"location_path"`
+		LocationNamePath string `json:"location_name_path"`
+		ItemName         string `json:"item_name"`
+	}
+
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	var completedDate time.Time
+	if body.CompletedDateStr != "" {
+		cd, parseErr := time.Parse("2006-01-02", body.CompletedDateStr)
+		if parseErr == nil {
+			completedDate = cd
+		}
+	}
+
+	err = h.appSvc.CreateTransactionRecord(
+		c.Request.Context(),
+		userID,
+		warehouseID,
+		body.TransactionType,
+		body.OrderName,
+		body.Description,
+		body.TransactionQ,
+		body.CompletedQ,
+		completedDate,
+		body.LocationPath,
+		body.LocationNamePath,
+		body.ItemName,
+	)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "transaction record created"})
+}
+
+// GetTransactionRecordByID handles GET /transaction-record/:record_id
+func (h *AppHandler) GetTransactionRecordByID(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	recordIDStr := c.Param("record_id")
+	recordID, err := uuid.Parse(recordIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
+		return
+	}
+
+	rec, err := h.appSvc.GetTransactionRecordByID(c.Request.Context(), userID, recordID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rec)
+}
+
+// UpdateTransactionRecordOrderName handles PUT /transaction-record/:record_id/order-name
+func (h *AppHandler) UpdateTransactionRecordOrderName(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	recordIDStr := c.Param("record_id")
+	recordID, err := uuid.Parse(recordIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
+		return
+	}
+
+	type reqBody struct {
+		NewOrderName string `json:"new_order_name"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	err = h.appSvc.UpdateTransactionRecordOrderName(c.Request.Context(), userID, recordID, body.NewOrderName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "transaction record order_name updated"})
+}
+
+// UpdateTransactionRecordDescription handles PUT /transaction-record/:record_id/description
+func (h *AppHandler) UpdateTransactionRecordDescription(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	recordIDStr := c.Param("record_id")
+	recordID, err := uuid.Parse(recordIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
+		return
+	}
+
+	type reqBody struct {
+		NewDescription string `json:"new_description"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	err = h.appSvc.UpdateTransactionRecordDescription(c.Request.Context(), userID, recordID, body.NewDescription)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "transaction record description updated"})
+}
+
+// UpdateTransactionRecordTransactionQuantity handles PUT /transaction-record/:record_id/transaction-quantity
+func (h *AppHandler) UpdateTransactionRecordTransactionQuantity(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	recordIDStr := c.Param("record_id")
+	recordID, err := uuid.Parse(recordIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
+		return
+	}
+
+	type reqBody struct {
+		NewTQuantity int64 `json:"new_transaction_quantity"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	err = h.appSvc.UpdateTransactionRecordTransactionQuantity(c.Request.Context(), userID, recordID, body.NewTQuantity)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "transaction record transaction_quantity updated"})
+}
+
+// UpdateTransactionRecordCompletedQuantity handles PUT /transaction-record/:record_id/completed-quantity
+func (h *AppHandler) UpdateTransactionRecordCompletedQuantity(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	recordIDStr := c.Param("record_id")
+	recordID, err := uuid.Parse(recordIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
+		return
+	}
+
+	type reqBody struct {
+		NewCQuantity int64 `json:"new_completed_quantity"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	err = h.appSvc.UpdateTransactionRecordCompletedQuantity(c.Request.Context(), userID, recordID, body.NewCQuantity)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "transaction record completed_quantity updated"})
+}
+
+// UpdateTransactionRecordCompletedDate handles PUT /transaction-record/:record_id/completed-date
+func (h *AppHandler) UpdateTransactionRecordCompletedDate(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	recordIDStr := c.Param("record_id")
+	recordID, err := uuid.Parse(recordIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
+		return
+	}
+
+	type reqBody struct {
+		NewDateStr string `json:"new_completed_date"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	var newDate time.Time
+	if body.NewDateStr != "" {
+		parsed, parseErr := time.Parse("2006-01-02", body.NewDateStr)
+		if parseErr == nil {
+			newDate = parsed
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format (YYYY-MM-DD)"})
+			return
+		}
+	}
+
+	err = h.appSvc.UpdateTransactionRecordCompletedDate(c.Request.Context(), userID, recordID, newDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "transaction record completed_date updated"})
+}
+
+// UpdateTransactionRecordTransactionType handles PUT /transaction-record/:record_id/transaction-type
+func (h *AppHandler) UpdateTransactionRecordTransactionType(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	recordIDStr := c.Param("record_id")
+	recordID, err := uuid.Parse(recordIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
+		return
+	}
+
+	type reqBody struct {
+		NewType string `json:"new_transaction_type"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	err = h.appSvc.UpdateTransactionRecordTransactionType(c.Request.Context(), userID, recordID, body.NewType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "transaction record transaction_type updated"})
+}
+
+// ListTransactionRecords handles GET /transaction-records
+func (h *AppHandler) ListTransactionRecords(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	var f repos.TransactionRecordFilter
+	// parse query params if needed (e.g. f.StartDate, f.EndDate)
+	recs, err := h.appSvc.ListTransactionRecords(c.Request.Context(), userID, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, recs)
+}
+
+// ---------------------------------------------------------------------------
+// USER Handlers
+// ---------------------------------------------------------------------------
+
+// UpdateUserAvatar handles PUT /user/:user_id/avatar
+func (h *AppHandler) UpdateUserAvatar(c *gin.Context) {
+	// "user_id" in the route is the user being updated, but the context user might be the same or an admin
+	requestingUserIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	requestingUserID := requestingUserIDVal.(uuid.UUID)
+
+	paramUserIDStr := c.Param("user_id")
+	targetUserID, err := uuid.Parse(paramUserIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	newAvatar, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read avatar"})
+		return
+	}
+
+	url, err := h.appSvc.UpdateUserAvatar(c.Request.Context(), targetUserID, newAvatar)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"avatar_url": url, "updated_for_user_id": targetUserID, "requested_by_user_id": requestingUserID})
+}
+
+// UpdateUserFirstName handles PUT /user/:user_id/first-name
+func (h *AppHandler) UpdateUserFirstName(c *gin.Context) {
+	requestingUserIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	_ = requestingUserIDVal.(uuid.UUID)
+
+	paramUserIDStr := c.Param("user_id")
+	targetUserID, err := uuid.Parse(paramUserIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	type reqBody struct {
+		NewFirst string `json:"new_first_name"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	err = h.appSvc.UpdateUserFirstName(c.Request.Context(), targetUserID, body.NewFirst)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "user first_name updated"})
+}
+
+// UpdateUserLastName handles PUT /user/:user_id/last-name
+func (h *AppHandler) UpdateUserLastName(c *gin.Context) {
+	requestingUserIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	_ = requestingUserIDVal.(uuid.UUID)
+
+	paramUserIDStr := c.Param("user_id")
+	targetUserID, err := uuid.Parse(paramUserIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	type reqBody struct {
+		NewLast string `json:"new_last_name"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	err = h.appSvc.UpdateUserLastName(c.Request.Context(), targetUserID, body.NewLast)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "user last_name updated"})
+}
+
+// UpdateUserEmail handles PUT /user/:user_id/email
+func (h *AppHandler) UpdateUserEmail(c *gin.Context) {
+	requestingUserIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	_ = requestingUserIDVal.(uuid.UUID)
+
+	paramUserIDStr := c.Param("user_id")
+	targetUserID, err := uuid.Parse(paramUserIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	type reqBody struct {
+		NewEmail string `json:"new_email"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	err = h.appSvc.UpdateUserEmail(c.Request.Context(), targetUserID, body.NewEmail)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "user email updated"})
+}
+
+// UpdateUserPassword handles PUT /user/:user_id/password
+func (h *AppHandler) UpdateUserPassword(c *gin.Context) {
+	requestingUserIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	requestingUserID := requestingUserIDVal.(uuid.UUID)
+
+	paramUserIDStr := c.Param("user_id")
+	targetUserID, err := uuid.Parse(paramUserIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	type reqBody struct {
+		NewPassword string `json:"new_password"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	// Usually you'd check requestingUserID == targetUserID or if an admin.
+	err = h.appSvc.UpdateUserPassword(c.Request.Context(), requestingUserID, targetUserID, body.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "user password updated"})
+}
+
+// DeleteUser handles DELETE /user/:user_id
+func (h *AppHandler) DeleteUser(c *gin.Context) {
+	requestingUserIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	requestingUserID := requestingUserIDVal.(uuid.UUID)
+
+	paramUserIDStr := c.Param("user_id")
+	targetUserID, err := uuid.Parse(paramUserIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	err = h.appSvc.DeleteUser(c.Request.Context(), requestingUserID, targetUserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "user deleted"})
+}
+
+// ListUsers handles GET /users
+func (h *AppHandler) ListUsers(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	var f repos.UserFilter
+	// parse optional query string e.g. f.EmailLike, f.Role, etc.
+	users, err := h.appSvc.ListUsers(c.Request.Context(), userID, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+// ---------------------------------------------------------------------------
+// ITEM Handler
+// ---------------------------------------------------------------------------
+
+// ListItems handles GET /items
+func (h *AppHandler) ListItems(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	var f repos.ItemFilter
+	// parse query params if needed
+	items, err := h.appSvc.ListItems(c.Request.Context(), userID, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// UpdateItemAttributes handles PUT /item/:item_id/attributes
+func (h *AppHandler) UpdateItemAttributes(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	itemIDStr := c.Param("item_id")
+	itemID, err := uuid.Parse(itemIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid item_id"})
+		return
+	}
+
+	type reqBody struct {
+		UnitWeightKg     float64 `json:"unit_weight_kg"`
+		LengthCm         float64 `json:"length_cm"`
+		WidthCm          float64 `json:"width_cm"`
+		HeightCm         float64 `json:"height_cm"`
+		TemperatureClass string  `json:"temperature_class"`
+		Hazmat           bool    `json:"hazmat"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	attrs := models.Item{
+		UnitWeightKg:     body.UnitWeightKg,
+		LengthCm:         body.LengthCm,
+		WidthCm:          body.WidthCm,
+		HeightCm:         body.HeightCm,
+		TemperatureClass: body.TemperatureClass,
+		Hazmat:           body.Hazmat,
+	}
+	item, err := h.appSvc.UpdateItemAttributes(c.Request.Context(), userID, itemID, attrs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// ---------------------------------------------------------------------------
+// LABEL Handlers
+// ---------------------------------------------------------------------------
+
+type labelTemplateBody struct {
+	Name       string  `json:"name"`
+	Symbology  string  `json:"symbology"`
+	WidthMM    float64 `json:"width_mm"`
+	HeightMM   float64 `json:"height_mm"`
+	DPI        int     `json:"dpi"`
+	TextSizeMM float64 `json:"text_size_mm"`
+	HideText   bool    `json:"hide_text"`
+	PageSize   string  `json:"page_size"`
+	Columns    int     `json:"columns"`
+	Rows       int     `json:"rows"`
+	MarginMM   float64 `json:"margin_mm"`
+	GapMM      float64 `json:"gap_mm"`
+}
+
+func (b labelTemplateBody) toModel() models.LabelTemplate {
+	return models.LabelTemplate{
+		Name:       b.Name,
+		Symbology:  b.Symbology,
+		WidthMM:    b.WidthMM,
+		HeightMM:   b.HeightMM,
+		DPI:        b.DPI,
+		TextSizeMM: b.TextSizeMM,
+		HideText:   b.HideText,
+		PageSize:   b.PageSize,
+		Columns:    b.Columns,
+		Rows:       b.Rows,
+		MarginMM:   b.MarginMM,
+		GapMM:      b.GapMM,
+	}
+}
+
+// CreateLabelTemplate handles POST /label-template
+func (h *AppHandler) CreateLabelTemplate(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	var body labelTemplateBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	tpl, err := h.appSvc.CreateLabelTemplate(c.Request.Context(), userID, body.toModel())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tpl)
+}
+
+// UpdateLabelTemplate handles PUT /label-template/:template_id
+func (h *AppHandler) UpdateLabelTemplate(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	templateIDStr := c.Param("template_id")
+	templateID, err := uuid.Parse(templateIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template_id"})
+		return
+	}
+
+	var body labelTemplateBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	tpl, err := h.appSvc.UpdateLabelTemplate(c.Request.Context(), userID, templateID, body.toModel())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tpl)
+}
+
+// DeleteLabelTemplate handles DELETE /label-template/:template_id
+func (h *AppHandler) DeleteLabelTemplate(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	templateIDStr := c.Param("template_id")
+	templateID, err := uuid.Parse(templateIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template_id"})
+		return
+	}
+
+	if err := h.appSvc.DeleteLabelTemplate(c.Request.Context(), userID, templateID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "label template deleted"})
+}
+
+// ListLabelTemplates handles GET /label-templates
+func (h *AppHandler) ListLabelTemplates(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	tpls, err := h.appSvc.ListLabelTemplates(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tpls)
+}
+
+// GenerateLocationLabels handles POST /warehouse/:warehouse_id/labels/locations
+func (h *AppHandler) GenerateLocationLabels(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	type reqBody struct {
+		TemplateID  uuid.UUID   `json:"template_id"`
+		Format      string      `json:"format"`
+		LocationIDs []uuid.UUID `json:"location_ids"`
+		SubtreePath string      `json:"subtree_path"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	batch, err := h.appSvc.GenerateLocationLabels(c.Request.Context(), userID, warehouseID, body.TemplateID, body.Format, body.LocationIDs, body.SubtreePath)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
+
+// GenerateItemLabels handles POST /labels/items
+func (h *AppHandler) GenerateItemLabels(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	type reqBody struct {
+		TemplateID uuid.UUID   `json:"template_id"`
+		Format     string      `json:"format"`
+		ItemIDs    []uuid.UUID `json:"item_ids"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	batch, err := h.appSvc.GenerateItemLabels(c.Request.Context(), userID, body.TemplateID, body.Format, body.ItemIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
+
+// GetLabelBatch handles GET /label-batch/:batch_id
+func (h *AppHandler) GetLabelBatch(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	batchIDStr := c.Param("batch_id")
+	batchID, err := uuid.Parse(batchIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid batch_id"})
+		return
+	}
+
+	batch, err := h.appSvc.GetLabelBatch(c.Request.Context(), userID, batchID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
+
+// ListLabelBatches handles GET /label-batches
+func (h *AppHandler) ListLabelBatches(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	var f repos.LabelBatchFilter
+	if wh := c.Query("warehouse_id"); wh != "" {
+		if id, err := uuid.Parse(wh); err == nil {
+			f.WarehouseID = id
+		}
+	}
+	f.Subject = c.Query("subject")
+	f.Format = c.Query("format")
+	f.SortField = c.Query("sort")
+	f.SortDir = c.Query("dir")
+	batches, err := h.appSvc.ListLabelBatches(c.Request.Context(), userID, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, batches)
+}
+
+// ---------------------------------------------------------------------------
+// ANALYTICS Handlers
+// ---------------------------------------------------------------------------
+
+// GetItemVelocity handles GET /warehouse/:warehouse_id/analytics/velocity
+// Query: start_date, end_date (YYYY-MM-DD), types (comma separated categories,
+// default pick), sort, dir, limit, offset, format=xlsx for a download.
+func (h *AppHandler) GetItemVelocity(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	var q services.VelocityQuery
+	q.StartDate, q.EndDate, err = parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	q.TransactionTypes = splitQueryList(c.Query("types"))
+	q.SortField = c.Query("sort")
+	q.SortDir = c.Query("dir")
+	q.Limit, _ = strconv.Atoi(c.Query("limit"))
+	q.Offset, _ = strconv.Atoi(c.Query("offset"))
+
+	if c.Query("format") == "xlsx" {
+		data, fileName, err := h.appSvc.ExportItemVelocity(c.Request.Context(), userID, warehouseID, q)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		sendXLSX(c, fileName, data)
+		return
+	}
+	report, err := h.appSvc.GetItemVelocity(c.Request.Context(), userID, warehouseID, q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetItemAffinity handles GET /warehouse/:warehouse_id/analytics/affinity
+// Query: start_date, end_date (YYYY-MM-DD), min_co_orders, min_lift,
+// max_order_items, max_cluster_size, limit, offset (pairs).
+func (h *AppHandler) GetItemAffinity(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	var q services.AffinityQuery
+	q.StartDate, q.EndDate, err = parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	q.MinCoOrders, _ = strconv.Atoi(c.Query("min_co_orders"))
+	q.MinLift, _ = strconv.ParseFloat(c.Query("min_lift"), 64)
+	q.MaxOrderItems, _ = strconv.Atoi(c.Query("max_order_items"))
+	q.MaxClusterSize, _ = strconv.Atoi(c.Query("max_cluster_size"))
+	q.Limit, _ = strconv.Atoi(c.Query("limit"))
+	q.Offset, _ = strconv.Atoi(c.Query("offset"))
+	report, err := h.appSvc.GetItemAffinity(c.Request.Context(), userID, warehouseID, q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetOrderProfile handles GET /warehouse/:warehouse_id/analytics/order-profile
+// Query: start_date, end_date (YYYY-MM-DD), types (comma separated categories
+// forming orders, default pick), format=xlsx for a download.
+func (h *AppHandler) GetOrderProfile(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	var q services.OrderProfileQuery
+	q.StartDate, q.EndDate, err = parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	q.TransactionTypes = splitQueryList(c.Query("types"))
+
+	if c.Query("format") == "xlsx" {
+		data, fileName, err := h.appSvc.ExportOrderProfile(c.Request.Context(), userID, warehouseID, q)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		sendXLSX(c, fileName, data)
+		return
+	}
+	profile, err := h.appSvc.GetOrderProfile(c.Request.Context(), userID, warehouseID, q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// GetSlowStock handles GET /warehouse/:warehouse_id/slow-stock
+// Query: as_of (YYYY-MM-DD), dead_days, window_days, drop_pct,
+// min_prior_lines, status (dead | slow), sort, dir, limit, offset.
+// Thresholds left out come from the warehouse's slow stock setting.
+func (h *AppHandler) GetSlowStock(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+	q, err := parseSlowStockQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	report, err := h.appSvc.GetSlowStock(c.Request.Context(), userID, warehouseID, q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ScanSlowStock handles POST /warehouse/:warehouse_id/slow-stock/scan
+// Takes the same query parameters as GetSlowStock.
+func (h *AppHandler) ScanSlowStock(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+	q, err := parseSlowStockQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	report, changes, err := h.appSvc.ScanSlowStock(c.Request.Context(), userID, warehouseID, q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"report": report, "changes": changes})
+}
+
+// GetSlowStockSetting handles GET /warehouse/:warehouse_id/slow-stock-setting
+func (h *AppHandler) GetSlowStockSetting(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	st, err := h.appSvc.GetSlowStockSetting(c.Request.Context(), userID, warehouseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, st)
+}
+
+// SetSlowStockSetting handles PUT /warehouse/:warehouse_id/slow-stock-setting
+// Fields left out of the body keep their current value.
+func (h *AppHandler) SetSlowStockSetting(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	type reqBody struct {
+		DeadDays      *int     `json:"dead_days"`
+		WindowDays    *int     `json:"window_days"`
+		DropPct       *float64 `json:"drop_pct"`
+		MinPriorLines *int64   `json:"min_prior_lines"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	current, err := h.appSvc.GetSlowStockSetting(c.Request.Context(), userID, warehouseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	st := *current
+	if body.DeadDays != nil {
+		st.DeadDays = *body.DeadDays
+	}
+	if body.WindowDays != nil {
+		st.WindowDays = *body.WindowDays
+	}
+	if body.DropPct != nil {
+		st.DropPct = *body.DropPct
+	}
+	if body.MinPriorLines != nil {
+		st.MinPriorLines = *body.MinPriorLines
+	}
+
+	saved, err := h.appSvc.SetSlowStockSetting(c.Request.Context(), userID, warehouseID, st)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, saved)
+}
+
+// ResetSlowStockSetting handles DELETE /warehouse/:warehouse_id/slow-stock-setting
+// and returns the defaults the warehouse is back on.
+func (h *AppHandler) ResetSlowStockSetting(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	st, err := h.appSvc.ResetSlowStockSetting(c.Request.Context(), userID, warehouseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, st)
+}
+
+// GetHeatmap handles GET /warehouse/:warehouse_id/heatmap
+// Query: start_date, end_date (YYYY-MM-DD), types (comma separated categories,
+// default pick), depth (0 = per location, N = per path prefix of N segments).
+func (h *AppHandler) GetHeatmap(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	var q services.HeatmapQuery
+	q.StartDate, q.EndDate, err = parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	q.TransactionTypes = splitQueryList(c.Query("types"))
+	if v := c.Query("depth"); v != "" {
+		if q.Depth, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid depth"})
+			return
+		}
+	}
+
+	heatmap, err := h.appSvc.GetHeatmap(c.Request.Context(), userID, warehouseID, q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, heatmap)
+}
+
+// GetRollupStatus handles GET /warehouse/:warehouse_id/rollups
+func (h *AppHandler) GetRollupStatus(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	status, err := h.appSvc.GetRollupStatus(c.Request.Context(), userID, warehouseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// RebuildRollups handles POST /warehouse/:warehouse_id/rollups/rebuild
+func (h *AppHandler) RebuildRollups(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	job, err := h.appSvc.RebuildRollups(c.Request.Context(), userID, warehouseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetProductivity handles GET /warehouse/:warehouse_id/productivity
+// Query: start_date, end_date (YYYY-MM-DD), types (comma separated categories,
+// default pick), operator, idle_minutes (default 30), format=xlsx for a download.
+func (h *AppHandler) GetProductivity(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	var q services.ProductivityQuery
+	q.StartDate, q.EndDate, err = parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	q.TransactionTypes = splitQueryList(c.Query("types"))
+	q.Operator = c.Query("operator")
+	if v := c.Query("idle_minutes"); v != "" {
+		if q.IdleMinutes, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid idle_minutes"})
+			return
+		}
+	}
+
+	if c.Query("format") == "xlsx" {
+		data, fileName, err := h.appSvc.ExportProductivity(c.Request.Context(), userID, warehouseID, q)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		sendXLSX(c, fileName, data)
+		return
+	}
+	report, err := h.appSvc.GetProductivity(c.Request.Context(), userID, warehouseID, q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ListLaborStandards handles GET /warehouse/:warehouse_id/labor-standards
+func (h *AppHandler) ListLaborStandards(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	standards, err := h.appSvc.ListLaborStandards(c.Request.Context(), userID, warehouseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, standards)
+}
+
+// SetLaborStandard handles PUT /warehouse/:warehouse_id/labor-standard
+func (h *AppHandler) SetLaborStandard(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	type reqBody struct {
+		TransactionType string  `json:"transaction_type"`
+		SecondsPerLine  float64 `json:"seconds_per_line"`
+		SecondsPerUnit  float64 `json:"seconds_per_unit"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	std := models.LaborStandard{TransactionType: body.TransactionType, SecondsPerLine: body.SecondsPerLine, SecondsPerUnit: body.SecondsPerUnit}
+	saved, err := h.appSvc.SetLaborStandard(c.Request.Context(), userID, warehouseID, std)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, saved)
+}
+
+// DeleteLaborStandard handles DELETE /warehouse/:warehouse_id/labor-standard/:transaction_type
+func (h *AppHandler) DeleteLaborStandard(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	if err := h.appSvc.DeleteLaborStandard(c.Request.Context(), userID, warehouseID, c.Param("transaction_type")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "labor standard deleted"})
+}
+
+// ScanTransactionExceptions handles POST /warehouse/:warehouse_id/exceptions/scan
+// Query: start_date, end_date (YYYY-MM-DD) and z (default 3) for the volume
+// outlier check, min_active_days (default 5).
+func (h *AppHandler) ScanTransactionExceptions(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	var q services.ExceptionQuery
+	q.StartDate, q.EndDate, err = parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if v := c.Query("z"); v != "" {
+		if q.ZThreshold, err = strconv.ParseFloat(v, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid z"})
+			return
+		}
+	}
+	if v := c.Query("min_active_days"); v != "" {
+		if q.MinActiveDays, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid min_active_days"})
+			return
+		}
+	}
+
+	scan, err := h.appSvc.ScanTransactionExceptions(c.Request.Context(), userID, warehouseID, q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, scan)
+}
+
+// ListTransactionExceptions handles GET /transaction-exceptions
+func (h *AppHandler) ListTransactionExceptions(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	var f repos.ExceptionFilter
+	if v := c.Query("warehouse_id"); v != "" {
+		if id, err := uuid.Parse(v); err == nil {
+			f.WarehouseID = id
+		}
+	}
+	if v := c.Query("item_id"); v != "" {
+		if id, err := uuid.Parse(v); err == nil {
+			f.ItemID = id
+		}
+	}
+	if v := c.Query("location_id"); v != "" {
+		if id, err := uuid.Parse(v); err == nil {
+			f.LocationID = id
+		}
+	}
+	if v := c.Query("record_id"); v != "" {
+		if id, err := uuid.Parse(v); err == nil {
+			f.TransactionRecordID = id
+		}
+	}
+	f.ExceptionType = c.Query("type")
+	f.IncludeAcknowledged = c.Query("include_acknowledged") == "true"
+	f.IncludeResolved = c.Query("include_resolved") == "true"
+	f.SortField = c.Query("sort")
+	f.SortDir = c.Query("dir")
+	exceptions, err := h.appSvc.ListTransactionExceptions(c.Request.Context(), userID, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, exceptions)
+}
+
+// AcknowledgeTransactionExceptions handles PUT /warehouse/:warehouse_id/exceptions/acknowledge
+func (h *AppHandler) AcknowledgeTransactionExceptions(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	type reqBody struct {
+		ExceptionIDs []uuid.UUID `json:"exception_ids"`
+		Note         string      `json:"note"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	acknowledged, err := h.appSvc.AcknowledgeTransactionExceptions(c.Request.Context(), userID, warehouseID, body.ExceptionIDs, body.Note)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"acknowledged": acknowledged})
+}
+
+// SuggestPutaway handles GET /warehouse/:warehouse_id/putaway-suggestions
+// Query: item_id, quantity, limit (default 10, at most 100).
+func (h *AppHandler) SuggestPutaway(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	var q services.PutawayQuery
+	if q.ItemID, err = uuid.Parse(c.Query("item_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid item_id"})
+		return
+	}
+	if q.Quantity, err = strconv.ParseInt(c.Query("quantity"), 10, 64); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid quantity"})
+		return
+	}
+	q.Limit, _ = strconv.Atoi(c.Query("limit"))
+
+	suggestion, err := h.appSvc.SuggestPutaway(c.Request.Context(), userID, warehouseID, q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, suggestion)
+}
+
+// SuggestRecordPutaway handles GET /transaction-record/:record_id/putaway-suggestions
+// for a receipt, leaving out the location it was received at.
+// Query: limit (default 10, at most 100).
+func (h *AppHandler) SuggestRecordPutaway(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	recordID, err := uuid.Parse(c.Param("record_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	suggestion, err := h.appSvc.SuggestRecordPutaway(c.Request.Context(), userID, recordID, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, suggestion)
+}
+
+// ListReplenishmentSettings handles GET /warehouse/:warehouse_id/replenishment-settings
+func (h *AppHandler) ListReplenishmentSettings(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	settings, err := h.appSvc.ListReplenishmentSettings(c.Request.Context(), userID, warehouseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// SetReplenishmentSetting handles PUT /warehouse/:warehouse_id/replenishment-setting
+// Levels are min_units/max_units for min_max (max_units 0 fills to slot
+// capacity) and min_days/max_days for days_of_supply.
+func (h *AppHandler) SetReplenishmentSetting(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	type reqBody struct {
+		LocationID uuid.UUID `json:"location_id"`
+		ItemID     uuid.UUID `json:"item_id"`
+		Method     string    `json:"method"`
+		MinUnits   int64     `json:"min_units"`
+		MaxUnits   int64     `json:"max_units"`
+		MinDays    float64   `json:"min_days"`
+		MaxDays    float64   `json:"max_days"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	st := models.ReplenishmentSetting{LocationID: &body.LocationID, ItemID: &body.ItemID, Method: body.Method, MinUnits: body.MinUnits, MaxUnits: body.MaxUnits, MinDays: body.MinDays, MaxDays: body.MaxDays}
+	saved, err := h.appSvc.SetReplenishmentSetting(c.Request.Context(), userID, warehouseID, st)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, saved)
+}
+
+// DeleteReplenishmentSetting handles DELETE /warehouse/:warehouse_id/replenishment-setting/:location_id/:item_id
+func (h *AppHandler) DeleteReplenishmentSetting(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+	locationID, err := uuid.Parse(c.Param("location_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
+		return
+	}
+	itemID, err := uuid.Parse(c.Param("item_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid item_id"})
+		return
+	}
+
+	if err := h.appSvc.DeleteReplenishmentSetting(c.Request.Context(), userID, warehouseID, locationID, itemID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "replenishment setting deleted"})
+}
+
+// GetReplenishmentStatus handles GET /warehouse/:warehouse_id/replenishment-status
+// reporting the estimated on-hand of every configured pick face without
+// raising tasks.
+func (h *AppHandler) GetReplenishmentStatus(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	run, err := h.appSvc.GetReplenishmentStatus(c.Request.Context(), userID, warehouseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// EvaluateReplenishment handles POST /warehouse/:warehouse_id/replenishment/evaluate
+func (h *AppHandler) EvaluateReplenishment(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	run, err := h.appSvc.EvaluateReplenishment(c.Request.Context(), userID, warehouseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// ListReplenishmentQueue handles GET /replenishment-queue
+// Query: warehouse_id, item_id, location_id, status (default open, "all" for
+// any), sort, dir. Unsorted, the lowest days of supply come first.
+func (h *AppHandler) ListReplenishmentQueue(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	var f repos.ReplenishmentTaskFilter
+	if wh := c.Query("warehouse_id"); wh != "" {
+		if id, err := uuid.Parse(wh); err == nil {
+			f.WarehouseID = id
+		}
+	}
+	if item := c.Query("item_id"); item != "" {
+		if id, err := uuid.Parse(item); err == nil {
+			f.ItemID = id
+		}
+	}
+	if loc := c.Query("location_id"); loc != "" {
+		if id, err := uuid.Parse(loc); err == nil {
+			f.ToLocationID = id
+		}
+	}
+	f.Status = c.Query("status")
+	f.SortField = c.Query("sort")
+	f.SortDir = c.Query("dir")
+	tasks, err := h.appSvc.ListReplenishmentQueue(c.Request.Context(), userID, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
+
+// GetReplenishmentTask handles GET /replenishment-task/:task_id
+func (h *AppHandler) GetReplenishmentTask(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	taskID, err := uuid.Parse(c.Param("task_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task_id"})
+		return
+	}
+	task, err := h.appSvc.GetReplenishmentTask(c.Request.Context(), userID, taskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+// CompleteReplenishmentTask handles PUT /replenishment-task/:task_id/complete
+func (h *AppHandler) CompleteReplenishmentTask(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	taskID, err := uuid.Parse(c.Param("task_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task_id"})
+		return
+	}
+	if err := h.appSvc.CompleteReplenishmentTask(c.Request.Context(), userID, taskID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "replenishment task completed"})
+}
+
+// CancelReplenishmentTask handles PUT /replenishment-task/:task_id/cancel
+func (h *AppHandler) CancelReplenishmentTask(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	taskID, err := uuid.Parse(c.Param("task_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task_id"})
+		return
+	}
+	if err := h.appSvc.CancelReplenishmentTask(c.Request.Context(), userID, taskID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "replenishment task cancelled"})
+}
+
+// GetCapacity handles GET /warehouse/:warehouse_id/capacity
+// Query: start_date, end_date (YYYY-MM-DD) for the trend, granularity (day or
+// week), role, forecast_run_id (latest run by default), horizon_days,
+// target_pct, format=xlsx for a download with charts.
+func (h *AppHandler) GetCapacity(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	var q services.CapacityQuery
+	q.StartDate, q.EndDate, err = parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	q.Granularity = c.Query("granularity")
+	q.SlotRole = c.Query("role")
+	q.HorizonDays, _ = strconv.Atoi(c.Query("horizon_days"))
+	q.TargetPct, _ = strconv.ParseFloat(c.Query("target_pct"), 64)
+	if fr := c.Query("forecast_run_id"); fr != "" {
+		if q.ForecastRunID, err = uuid.Parse(fr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid forecast_run_id"})
+			return
+		}
+	}
+
+	if c.Query("format") == "xlsx" {
+		data, fileName, err := h.appSvc.ExportCapacity(c.Request.Context(), userID, warehouseID, q)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		sendXLSX(c, fileName, data)
+		return
+	}
+	report, err := h.appSvc.GetCapacity(c.Request.Context(), userID, warehouseID, q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetErgonomicModel handles GET /warehouse/:warehouse_id/ergonomic-model
+func (h *AppHandler) GetErgonomicModel(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	m, err := h.appSvc.GetErgonomicModel(c.Request.Context(), userID, warehouseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+// SetErgonomicModel handles PUT /warehouse/:warehouse_id/ergonomic-model
+// Fields left out of the body keep their current value.
+func (h *AppHandler) SetErgonomicModel(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	type reqBody struct {
+		KneeHeightCm       *float64 `json:"knee_height_cm"`
+		WaistHeightCm      *float64 `json:"waist_height_cm"`
+		ShoulderHeightCm   *float64 `json:"shoulder_height_cm"`
+		BelowKneeScore     *float64 `json:"below_knee_score"`
+		KneeToWaistScore   *float64 `json:"knee_to_waist_score"`
+		GoldenScore        *float64 `json:"golden_score"`
+		AboveShoulderScore *float64 `json:"above_shoulder_score"`
+		ReachDepthCm       *float64 `json:"reach_depth_cm"`
+		MaxReachDepthCm    *float64 `json:"max_reach_depth_cm"`
+		DeepReachScore     *float64 `json:"deep_reach_score"`
+		HeightWeight       *float64 `json:"height_weight"`
+		ReachWeight        *float64 `json:"reach_weight"`
+		HeavyItemKg        *float64 `json:"heavy_item_kg"`
+		FastMoverShare     *float64 `json:"fast_mover_share"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	current, err := h.appSvc.GetErgonomicModel(c.Request.Context(), userID, warehouseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	m := *current
+	for _, f := range []struct {
+		dst *float64
+		src *float64
+	}{
+		{&m.KneeHeightCm, body.KneeHeightCm},
+		{&m.WaistHeightCm, body.WaistHeightCm},
+		{&m.ShoulderHeightCm, body.ShoulderHeightCm},
+		{&m.BelowKneeScore, body.BelowKneeScore},
+		{&m.KneeToWaistScore, body.KneeToWaistScore},
+		{&m.GoldenScore, body.GoldenScore},
+		{&m.AboveShoulderScore, body.AboveShoulderScore},
+		{&m.ReachDepthCm, body.ReachDepthCm},
+		{&m.MaxReachDepthCm, body.MaxReachDepthCm},
+		{&m.DeepReachScore, body.DeepReachScore},
+		{&m.HeightWeight, body.HeightWeight},
+		{&m.ReachWeight, body.ReachWeight},
+		{&m.HeavyItemKg, body.HeavyItemKg},
+		{&m.FastMoverShare, body.FastMoverShare},
+	} {
+		if f.src != nil {
+			*f.dst = *f.src
+		}
+	}
+
+	saved, err := h.appSvc.SetErgonomicModel(c.Request.Context(), userID, warehouseID, m)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, saved)
+}
+
+// ResetErgonomicModel handles DELETE /warehouse/:warehouse_id/ergonomic-model
+// and returns the defaults the warehouse is back on.
+func (h *AppHandler) ResetErgonomicModel(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	m, err := h.appSvc.ResetErgonomicModel(c.Request.Context(), userID, warehouseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+// GetErgonomics handles GET /warehouse/:warehouse_id/ergonomics
+// Query: start_date, end_date (YYYY-MM-DD), types (comma separated categories,
+// default pick), heavy_fast (true = heavy fast movers only), awkward (true =
+// items picked below knee or above shoulder only), sort (lines | score |
+// awkward_lines | unit_weight_kg | item_name), dir, limit, offset,
+// format (xlsx for a workbook of every item).
+func (h *AppHandler) GetErgonomics(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	var q services.ErgonomicQuery
+	q.StartDate, q.EndDate, err = parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	q.TransactionTypes = splitQueryList(c.Query("types"))
+	q.HeavyFastOnly = c.Query("heavy_fast") == "true"
+	q.AwkwardOnly = c.Query("awkward") == "true"
+	q.SortField = c.Query("sort")
+	q.SortDir = c.Query("dir")
+	q.Limit, _ = strconv.Atoi(c.Query("limit"))
+	q.Offset, _ = strconv.Atoi(c.Query("offset"))
+
+	if c.Query("format") == "xlsx" {
+		data, fileName, err := h.appSvc.ExportErgonomics(c.Request.Context(), userID, warehouseID, q)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		sendXLSX(c, fileName, data)
+		return
+	}
+	report, err := h.appSvc.GetErgonomics(c.Request.Context(), userID, warehouseID, q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// RunClassification handles POST /warehouse/:warehouse_id/classification
+func (h *AppHandler) RunClassification(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	type reqBody struct {
+		Basis         string    `json:"basis"`
+		Cutoffs       []float64 `json:"cutoffs"`
+		XMaxCV        float64   `json:"x_max_cv"`
+		YMaxCV        float64   `json:"y_max_cv"`
+		StartDate     string    `json:"start_date"`
+		EndDate       string    `json:"end_date"`
+		Types         []string  `json:"types"`
+		ForecastRunID string    `json:"forecast_run_id"` // classify on a forecast instead of history
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	p := services.ClassificationParams{
+		Basis:            body.Basis,
+		Cutoffs:          body.Cutoffs,
+		XMaxCV:           body.XMaxCV,
+		YMaxCV:           body.YMaxCV,
+		TransactionTypes: body.Types,
+	}
+	if p.StartDate, err = parseDay("start_date", body.StartDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if p.EndDate, err = parseDay("end_date", body.EndDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if body.ForecastRunID != "" {
+		if p.ForecastRunID, err = uuid.Parse(body.ForecastRunID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid forecast_run_id"})
+			return
+		}
+	}
+	run, err := h.appSvc.RunClassification(c.Request.Context(), userID, warehouseID, p)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// ListClassificationRuns handles GET /classifications
+func (h *AppHandler) ListClassificationRuns(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	var f repos.ClassificationRunFilter
+	if wh := c.Query("warehouse_id"); wh != "" {
+		if id, err := uuid.Parse(wh); err == nil {
+			f.WarehouseID = id
+		}
+	}
+	f.Basis = c.Query("basis")
+	f.SortField = c.Query("sort")
+	f.SortDir = c.Query("dir")
+	runs, err := h.appSvc.ListClassificationRuns(c.Request.Context(), userID, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+// GetClassificationRun handles GET /classification/:run_id
+func (h *AppHandler) GetClassificationRun(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	runIDStr := c.Param("run_id")
+	runID, err := uuid.Parse(runIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run_id"})
+		return
+	}
+	run, err := h.appSvc.GetClassificationRun(c.Request.Context(), userID, runID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// DeleteClassificationRun handles DELETE /classification/:run_id
+func (h *AppHandler) DeleteClassificationRun(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	runIDStr := c.Param("run_id")
+	runID, err := uuid.Parse(runIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run_id"})
+		return
+	}
+	if err := h.appSvc.DeleteClassificationRun(c.Request.Context(), userID, runID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "classification run deleted"})
+}
+
+// ListItemClassifications handles GET /classification/:run_id/items
+func (h *AppHandler) ListItemClassifications(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	runIDStr := c.Param("run_id")
+	runID, err := uuid.Parse(runIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run_id"})
+		return
+	}
+	var f repos.ItemClassificationFilter
+	f.ABCClass = strings.ToUpper(c.Query("abc"))
+	f.XYZClass = strings.ToUpper(c.Query("xyz"))
+	f.SortField = c.Query("sort")
+	f.SortDir = c.Query("dir")
+	items, err := h.appSvc.ListItemClassifications(c.Request.Context(), userID, runID, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// CompareClassificationRuns handles GET /classification/:run_id/changes?from=<run_id>
+// and reports how items moved between the earlier run and this one.
+func (h *AppHandler) CompareClassificationRuns(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	runIDStr := c.Param("run_id")
+	runID, err := uuid.Parse(runIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run_id"})
+		return
+	}
+	fromIDStr := c.Query("from")
+	fromID, err := uuid.Parse(fromIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from run id"})
+		return
+	}
+	report, err := h.appSvc.CompareClassificationRuns(c.Request.Context(), userID, fromID, runID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// RunForecast handles POST /warehouse/:warehouse_id/forecast
+func (h *AppHandler) RunForecast(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	type reqBody struct {
+		Granularity    string   `json:"granularity"`
+		SeasonLength   int      `json:"season_length"`
+		Horizon        int      `json:"horizon"`
+		HoldoutPeriods int      `json:"holdout_periods"`
+		StartDate      string   `json:"start_date"`
+		EndDate        string   `json:"end_date"`
+		Types          []string `json:"types"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	p := services.ForecastParams{
+		Granularity:      body.Granularity,
+		SeasonLength:     body.SeasonLength,
+		Horizon:          body.Horizon,
+		HoldoutPeriods:   body.HoldoutPeriods,
+		TransactionTypes: body.Types,
+	}
+	if p.StartDate, err = parseDay("start_date", body.StartDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if p.EndDate, err = parseDay("end_date", body.EndDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	run, err := h.appSvc.RunForecast(c.Request.Context(), userID, warehouseID, p)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// ListForecastRuns handles GET /forecasts
+func (h *AppHandler) ListForecastRuns(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	var f repos.ForecastRunFilter
+	if wh := c.Query("warehouse_id"); wh != "" {
+		if id, err := uuid.Parse(wh); err == nil {
+			f.WarehouseID = id
+		}
+	}
+	f.Granularity = c.Query("granularity")
+	f.SortField = c.Query("sort")
+	f.SortDir = c.Query("dir")
+	runs, err := h.appSvc.ListForecastRuns(c.Request.Context(), userID, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+// GetForecastRun handles GET /forecast/:run_id
+func (h *AppHandler) GetForecastRun(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	runIDStr := c.Param("run_id")
+	runID, err := uuid.Parse(runIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run_id"})
+		return
+	}
+	run, err := h.appSvc.GetForecastRun(c.Request.Context(), userID, runID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// DeleteForecastRun handles DELETE /forecast/:run_id
+func (h *AppHandler) DeleteForecastRun(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	runIDStr := c.Param("run_id")
+	runID, err := uuid.Parse(runIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run_id"})
+		return
+	}
+	if err := h.appSvc.DeleteForecastRun(c.Request.Context(), userID, runID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "forecast run deleted"})
+}
+
+// ListItemForecasts handles GET /forecast/:run_id/items
+// Query: item_id, method, sort, dir.
+func (h *AppHandler) ListItemForecasts(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	runIDStr := c.Param("run_id")
+	runID, err := uuid.Parse(runIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run_id"})
+		return
+	}
+	var f repos.ItemForecastFilter
+	if it := c.Query("item_id"); it != "" {
+		if id, err := uuid.Parse(it); err == nil {
+			f.ItemID = id
+		}
+	}
+	f.Method = c.Query("method")
+	f.SortField = c.Query("sort")
+	f.SortDir = c.Query("dir")
+	items, err := h.appSvc.ListItemForecasts(c.Request.Context(), userID, runID, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// RecommendSlotPlan handles POST /warehouse/:warehouse_id/slot-plan
+func (h *AppHandler) RecommendSlotPlan(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	var body slotPlanBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	p, err := body.params()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	plan, err := h.appSvc.RecommendSlotPlan(c.Request.Context(), userID, warehouseID, p)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// ListSlotPlans handles GET /slot-plans
+func (h *AppHandler) ListSlotPlans(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	var f repos.SlotPlanFilter
+	if wh := c.Query("warehouse_id"); wh != "" {
+		if id, err := uuid.Parse(wh); err == nil {
+			f.WarehouseID = id
+		}
+	}
+	if z := c.Query("zone_id"); z != "" {
+		if id, err := uuid.Parse(z); err == nil {
+			f.ZoneID = id
+		}
+	}
+	f.SortField = c.Query("sort")
+	f.SortDir = c.Query("dir")
+	plans, err := h.appSvc.ListSlotPlans(c.Request.Context(), userID, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, plans)
+}
+
+// GetSlotPlan handles GET /slot-plan/:plan_id
+func (h *AppHandler) GetSlotPlan(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	planIDStr := c.Param("plan_id")
+	planID, err := uuid.Parse(planIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid plan_id"})
+		return
+	}
+	plan, err := h.appSvc.GetSlotPlan(c.Request.Context(), userID, planID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// DeleteSlotPlan handles DELETE /slot-plan/:plan_id
+func (h *AppHandler) DeleteSlotPlan(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	planIDStr := c.Param("plan_id")
+	planID, err := uuid.Parse(planIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid plan_id"})
+		return
+	}
+	if err := h.appSvc.DeleteSlotPlan(c.Request.Context(), userID, planID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "slot plan deleted"})
+}
+
+// ListSlotPlanEntries handles GET /slot-plan/:plan_id/entries
+func (h *AppHandler) ListSlotPlanEntries(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	planIDStr := c.Param("plan_id")
+	planID, err := uuid.Parse(planIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid plan_id"})
+		return
+	}
+	var f repos.SlotPlanEntryFilter
+	f.MovesOnly = c.Query("moves_only") == "true"
+	f.SortField = c.Query("sort")
+	f.SortDir = c.Query("dir")
+	entries, err := h.appSvc.ListSlotPlanEntries(c.Request.Context(), userID, planID, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// GetSlotPlanMoves handles GET /slot-plan/:plan_id/moves
+func (h *AppHandler) GetSlotPlanMoves(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	planIDStr := c.Param("plan_id")
+	planID, err := uuid.Parse(planIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid plan_id"})
+		return
+	}
+	var p services.MoveListParams
+	p.MaxMoves, _ = strconv.Atoi(c.Query("max_moves"))
+	p.SecondsPerMove, _ = strconv.ParseFloat(c.Query("seconds_per_move"), 64)
+	p.WalkSpeedMps, _ = strconv.ParseFloat(c.Query("walk_speed_mps"), 64)
+	list, err := h.appSvc.GetSlotPlanMoves(c.Request.Context(), userID, planID, p)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// AcceptSlotPlanMoves handles POST /slot-plan/:plan_id/moves/accept
+func (h *AppHandler) AcceptSlotPlanMoves(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	planIDStr := c.Param("plan_id")
+	planID, err := uuid.Parse(planIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid plan_id"})
+		return
+	}
+	var p services.MoveListParams
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	tasks, err := h.appSvc.AcceptSlotPlanMoves(c.Request.Context(), userID, planID, p)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
+
+// ListMoveTasks handles GET /move-tasks
+func (h *AppHandler) ListMoveTasks(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	var f repos.MoveTaskFilter
+	if wh := c.Query("warehouse_id"); wh != "" {
+		if id, err := uuid.Parse(wh); err == nil {
+			f.WarehouseID = id
+		}
+	}
+	if plan := c.Query("plan_id"); plan != "" {
+		if id, err := uuid.Parse(plan); err == nil {
+			f.PlanID = id
+		}
+	}
+	if item := c.Query("item_id"); item != "" {
+		if id, err := uuid.Parse(item); err == nil {
+			f.ItemID = id
+		}
+	}
+	f.Status = c.Query("status")
+	f.SortField = c.Query("sort")
+	f.SortDir = c.Query("dir")
+	tasks, err := h.appSvc.ListMoveTasks(c.Request.Context(), userID, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
+
+// GetMoveTask handles GET /move-task/:task_id
+func (h *AppHandler) GetMoveTask(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	taskIDStr := c.Param("task_id")
+	taskID, err := uuid.Parse(taskIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task_id"})
+		return
+	}
+	task, err := h.appSvc.GetMoveTask(c.Request.Context(), userID, taskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+// ConfirmMoveTask handles PUT /move-task/:task_id/confirm
+func (h *AppHandler) ConfirmMoveTask(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	taskIDStr := c.Param("task_id")
+	taskID, err := uuid.Parse(taskIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task_id"})
+		return
+	}
+	violations, err := h.appSvc.ConfirmMoveTask(c.Request.Context(), userID, taskID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "move task confirmed", "violations": violations})
+}
+
+// CancelMoveTask handles PUT /move-task/:task_id/cancel
+func (h *AppHandler) CancelMoveTask(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	taskIDStr := c.Param("task_id")
+	taskID, err := uuid.Parse(taskIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task_id"})
+		return
+	}
+	if err := h.appSvc.CancelMoveTask(c.Request.Context(), userID, taskID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "move task cancelled"})
+}
+
+// ReplaySlotPlan handles GET /slot-plan/:plan_id/replay
+// Query: start_date, end_date (YYYY-MM-DD, default the plan window), routing
+// (s_shape, return, largest_gap, optimal), walk_speed_mps, seconds_per_line,
+// seconds_per_order, format=xlsx for a download.
+func (h *AppHandler) ReplaySlotPlan(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	planIDStr := c.Param("plan_id")
+	planID, err := uuid.Parse(planIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid plan_id"})
+		return
+	}
+	var p services.ReplayParams
+	p.StartDate, p.EndDate, err = parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p.Routing = c.Query("routing")
+	p.WalkSpeedMps, _ = strconv.ParseFloat(c.Query("walk_speed_mps"), 64)
+	p.SecondsPerLine, _ = strconv.ParseFloat(c.Query("seconds_per_line"), 64)
+	p.SecondsPerOrder, _ = strconv.ParseFloat(c.Query("seconds_per_order"), 64)
+
+	if c.Query("format") == "xlsx" {
+		data, fileName, err := h.appSvc.ExportSlotPlanReplay(c.Request.Context(), userID, planID, p)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		sendXLSX(c, fileName, data)
+		return
+	}
+	report, err := h.appSvc.ReplaySlotPlan(c.Request.Context(), userID, planID, p)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetPickFaceSizing handles GET /warehouse/:warehouse_id/pick-face-sizing
+// Query: start_date, end_date (YYYY-MM-DD), target_days, min_days, max_days,
+// status, sort, dir, limit, offset, format=xlsx for a download, and
+// forecast_run_id to size on a forecast instead of history.
+func (h *AppHandler) GetPickFaceSizing(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	var q services.SizingQuery
+	q.StartDate, q.EndDate, err = parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	q.TargetDays, _ = strconv.ParseFloat(c.Query("target_days"), 64)
+	q.MinDays, _ = strconv.ParseFloat(c.Query("min_days"), 64)
+	q.MaxDays, _ = strconv.ParseFloat(c.Query("max_days"), 64)
+	q.Status = c.Query("status")
+	q.SortField = c.Query("sort")
+	q.SortDir = c.Query("dir")
+	q.Limit, _ = strconv.Atoi(c.Query("limit"))
+	q.Offset, _ = strconv.Atoi(c.Query("offset"))
+	if fr := c.Query("forecast_run_id"); fr != "" {
+		if q.ForecastRunID, err = uuid.Parse(fr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid forecast_run_id"})
+			return
+		}
+	}
+
+	if c.Query("format") == "xlsx" {
+		data, fileName, err := h.appSvc.ExportPickFaceSizing(c.Request.Context(), userID, warehouseID, q)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		sendXLSX(c, fileName, data)
+		return
+	}
+	report, err := h.appSvc.GetPickFaceSizing(c.Request.Context(), userID, warehouseID, q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// CreateScenario handles POST /warehouse/:warehouse_id/scenario
+// Body: name, description and params, the slot plan request body.
+func (h *AppHandler) CreateScenario(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseIDStr := c.Param("warehouse_id")
+	warehouseID, err := uuid.Parse(warehouseIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	type reqBody struct {
+		Name        string       `json:"name"`
+		Description string       `json:"description"`
+		Params      slotPlanBody `json:"params"`
+	}
+	var body reqBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	p := services.ScenarioParams{Name: body.Name, Description: body.Description}
+	if p.Plan, err = body.Params.params(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	scenario, err := h.appSvc.CreateScenario(c.Request.Context(), userID, warehouseID, p)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, scenario)
+}
+
+// ListScenarios handles GET /scenarios
+func (h *AppHandler) ListScenarios(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	var f repos.ScenarioFilter
+	if wh := c.Query("warehouse_id"); wh != "" {
+		if id, err := uuid.Parse(wh); err == nil {
+			f.WarehouseID = id
+		}
+	}
+	f.SortField = c.Query("sort")
+	f.SortDir = c.Query("dir")
+	scenarios, err := h.appSvc.ListScenarios(c.Request.Context(), userID, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, scenarios)
+}
+
+// CompareScenarios handles GET /scenarios/compare?ids=<id>,<id>,...
+func (h *AppHandler) CompareScenarios(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	var ids []uuid.UUID
+	for _, raw := range splitQueryList(c.Query("ids")) {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid scenario id '%s'", raw)})
+			return
+		}
+		ids = append(ids, id)
+	}
+	cmp, err := h.appSvc.CompareScenarios(c.Request.Context(), userID, ids)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cmp)
+}
+
+// GetScenario handles GET /scenario/:scenario_id
+func (h *AppHandler) GetScenario(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	scenarioIDStr := c.Param("scenario_id")
+	scenarioID, err := uuid.Parse(scenarioIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scenario_id"})
+		return
+	}
+	scenario, err := h.appSvc.GetScenario(c.Request.Context(), userID, scenarioID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, scenario)
+}
+
+// DeleteScenario handles DELETE /scenario/:scenario_id
+func (h *AppHandler) DeleteScenario(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	scenarioIDStr := c.Param("scenario_id")
+	scenarioID, err := uuid.Parse(scenarioIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scenario_id"})
+		return
+	}
+	if err := h.appSvc.DeleteScenario(c.Request.Context(), userID, scenarioID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "scenario deleted"})
+}
+
+// CloneScenario handles POST /scenario/:scenario_id/clone
+// Body (all optional): name, description and params to run instead of the
+// source scenario's.
+func (h *AppHandler) CloneScenario(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	scenarioIDStr := c.Param("scenario_id")
+	scenarioID, err := uuid.Parse(scenarioIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scenario_id"})
+		return
+	}
+
+	type reqBody struct {
+		Name        string        `json:"name"`
+		Description string        `json:"description"`
+		Params      *slotPlanBody `json:"params"`
+	}
+	var body reqBody
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+	var plan *services.SlotPlanParams
+	if body.Params != nil {
+		p, err := body.Params.params()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		plan = &p
+	}
+	scenario, err := h.appSvc.CloneScenario(c.Request.Context(), userID, scenarioID, body.Name, body.Description, plan)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, scenario)
+}
+
+// slotPlanBody is the request form of services.SlotPlanParams, shared by slot
+// plans and scenarios.
+type slotPlanBody struct {
+	ZoneID         string      `json:"zone_id"`
+	StartDate      string      `json:"start_date"`
+	EndDate        string      `json:"end_date"`
+	TravelWeight   float64     `json:"travel_weight"`
+	ErgoWeight     float64     `json:"ergo_weight"`
+	AffinityWeight float64     `json:"affinity_weight"`
+	PinnedItemIDs  []uuid.UUID `json:"pinned_item_ids"`
+	ExcludeZoneIDs []uuid.UUID `json:"exclude_zone_ids"`
+}
+
+func (b slotPlanBody) params() (services.SlotPlanParams, error) {
+	p := services.SlotPlanParams{
+		TravelWeight:   b.TravelWeight,
+		ErgoWeight:     b.ErgoWeight,
+		AffinityWeight: b.AffinityWeight,
+		PinnedItemIDs:  b.PinnedItemIDs,
+		ExcludeZoneIDs: b.ExcludeZoneIDs,
+	}
+	var err error
+	if b.ZoneID != "" {
+		if p.ZoneID, err = uuid.Parse(b.ZoneID); err != nil {
+			return p, fmt.Errorf("invalid zone_id")
+		}
+	}
+	if p.StartDate, err = parseDay("start_date", b.StartDate); err != nil {
+		return p, err
+	}
+	if p.EndDate, err = parseDay("end_date", b.EndDate); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// GetSlotMap handles GET /warehouse/:warehouse_id/slot-map
+// Query: as_of (YYYY-MM-DD, the slot map at the end of that day; default now),
+// type, item_id, location_id.
+func (h *AppHandler) GetSlotMap(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+	f, err := parseSlotAssignmentQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	asOf, err := parseDay("as_of", c.Query("as_of"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	f.AsOf = time.Now()
+	if !asOf.IsZero() {
+		f.AsOf = asOf.AddDate(0, 0, 1).Add(-time.Microsecond)
+	}
+	assignments, err := h.appSvc.ListSlotAssignments(c.Request.Context(), userID, warehouseID, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"as_of": f.AsOf, "assignments": assignments})
+}
+
+// ListSlotAssignments handles GET /warehouse/:warehouse_id/slot-assignments
+// Query: item_id, location_id, type, open, start_date/end_date (assignments
+// overlapping the range), sort, dir.
+func (h *AppHandler) ListSlotAssignments(c *gin.Context) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(c.Param("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+	f, err := parseSlotAssignmentQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if f.StartDate, f.EndDate, err = parseDateRange(c); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	f.OpenOnly = c.Query("open") == "true"
+	f.SortField = c.Query("sort")
+	f.SortDir = c.Query("dir")
+	assignments, err := h.appSvc.ListSlotAssignments(c.Request.Context(), userID, warehouseID, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, assignments)
+}
+
+// parseDateRange reads the optional start_date / end_date query params.
+func parseDateRange(c *gin.Context) (time.Time, time.Time, error) {
+	start, err := parseDay("start_date", c.Query("start_date"))
+	if err != nil {
+		return start, time.Time{}, err
+	}
+	end, err := parseDay("end_date", c.Query("end_date"))
+	return start, end, err
+}
+
+// parseDay parses an optional YYYY-MM-DD value; empty yields the zero time.
+func parseDay(field, v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s, expected YYYY-MM-DD", field)
+	}
+	return t, nil
+}
+
+func splitQueryList(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func parseSlotAssignmentQuery(c *gin.Context) (repos.SlotAssignmentFilter, error) {
+	f := repos.SlotAssignmentFilter{AssignmentType: c.Query("type")}
+	if v := c.Query("item_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid item_id")
+		}
+		f.ItemID = id
+	}
+	if v := c.Query("location_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid location_id")
+		}
+		f.LocationID = id
+	}
+	return f, nil
+}
+
+func parseSlowStockQuery(c *gin.Context) (services.SlowStockQuery, error) {
+	var q services.SlowStockQuery
+	var err error
+	if q.AsOf, err = parseDay("as_of", c.Query("as_of")); err != nil {
+		return q, err
+	}
+	q.DeadDays, _ = strconv.Atoi(c.Query("dead_days"))
+	q.WindowDays, _ = strconv.Atoi(c.Query("window_days"))
+	q.DropPct, _ = strconv.ParseFloat(c.Query("drop_pct"), 64)
+	q.MinPriorLines, _ = strconv.ParseInt(c.Query("min_prior_lines"), 10, 64)
+	q.Status = c.Query("status")
+	q.SortField = c.Query("sort")
+	q.SortDir = c.Query("dir")
+	q.Limit, _ = strconv.Atoi(c.Query("limit"))
+	q.Offset, _ = strconv.Atoi(c.Query("offset"))
+	return q, nil
+}
+
+func sendXLSX(c *gin.Context, fileName string, data []byte) {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Data(http.StatusOK, export.XLSXContentType, data)
+}
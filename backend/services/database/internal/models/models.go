@@ -54,6 +54,9 @@ type Warehouse struct {
   TransactionFiles    []*TransactionFile    `gorm:"foreignKey:WarehouseID"`
   TransactionRecords  []*TransactionRecord  `gorm:"foreignKey:WarehouseID"`
   Items               []*Item               `gorm:"many2many:items_warehouses;"`
+  DepotX              float64               // pick/drop point in layout metres
+  DepotY              float64
+  GoLiveDate          *time.Time            `gorm:"type:date"` // records completed before it are exceptions; nil accepts any history
   CreatedAt           time.Time             `gorm:"not null;default:now()"`
   UpdatedAt           time.Time             `gorm:"not null;default:now()"`
 }
@@ -70,10 +73,44 @@ type Location struct {
   TransactionRecords  []*TransactionRecord  `gorm:"foreignKey:LocationID"`
   LocationPath        string                `gorm:"not null"`
   LocationNamePath    string                `gorm:"not null"`
+  Aliases             []*LocationAlias      `gorm:"foreignKey:LocationID"`
+  Status              string                `gorm:"not null;default:'active';index"` // see constants.LocationStatuses
+  StatusReason        string
+  StatusChangedByID   *uuid.UUID            `gorm:"index"`
+  StatusChangedBy     *User                 `gorm:"constraint:OnDelete:SET NULL"`
+  StatusChangedAt     *time.Time
+  // Slot attributes. Zero means unknown; slotting treats unknown dimensions as
+  // unconstrained and unknown coordinates as average travel.
+  SlotRole            string                `gorm:"not null;default:'pick'"` // see constants.SlotRoles
+  SlotType            string                // equipment, e.g. shelf | flow_rack | pallet
+  Aisle               string                `gorm:"index"`
+  CoordX              float64               // layout position in metres
+  CoordY              float64
+  LevelHeightCm       float64               // height of the slot floor above ground
+  SlotWidthCm         float64
+  SlotDepthCm         float64
+  SlotHeightCm        float64
+  MaxWeightKg         float64
   CreatedAt           time.Time             `gorm:"not null;default:now()"`
   UpdatedAt           time.Time             `gorm:"not null;default:now()"` 
 }
 
+// ----------------------------------------------------
+// LocationAlias
+// ----------------------------------------------------
+// Maps a retired location path (after a rename or merge) onto the location
+// that now owns it, so imports still using the old label resolve correctly.
+type LocationAlias struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;uniqueIndex:idx_location_aliases_path"`
+  Warehouse           *Warehouse            `gorm:"constraint:OnDelete:CASCADE"`
+  LocationID          *uuid.UUID            `gorm:"not null;index"`
+  Location            *Location             `gorm:"constraint:OnDelete:CASCADE"`
+  AliasPath           string                `gorm:"not null;uniqueIndex:idx_location_aliases_path"`
+  AliasNamePath       string
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
 // ----------------------------------------------------
 // TransactionRecord
 // ----------------------------------------------------
@@ -95,6 +132,7 @@ type TransactionRecord struct {
   TransactionQuantity int
   CompletedDate       time.Time
   CompletedQuantity   int
+  CompletedBy         string            `gorm:"index"` // operator as given by the import or the creating user's email
   CreatedAt           time.Time         `gorm:"not null;default:now()"`
   UpdatedAt           time.Time         `gorm:"not null;default:now()"`
 
@@ -112,10 +150,608 @@ type Item struct {
   Locations          []*Location            `gorm:"many2many:items_locations;"`
   TransactionRecords []*TransactionRecord   `gorm:"foreignKey:ItemID"`
   TransactionFiles    []*TransactionFile     `gorm:"many2many:items_transaction_files;"`
+  UnitWeightKg       float64
+  LengthCm           float64
+  WidthCm            float64
+  HeightCm           float64
+  TemperatureClass   string                 `gorm:"not null;default:'ambient'"` // ambient | chilled | frozen
+  Hazmat             bool                   `gorm:"not null;default:false"`
   CreatedAt          time.Time              `gorm:"not null;default:now()"`
   UpdatedAt          time.Time              `gorm:"not null;default:now()"`
 }
 
+// ----------------------------------------------------
+// Zone
+// ----------------------------------------------------
+// A zone covers every location at or below PathPrefix. Zero-valued limits are
+// not enforced; an empty TemperatureClass accepts any item.
+type Zone struct {
+  ID                   uuid.UUID            `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID            *uuid.UUID           `gorm:"not null;index"`
+  Company              *Company             `gorm:"constraint:OnDelete:CASCADE"`
+  WarehouseID          *uuid.UUID           `gorm:"not null;uniqueIndex:idx_zones_warehouse_name"`
+  Warehouse            *Warehouse           `gorm:"constraint:OnDelete:CASCADE"`
+  Name                 string               `gorm:"not null;uniqueIndex:idx_zones_warehouse_name"`
+  PathPrefix           string               `gorm:"not null;index"`
+  TemperatureClass     string
+  HazmatAllowed        bool                 `gorm:"not null;default:false"`
+  MaxUnitWeightKg      float64
+  WeightLimitFromLevel int                  // weight limit applies at this level and above; 0 = every level
+  MaxItemCubeCm3       float64
+  Violations           []*ZoneViolation     `gorm:"foreignKey:ZoneID"`
+  CreatedAt            time.Time            `gorm:"not null;default:now()"`
+  UpdatedAt            time.Time            `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// ZoneViolation
+// ----------------------------------------------------
+// One broken zone rule for an item linked to a location. Re-checking the same
+// link refreshes the row instead of adding a new one.
+type ZoneViolation struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;index"`
+  Warehouse           *Warehouse            `gorm:"constraint:OnDelete:CASCADE"`
+  ZoneID              *uuid.UUID            `gorm:"not null;uniqueIndex:idx_zone_violations_link"`
+  Zone                *Zone                 `gorm:"constraint:OnDelete:CASCADE"`
+  LocationID          *uuid.UUID            `gorm:"not null;uniqueIndex:idx_zone_violations_link"`
+  Location            *Location             `gorm:"constraint:OnDelete:CASCADE"`
+  ItemID              *uuid.UUID            `gorm:"not null;uniqueIndex:idx_zone_violations_link"`
+  Item                *Item                 `gorm:"constraint:OnDelete:CASCADE"`
+  Rule                string                `gorm:"not null;uniqueIndex:idx_zone_violations_link"`
+  Detail              string
+  Source              string                `gorm:"not null"` // link | import
+  TransactionFileID   *uuid.UUID            `gorm:"index"`
+  TransactionFile     *TransactionFile      `gorm:"constraint:OnDelete:SET NULL"`
+  ResolvedAt          *time.Time
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+  UpdatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// Job
+// ----------------------------------------------------
+// A long-running operation executed in the background. Clients poll it by id;
+// Params and Result are job-type specific JSON.
+type Job struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  Type                string                `gorm:"not null;index"`
+  Status              string                `gorm:"not null;default:'queued';index"` // see constants.JobStatuses
+  Progress            int
+  Total               int
+  Params              datatypes.JSON        `gorm:"type:jsonb"`
+  Result              datatypes.JSON        `gorm:"type:jsonb"`
+  Error               string
+  CreatedByID         *uuid.UUID            `gorm:"index"`
+  CreatedBy           *User                 `gorm:"constraint:OnDelete:SET NULL"`
+  StartedAt           *time.Time
+  FinishedAt          *time.Time
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+  UpdatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// LabelTemplate
+// ----------------------------------------------------
+// Geometry for printable barcode labels. Columns/Rows/PageSize/Margin/Gap only
+// apply to PDF sheets; DPI drives ZPL and PNG output.
+type LabelTemplate struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  Name                string                `gorm:"not null"`
+  Symbology           string                `gorm:"not null;default:'code128'"` // code128 | qr
+  WidthMM             float64               `gorm:"not null;default:100"`
+  HeightMM            float64               `gorm:"not null;default:50"`
+  DPI                 int                   `gorm:"not null;default:203"`
+  TextSizeMM          float64               `gorm:"not null;default:5"`
+  HideText            bool                  `gorm:"not null;default:false"`
+  PageSize            string                `gorm:"not null;default:'A4'"` // A4 | LETTER
+  Columns             int                   `gorm:"not null;default:2"`
+  Rows                int                   `gorm:"not null;default:5"`
+  MarginMM            float64               `gorm:"not null;default:10"`
+  GapMM               float64               `gorm:"not null;default:2"`
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+  UpdatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// LabelBatch
+// ----------------------------------------------------
+// A rendered set of labels stored in S3 so it can be downloaded again.
+type LabelBatch struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  WarehouseID         *uuid.UUID            `gorm:"index"`
+  Warehouse           *Warehouse            `gorm:"constraint:OnDelete:CASCADE"`
+  TemplateID          *uuid.UUID            `gorm:"index"`
+  Template            *LabelTemplate        `gorm:"constraint:OnDelete:SET NULL"`
+  CreatedByID         *uuid.UUID            `gorm:"index"`
+  CreatedBy           *User                 `gorm:"constraint:OnDelete:SET NULL"`
+  Subject             string                `gorm:"not null"` // location | item
+  Format              string                `gorm:"not null"` // zpl | pdf | png
+  LabelCount          int
+  FileName            string
+  FilePathURL         string                `gorm:"column:file_path_url"`
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// ClassificationRun
+// ----------------------------------------------------
+// One ABC/XYZ classification of a warehouse's items over [StartDate, EndDate].
+// Cutoffs are percentages of the basis total; XYZ uses the coefficient of
+// variation of daily demand.
+type ClassificationRun struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;index"`
+  Warehouse           *Warehouse            `gorm:"constraint:OnDelete:CASCADE"`
+  Basis               string                `gorm:"not null"` // lines | units | cube
+  TransactionTypes    string                // comma separated categories
+  ACutoff             float64
+  BCutoff             float64
+  CCutoff             float64
+  XMaxCV              float64               `gorm:"column:x_max_cv"`
+  YMaxCV              float64               `gorm:"column:y_max_cv"`
+  StartDate           time.Time             `gorm:"not null"`
+  EndDate             time.Time             `gorm:"not null"`
+  ItemCount           int
+  ForecastRunID       *uuid.UUID            `gorm:"index"` // set when classified on forecast demand
+  ForecastRun         *ForecastRun          `gorm:"constraint:OnDelete:SET NULL"`
+  CreatedByID         *uuid.UUID            `gorm:"index"`
+  CreatedBy           *User                 `gorm:"constraint:OnDelete:SET NULL"`
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// ItemClassification
+// ----------------------------------------------------
+type ItemClassification struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  RunID               *uuid.UUID            `gorm:"not null;uniqueIndex:idx_item_class_run_item"`
+  Run                 *ClassificationRun    `gorm:"constraint:OnDelete:CASCADE"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;index"`
+  ItemID              *uuid.UUID            `gorm:"not null;uniqueIndex:idx_item_class_run_item"`
+  Item                *Item                 `gorm:"constraint:OnDelete:CASCADE"`
+  ABCClass            string                `gorm:"column:abc_class;not null;index"`
+  XYZClass            string                `gorm:"column:xyz_class;not null;index"`
+  Value               float64               // basis value the ABC rank is computed on
+  Share               float64               // percent of the basis total
+  CumulativeShare     float64
+  Lines               int64
+  Units               int64
+  MeanDailyUnits      float64
+  CV                  float64               `gorm:"column:cv"`
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// SlotPlan
+// ----------------------------------------------------
+// A recommended item-to-slot assignment for a warehouse, or one zone of it,
+// with travel and ergonomic KPIs for the current and the proposed layout.
+// Travel is the round trip from the depot per pick line, in metres.
+type SlotPlan struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;index"`
+  Warehouse           *Warehouse            `gorm:"constraint:OnDelete:CASCADE"`
+  ZoneID              *uuid.UUID            `gorm:"index"`
+  Zone                *Zone                 `gorm:"constraint:OnDelete:SET NULL"`
+  Params              datatypes.JSON        `gorm:"type:jsonb"`
+  StartDate           time.Time             `gorm:"not null"`
+  EndDate             time.Time             `gorm:"not null"`
+  ItemCount           int
+  MoveCount           int                   // items whose recommended slot is not a current one
+  UnassignedCount     int
+  CurrentTravelM      float64
+  PlanTravelM         float64
+  CurrentErgoScore    float64               // pick-line weighted, 0..1
+  PlanErgoScore       float64
+  Entries             []*SlotPlanEntry      `gorm:"foreignKey:PlanID"`
+  CreatedByID         *uuid.UUID            `gorm:"index"`
+  CreatedBy           *User                 `gorm:"constraint:OnDelete:SET NULL"`
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// SlotPlanEntry
+// ----------------------------------------------------
+// One item of a slot plan. ToLocationID is nil when no compatible slot was left.
+type SlotPlanEntry struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  PlanID              *uuid.UUID            `gorm:"not null;index"`
+  Plan                *SlotPlan             `gorm:"constraint:OnDelete:CASCADE"`
+  ItemID              *uuid.UUID            `gorm:"not null;index"`
+  Item                *Item                 `gorm:"constraint:OnDelete:CASCADE"`
+  FromLocationID      *uuid.UUID            `gorm:"index"`
+  FromLocation        *Location             `gorm:"constraint:OnDelete:SET NULL"`
+  ToLocationID        *uuid.UUID            `gorm:"index"`
+  ToLocation          *Location             `gorm:"constraint:OnDelete:SET NULL"`
+  Lines               int64
+  FromScore           float64
+  ToScore             float64
+  Pinned              bool                  `gorm:"not null;default:false"`
+  Note                string
+}
+
+// ----------------------------------------------------
+// MoveTask
+// ----------------------------------------------------
+// One item move of an accepted re-slot move list. Moves of one group (a swap
+// or chain) share GroupNo and must be done in Sequence order; confirming a
+// task relinks the item from FromLocation to ToLocation.
+type MoveTask struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;index"`
+  Warehouse           *Warehouse            `gorm:"constraint:OnDelete:CASCADE"`
+  PlanID              *uuid.UUID            `gorm:"index"`
+  Plan                *SlotPlan             `gorm:"constraint:OnDelete:SET NULL"`
+  ItemID              *uuid.UUID            `gorm:"not null;index"`
+  Item                *Item                 `gorm:"constraint:OnDelete:CASCADE"`
+  FromLocationID      *uuid.UUID            `gorm:"index"`
+  FromLocation        *Location             `gorm:"constraint:OnDelete:SET NULL"`
+  ToLocationID        *uuid.UUID            `gorm:"index"`
+  ToLocation          *Location             `gorm:"constraint:OnDelete:SET NULL"`
+  GroupNo             int                   `gorm:"not null"`
+  Kind                string                `gorm:"not null"`         // move, swap or chain
+  Sequence            int                   `gorm:"not null"`
+  ViaTemporary        bool                  `gorm:"not null;default:false"` // stage in a spare slot first to break a cycle
+  Status              string                `gorm:"not null;default:'open';index"`
+  EstimatedCostSec    float64
+  BenefitSecPerDay    float64
+  CreatedByID         *uuid.UUID            `gorm:"index"`
+  CreatedBy           *User                 `gorm:"constraint:OnDelete:SET NULL"`
+  CompletedByID       *uuid.UUID
+  CompletedBy         *User                 `gorm:"constraint:OnDelete:SET NULL"`
+  CompletedAt         *time.Time
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+  UpdatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// StockFlag
+// ----------------------------------------------------
+// An item the last slow stock scan found dead or slow moving in a warehouse.
+// Scans compare against these to tell which items crossed a threshold.
+type StockFlag struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;uniqueIndex:idx_stock_flag_item"`
+  Warehouse           *Warehouse            `gorm:"constraint:OnDelete:CASCADE"`
+  ItemID              *uuid.UUID            `gorm:"not null;uniqueIndex:idx_stock_flag_item"`
+  Item                *Item                 `gorm:"constraint:OnDelete:CASCADE"`
+  Status              string                `gorm:"not null"` // see constants.StockFlagStatuses
+  FlaggedAt           time.Time             `gorm:"not null;default:now()"`
+  UpdatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// SlowStockSetting
+// ----------------------------------------------------
+// The thresholds slow stock reports and scans of a warehouse use where a
+// request leaves them out, the post-import scan included. Warehouses without
+// one use the defaults.
+type SlowStockSetting struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;uniqueIndex"`
+  Warehouse           *Warehouse            `gorm:"constraint:OnDelete:CASCADE"`
+  DeadDays            int                   `gorm:"not null"`
+  WindowDays          int                   `gorm:"not null"`
+  DropPct             float64               `gorm:"not null"`
+  MinPriorLines       int64                 `gorm:"not null"`
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+  UpdatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// SlottingScenario
+// ----------------------------------------------------
+// A named slotting strategy for a warehouse: the slot plan parameters, the
+// plan they produced and its KPIs, kept so strategies can be compared.
+// Travel is in metres over the plan window, ergonomic scores are 0..1.
+type SlottingScenario struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;index"`
+  Warehouse           *Warehouse            `gorm:"constraint:OnDelete:CASCADE"`
+  Name                string                `gorm:"not null"`
+  Description         string
+  Params              datatypes.JSON        `gorm:"type:jsonb"` // services.SlotPlanParams
+  PlanID              *uuid.UUID            `gorm:"index"`
+  Plan                *SlotPlan             `gorm:"constraint:OnDelete:SET NULL"`
+  ClonedFromID        *uuid.UUID            `gorm:"index"`
+  ClonedFrom          *SlottingScenario     `gorm:"constraint:OnDelete:SET NULL"`
+  ItemCount           int
+  CurrentTravelM      float64
+  TravelM             float64
+  CurrentErgoScore    float64
+  ErgoScore           float64
+  MovesRequired       int                   // item moves incl. staging through temporary slots
+  MoveHours           float64               // estimated labour for the moves
+  CapacityViolations  int                   // assigned pick faces that cannot hold a peak day
+  UnassignedCount     int
+  CreatedByID         *uuid.UUID            `gorm:"index"`
+  CreatedBy           *User                 `gorm:"constraint:OnDelete:SET NULL"`
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+  UpdatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// ForecastRun
+// ----------------------------------------------------
+// One demand forecast of a warehouse's items fitted on history over
+// [StartDate, EndDate] and projected Horizon periods past EndDate. The last
+// HoldoutPeriods of history are held back to measure accuracy.
+type ForecastRun struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;index"`
+  Warehouse           *Warehouse            `gorm:"constraint:OnDelete:CASCADE"`
+  Granularity         string                `gorm:"not null"` // day | week
+  SeasonLength        int                   // periods per season
+  Horizon             int                   // periods forecast
+  HoldoutPeriods      int
+  TransactionTypes    string                // comma separated categories
+  StartDate           time.Time             `gorm:"not null"`
+  EndDate             time.Time             `gorm:"not null"`
+  ItemCount           int
+  MAE                 *float64              `gorm:"column:mae"`  // mean absolute error per item period
+  WAPE                *float64              `gorm:"column:wape"` // percent, over all items
+  Bias                *float64              // percent, positive when over-forecasting
+  CreatedByID         *uuid.UUID            `gorm:"index"`
+  CreatedBy           *User                 `gorm:"constraint:OnDelete:SET NULL"`
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// ItemForecast
+// ----------------------------------------------------
+// An item's fitted model and forecast in a run. Points holds the forecast
+// units per period; accuracy fields are nil when the history was too short
+// for a holdout fitted with the same method as the forecast.
+type ItemForecast struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  RunID               *uuid.UUID            `gorm:"not null;uniqueIndex:idx_item_forecast_run_item"`
+  Run                 *ForecastRun          `gorm:"constraint:OnDelete:CASCADE"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;index"`
+  ItemID              *uuid.UUID            `gorm:"not null;uniqueIndex:idx_item_forecast_run_item"`
+  Item                *Item                 `gorm:"constraint:OnDelete:CASCADE"`
+  Method              string                `gorm:"not null;index"` // see constants.ForecastMethod*
+  Alpha               float64
+  Beta                float64
+  Gamma               float64
+  HistoryLines        int64
+  HistoryUnits        int64
+  ForecastLines       float64               // over the horizon
+  ForecastUnits       float64
+  AvgDailyUnits       float64
+  PeakDailyUnits      int64                 // average scaled by the item's historical peak-to-average ratio
+  Points              datatypes.JSON        `gorm:"type:jsonb"`
+  EvaluatedMethod     string                // method the holdout was scored with, empty without one
+  MAE                 *float64              `gorm:"column:mae"`
+  WAPE                *float64              `gorm:"column:wape"`
+  Bias                *float64
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// SlotAssignment
+// ----------------------------------------------------
+// One period an item was slotted to a location. EffectiveTo is nil while the
+// assignment is open; open assignments mirror the items_locations links, and
+// closed ones keep where items were slotted in the past.
+type SlotAssignment struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;index"`
+  Warehouse           *Warehouse            `gorm:"constraint:OnDelete:CASCADE"`
+  ItemID              *uuid.UUID            `gorm:"not null;index:idx_slot_assignment_pair"`
+  Item                *Item                 `gorm:"constraint:OnDelete:CASCADE"`
+  LocationID          *uuid.UUID            `gorm:"not null;index:idx_slot_assignment_pair"`
+  Location            *Location             `gorm:"constraint:OnDelete:CASCADE"`
+  AssignmentType      string                `gorm:"not null;default:'primary'"` // see constants.AssignmentTypes
+  EffectiveFrom       time.Time             `gorm:"not null;index"`
+  EffectiveTo         *time.Time            `gorm:"index"`
+  OpenSource          string                `gorm:"not null"` // see constants.AssignmentSource*
+  CloseSource         string
+  TransactionFileID   *uuid.UUID            `gorm:"index"` // import that opened the assignment
+  TransactionFile     *TransactionFile      `gorm:"constraint:OnDelete:SET NULL"`
+  OpenedByID          *uuid.UUID
+  OpenedBy            *User                 `gorm:"constraint:OnDelete:SET NULL"`
+  ClosedByID          *uuid.UUID
+  ClosedBy            *User                 `gorm:"constraint:OnDelete:SET NULL"`
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+  UpdatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// ActivityRollup
+// ----------------------------------------------------
+// Transaction records of one day summed per location, item and transaction
+// type (lowercased as imported). Built from transaction_records so analytics
+// need not scan the raw rows.
+type ActivityRollup struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;uniqueIndex:idx_activity_rollup"`
+  Warehouse           *Warehouse            `gorm:"constraint:OnDelete:CASCADE"`
+  Day                 time.Time             `gorm:"type:date;not null;uniqueIndex:idx_activity_rollup"`
+  LocationID          *uuid.UUID            `gorm:"not null;uniqueIndex:idx_activity_rollup;index"`
+  Location            *Location             `gorm:"constraint:OnDelete:CASCADE"`
+  ItemID              *uuid.UUID            `gorm:"not null;uniqueIndex:idx_activity_rollup;index"`
+  Item                *Item                 `gorm:"constraint:OnDelete:CASCADE"`
+  TransactionType     string                `gorm:"not null;uniqueIndex:idx_activity_rollup"`
+  Lines               int64                 `gorm:"not null"`
+  Units               int64                 `gorm:"not null"`
+  Orders              int64                 `gorm:"not null"` // distinct order names
+  UpdatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// LaborStandard
+// ----------------------------------------------------
+// The engineered time for one transaction type category in a warehouse: a
+// line is expected to take SecondsPerLine plus SecondsPerUnit per unit.
+// Productivity reports measure operators against it.
+type LaborStandard struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;uniqueIndex:idx_labor_standard_type"`
+  Warehouse           *Warehouse            `gorm:"constraint:OnDelete:CASCADE"`
+  TransactionType     string                `gorm:"not null;uniqueIndex:idx_labor_standard_type"` // see constants.TransactionTypes
+  SecondsPerLine      float64               `gorm:"not null;default:0"`
+  SecondsPerUnit      float64               `gorm:"not null;default:0"`
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+  UpdatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// TransactionException
+// ----------------------------------------------------
+// A transaction record the exceptions scan found suspect. A record has at
+// most one exception per type; rescans refresh it, keep its acknowledgement
+// and resolve it once the record no longer qualifies. Day is the record's
+// completed day, Value the offending figure (quantity, z-score).
+type TransactionException struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;index"`
+  Warehouse           *Warehouse            `gorm:"constraint:OnDelete:CASCADE"`
+  TransactionRecordID *uuid.UUID            `gorm:"not null;uniqueIndex:idx_transaction_exception"`
+  TransactionRecord   *TransactionRecord    `gorm:"constraint:OnDelete:CASCADE"`
+  ExceptionType       string                `gorm:"not null;uniqueIndex:idx_transaction_exception;index"` // see constants.ExceptionTypes
+  ItemID              *uuid.UUID            `gorm:"index"`
+  Item                *Item                 `gorm:"constraint:OnDelete:CASCADE"`
+  LocationID          *uuid.UUID            `gorm:"index"`
+  Location            *Location             `gorm:"constraint:OnDelete:CASCADE"`
+  Day                 time.Time             `gorm:"type:date"`
+  Value               float64
+  Detail              string
+  AcknowledgedByID    *uuid.UUID            `gorm:"index"`
+  AcknowledgedBy      *User                 `gorm:"constraint:OnDelete:SET NULL"`
+  AcknowledgedAt      *time.Time
+  AcknowledgeNote     string
+  ResolvedAt          *time.Time
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+  UpdatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// ErgonomicModel
+// ----------------------------------------------------
+// How a warehouse rates the ergonomics of its slots. The slot floor height
+// falls in a band bounded by KneeHeightCm, WaistHeightCm and
+// ShoulderHeightCm, each band with its own score; slots deeper than
+// ReachDepthCm score less, down to DeepReachScore at MaxReachDepthCm. A
+// slot's score is the HeightWeight/ReachWeight weighted mean of the two.
+// HeavyItemKg and FastMoverShare define the heavy items and the fast movers
+// ergonomic reports single out. Warehouses without one use the defaults.
+type ErgonomicModel struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;uniqueIndex"`
+  Warehouse           *Warehouse            `gorm:"constraint:OnDelete:CASCADE"`
+  KneeHeightCm        float64               `gorm:"not null"`
+  WaistHeightCm       float64               `gorm:"not null"`
+  ShoulderHeightCm    float64               `gorm:"not null"`
+  BelowKneeScore      float64               `gorm:"not null"`
+  KneeToWaistScore    float64               `gorm:"not null"`
+  GoldenScore         float64               `gorm:"not null"`
+  AboveShoulderScore  float64               `gorm:"not null"`
+  ReachDepthCm        float64               `gorm:"not null"`
+  MaxReachDepthCm     float64               `gorm:"not null"`
+  DeepReachScore      float64               `gorm:"not null"`
+  HeightWeight        float64               `gorm:"not null"`
+  ReachWeight         float64               `gorm:"not null"`
+  HeavyItemKg         float64               `gorm:"not null"`
+  FastMoverShare      float64               `gorm:"not null"` // top share of picked items by lines
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+  UpdatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// ReplenishmentSetting
+// ----------------------------------------------------
+// When a pick face of an item is replenished from reserve: below MinUnits,
+// up to MaxUnits (0 = the slot capacity) with the min_max method, or below
+// MinDays and up to MaxDays of its recent picks with days_of_supply. Setting
+// it up assumes the face is at its max level.
+type ReplenishmentSetting struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;index"`
+  Warehouse           *Warehouse            `gorm:"constraint:OnDelete:CASCADE"`
+  LocationID          *uuid.UUID            `gorm:"not null;uniqueIndex:idx_replenishment_setting"`
+  Location            *Location             `gorm:"constraint:OnDelete:CASCADE"`
+  ItemID              *uuid.UUID            `gorm:"not null;uniqueIndex:idx_replenishment_setting"`
+  Item                *Item                 `gorm:"constraint:OnDelete:CASCADE"`
+  Method              string                `gorm:"not null"` // see constants.ReplenishMethods
+  MinUnits            int64                 `gorm:"not null;default:0"`
+  MaxUnits            int64                 `gorm:"not null;default:0"`
+  MinDays             float64               `gorm:"not null;default:0"`
+  MaxDays             float64               `gorm:"not null;default:0"`
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+  UpdatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
+// ----------------------------------------------------
+// ReplenishmentTask
+// ----------------------------------------------------
+// A move of Quantity units from a reserve slot to a pick face that dropped
+// below its min level. OnHand, the levels and DaysOfSupply are as estimated
+// when the task was raised. FromLocation is nil when no reserve slot of the
+// item was known.
+type ReplenishmentTask struct {
+  ID                  uuid.UUID             `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+  CompanyID           *uuid.UUID            `gorm:"not null;index"`
+  Company             *Company              `gorm:"constraint:OnDelete:CASCADE"`
+  WarehouseID         *uuid.UUID            `gorm:"not null;index"`
+  Warehouse           *Warehouse            `gorm:"constraint:OnDelete:CASCADE"`
+  ItemID              *uuid.UUID            `gorm:"not null;index;uniqueIndex:idx_replenishment_open_task,where:status = 'open'"`
+  Item                *Item                 `gorm:"constraint:OnDelete:CASCADE"`
+  FromLocationID      *uuid.UUID            `gorm:"index"`
+  FromLocation        *Location             `gorm:"constraint:OnDelete:SET NULL"`
+  ToLocationID        *uuid.UUID            `gorm:"not null;index;uniqueIndex:idx_replenishment_open_task,where:status = 'open'"` // one open task per pick face and item
+  ToLocation          *Location             `gorm:"constraint:OnDelete:CASCADE"`
+  Quantity            int64                 `gorm:"not null"`
+  OnHand              int64                 `gorm:"not null"`
+  OnHandSource        string                `gorm:"not null"` // see constants.OnHandTransactions
+  MinUnits            int64                 `gorm:"not null"`
+  MaxUnits            int64                 `gorm:"not null"`
+  DaysOfSupply        *float64
+  Note                string
+  Status              string                `gorm:"not null;default:'open';index"` // see constants.ReplenishTaskStatuses
+  CompletedByID       *uuid.UUID
+  CompletedBy         *User                 `gorm:"constraint:OnDelete:SET NULL"`
+  CompletedAt         *time.Time
+  CreatedAt           time.Time             `gorm:"not null;default:now()"`
+  UpdatedAt           time.Time             `gorm:"not null;default:now()"`
+}
+
 // ----------------------------------------------------
 // UserAction
 // ----------------------------------------------------
@@ -13,7 +13,10 @@ import (
 	"github.com/google/uuid"
 	"github.com/xuri/excelize/v2"
 
+	"github.com/yungbote/slotter/backend/services/database/internal/constants"
+	"github.com/yungbote/slotter/backend/services/database/internal/events"
 	"github.com/yungbote/slotter/backend/services/database/internal/models"
+	"github.com/yungbote/slotter/backend/services/database/internal/repos"
 	"github.com/yungbote/slotter/backend/services/database/internal/services"
 )
 
@@ -30,6 +33,8 @@ type parserService struct {
 	trsvc services.TRSvc
 	tfsvc services.TFSvc
 	wsvc  services.WSvc // optional if you want to link items to the warehouse
+	zsvc  services.ZSvc
+	pub   events.PubSubPublisher
 }
 
 // Ensure we only treat certain columns as known transaction columns, and the rest as location columns.
@@ -50,6 +55,11 @@ type locationCache struct {
 	LocationPath     string
 	LocationNamePath string
 	ID               uuid.UUID
+	Status           string
+	SlotRole         string
+	// Model is the resolved location, which differs in path from the cache
+	// key when the key is an alias
+	Model            *models.Location
 }
 
 type itemCache struct {
@@ -64,6 +74,7 @@ type transactionRow struct {
 	TransactionQuantity int
 	CompletedQuantity   int
 	CompletedDate       *time.Time
+	CompletedBy         string
 	LocationPathKey     string
 	ItemNameKey         string
 }
@@ -75,6 +86,8 @@ func NewParserService(
 	trsvc services.TRSvc,
 	tfsvc services.TFSvc,
 	wsvc services.WSvc,
+	zsvc services.ZSvc,
+	pub events.PubSubPublisher,
 ) ParserService {
 	return &parserService{
 		lsvc:  lsvc,
@@ -82,6 +95,8 @@ func NewParserService(
 		trsvc: trsvc,
 		tfsvc: tfsvc,
 		wsvc:  wsvc,
+		zsvc:  zsvc,
+		pub:   pub,
 	}
 }
 
@@ -259,6 +274,7 @@ func handleRow(
 
 	dateStr := strings.TrimSpace(rowMap["completed date"])
 	dateVal := parseDate(dateStr)
+	completedBy := strings.TrimSpace(rowMap["completed by"])
 
 	// Cache location
 	if _, exists := locationMap[locPath]; !exists {
@@ -285,6 +301,7 @@ func handleRow(
 		TransactionQuantity: qty,
 		CompletedQuantity:   compQty,
 		CompletedDate:       dateVal,
+		CompletedBy:         completedBy,
 		LocationPathKey:     locPath,
 		ItemNameKey:         itemName,
 	})
@@ -311,14 +328,26 @@ func parseInt(s string) int {
 	return i
 }
 
-// parseDate tries "2006-01-02" layout
+// dateLayouts are the completed date formats understood, timestamps first so
+// productivity reports get the time of day when the file has it.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseDate tries each of dateLayouts
 func parseDate(s string) *time.Time {
 	s = strings.TrimSpace(s)
 	if s == "" {
 		return nil
 	}
-	if t, err := time.Parse("2006-01-02", s); err == nil {
-		return &t
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
 	}
 	return nil
 }
@@ -336,10 +365,13 @@ func flushToDB(
 		if loc.ID != uuid.Nil {
 			continue
 		}
-		// Check if location already exists
+		// Check if location already exists (renamed/merged paths resolve through aliases)
 		existing, err := p.lsvc.GetLocationByPath(companyID, warehouseID, loc.LocationPath)
 		if err == nil && existing != nil {
 			loc.ID = existing.ID
+			loc.Status = existing.Status
+			loc.SlotRole = existing.SlotRole
+			loc.Model = existing
 		} else {
 			// create
 			lModel := models.Location{
@@ -352,6 +384,8 @@ func flushToDB(
 				return 0, fmt.Errorf("failed to create location '%s': %w", loc.LocationPath, errCreate)
 			}
 			loc.ID = created.ID
+			loc.SlotRole = created.SlotRole
+			loc.Model = created
 		}
 	}
 
@@ -384,8 +418,27 @@ func flushToDB(
 		}
 	}
 
-	// 3) Link location<->item for each transaction row
+	// 3) Assign item->location for each transaction row, checking zone rules per new pair.
+	// Assignments start at the pair's first transaction in the file.
+	pairFirst := make(map[string]time.Time)
+	pairLast := make(map[string]time.Time)
+	for _, row := range rows {
+		loc := locationMap[row.LocationPathKey]
+		itm := itemMap[row.ItemNameKey]
+		if loc.ID == uuid.Nil || itm.ID == uuid.Nil || row.CompletedDate == nil {
+			continue
+		}
+		key := fmt.Sprintf("%s_%s", loc.ID.String(), itm.ID.String())
+		if first, ok := pairFirst[key]; !ok || row.CompletedDate.Before(first) {
+			pairFirst[key] = *row.CompletedDate
+		}
+		if row.CompletedDate.After(pairLast[key]) {
+			pairLast[key] = *row.CompletedDate
+		}
+	}
 	linkedSet := make(map[string]bool)
+	itemModels := make(map[uuid.UUID]*models.Item)
+	violationCount := 0
 	for _, row := range rows {
 		loc := locationMap[row.LocationPathKey]
 		itm := itemMap[row.ItemNameKey]
@@ -394,21 +447,63 @@ func flushToDB(
 		}
 		key := fmt.Sprintf("%s_%s", loc.ID.String(), itm.ID.String())
 		if !linkedSet[key] {
-			if err := p.lsvc.LinkToItem(loc.ID, itm.ID); err != nil {
+			if loc.SlotRole == constants.SlotRolePick {
+				if err := p.closeSupersededPrimaries(warehouseID, loc.ID, itm.ID, pairFirst, pairLast); err != nil {
+					return 0, fmt.Errorf("failed to re-slot item '%s' to '%s': %w", itm.Name, loc.LocationPath, err)
+				}
+			}
+			assignment := models.SlotAssignment{
+				CompanyID:         &companyID,
+				ItemID:            &itm.ID,
+				LocationID:        &loc.ID,
+				EffectiveFrom:     pairFirst[key],
+				OpenSource:        constants.AssignmentSourceImport,
+				TransactionFileID: &transactionFileID,
+			}
+			if _, err := p.lsvc.AssignItem(assignment); err != nil {
 				return 0, fmt.Errorf("failed to link location '%s' with item '%s': %w", loc.LocationPath, itm.Name, err)
 			}
 			linkedSet[key] = true
+
+			itemModel, ok := itemModels[itm.ID]
+			if !ok {
+				fetched, err := p.isvc.GetItemByID(itm.ID)
+				if err != nil {
+					return 0, fmt.Errorf("failed to load item '%s': %w", itm.Name, err)
+				}
+				itemModels[itm.ID] = fetched
+				itemModel = fetched
+			}
+			if loc.Model == nil {
+				fetched, err := p.lsvc.GetLocationByID(loc.ID)
+				if err != nil {
+					return 0, fmt.Errorf("failed to load location '%s': %w", loc.LocationPath, err)
+				}
+				loc.Model = fetched
+			}
+			found, err := p.zsvc.CheckLink(loc.Model, itemModel, constants.ViolationSourceImport, &transactionFileID)
+			if err != nil {
+				return 0, fmt.Errorf("failed to check zone rules for '%s' in '%s': %w", itm.Name, loc.LocationPath, err)
+			}
+			violationCount += len(found)
 		}
 	}
+	if violationCount > 0 {
+		_ = p.pub.PublishCompanyEvent(companyID, "IMPORT_ZONE_VIOLATIONS", map[string]interface{}{"transaction_file_id": transactionFileID, "warehouse_id": warehouseID, "violation_count": violationCount})
+	}
 
 	// 4) Create transaction records
 	createdCount := 0
+	closedHits := make(map[string]int)
 	for _, row := range rows {
 		loc := locationMap[row.LocationPathKey]
 		itm := itemMap[row.ItemNameKey]
 		if loc.ID == uuid.Nil || itm.ID == uuid.Nil {
 			continue
 		}
+		if constants.LocationStatusesClosed[loc.Status] {
+			closedHits[row.LocationPathKey]++
+		}
 
 		rec := models.TransactionRecord{
 			CompanyID:          &companyID,
@@ -421,6 +516,7 @@ func flushToDB(
 			Description:        row.Description,
 			TransactionQuantity: row.TransactionQuantity,
 			CompletedQuantity:   row.CompletedQuantity,
+			CompletedBy:         row.CompletedBy,
 		}
 		if row.CompletedDate != nil {
 			rec.CompletedDate = *row.CompletedDate
@@ -449,6 +545,50 @@ func flushToDB(
 		}
 	}
 
+	// 6) Flag imported activity on blocked/decommissioned locations
+	if len(closedHits) > 0 {
+		var flagged []map[string]interface{}
+		for path, count := range closedHits {
+			loc := locationMap[path]
+			flagged = append(flagged, map[string]interface{}{"location_id": loc.ID, "location_path": loc.LocationPath, "status": loc.Status, "record_count": count})
+		}
+		_ = p.pub.PublishCompanyEvent(companyID, "IMPORT_CLOSED_LOCATION_ACTIVITY", map[string]interface{}{"transaction_file_id": transactionFileID, "warehouse_id": warehouseID, "locations": flagged})
+	}
+
 	return createdCount, nil
 }
 
+// closeSupersededPrimaries ends the item's open primary assignments whose
+// slot saw its last activity in the file before the item's first activity at
+// newLocationID, i.e. the item was re-slotted there.
+func (p *parserService) closeSupersededPrimaries(
+	warehouseID, newLocationID, itemID uuid.UUID,
+	pairFirst, pairLast map[string]time.Time,
+) error {
+	first, ok := pairFirst[fmt.Sprintf("%s_%s", newLocationID.String(), itemID.String())]
+	if !ok {
+		return nil
+	}
+	primaries, err := p.lsvc.ListAssignments(repos.SlotAssignmentFilter{
+		WarehouseID:    warehouseID,
+		ItemID:         itemID,
+		AssignmentType: constants.AssignmentPrimary,
+		OpenOnly:       true,
+	})
+	if err != nil {
+		return err
+	}
+	for _, a := range primaries {
+		if *a.LocationID == newLocationID {
+			continue
+		}
+		last, seen := pairLast[fmt.Sprintf("%s_%s", a.LocationID.String(), itemID.String())]
+		if !seen || !last.Before(first) {
+			continue
+		}
+		if err := p.lsvc.ReleaseItem(*a.LocationID, itemID, first, constants.AssignmentSourceImport, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
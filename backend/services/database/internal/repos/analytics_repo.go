@@ -0,0 +1,316 @@
+package repos
+
+import (
+  "fmt"
+  "time"
+
+  "gorm.io/gorm"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+// AnalyticsFilter scopes the aggregate queries. EndDate is exclusive; RawTypes
+// are matched case-insensitively against transaction_type.
+type AnalyticsFilter struct {
+  CompanyID   uuid.UUID
+  WarehouseID uuid.UUID
+  ItemID      uuid.UUID
+  StartDate   time.Time
+  EndDate     time.Time
+  RawTypes    []string
+}
+
+// ItemDailyActivity is one item's activity of one transaction type on one day.
+type ItemDailyActivity struct {
+  ItemID          uuid.UUID
+  TransactionType string
+  Day             time.Time
+  Lines           int64
+  Units           int64
+}
+
+// ItemSummary carries per item figures that are not per day: distinct orders
+// and the item cube used for cube-movement metrics.
+type ItemSummary struct {
+  ItemID   uuid.UUID
+  ItemName string
+  CubeCm3  float64
+  Orders   int64
+}
+
+// PickLine is one transaction line with what a replay needs to rebuild orders.
+type PickLine struct {
+  ItemID        uuid.UUID
+  OrderName     string
+  CompletedDate time.Time
+}
+
+// OrderSummary is one order's size and the time of its first line.
+type OrderSummary struct {
+  OrderName   string
+  Lines       int64
+  Units       int64
+  CompletedAt time.Time
+}
+
+// OperatorLine is one line of an operator with where and what it was, for
+// productivity reports.
+type OperatorLine struct {
+  CompletedBy     string
+  CompletedDate   time.Time
+  TransactionType string
+  LocationPath    string
+  Units           int64
+}
+
+// UnitCount is how many lines were for a given quantity.
+type UnitCount struct {
+  Units int64
+  Lines int64
+}
+
+type TypeTotal struct {
+  TransactionType string
+  Lines           int64
+  Units           int64
+}
+
+// ItemLastActivity is an item's line count in range and the day of its latest line.
+type ItemLastActivity struct {
+  ItemID   uuid.UUID
+  Lines    int64
+  LastDate time.Time
+}
+
+// LocationItemUnits is the units of one transaction type an item moved at a
+// location, with the last day it did.
+type LocationItemUnits struct {
+  LocationID      uuid.UUID
+  ItemID          uuid.UUID
+  TransactionType string
+  Units           int64
+  LastDay         time.Time
+}
+
+// LocationItemDay is the units of f.RawTypes an item moved at a location on
+// one day.
+type LocationItemDay struct {
+  LocationID uuid.UUID
+  ItemID     uuid.UUID
+  Day        time.Time
+  Units      int64
+}
+
+// AnalyticsRepo aggregates transaction activity. Per item, day and type
+// figures come from the activity rollups. ItemSummaries, PickLines,
+// OrderSummaries, CountLinesWithoutOrder, LineUnitCounts and OperatorLines
+// need distinct orders or single lines, which the rollups do not keep, and
+// deliberately read the raw transaction_records.
+type AnalyticsRepo interface {
+  // ListTransactionTypes returns the distinct lowercased transaction types in range.
+  ListTransactionTypes(f AnalyticsFilter) ([]string, error)
+  ItemDailyActivity(f AnalyticsFilter) ([]ItemDailyActivity, error)
+  ItemSummaries(f AnalyticsFilter) ([]ItemSummary, error)
+  // PickLines lists the lines in range ordered by order name and time.
+  PickLines(f AnalyticsFilter) ([]PickLine, error)
+  // OrderSummaries aggregates lines with an order name per order.
+  OrderSummaries(f AnalyticsFilter) ([]OrderSummary, error)
+  CountLinesWithoutOrder(f AnalyticsFilter) (int64, error)
+  LineUnitCounts(f AnalyticsFilter) ([]UnitCount, error)
+  TypeTotals(f AnalyticsFilter) ([]TypeTotal, error)
+  ItemLastActivity(f AnalyticsFilter) ([]ItemLastActivity, error)
+  // OperatorLines lists the lines in range ordered by operator and time;
+  // lines without an operator come first with an empty CompletedBy.
+  OperatorLines(f AnalyticsFilter) ([]OperatorLine, error)
+  LocationItemUnits(f AnalyticsFilter) ([]LocationItemUnits, error)
+  LocationItemDays(f AnalyticsFilter) ([]LocationItemDay, error)
+}
+
+// unitsExpr is a line's quantity: the completed quantity when recorded,
+// otherwise the requested one.
+const unitsExpr = "COALESCE(NULLIF(transaction_records.completed_quantity, 0), transaction_records.transaction_quantity)"
+
+type analyticsRepo struct {
+  db *gorm.DB
+}
+
+func NewAnalyticsRepo(db *gorm.DB) AnalyticsRepo {
+  return &analyticsRepo{db: db}
+}
+
+func (r *analyticsRepo) scope(f AnalyticsFilter) *gorm.DB {
+  dbq := r.db.Model(&models.TransactionRecord{})
+  if f.CompanyID != uuid.Nil {
+    dbq = dbq.Where("transaction_records.company_id = ?", f.CompanyID)
+  }
+  if f.WarehouseID != uuid.Nil {
+    dbq = dbq.Where("transaction_records.warehouse_id = ?", f.WarehouseID)
+  }
+  if f.ItemID != uuid.Nil {
+    dbq = dbq.Where("transaction_records.item_id = ?", f.ItemID)
+  }
+  if !f.StartDate.IsZero() {
+    dbq = dbq.Where("transaction_records.completed_date >= ?", f.StartDate)
+  }
+  if !f.EndDate.IsZero() {
+    dbq = dbq.Where("transaction_records.completed_date < ?", f.EndDate)
+  }
+  if len(f.RawTypes) > 0 {
+    dbq = dbq.Where("lower(trim(transaction_records.transaction_type)) IN ?", f.RawTypes)
+  }
+  return dbq
+}
+
+func (r *analyticsRepo) ListTransactionTypes(f AnalyticsFilter) ([]string, error) {
+  var types []string
+  err := rollupScope(r.db, f).
+    Distinct("activity_rollups.transaction_type").
+    Pluck("activity_rollups.transaction_type", &types).Error
+  if err != nil {
+    return nil, fmt.Errorf("Failed to list transaction types: %w", err)
+  }
+  return types, nil
+}
+
+func (r *analyticsRepo) ItemDailyActivity(f AnalyticsFilter) ([]ItemDailyActivity, error) {
+  var rows []ItemDailyActivity
+  err := rollupScope(r.db, f).
+    Select(`activity_rollups.item_id AS item_id, activity_rollups.transaction_type AS transaction_type,
+      activity_rollups.day AS day, SUM(activity_rollups.lines) AS lines, SUM(activity_rollups.units) AS units`).
+    Group("1, 2, 3").
+    Scan(&rows).Error
+  if err != nil {
+    return nil, fmt.Errorf("Failed to aggregate item activity: %w", err)
+  }
+  return rows, nil
+}
+
+func (r *analyticsRepo) ItemSummaries(f AnalyticsFilter) ([]ItemSummary, error) {
+  var rows []ItemSummary
+  err := r.scope(f).
+    Joins("JOIN items ON items.id = transaction_records.item_id").
+    Select(`transaction_records.item_id AS item_id, items.name AS item_name,
+      items.length_cm * items.width_cm * items.height_cm AS cube_cm3,
+      COUNT(DISTINCT NULLIF(transaction_records.order_name, '')) AS orders`).
+    Group("transaction_records.item_id, items.name, items.length_cm, items.width_cm, items.height_cm").
+    Scan(&rows).Error
+  if err != nil {
+    return nil, fmt.Errorf("Failed to summarize items: %w", err)
+  }
+  return rows, nil
+}
+
+func (r *analyticsRepo) PickLines(f AnalyticsFilter) ([]PickLine, error) {
+  var rows []PickLine
+  err := r.scope(f).
+    Select("transaction_records.item_id, transaction_records.order_name, transaction_records.completed_date").
+    Order("transaction_records.order_name, transaction_records.completed_date").
+    Scan(&rows).Error
+  if err != nil {
+    return nil, fmt.Errorf("Failed to list pick lines: %w", err)
+  }
+  return rows, nil
+}
+
+func (r *analyticsRepo) OrderSummaries(f AnalyticsFilter) ([]OrderSummary, error) {
+  var rows []OrderSummary
+  err := r.scope(f).
+    Where("COALESCE(transaction_records.order_name, '') <> ''").
+    Select(`transaction_records.order_name AS order_name, COUNT(*) AS lines,
+      COALESCE(SUM(` + unitsExpr + `), 0) AS units,
+      MIN(transaction_records.completed_date) AS completed_at`).
+    Group("transaction_records.order_name").
+    Scan(&rows).Error
+  if err != nil {
+    return nil, fmt.Errorf("Failed to summarize orders: %w", err)
+  }
+  return rows, nil
+}
+
+func (r *analyticsRepo) CountLinesWithoutOrder(f AnalyticsFilter) (int64, error) {
+  var count int64
+  if err := r.scope(f).Where("COALESCE(transaction_records.order_name, '') = ''").Count(&count).Error; err != nil {
+    return 0, fmt.Errorf("Failed to count lines without order: %w", err)
+  }
+  return count, nil
+}
+
+func (r *analyticsRepo) LineUnitCounts(f AnalyticsFilter) ([]UnitCount, error) {
+  var rows []UnitCount
+  err := r.scope(f).
+    Select(unitsExpr + " AS units, COUNT(*) AS lines").
+    Group("1").
+    Scan(&rows).Error
+  if err != nil {
+    return nil, fmt.Errorf("Failed to count line quantities: %w", err)
+  }
+  return rows, nil
+}
+
+func (r *analyticsRepo) TypeTotals(f AnalyticsFilter) ([]TypeTotal, error) {
+  var rows []TypeTotal
+  err := rollupScope(r.db, f).
+    Select(`activity_rollups.transaction_type AS transaction_type, SUM(activity_rollups.lines) AS lines,
+      SUM(activity_rollups.units) AS units`).
+    Group("1").
+    Scan(&rows).Error
+  if err != nil {
+    return nil, fmt.Errorf("Failed to total transaction types: %w", err)
+  }
+  return rows, nil
+}
+
+func (r *analyticsRepo) ItemLastActivity(f AnalyticsFilter) ([]ItemLastActivity, error) {
+  var rows []ItemLastActivity
+  err := rollupScope(r.db, f).
+    Select("activity_rollups.item_id AS item_id, SUM(activity_rollups.lines) AS lines, MAX(activity_rollups.day) AS last_date").
+    Group("activity_rollups.item_id").
+    Scan(&rows).Error
+  if err != nil {
+    return nil, fmt.Errorf("Failed to aggregate item activity: %w", err)
+  }
+  return rows, nil
+}
+
+func (r *analyticsRepo) OperatorLines(f AnalyticsFilter) ([]OperatorLine, error) {
+  var rows []OperatorLine
+  err := r.scope(f).
+    Joins("JOIN locations ON locations.id = transaction_records.location_id").
+    Select(`COALESCE(trim(transaction_records.completed_by), '') AS completed_by,
+      transaction_records.completed_date AS completed_date,
+      lower(trim(transaction_records.transaction_type)) AS transaction_type,
+      locations.location_path AS location_path, ` + unitsExpr + ` AS units`).
+    Order("1, transaction_records.completed_date").
+    Scan(&rows).Error
+  if err != nil {
+    return nil, fmt.Errorf("Failed to list operator lines: %w", err)
+  }
+  return rows, nil
+}
+
+func (r *analyticsRepo) LocationItemUnits(f AnalyticsFilter) ([]LocationItemUnits, error) {
+  var rows []LocationItemUnits
+  err := rollupScope(r.db, f).
+    Select(`activity_rollups.location_id AS location_id, activity_rollups.item_id AS item_id,
+      activity_rollups.transaction_type AS transaction_type, SUM(activity_rollups.units) AS units,
+      MAX(activity_rollups.day) AS last_day`).
+    Group("1, 2, 3").
+    Scan(&rows).Error
+  if err != nil {
+    return nil, fmt.Errorf("Failed to aggregate location activity: %w", err)
+  }
+  return rows, nil
+}
+
+func (r *analyticsRepo) LocationItemDays(f AnalyticsFilter) ([]LocationItemDay, error) {
+  var rows []LocationItemDay
+  err := rollupScope(r.db, f).
+    Select(`activity_rollups.location_id AS location_id, activity_rollups.item_id AS item_id,
+      activity_rollups.day AS day, SUM(activity_rollups.units) AS units`).
+    Group("1, 2, 3").
+    Scan(&rows).Error
+  if err != nil {
+    return nil, fmt.Errorf("Failed to aggregate location activity: %w", err)
+  }
+  return rows, nil
+}
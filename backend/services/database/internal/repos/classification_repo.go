@@ -0,0 +1,132 @@
+package repos
+
+import (
+  "fmt"
+
+  "gorm.io/gorm"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+type ClassificationRunFilter struct {
+  CompanyID     uuid.UUID
+  WarehouseID   uuid.UUID
+  Basis         string
+  SortField     string
+  SortDir       string
+}
+
+type ItemClassificationFilter struct {
+  RunID         uuid.UUID
+  ItemID        uuid.UUID
+  ABCClass      string
+  XYZClass      string
+  SortField     string
+  SortDir       string
+}
+
+type ClassificationRepo interface {
+  // CreateRun stores the run and its item rows in one transaction.
+  CreateRun(run models.ClassificationRun, items []*models.ItemClassification) (*models.ClassificationRun, error)
+  GetRunByID(runID uuid.UUID) (*models.ClassificationRun, error)
+  LatestRun(warehouseID uuid.UUID) (*models.ClassificationRun, error)
+  DeleteRun(runID uuid.UUID) error
+  ListRuns(f ClassificationRunFilter) ([]*models.ClassificationRun, error)
+  ListItems(f ItemClassificationFilter) ([]*models.ItemClassification, error)
+}
+
+type classificationRepo struct {
+  db *gorm.DB
+}
+
+func NewClassificationRepo(db *gorm.DB) ClassificationRepo {
+  return &classificationRepo{db: db}
+}
+
+func (r *classificationRepo) CreateRun(run models.ClassificationRun, items []*models.ItemClassification) (*models.ClassificationRun, error) {
+  err := r.db.Transaction(func(tx *gorm.DB) error {
+    if err := tx.Create(&run).Error; err != nil {
+      return err
+    }
+    for _, it := range items {
+      it.RunID = &run.ID
+    }
+    if len(items) == 0 {
+      return nil
+    }
+    return tx.CreateInBatches(items, 500).Error
+  })
+  if err != nil {
+    return nil, fmt.Errorf("Failed to create classification run: %w", err)
+  }
+  return &run, nil
+}
+
+func (r *classificationRepo) GetRunByID(runID uuid.UUID) (*models.ClassificationRun, error) {
+  var run models.ClassificationRun
+  if err := r.db.First(&run, "id = ?", runID).Error; err != nil {
+    return nil, fmt.Errorf("Classification run not found with id: '%s': %w", runID, err)
+  }
+  return &run, nil
+}
+
+func (r *classificationRepo) LatestRun(warehouseID uuid.UUID) (*models.ClassificationRun, error) {
+  var run models.ClassificationRun
+  err := r.db.Where("warehouse_id = ?", warehouseID).
+    Order("created_at DESC").
+    First(&run).Error
+  if err != nil {
+    return nil, fmt.Errorf("No classification run for warehouse '%s': %w", warehouseID, err)
+  }
+  return &run, nil
+}
+
+func (r *classificationRepo) DeleteRun(runID uuid.UUID) error {
+  return r.db.Delete(&models.ClassificationRun{}, "id = ?", runID).Error
+}
+
+func (r *classificationRepo) ListRuns(f ClassificationRunFilter) ([]*models.ClassificationRun, error) {
+  dbq := r.db.Model(&models.ClassificationRun{})
+  if f.CompanyID != uuid.Nil {
+    dbq = dbq.Where("company_id = ?", f.CompanyID)
+  }
+  if f.WarehouseID != uuid.Nil {
+    dbq = dbq.Where("warehouse_id = ?", f.WarehouseID)
+  }
+  if f.Basis != "" {
+    dbq = dbq.Where("basis = ?", f.Basis)
+  }
+  allowed := []string{"basis", "start_date", "end_date", "item_count", "created_at"}
+  dbq = applySorting(dbq, f.SortField, f.SortDir, allowed)
+  var runs []*models.ClassificationRun
+  if err := dbq.Find(&runs).Error; err != nil {
+    return nil, err
+  }
+  return runs, nil
+}
+
+func (r *classificationRepo) ListItems(f ItemClassificationFilter) ([]*models.ItemClassification, error) {
+  dbq := r.db.Model(&models.ItemClassification{}).Preload("Item")
+  if f.RunID != uuid.Nil {
+    dbq = dbq.Where("run_id = ?", f.RunID)
+  }
+  if f.ItemID != uuid.Nil {
+    dbq = dbq.Where("item_id = ?", f.ItemID)
+  }
+  if f.ABCClass != "" {
+    dbq = dbq.Where("abc_class = ?", f.ABCClass)
+  }
+  if f.XYZClass != "" {
+    dbq = dbq.Where("xyz_class = ?", f.XYZClass)
+  }
+  if f.SortField == "" {
+    f.SortField, f.SortDir = "value", "desc"
+  }
+  allowed := []string{"abc_class", "xyz_class", "value", "share", "cumulative_share", "lines", "units", "cv", "created_at"}
+  dbq = applySorting(dbq, f.SortField, f.SortDir, allowed)
+  var items []*models.ItemClassification
+  if err := dbq.Find(&items).Error; err != nil {
+    return nil, err
+  }
+  return items, nil
+}
@@ -0,0 +1,64 @@
+package repos
+
+import (
+  "fmt"
+  "time"
+
+  "gorm.io/gorm"
+  "gorm.io/gorm/clause"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+type ErgonomicRepo interface {
+  // GetByWarehouse returns the warehouse's ergonomic model, nil when it has
+  // none.
+  GetByWarehouse(warehouseID uuid.UUID) (*models.ErgonomicModel, error)
+  // Upsert creates the warehouse's model or replaces its parameters.
+  Upsert(m *models.ErgonomicModel) error
+  Delete(warehouseID uuid.UUID) error
+}
+
+type ergonomicRepo struct {
+  db *gorm.DB
+}
+
+func NewErgonomicRepo(db *gorm.DB) ErgonomicRepo {
+  return &ergonomicRepo{db: db}
+}
+
+func (r *ergonomicRepo) GetByWarehouse(warehouseID uuid.UUID) (*models.ErgonomicModel, error) {
+  var m models.ErgonomicModel
+  err := r.db.Where("warehouse_id = ?", warehouseID).First(&m).Error
+  switch {
+  case err == gorm.ErrRecordNotFound:
+    return nil, nil
+  case err != nil:
+    return nil, fmt.Errorf("Failed to get ergonomic model for warehouse with id: '%s': %w", warehouseID, err)
+  }
+  return &m, nil
+}
+
+func (r *ergonomicRepo) Upsert(m *models.ErgonomicModel) error {
+  m.UpdatedAt = time.Now()
+  err := r.db.Clauses(clause.OnConflict{
+    Columns:   []clause.Column{{Name: "warehouse_id"}},
+    DoUpdates: clause.AssignmentColumns([]string{
+      "knee_height_cm", "waist_height_cm", "shoulder_height_cm",
+      "below_knee_score", "knee_to_waist_score", "golden_score", "above_shoulder_score",
+      "reach_depth_cm", "max_reach_depth_cm", "deep_reach_score",
+      "height_weight", "reach_weight", "heavy_item_kg", "fast_mover_share", "updated_at",
+    }),
+  }).Create(m).Error
+  if err != nil {
+    return fmt.Errorf("Failed to save ergonomic model: %w", err)
+  }
+  return nil
+}
+
+func (r *ergonomicRepo) Delete(warehouseID uuid.UUID) error {
+  if err := r.db.Where("warehouse_id = ?", warehouseID).Delete(&models.ErgonomicModel{}).Error; err != nil {
+    return fmt.Errorf("Failed to delete ergonomic model: %w", err)
+  }
+  return nil
+}
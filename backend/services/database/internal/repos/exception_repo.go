@@ -0,0 +1,200 @@
+package repos
+
+import (
+  "fmt"
+  "time"
+
+  "gorm.io/gorm"
+  "gorm.io/gorm/clause"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+type ExceptionFilter struct {
+  CompanyID           uuid.UUID
+  WarehouseID         uuid.UUID
+  ItemID              uuid.UUID
+  LocationID          uuid.UUID
+  TransactionRecordID uuid.UUID
+  ExceptionType       string
+  IncludeAcknowledged bool
+  IncludeResolved     bool
+  SortField           string
+  SortDir             string
+}
+
+// RecordCheck is a transaction record with what the record level exception
+// rules look at.
+type RecordCheck struct {
+  RecordID            uuid.UUID
+  ItemID              uuid.UUID
+  LocationID          uuid.UUID
+  TransactionType     string
+  TransactionQuantity int64
+  CompletedQuantity   int64
+  CompletedDate       time.Time
+  LocationStatus      string
+  SlotRole            string
+  WarehouseGoLive     *time.Time
+}
+
+type ExceptionRepo interface {
+  // SuspectRecords returns the warehouse's records breaking a quantity or
+  // date rule, plus those of pickTypes on a closed or non-pick location.
+  SuspectRecords(warehouseID uuid.UUID, pickTypes []string) ([]RecordCheck, error)
+  // LargestRecord is the item's record of rawTypes with the most units on day.
+  LargestRecord(warehouseID, itemID uuid.UUID, day time.Time, rawTypes []string) (*models.TransactionRecord, error)
+  ListOpen(warehouseID uuid.UUID) ([]*models.TransactionException, error)
+  // Upsert saves an exception, refreshing and reopening an existing one of
+  // the same record and type without touching its acknowledgement.
+  Upsert(e *models.TransactionException) error
+  // ResolveStale resolves the warehouse's open exceptions of the type whose
+  // record is not in keep. Non-zero start/end limit it to days in [start, end).
+  ResolveStale(warehouseID uuid.UUID, exceptionType string, keep []uuid.UUID, start, end time.Time) (int64, error)
+  Acknowledge(warehouseID uuid.UUID, exceptionIDs []uuid.UUID, userID uuid.UUID, note string) (int64, error)
+  ListExceptions(f ExceptionFilter) ([]*models.TransactionException, error)
+}
+
+type exceptionRepo struct {
+  db *gorm.DB
+}
+
+func NewExceptionRepo(db *gorm.DB) ExceptionRepo {
+  return &exceptionRepo{db: db}
+}
+
+func (r *exceptionRepo) SuspectRecords(warehouseID uuid.UUID, pickTypes []string) ([]RecordCheck, error) {
+  closed := make([]string, 0, len(constants.LocationStatusesClosed))
+  for status := range constants.LocationStatusesClosed {
+    closed = append(closed, status)
+  }
+  var rows []RecordCheck
+  err := r.db.Table("transaction_records tr").
+    Joins("JOIN locations l ON l.id = tr.location_id").
+    Joins("JOIN warehouses w ON w.id = tr.warehouse_id").
+    Select(`tr.id AS record_id, tr.item_id, tr.location_id, lower(trim(tr.transaction_type)) AS transaction_type,
+      tr.transaction_quantity, tr.completed_quantity, tr.completed_date,
+      l.status AS location_status, l.slot_role, w.go_live_date AS warehouse_go_live`).
+    Where("tr.warehouse_id = ?", warehouseID).
+    Where(`tr.completed_quantity > tr.transaction_quantity OR tr.transaction_quantity <= 0 OR tr.completed_quantity < 0
+      OR tr.completed_date > now() OR tr.completed_date < w.go_live_date
+      OR ((l.status IN ? OR l.slot_role <> ?) AND lower(trim(tr.transaction_type)) IN ?)`,
+      closed, constants.SlotRolePick, pickTypes).
+    Scan(&rows).Error
+  if err != nil {
+    return nil, fmt.Errorf("Failed to check transaction records: %w", err)
+  }
+  return rows, nil
+}
+
+func (r *exceptionRepo) LargestRecord(warehouseID, itemID uuid.UUID, day time.Time, rawTypes []string) (*models.TransactionRecord, error) {
+  var rec models.TransactionRecord
+  err := r.db.Where("warehouse_id = ? AND item_id = ?", warehouseID, itemID).
+    Where("completed_date >= ? AND completed_date < ?", day, day.AddDate(0, 0, 1)).
+    Where("lower(trim(transaction_type)) IN ?", rawTypes).
+    Order(unitsExpr + " DESC").
+    First(&rec).Error
+  if err != nil {
+    return nil, fmt.Errorf("Failed to find record of item with id: '%s' on %s: %w", itemID, day.Format("2006-01-02"), err)
+  }
+  return &rec, nil
+}
+
+func (r *exceptionRepo) ListOpen(warehouseID uuid.UUID) ([]*models.TransactionException, error) {
+  var out []*models.TransactionException
+  if err := r.db.Where("warehouse_id = ? AND resolved_at IS NULL", warehouseID).Find(&out).Error; err != nil {
+    return nil, fmt.Errorf("Failed to list exceptions for warehouse with id: '%s': %w", warehouseID, err)
+  }
+  return out, nil
+}
+
+func (r *exceptionRepo) Upsert(e *models.TransactionException) error {
+  err := r.db.Clauses(clause.OnConflict{
+    Columns:   []clause.Column{{Name: "transaction_record_id"}, {Name: "exception_type"}},
+    DoUpdates: clause.Assignments(map[string]interface{}{
+      "item_id":     e.ItemID,
+      "location_id": e.LocationID,
+      "day":         e.Day,
+      "value":       e.Value,
+      "detail":      e.Detail,
+      "resolved_at": nil,
+      "updated_at":  time.Now(),
+    }),
+  }).Create(e).Error
+  if err != nil {
+    return fmt.Errorf("Failed to save transaction exception: %w", err)
+  }
+  return nil
+}
+
+func (r *exceptionRepo) ResolveStale(warehouseID uuid.UUID, exceptionType string, keep []uuid.UUID, start, end time.Time) (int64, error) {
+  dbq := r.db.Model(&models.TransactionException{}).
+    Where("warehouse_id = ? AND exception_type = ? AND resolved_at IS NULL", warehouseID, exceptionType)
+  if len(keep) > 0 {
+    dbq = dbq.Where("transaction_record_id NOT IN ?", keep)
+  }
+  if !start.IsZero() {
+    dbq = dbq.Where("day >= ?", start)
+  }
+  if !end.IsZero() {
+    dbq = dbq.Where("day < ?", end)
+  }
+  now := time.Now()
+  res := dbq.Updates(map[string]interface{}{"resolved_at": now, "updated_at": now})
+  if res.Error != nil {
+    return 0, fmt.Errorf("Failed to resolve transaction exceptions: %w", res.Error)
+  }
+  return res.RowsAffected, nil
+}
+
+func (r *exceptionRepo) Acknowledge(warehouseID uuid.UUID, exceptionIDs []uuid.UUID, userID uuid.UUID, note string) (int64, error) {
+  now := time.Now()
+  res := r.db.Model(&models.TransactionException{}).
+    Where("warehouse_id = ? AND id IN ? AND acknowledged_at IS NULL", warehouseID, exceptionIDs).
+    Updates(map[string]interface{}{
+      "acknowledged_by_id": userID,
+      "acknowledged_at":    now,
+      "acknowledge_note":   note,
+      "updated_at":         now,
+    })
+  if res.Error != nil {
+    return 0, fmt.Errorf("Failed to acknowledge transaction exceptions: %w", res.Error)
+  }
+  return res.RowsAffected, nil
+}
+
+func (r *exceptionRepo) ListExceptions(f ExceptionFilter) ([]*models.TransactionException, error) {
+  dbq := r.db.Model(&models.TransactionException{}).Preload("TransactionRecord").Preload("Item").Preload("Location")
+  if f.CompanyID != uuid.Nil {
+    dbq = dbq.Where("transaction_exceptions.company_id = ?", f.CompanyID)
+  }
+  if f.WarehouseID != uuid.Nil {
+    dbq = dbq.Where("transaction_exceptions.warehouse_id = ?", f.WarehouseID)
+  }
+  if f.ItemID != uuid.Nil {
+    dbq = dbq.Where("transaction_exceptions.item_id = ?", f.ItemID)
+  }
+  if f.LocationID != uuid.Nil {
+    dbq = dbq.Where("transaction_exceptions.location_id = ?", f.LocationID)
+  }
+  if f.TransactionRecordID != uuid.Nil {
+    dbq = dbq.Where("transaction_exceptions.transaction_record_id = ?", f.TransactionRecordID)
+  }
+  if f.ExceptionType != "" {
+    dbq = dbq.Where("transaction_exceptions.exception_type = ?", f.ExceptionType)
+  }
+  if !f.IncludeAcknowledged {
+    dbq = dbq.Where("transaction_exceptions.acknowledged_at IS NULL")
+  }
+  if !f.IncludeResolved {
+    dbq = dbq.Where("transaction_exceptions.resolved_at IS NULL")
+  }
+  allowed := []string{"exception_type", "day", "value", "created_at", "updated_at", "acknowledged_at"}
+  dbq = applySorting(dbq, f.SortField, f.SortDir, allowed)
+  var out []*models.TransactionException
+  if err := dbq.Find(&out).Error; err != nil {
+    return nil, err
+  }
+  return out, nil
+}
@@ -0,0 +1,128 @@
+package repos
+
+import (
+  "fmt"
+
+  "gorm.io/gorm"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+type ForecastRunFilter struct {
+  CompanyID     uuid.UUID
+  WarehouseID   uuid.UUID
+  Granularity   string
+  SortField     string
+  SortDir       string
+}
+
+type ItemForecastFilter struct {
+  RunID         uuid.UUID
+  ItemID        uuid.UUID
+  Method        string
+  SortField     string
+  SortDir       string
+}
+
+type ForecastRepo interface {
+  // CreateRun stores the run and its item rows in one transaction.
+  CreateRun(run models.ForecastRun, items []*models.ItemForecast) (*models.ForecastRun, error)
+  GetRunByID(runID uuid.UUID) (*models.ForecastRun, error)
+  LatestRun(warehouseID uuid.UUID) (*models.ForecastRun, error)
+  DeleteRun(runID uuid.UUID) error
+  ListRuns(f ForecastRunFilter) ([]*models.ForecastRun, error)
+  ListItems(f ItemForecastFilter) ([]*models.ItemForecast, error)
+}
+
+type forecastRepo struct {
+  db *gorm.DB
+}
+
+func NewForecastRepo(db *gorm.DB) ForecastRepo {
+  return &forecastRepo{db: db}
+}
+
+func (r *forecastRepo) CreateRun(run models.ForecastRun, items []*models.ItemForecast) (*models.ForecastRun, error) {
+  err := r.db.Transaction(func(tx *gorm.DB) error {
+    if err := tx.Create(&run).Error; err != nil {
+      return err
+    }
+    for _, it := range items {
+      it.RunID = &run.ID
+    }
+    if len(items) == 0 {
+      return nil
+    }
+    return tx.CreateInBatches(items, 500).Error
+  })
+  if err != nil {
+    return nil, fmt.Errorf("Failed to create forecast run: %w", err)
+  }
+  return &run, nil
+}
+
+func (r *forecastRepo) GetRunByID(runID uuid.UUID) (*models.ForecastRun, error) {
+  var run models.ForecastRun
+  if err := r.db.First(&run, "id = ?", runID).Error; err != nil {
+    return nil, fmt.Errorf("Forecast run not found with id: '%s': %w", runID, err)
+  }
+  return &run, nil
+}
+
+func (r *forecastRepo) LatestRun(warehouseID uuid.UUID) (*models.ForecastRun, error) {
+  var run models.ForecastRun
+  err := r.db.Where("warehouse_id = ?", warehouseID).
+    Order("created_at DESC").
+    First(&run).Error
+  if err != nil {
+    return nil, fmt.Errorf("No forecast run for warehouse '%s': %w", warehouseID, err)
+  }
+  return &run, nil
+}
+
+func (r *forecastRepo) DeleteRun(runID uuid.UUID) error {
+  return r.db.Delete(&models.ForecastRun{}, "id = ?", runID).Error
+}
+
+func (r *forecastRepo) ListRuns(f ForecastRunFilter) ([]*models.ForecastRun, error) {
+  dbq := r.db.Model(&models.ForecastRun{})
+  if f.CompanyID != uuid.Nil {
+    dbq = dbq.Where("company_id = ?", f.CompanyID)
+  }
+  if f.WarehouseID != uuid.Nil {
+    dbq = dbq.Where("warehouse_id = ?", f.WarehouseID)
+  }
+  if f.Granularity != "" {
+    dbq = dbq.Where("granularity = ?", f.Granularity)
+  }
+  allowed := []string{"granularity", "start_date", "end_date", "item_count", "wape", "created_at"}
+  dbq = applySorting(dbq, f.SortField, f.SortDir, allowed)
+  var runs []*models.ForecastRun
+  if err := dbq.Find(&runs).Error; err != nil {
+    return nil, err
+  }
+  return runs, nil
+}
+
+func (r *forecastRepo) ListItems(f ItemForecastFilter) ([]*models.ItemForecast, error) {
+  dbq := r.db.Model(&models.ItemForecast{}).Preload("Item")
+  if f.RunID != uuid.Nil {
+    dbq = dbq.Where("run_id = ?", f.RunID)
+  }
+  if f.ItemID != uuid.Nil {
+    dbq = dbq.Where("item_id = ?", f.ItemID)
+  }
+  if f.Method != "" {
+    dbq = dbq.Where("method = ?", f.Method)
+  }
+  if f.SortField == "" {
+    f.SortField, f.SortDir = "forecast_units", "desc"
+  }
+  allowed := []string{"method", "history_units", "forecast_units", "forecast_lines", "avg_daily_units", "mae", "wape", "bias", "created_at"}
+  dbq = applySorting(dbq, f.SortField, f.SortDir, allowed)
+  var items []*models.ItemForecast
+  if err := dbq.Find(&items).Error; err != nil {
+    return nil, err
+  }
+  return items, nil
+}
@@ -10,6 +10,7 @@ import (
 )
 
 type ItemFilter struct {
+    IDs         []uuid.UUID
     CompanyID   uuid.UUID
     WarehouseID uuid.UUID
     LocationID  uuid.UUID
@@ -25,6 +26,7 @@ type IRepo interface {
     //GENERAL CRUD
     Create(item models.Item) (*models.Item, error)
     UpdateName(itemID uuid.UUID, newName string) error
+    UpdateAttributes(itemID uuid.UUID, attrs models.Item) error
     GetByID(itemID uuid.UUID) (*models.Item, error)
     GetByNameAndCompanyID(companyID uuid.UUID, name string) (*models.Item, error)
     Delete(itemID uuid.UUID) error
@@ -66,6 +68,19 @@ func (r *iRepo) UpdateName(itemID uuid.UUID, newName string) error {
         Update("name", newName).Error
 }
 
+func (r *iRepo) UpdateAttributes(itemID uuid.UUID, attrs models.Item) error {
+    return r.db.Model(&models.Item{}).
+        Where("id = ?", itemID).
+        Updates(map[string]interface{}{
+            "unit_weight_kg":    attrs.UnitWeightKg,
+            "length_cm":         attrs.LengthCm,
+            "width_cm":          attrs.WidthCm,
+            "height_cm":         attrs.HeightCm,
+            "temperature_class": attrs.TemperatureClass,
+            "hazmat":            attrs.Hazmat,
+        }).Error
+}
+
 func (r *iRepo) GetByID(itemID uuid.UUID) (*models.Item, error) {
     var i models.Item
     if err := r.db.First(&i, "id = ?", itemID).Error; err != nil {
@@ -250,6 +265,9 @@ func (r *iRepo) GetByNameAndCompanyID(companyID uuid.UUID, name string) (*models
 
 func (r *iRepo) ListItems(f ItemFilter) ([]*models.Item, error) {
     dbq := r.db.Model(&models.Item{}).Select("DISTINCT items.*")
+    if len(f.IDs) > 0 {
+        dbq = dbq.Where("items.id IN ?", f.IDs)
+    }
     if f.CompanyID != uuid.Nil {
         dbq = dbq.Where("items.company_id = ?", f.CompanyID)
     }
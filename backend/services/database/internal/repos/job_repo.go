@@ -0,0 +1,99 @@
+package repos
+
+import (
+  "fmt"
+  "time"
+
+  "gorm.io/datatypes"
+  "gorm.io/gorm"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+type JobFilter struct {
+  CompanyID     uuid.UUID
+  Type          string
+  Status        string
+  SortField     string
+  SortDir       string
+}
+
+type JRepo interface {
+  Create(job models.Job) (*models.Job, error)
+  GetByID(jobID uuid.UUID) (*models.Job, error)
+  MarkRunning(jobID uuid.UUID) error
+  UpdateTotal(jobID uuid.UUID, total int) error
+  UpdateProgress(jobID uuid.UUID, progress int) error
+  Finish(jobID uuid.UUID, status string, result datatypes.JSON, errMsg string) error
+  ListJobs(f JobFilter) ([]*models.Job, error)
+}
+
+type jRepo struct {
+  db *gorm.DB
+}
+
+func NewJRepo(db *gorm.DB) JRepo {
+  return &jRepo{db: db}
+}
+
+func (r *jRepo) Create(job models.Job) (*models.Job, error) {
+  if err := r.db.Create(&job).Error; err != nil {
+    return nil, fmt.Errorf("Failed to create job: %w", err)
+  }
+  return &job, nil
+}
+
+func (r *jRepo) GetByID(jobID uuid.UUID) (*models.Job, error) {
+  var job models.Job
+  if err := r.db.First(&job, "id = ?", jobID).Error; err != nil {
+    return nil, fmt.Errorf("Job not found with id: '%s': %w", jobID, err)
+  }
+  return &job, nil
+}
+
+func (r *jRepo) MarkRunning(jobID uuid.UUID) error {
+  now := time.Now()
+  return r.db.Model(&models.Job{}).
+    Where("id = ?", jobID).
+    Updates(map[string]interface{}{"status": constants.JobStatusRunning, "started_at": &now}).Error
+}
+
+func (r *jRepo) UpdateTotal(jobID uuid.UUID, total int) error {
+  return r.db.Model(&models.Job{}).
+    Where("id = ?", jobID).
+    Update("total", total).Error
+}
+
+func (r *jRepo) UpdateProgress(jobID uuid.UUID, progress int) error {
+  return r.db.Model(&models.Job{}).
+    Where("id = ?", jobID).
+    Update("progress", progress).Error
+}
+
+func (r *jRepo) Finish(jobID uuid.UUID, status string, result datatypes.JSON, errMsg string) error {
+  now := time.Now()
+  return r.db.Model(&models.Job{}).
+    Where("id = ?", jobID).
+    Updates(map[string]interface{}{"status": status, "result": result, "error": errMsg, "finished_at": &now}).Error
+}
+
+func (r *jRepo) ListJobs(f JobFilter) ([]*models.Job, error) {
+  dbq := r.db.Model(&models.Job{})
+  if f.CompanyID != uuid.Nil {
+    dbq = dbq.Where("company_id = ?", f.CompanyID)
+  }
+  if f.Type != "" {
+    dbq = dbq.Where("type = ?", f.Type)
+  }
+  if f.Status != "" {
+    dbq = dbq.Where("status = ?", f.Status)
+  }
+  allowed := []string{"type", "status", "created_at", "updated_at"}
+  dbq = applySorting(dbq, f.SortField, f.SortDir, allowed)
+  var jobs []*models.Job
+  if err := dbq.Find(&jobs).Error; err != nil {
+    return nil, err
+  }
+  return jobs, nil
+}
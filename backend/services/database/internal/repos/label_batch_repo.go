@@ -0,0 +1,74 @@
+package repos
+
+import (
+  "fmt"
+
+  "gorm.io/gorm"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+type LabelBatchFilter struct {
+  CompanyID     uuid.UUID
+  WarehouseID   uuid.UUID
+  TemplateID    uuid.UUID
+  Subject       string
+  Format        string
+  SortField     string
+  SortDir       string
+}
+
+type LBRepo interface {
+  Create(batch models.LabelBatch) (*models.LabelBatch, error)
+  GetByID(batchID uuid.UUID) (*models.LabelBatch, error)
+  ListLabelBatches(f LabelBatchFilter) ([]*models.LabelBatch, error)
+}
+
+type lbRepo struct {
+  db *gorm.DB
+}
+
+func NewLBRepo(db *gorm.DB) LBRepo {
+  return &lbRepo{db: db}
+}
+
+func (r *lbRepo) Create(batch models.LabelBatch) (*models.LabelBatch, error) {
+  if err := r.db.Create(&batch).Error; err != nil {
+    return nil, fmt.Errorf("Failed to create label batch: %w", err)
+  }
+  return &batch, nil
+}
+
+func (r *lbRepo) GetByID(batchID uuid.UUID) (*models.LabelBatch, error) {
+  var batch models.LabelBatch
+  if err := r.db.First(&batch, "id = ?", batchID).Error; err != nil {
+    return nil, fmt.Errorf("Label batch not found with id: '%s': %w", batchID, err)
+  }
+  return &batch, nil
+}
+
+func (r *lbRepo) ListLabelBatches(f LabelBatchFilter) ([]*models.LabelBatch, error) {
+  dbq := r.db.Model(&models.LabelBatch{})
+  if f.CompanyID != uuid.Nil {
+    dbq = dbq.Where("company_id = ?", f.CompanyID)
+  }
+  if f.WarehouseID != uuid.Nil {
+    dbq = dbq.Where("warehouse_id = ?", f.WarehouseID)
+  }
+  if f.TemplateID != uuid.Nil {
+    dbq = dbq.Where("template_id = ?", f.TemplateID)
+  }
+  if f.Subject != "" {
+    dbq = dbq.Where("subject = ?", f.Subject)
+  }
+  if f.Format != "" {
+    dbq = dbq.Where("format = ?", f.Format)
+  }
+  allowed := []string{"subject", "format", "label_count", "created_at"}
+  dbq = applySorting(dbq, f.SortField, f.SortDir, allowed)
+  var batches []*models.LabelBatch
+  if err := dbq.Find(&batches).Error; err != nil {
+    return nil, err
+  }
+  return batches, nil
+}
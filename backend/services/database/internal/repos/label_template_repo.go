@@ -0,0 +1,64 @@
+package repos
+
+import (
+  "fmt"
+
+  "gorm.io/gorm"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+type LTRepo interface {
+  Create(tpl models.LabelTemplate) (*models.LabelTemplate, error)
+  Update(tpl models.LabelTemplate) error
+  GetByID(templateID uuid.UUID) (*models.LabelTemplate, error)
+  Delete(templateID uuid.UUID) error
+  ListByCompany(companyID uuid.UUID) ([]*models.LabelTemplate, error)
+}
+
+type ltRepo struct {
+  db *gorm.DB
+}
+
+func NewLTRepo(db *gorm.DB) LTRepo {
+  return &ltRepo{db: db}
+}
+
+func (r *ltRepo) Create(tpl models.LabelTemplate) (*models.LabelTemplate, error) {
+  if err := r.db.Create(&tpl).Error; err != nil {
+    return nil, fmt.Errorf("Failed to create label template: %w", err)
+  }
+  return &tpl, nil
+}
+
+func (r *ltRepo) Update(tpl models.LabelTemplate) error {
+  // Select("*") so zero values such as HideText=false are written too
+  if err := r.db.Model(&models.LabelTemplate{}).Where("id = ?", tpl.ID).
+    Select("*").Omit("id", "company_id", "created_at").Updates(&tpl).Error; err != nil {
+    return fmt.Errorf("Failed to update label template with id: '%s': %w", tpl.ID, err)
+  }
+  return nil
+}
+
+func (r *ltRepo) GetByID(templateID uuid.UUID) (*models.LabelTemplate, error) {
+  var tpl models.LabelTemplate
+  if err := r.db.First(&tpl, "id = ?", templateID).Error; err != nil {
+    return nil, fmt.Errorf("Label template not found with id: '%s': %w", templateID, err)
+  }
+  return &tpl, nil
+}
+
+func (r *ltRepo) Delete(templateID uuid.UUID) error {
+  if err := r.db.Delete(&models.LabelTemplate{}, "id = ?", templateID).Error; err != nil {
+    return fmt.Errorf("Failed to delete label template: %w", err)
+  }
+  return nil
+}
+
+func (r *ltRepo) ListByCompany(companyID uuid.UUID) ([]*models.LabelTemplate, error) {
+  var tpls []*models.LabelTemplate
+  if err := r.db.Where("company_id = ?", companyID).Order("name ASC").Find(&tpls).Error; err != nil {
+    return nil, fmt.Errorf("Failed to list label templates: %w", err)
+  }
+  return tpls, nil
+}
@@ -0,0 +1,58 @@
+package repos
+
+import (
+  "fmt"
+  "time"
+
+  "gorm.io/gorm"
+  "gorm.io/gorm/clause"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+type LaborStandardRepo interface {
+  ListByWarehouse(warehouseID uuid.UUID) ([]*models.LaborStandard, error)
+  // Upsert creates the warehouse's standard for the transaction type or
+  // replaces its times.
+  Upsert(std *models.LaborStandard) error
+  Delete(warehouseID uuid.UUID, transactionType string) error
+}
+
+type laborStandardRepo struct {
+  db *gorm.DB
+}
+
+func NewLaborStandardRepo(db *gorm.DB) LaborStandardRepo {
+  return &laborStandardRepo{db: db}
+}
+
+func (r *laborStandardRepo) ListByWarehouse(warehouseID uuid.UUID) ([]*models.LaborStandard, error) {
+  var stds []*models.LaborStandard
+  if err := r.db.Where("warehouse_id = ?", warehouseID).Order("transaction_type").Find(&stds).Error; err != nil {
+    return nil, fmt.Errorf("Failed to list labor standards for warehouse with id: '%s': %w", warehouseID, err)
+  }
+  return stds, nil
+}
+
+func (r *laborStandardRepo) Upsert(std *models.LaborStandard) error {
+  std.UpdatedAt = time.Now()
+  err := r.db.Clauses(clause.OnConflict{
+    Columns:   []clause.Column{{Name: "warehouse_id"}, {Name: "transaction_type"}},
+    DoUpdates: clause.AssignmentColumns([]string{"seconds_per_line", "seconds_per_unit", "updated_at"}),
+  }).Create(std).Error
+  if err != nil {
+    return fmt.Errorf("Failed to save labor standard: %w", err)
+  }
+  return nil
+}
+
+func (r *laborStandardRepo) Delete(warehouseID uuid.UUID, transactionType string) error {
+  res := r.db.Where("warehouse_id = ? AND transaction_type = ?", warehouseID, transactionType).Delete(&models.LaborStandard{})
+  if res.Error != nil {
+    return fmt.Errorf("Failed to delete labor standard: %w", res.Error)
+  }
+  if res.RowsAffected == 0 {
+    return fmt.Errorf("no labor standard for transaction type '%s'", transactionType)
+  }
+  return nil
+}
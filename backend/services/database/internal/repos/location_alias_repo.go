@@ -0,0 +1,55 @@
+package repos
+
+import (
+  "fmt"
+
+  "gorm.io/gorm"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+type LARepo interface {
+  Create(alias models.LocationAlias) (*models.LocationAlias, error)
+  GetByPath(warehouseID uuid.UUID, aliasPath string) (*models.LocationAlias, error)
+  ListByLocation(locationID uuid.UUID) ([]*models.LocationAlias, error)
+  Delete(aliasID uuid.UUID) error
+}
+
+type laRepo struct {
+  db *gorm.DB
+}
+
+func NewLARepo(db *gorm.DB) LARepo {
+  return &laRepo{db: db}
+}
+
+func (r *laRepo) Create(alias models.LocationAlias) (*models.LocationAlias, error) {
+  if err := r.db.Create(&alias).Error; err != nil {
+    return nil, fmt.Errorf("Failed to create location alias: %w", err)
+  }
+  return &alias, nil
+}
+
+func (r *laRepo) GetByPath(warehouseID uuid.UUID, aliasPath string) (*models.LocationAlias, error) {
+  var alias models.LocationAlias
+  if err := r.db.Where("warehouse_id = ? AND alias_path = ?", warehouseID, aliasPath).
+    First(&alias).Error; err != nil {
+    return nil, fmt.Errorf("Location alias not found for warehouse with id: '%s' by path: '%s': %w", warehouseID, aliasPath, err)
+  }
+  return &alias, nil
+}
+
+func (r *laRepo) ListByLocation(locationID uuid.UUID) ([]*models.LocationAlias, error) {
+  var aliases []*models.LocationAlias
+  if err := r.db.Where("location_id = ?", locationID).Order("created_at DESC").Find(&aliases).Error; err != nil {
+    return nil, fmt.Errorf("Failed to list location aliases: %w", err)
+  }
+  return aliases, nil
+}
+
+func (r *laRepo) Delete(aliasID uuid.UUID) error {
+  if err := r.db.Delete(&models.LocationAlias{}, "id = ?", aliasID).Error; err != nil {
+    return fmt.Errorf("Failed to delete location alias: %w", err)
+  }
+  return nil
+}
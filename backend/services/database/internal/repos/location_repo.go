@@ -3,9 +3,12 @@ package repos
 import (
   "time"
   "fmt"
+  "strings"
 
   "gorm.io/gorm"
+  "gorm.io/gorm/clause"
   "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
   "github.com/yungbote/slotter/backend/services/database/internal/models"
 )
 
@@ -15,22 +18,36 @@ type LocationFilter struct {
   ItemID        uuid.UUID
   FileID        uuid.UUID
   RecordID      uuid.UUID
+  Status        string
+  PathPrefix    string // matches the location itself and everything below it
   StartDate     time.Time
   EndDate       time.Time
   SortField     string
   SortDir       string
 }
 
+// ItemLocationLink is one row of the items_locations join table.
+type ItemLocationLink struct {
+  LocationID    uuid.UUID
+  ItemID        uuid.UUID
+}
+
 type LRepo interface {
   Create(location models.Location) (*models.Location, error)
   UpdatePath(locationID uuid.UUID, newName string) error
   UpdateNamePath(locationID uuid.UUID, newNumber string) error
+  UpdateStatus(locationID uuid.UUID, status, reason string, changedByID uuid.UUID) error
+  UpdateSlotAttributes(locationID uuid.UUID, attrs models.Location) error
   GetByID(locationID uuid.UUID) (*models.Location, error)
   GetByPath(warehouseID uuid.UUID, locationPath string) (*models.Location, error)
   Delete(locationID uuid.UUID) error
+  //MOVE & MERGE (RETIRED PATHS ARE KEPT AS ALIASES)
+  Move(locationID uuid.UUID, newPath, newNamePath string) error
+  Merge(sourceID, targetID uuid.UUID) error
   //LINK & UNLINK TO ITEM
   LinkToItem(locationID, itemID uuid.UUID) error
   UnlinkFromItem(locationID, itemID uuid.UUID) error
+  ListItemLinks(warehouseID uuid.UUID) ([]ItemLocationLink, error)
   //LINK & UNLINK TO TRANSACTIONFILE
   LinkToTransactionFile(locationID, fileID uuid.UUID) error
   UnlinkFromTransactionFile(locationID, fileID uuid.UUID) error
@@ -70,6 +87,32 @@ func (r *lRepo) UpdateNamePath(locationID uuid.UUID, newNamePath string) error {
   return nil
 }
 
+func (r *lRepo) UpdateStatus(locationID uuid.UUID, status, reason string, changedByID uuid.UUID) error {
+  if err := r.db.Model(&models.Location{}).
+    Where("id = ?", locationID).
+    Updates(map[string]interface{}{
+      "status":               status,
+      "status_reason":        reason,
+      "status_changed_by_id": changedByID,
+      "status_changed_at":    time.Now(),
+    }).Error; err != nil {
+    return fmt.Errorf("Failed to update location status: %w", err)
+  }
+  return nil
+}
+
+// UpdateSlotAttributes writes every slot attribute column, zero values included.
+func (r *lRepo) UpdateSlotAttributes(locationID uuid.UUID, attrs models.Location) error {
+  if err := r.db.Model(&models.Location{}).
+    Where("id = ?", locationID).
+    Select("slot_role", "slot_type", "aisle", "coord_x", "coord_y", "level_height_cm",
+      "slot_width_cm", "slot_depth_cm", "slot_height_cm", "max_weight_kg").
+    Updates(&attrs).Error; err != nil {
+    return fmt.Errorf("Failed to update location slot attributes: %w", err)
+  }
+  return nil
+}
+
 func (r *lRepo) GetByID(locationID uuid.UUID) (*models.Location, error) {
   var loc models.Location
   if err := r.db.First(&loc, "id = ?", locationID).Error; err != nil {
@@ -98,6 +141,168 @@ func (r *lRepo) Delete(locationID uuid.UUID) error {
   }
   return nil
 }
+// Move relabels a location in place and keeps its previous path as an alias.
+func (r *lRepo) Move(locationID uuid.UUID, newPath, newNamePath string) error {
+  return r.db.Transaction(func(tx *gorm.DB) error {
+    var loc models.Location
+    if err := tx.First(&loc, "id = ?", locationID).Error; err != nil {
+      return fmt.Errorf("Failed to find location with ID '%s': %w", locationID, err)
+    }
+    if loc.LocationPath != newPath {
+      // The new label takes precedence over any alias that still points at it.
+      if err := tx.Where("warehouse_id = ? AND alias_path = ?", loc.WarehouseID, newPath).
+        Delete(&models.LocationAlias{}).Error; err != nil {
+        return fmt.Errorf("Failed to clear alias '%s': %w", newPath, err)
+      }
+      if err := saveAlias(tx, loc, locationID); err != nil {
+        return err
+      }
+    }
+    if err := tx.Model(&models.Location{}).
+      Where("id = ?", locationID).
+      Updates(map[string]interface{}{"location_path": newPath, "location_name_path": newNamePath}).Error; err != nil {
+      return fmt.Errorf("Failed to move location: %w", err)
+    }
+    return nil
+  })
+}
+
+// Merge folds source into target: transaction records, item links, slot
+// assignments, replenishment settings and tasks, move tasks, transaction
+// exceptions, zone violations, activity rollups, file links and aliases move
+// over, the source path becomes an alias of target, and the source location
+// is deleted.
+func (r *lRepo) Merge(sourceID, targetID uuid.UUID) error {
+  return r.db.Transaction(func(tx *gorm.DB) error {
+    var source models.Location
+    if err := tx.First(&source, "id = ?", sourceID).Error; err != nil {
+      return fmt.Errorf("Failed to find location with ID '%s': %w", sourceID, err)
+    }
+    var target models.Location
+    if err := tx.First(&target, "id = ?", targetID).Error; err != nil {
+      return fmt.Errorf("Failed to find location with ID '%s': %w", targetID, err)
+    }
+    if err := tx.Model(&models.TransactionRecord{}).
+      Where("location_id = ?", sourceID).
+      Update("location_id", targetID).Error; err != nil {
+      return fmt.Errorf("Failed to move transaction records: %w", err)
+    }
+    if err := tx.Exec("INSERT INTO items_locations (item_id, location_id) SELECT item_id, ? FROM items_locations WHERE location_id = ? ON CONFLICT DO NOTHING", targetID, sourceID).Error; err != nil {
+      return fmt.Errorf("Failed to move item links: %w", err)
+    }
+    if err := tx.Exec("DELETE FROM items_locations WHERE location_id = ?", sourceID).Error; err != nil {
+      return fmt.Errorf("Failed to clear item links: %w", err)
+    }
+    if err := tx.Model(&models.SlotAssignment{}).
+      Where("location_id = ?", sourceID).
+      Update("location_id", targetID).Error; err != nil {
+      return fmt.Errorf("Failed to move slot assignments: %w", err)
+    }
+    // An item slotted in both keeps the earlier open assignment, which covers the later one.
+    if err := tx.Exec("DELETE FROM slot_assignments sa WHERE sa.location_id = ? AND sa.effective_to IS NULL AND EXISTS (SELECT 1 FROM slot_assignments o WHERE o.location_id = sa.location_id AND o.item_id = sa.item_id AND o.effective_to IS NULL AND (o.effective_from, o.id) < (sa.effective_from, sa.id))", targetID).Error; err != nil {
+      return fmt.Errorf("Failed to clear duplicate slot assignments: %w", err)
+    }
+    // Replenishment settings move over; an item set up on both keeps target's.
+    if err := tx.Exec(`INSERT INTO replenishment_settings (company_id, warehouse_id, location_id, item_id, method, min_units, max_units, min_days, max_days)
+      SELECT company_id, warehouse_id, ?, item_id, method, min_units, max_units, min_days, max_days FROM replenishment_settings WHERE location_id = ?
+      ON CONFLICT (location_id, item_id) DO NOTHING`, targetID, sourceID).Error; err != nil {
+      return fmt.Errorf("Failed to move replenishment settings: %w", err)
+    }
+    if err := tx.Where("location_id = ?", sourceID).Delete(&models.ReplenishmentSetting{}).Error; err != nil {
+      return fmt.Errorf("Failed to clear replenishment settings: %w", err)
+    }
+    // Target can hold one open task per item; a source task for an item
+    // target already has one open for is cancelled instead of moved.
+    if err := tx.Model(&models.ReplenishmentTask{}).
+      Where("to_location_id = ? AND status = ?", sourceID, constants.ReplenishTaskOpen).
+      Where("EXISTS (SELECT 1 FROM replenishment_tasks o WHERE o.to_location_id = ? AND o.item_id = replenishment_tasks.item_id AND o.status = ?)", targetID, constants.ReplenishTaskOpen).
+      Updates(map[string]interface{}{"status": constants.ReplenishTaskCancelled, "updated_at": time.Now()}).Error; err != nil {
+      return fmt.Errorf("Failed to cancel duplicate replenishment tasks: %w", err)
+    }
+    if err := tx.Model(&models.ReplenishmentTask{}).
+      Where("to_location_id = ?", sourceID).
+      Update("to_location_id", targetID).Error; err != nil {
+      return fmt.Errorf("Failed to move replenishment tasks: %w", err)
+    }
+    if err := tx.Model(&models.ReplenishmentTask{}).
+      Where("from_location_id = ?", sourceID).
+      Update("from_location_id", targetID).Error; err != nil {
+      return fmt.Errorf("Failed to move replenishment tasks: %w", err)
+    }
+    if err := tx.Model(&models.MoveTask{}).
+      Where("from_location_id = ?", sourceID).
+      Update("from_location_id", targetID).Error; err != nil {
+      return fmt.Errorf("Failed to move move tasks: %w", err)
+    }
+    if err := tx.Model(&models.MoveTask{}).
+      Where("to_location_id = ?", sourceID).
+      Update("to_location_id", targetID).Error; err != nil {
+      return fmt.Errorf("Failed to move move tasks: %w", err)
+    }
+    // Exceptions keep their acknowledgements, the records they point at
+    // moved over above.
+    if err := tx.Model(&models.TransactionException{}).
+      Where("location_id = ?", sourceID).
+      Update("location_id", targetID).Error; err != nil {
+      return fmt.Errorf("Failed to move transaction exceptions: %w", err)
+    }
+    // A zone violation target already has for the same item and rule wins;
+    // the caller re-checks the moved links against target's zones.
+    if err := tx.Exec("DELETE FROM zone_violations zv WHERE zv.location_id = ? AND EXISTS (SELECT 1 FROM zone_violations o WHERE o.location_id = ? AND o.zone_id = zv.zone_id AND o.item_id = zv.item_id AND o.rule = zv.rule)", sourceID, targetID).Error; err != nil {
+      return fmt.Errorf("Failed to clear duplicate zone violations: %w", err)
+    }
+    if err := tx.Model(&models.ZoneViolation{}).
+      Where("location_id = ?", sourceID).
+      Update("location_id", targetID).Error; err != nil {
+      return fmt.Errorf("Failed to move zone violations: %w", err)
+    }
+    // The source's rollups go with it; fold them into target's. An order on
+    // both locations the same day then counts twice until the day is refreshed.
+    if err := tx.Exec(`INSERT INTO activity_rollups (company_id, warehouse_id, day, location_id, item_id, transaction_type, lines, units, orders)
+      SELECT company_id, warehouse_id, day, ?, item_id, transaction_type, lines, units, orders FROM activity_rollups WHERE location_id = ?
+      ON CONFLICT (warehouse_id, day, location_id, item_id, transaction_type) DO UPDATE SET
+        lines = activity_rollups.lines + EXCLUDED.lines, units = activity_rollups.units + EXCLUDED.units,
+        orders = activity_rollups.orders + EXCLUDED.orders, updated_at = now()`, targetID, sourceID).Error; err != nil {
+      return fmt.Errorf("Failed to move activity rollups: %w", err)
+    }
+    if err := tx.Exec("INSERT INTO transaction_files_locations (transaction_file_id, location_id) SELECT transaction_file_id, ? FROM transaction_files_locations WHERE location_id = ? ON CONFLICT DO NOTHING", targetID, sourceID).Error; err != nil {
+      return fmt.Errorf("Failed to move transaction file links: %w", err)
+    }
+    if err := tx.Exec("DELETE FROM transaction_files_locations WHERE location_id = ?", sourceID).Error; err != nil {
+      return fmt.Errorf("Failed to clear transaction file links: %w", err)
+    }
+    if err := tx.Model(&models.LocationAlias{}).
+      Where("location_id = ?", sourceID).
+      Update("location_id", targetID).Error; err != nil {
+      return fmt.Errorf("Failed to move location aliases: %w", err)
+    }
+    if err := saveAlias(tx, source, targetID); err != nil {
+      return err
+    }
+    if err := tx.Delete(&source).Error; err != nil {
+      return fmt.Errorf("Failed to delete merged location: %w", err)
+    }
+    return nil
+  })
+}
+
+// saveAlias points loc's current path at ownerID, replacing any older alias with the same path.
+func saveAlias(tx *gorm.DB, loc models.Location, ownerID uuid.UUID) error {
+  alias := models.LocationAlias{
+    WarehouseID:    loc.WarehouseID,
+    LocationID:     &ownerID,
+    AliasPath:      loc.LocationPath,
+    AliasNamePath:  loc.LocationNamePath,
+  }
+  if err := tx.Clauses(clause.OnConflict{
+    Columns:   []clause.Column{{Name: "warehouse_id"}, {Name: "alias_path"}},
+    DoUpdates: clause.AssignmentColumns([]string{"location_id", "alias_name_path"}),
+  }).Create(&alias).Error; err != nil {
+    return fmt.Errorf("Failed to save alias '%s': %w", loc.LocationPath, err)
+  }
+  return nil
+}
+
 func (r *lRepo) LinkToItem(locationID, itemID uuid.UUID) error {
   var loc models.Location
   if err := r.db.First(&loc, "id = ?", locationID).Error; err != nil {
@@ -172,6 +377,18 @@ func (r *lRepo) UnlinkFromTransactionFile(locationID, fileID uuid.UUID) error {
   return nil
 }
 
+func (r *lRepo) ListItemLinks(warehouseID uuid.UUID) ([]ItemLocationLink, error) {
+  var links []ItemLocationLink
+  if err := r.db.Table("items_locations il").
+    Select("il.location_id, il.item_id").
+    Joins("JOIN locations l ON l.id = il.location_id").
+    Where("l.warehouse_id = ?", warehouseID).
+    Scan(&links).Error; err != nil {
+    return nil, fmt.Errorf("Failed to list item links for warehouse with id: '%s': %w", warehouseID, err)
+  }
+  return links, nil
+}
+
 func (r *lRepo) ListLocations(f LocationFilter) ([]*models.Location, error) {
   dbq := r.db.Model(&models.Location{}).Select("DISTINCT locations.*")
   if f.CompanyID != uuid.Nil {
@@ -189,6 +406,13 @@ func (r *lRepo) ListLocations(f LocationFilter) ([]*models.Location, error) {
   if f.RecordID != uuid.Nil {
     dbq = dbq.Joins("JOIN transaction_records tr ON tr.location_id = locations.id").Where("tr.id = ?", f.RecordID)
   }
+  if f.Status != "" {
+    dbq = dbq.Where("locations.status = ?", f.Status)
+  }
+  if f.PathPrefix != "" {
+    prefix := strings.TrimSuffix(f.PathPrefix, "/")
+    dbq = dbq.Where("locations.location_path = ? OR locations.location_path LIKE ?", prefix, escapeLike(prefix)+"/%")
+  }
   if !f.StartDate.IsZero() || !f.EndDate.IsZero() {
     dbq = dbq.Joins("JOIN transaction_records trDate ON trDate.location_id = locations.id")
     if !f.StartDate.IsZero() {
@@ -198,7 +422,7 @@ func (r *lRepo) ListLocations(f LocationFilter) ([]*models.Location, error) {
       dbq = dbq.Where("trDate.completed_date <= ?", f.EndDate)
     }
   }
-  allowed := []string{"location_path", "location_name_path", "created_at", "updated_at"}
+  allowed := []string{"location_path", "location_name_path", "status", "created_at", "updated_at"}
   dbq = applySorting(dbq, f.SortField, f.SortDir, allowed)
   var locs []*models.Location
   if err := dbq.Find(&locs).Error; err != nil {
@@ -207,4 +431,7 @@ func (r *lRepo) ListLocations(f LocationFilter) ([]*models.Location, error) {
   return locs, nil
 }
 
-
+// escapeLike escapes LIKE wildcards so a path prefix is matched literally.
+func escapeLike(s string) string {
+  return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,140 @@
+package repos
+
+import (
+  "fmt"
+  "time"
+
+  "gorm.io/gorm"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+type MoveTaskFilter struct {
+  CompanyID     uuid.UUID
+  WarehouseID   uuid.UUID
+  PlanID        uuid.UUID
+  ItemID        uuid.UUID
+  Status        string
+  SortField     string
+  SortDir       string
+}
+
+type MoveTaskRepo interface {
+  CreateTasks(tasks []*models.MoveTask) error
+  GetByID(taskID uuid.UUID) (*models.MoveTask, error)
+  // UpdateStatus moves an open task to status; completedBy is recorded with a
+  // timestamp when non-nil. It fails when the task is no longer open.
+  UpdateStatus(taskID uuid.UUID, status string, completedBy *uuid.UUID) error
+  // Complete confirms an open task in one transaction: it refuses while an
+  // earlier task of the task's group is open, marks the task done, opens
+  // moved and closes the item's assignment to the source slot.
+  Complete(task *models.MoveTask, moved *models.SlotAssignment, completedBy uuid.UUID) error
+  ListMoveTasks(f MoveTaskFilter) ([]*models.MoveTask, error)
+}
+
+type moveTaskRepo struct {
+  db *gorm.DB
+}
+
+func NewMoveTaskRepo(db *gorm.DB) MoveTaskRepo {
+  return &moveTaskRepo{db: db}
+}
+
+func (r *moveTaskRepo) CreateTasks(tasks []*models.MoveTask) error {
+  if len(tasks) == 0 {
+    return nil
+  }
+  if err := r.db.CreateInBatches(tasks, 500).Error; err != nil {
+    return fmt.Errorf("Failed to create move tasks: %w", err)
+  }
+  return nil
+}
+
+func (r *moveTaskRepo) GetByID(taskID uuid.UUID) (*models.MoveTask, error) {
+  var task models.MoveTask
+  if err := r.db.Preload("Item").Preload("FromLocation").Preload("ToLocation").First(&task, "id = ?", taskID).Error; err != nil {
+    return nil, fmt.Errorf("Move task not found with id: '%s': %w", taskID, err)
+  }
+  return &task, nil
+}
+
+func (r *moveTaskRepo) UpdateStatus(taskID uuid.UUID, status string, completedBy *uuid.UUID) error {
+  return updateOpenMoveTask(r.db, taskID, status, completedBy)
+}
+
+func updateOpenMoveTask(db *gorm.DB, taskID uuid.UUID, status string, completedBy *uuid.UUID) error {
+  updates := map[string]interface{}{"status": status, "updated_at": time.Now()}
+  if completedBy != nil {
+    updates["completed_by_id"] = *completedBy
+    updates["completed_at"] = time.Now()
+  }
+  res := db.Model(&models.MoveTask{}).
+    Where("id = ? AND status = ?", taskID, constants.MoveTaskStatusOpen).
+    Updates(updates)
+  if res.Error != nil {
+    return fmt.Errorf("Failed to update move task with id: '%s': %w", taskID, res.Error)
+  }
+  if res.RowsAffected == 0 {
+    return fmt.Errorf("move task is no longer open")
+  }
+  return nil
+}
+
+func (r *moveTaskRepo) Complete(task *models.MoveTask, moved *models.SlotAssignment, completedBy uuid.UUID) error {
+  return r.db.Transaction(func(tx *gorm.DB) error {
+    if task.PlanID != nil {
+      var waiting int64
+      if err := tx.Model(&models.MoveTask{}).
+        Where("plan_id = ? AND group_no = ? AND sequence < ? AND status = ?", *task.PlanID, task.GroupNo, task.Sequence, constants.MoveTaskStatusOpen).
+        Count(&waiting).Error; err != nil {
+        return fmt.Errorf("Failed to check earlier move tasks: %w", err)
+      }
+      if waiting > 0 {
+        return fmt.Errorf("%d earlier move tasks of group %d are still open", waiting, task.GroupNo)
+      }
+    }
+    if err := updateOpenMoveTask(tx, task.ID, constants.MoveTaskStatusDone, &completedBy); err != nil {
+      return err
+    }
+    assignments := &slotAssignmentRepo{db: tx}
+    if err := assignments.Open(moved); err != nil {
+      return err
+    }
+    if task.FromLocationID != nil && moved.ItemID != nil {
+      return assignments.Close(*task.FromLocationID, *moved.ItemID, time.Now(), constants.AssignmentSourceMoveTask, &completedBy)
+    }
+    return nil
+  })
+}
+
+func (r *moveTaskRepo) ListMoveTasks(f MoveTaskFilter) ([]*models.MoveTask, error) {
+  dbq := r.db.Model(&models.MoveTask{}).
+    Preload("Item").Preload("FromLocation").Preload("ToLocation")
+  if f.CompanyID != uuid.Nil {
+    dbq = dbq.Where("company_id = ?", f.CompanyID)
+  }
+  if f.WarehouseID != uuid.Nil {
+    dbq = dbq.Where("warehouse_id = ?", f.WarehouseID)
+  }
+  if f.PlanID != uuid.Nil {
+    dbq = dbq.Where("plan_id = ?", f.PlanID)
+  }
+  if f.ItemID != uuid.Nil {
+    dbq = dbq.Where("item_id = ?", f.ItemID)
+  }
+  if f.Status != "" {
+    dbq = dbq.Where("status = ?", f.Status)
+  }
+  if f.SortField == "" {
+    dbq = dbq.Order("created_at DESC").Order("group_no ASC").Order("sequence ASC")
+  } else {
+    allowed := []string{"group_no", "sequence", "status", "estimated_cost_sec", "benefit_sec_per_day", "created_at", "completed_at"}
+    dbq = applySorting(dbq, f.SortField, f.SortDir, allowed)
+  }
+  var tasks []*models.MoveTask
+  if err := dbq.Find(&tasks).Error; err != nil {
+    return nil, err
+  }
+  return tasks, nil
+}
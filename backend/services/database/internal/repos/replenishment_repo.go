@@ -0,0 +1,171 @@
+package repos
+
+import (
+  "fmt"
+  "time"
+
+  "gorm.io/gorm"
+  "gorm.io/gorm/clause"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+type ReplenishmentTaskFilter struct {
+  CompanyID     uuid.UUID
+  WarehouseID   uuid.UUID
+  ItemID        uuid.UUID
+  ToLocationID  uuid.UUID
+  Status        string
+  SortField     string // most urgent first when empty
+  SortDir       string
+}
+
+type ReplenishmentRepo interface {
+  ListSettings(warehouseID uuid.UUID) ([]*models.ReplenishmentSetting, error)
+  // UpsertSetting creates the setting of the item at the location or
+  // replaces its method and levels.
+  UpsertSetting(st *models.ReplenishmentSetting) error
+  DeleteSetting(warehouseID, locationID, itemID uuid.UUID) error
+  // CreateTasks inserts the tasks, skipping those whose pick face and item
+  // already has an open task, and returns the ones it inserted.
+  CreateTasks(tasks []*models.ReplenishmentTask) ([]*models.ReplenishmentTask, error)
+  GetTask(taskID uuid.UUID) (*models.ReplenishmentTask, error)
+  // UpdateTaskStatus sets the status; completedBy is recorded with a timestamp when non-nil.
+  UpdateTaskStatus(taskID uuid.UUID, status string, completedBy *uuid.UUID) error
+  ListTasks(f ReplenishmentTaskFilter) ([]*models.ReplenishmentTask, error)
+  // LatestDone returns the last completed task of every pick face and item
+  // of the warehouse.
+  LatestDone(warehouseID uuid.UUID) ([]*models.ReplenishmentTask, error)
+}
+
+type replenishmentRepo struct {
+  db *gorm.DB
+}
+
+func NewReplenishmentRepo(db *gorm.DB) ReplenishmentRepo {
+  return &replenishmentRepo{db: db}
+}
+
+func (r *replenishmentRepo) ListSettings(warehouseID uuid.UUID) ([]*models.ReplenishmentSetting, error) {
+  var out []*models.ReplenishmentSetting
+  err := r.db.Preload("Item").Preload("Location").
+    Where("warehouse_id = ?", warehouseID).
+    Order("created_at").
+    Find(&out).Error
+  if err != nil {
+    return nil, fmt.Errorf("Failed to list replenishment settings for warehouse with id: '%s': %w", warehouseID, err)
+  }
+  return out, nil
+}
+
+func (r *replenishmentRepo) UpsertSetting(st *models.ReplenishmentSetting) error {
+  st.UpdatedAt = time.Now()
+  err := r.db.Clauses(clause.OnConflict{
+    Columns:   []clause.Column{{Name: "location_id"}, {Name: "item_id"}},
+    DoUpdates: clause.AssignmentColumns([]string{"method", "min_units", "max_units", "min_days", "max_days", "updated_at"}),
+  }).Create(st).Error
+  if err != nil {
+    return fmt.Errorf("Failed to save replenishment setting: %w", err)
+  }
+  return nil
+}
+
+func (r *replenishmentRepo) DeleteSetting(warehouseID, locationID, itemID uuid.UUID) error {
+  res := r.db.Where("warehouse_id = ? AND location_id = ? AND item_id = ?", warehouseID, locationID, itemID).
+    Delete(&models.ReplenishmentSetting{})
+  if res.Error != nil {
+    return fmt.Errorf("Failed to delete replenishment setting: %w", res.Error)
+  }
+  if res.RowsAffected == 0 {
+    return fmt.Errorf("no replenishment setting for item at location")
+  }
+  return nil
+}
+
+func (r *replenishmentRepo) CreateTasks(tasks []*models.ReplenishmentTask) ([]*models.ReplenishmentTask, error) {
+  created := make([]*models.ReplenishmentTask, 0, len(tasks))
+  err := r.db.Transaction(func(tx *gorm.DB) error {
+    for _, t := range tasks {
+      res := tx.Clauses(clause.OnConflict{
+        Columns:     []clause.Column{{Name: "to_location_id"}, {Name: "item_id"}},
+        TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "status = 'open'"}}},
+        DoNothing:   true,
+      }).Create(t)
+      if res.Error != nil {
+        return res.Error
+      }
+      if res.RowsAffected > 0 {
+        created = append(created, t)
+      }
+    }
+    return nil
+  })
+  if err != nil {
+    return nil, fmt.Errorf("Failed to create replenishment tasks: %w", err)
+  }
+  return created, nil
+}
+
+func (r *replenishmentRepo) GetTask(taskID uuid.UUID) (*models.ReplenishmentTask, error) {
+  var task models.ReplenishmentTask
+  if err := r.db.Preload("Item").Preload("FromLocation").Preload("ToLocation").First(&task, "id = ?", taskID).Error; err != nil {
+    return nil, fmt.Errorf("Replenishment task not found with id: '%s': %w", taskID, err)
+  }
+  return &task, nil
+}
+
+func (r *replenishmentRepo) UpdateTaskStatus(taskID uuid.UUID, status string, completedBy *uuid.UUID) error {
+  updates := map[string]interface{}{"status": status, "updated_at": time.Now()}
+  if completedBy != nil {
+    updates["completed_by_id"] = *completedBy
+    updates["completed_at"] = time.Now()
+  }
+  if err := r.db.Model(&models.ReplenishmentTask{}).Where("id = ?", taskID).Updates(updates).Error; err != nil {
+    return fmt.Errorf("Failed to update replenishment task with id: '%s': %w", taskID, err)
+  }
+  return nil
+}
+
+func (r *replenishmentRepo) ListTasks(f ReplenishmentTaskFilter) ([]*models.ReplenishmentTask, error) {
+  dbq := r.db.Model(&models.ReplenishmentTask{}).
+    Preload("Item").Preload("FromLocation").Preload("ToLocation")
+  if f.CompanyID != uuid.Nil {
+    dbq = dbq.Where("company_id = ?", f.CompanyID)
+  }
+  if f.WarehouseID != uuid.Nil {
+    dbq = dbq.Where("warehouse_id = ?", f.WarehouseID)
+  }
+  if f.ItemID != uuid.Nil {
+    dbq = dbq.Where("item_id = ?", f.ItemID)
+  }
+  if f.ToLocationID != uuid.Nil {
+    dbq = dbq.Where("to_location_id = ?", f.ToLocationID)
+  }
+  if f.Status != "" {
+    dbq = dbq.Where("status = ?", f.Status)
+  }
+  if f.SortField == "" {
+    dbq = dbq.Order("days_of_supply ASC NULLS LAST, created_at")
+  } else {
+    allowed := []string{"created_at", "updated_at", "completed_at", "days_of_supply", "quantity", "on_hand"}
+    dbq = applySorting(dbq, f.SortField, f.SortDir, allowed)
+  }
+  var out []*models.ReplenishmentTask
+  if err := dbq.Find(&out).Error; err != nil {
+    return nil, err
+  }
+  return out, nil
+}
+
+func (r *replenishmentRepo) LatestDone(warehouseID uuid.UUID) ([]*models.ReplenishmentTask, error) {
+  var out []*models.ReplenishmentTask
+  err := r.db.Raw(`SELECT DISTINCT ON (to_location_id, item_id) * FROM replenishment_tasks
+    WHERE warehouse_id = ? AND status = ?
+    ORDER BY to_location_id, item_id, completed_at DESC`, warehouseID, constants.ReplenishTaskDone).
+    Scan(&out).Error
+  if err != nil {
+    return nil, fmt.Errorf("Failed to list completed replenishment tasks: %w", err)
+  }
+  return out, nil
+}
@@ -0,0 +1,213 @@
+package repos
+
+import (
+  "fmt"
+  "time"
+
+  "gorm.io/gorm"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+// HeatNode is the activity of one location, or of one hierarchy node (a
+// location path prefix) summed over its locations. Coordinates span the
+// node's located slots and are nil when none has coordinates.
+type HeatNode struct {
+  Node          string
+  LocationID    *uuid.UUID
+  Locations     int64
+  Lines         int64
+  Units         int64
+  Items         int64
+  MinX          *float64
+  MaxX          *float64
+  MinY          *float64
+  MaxY          *float64
+  LevelHeightCm *float64
+}
+
+// RollupStatus describes a warehouse's rollups against its records.
+type RollupStatus struct {
+  Rows            int64      `json:"rows"`
+  FirstDay        *time.Time `json:"first_day"`
+  LastDay         *time.Time `json:"last_day"`
+  UpdatedAt       *time.Time `json:"updated_at"`
+  RecordFirstDate *time.Time `json:"record_first_date"`
+  RecordLastDate  *time.Time `json:"record_last_date"`
+}
+
+type RollupRepo interface {
+  // Refresh rebuilds the warehouse's rollups for the days firstDay..lastDay.
+  // Refreshes of one warehouse run one at a time.
+  Refresh(warehouseID uuid.UUID, firstDay, lastDay time.Time) error
+  // RefreshFile rebuilds the rollups of the days the file's records fall on.
+  RefreshFile(fileID uuid.UUID) error
+  Status(warehouseID uuid.UUID) (*RollupStatus, error)
+  // BuildMissing rolls up every warehouse with records but no rollups yet and
+  // returns how many rollup rows were written.
+  BuildMissing() (int64, error)
+  // ListTransactionTypes returns the distinct rolled up transaction types in range.
+  ListTransactionTypes(f AnalyticsFilter) ([]string, error)
+  // LocationHeat sums the activity of f.RawTypes per location of the
+  // warehouse, idle ones included, or per path prefix of depth segments.
+  LocationHeat(f AnalyticsFilter, depth int) ([]HeatNode, error)
+}
+
+type rollupRepo struct {
+  db *gorm.DB
+}
+
+func NewRollupRepo(db *gorm.DB) RollupRepo {
+  return &rollupRepo{db: db}
+}
+
+// rollupInsert aggregates transaction_records into activity_rollups; callers
+// append the WHERE clause.
+const rollupInsert = `INSERT INTO activity_rollups (company_id, warehouse_id, day, location_id, item_id, transaction_type, lines, units, orders)
+  SELECT transaction_records.company_id, transaction_records.warehouse_id,
+    date_trunc('day', transaction_records.completed_date)::date,
+    transaction_records.location_id, transaction_records.item_id,
+    lower(trim(transaction_records.transaction_type)),
+    COUNT(*), COALESCE(SUM(` + unitsExpr + `), 0),
+    COUNT(DISTINCT NULLIF(transaction_records.order_name, ''))
+  FROM transaction_records `
+
+const rollupGroup = ` GROUP BY 1, 2, 3, 4, 5, 6`
+
+func (r *rollupRepo) Refresh(warehouseID uuid.UUID, firstDay, lastDay time.Time) error {
+  return r.db.Transaction(func(tx *gorm.DB) error {
+    if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "activity_rollups:"+warehouseID.String()).Error; err != nil {
+      return fmt.Errorf("Failed to lock activity rollups: %w", err)
+    }
+    if err := tx.Exec(`DELETE FROM activity_rollups WHERE warehouse_id = ?
+      AND day >= date_trunc('day', ?::timestamptz)::date AND day <= date_trunc('day', ?::timestamptz)::date`,
+      warehouseID, firstDay, lastDay).Error; err != nil {
+      return fmt.Errorf("Failed to clear activity rollups: %w", err)
+    }
+    if err := tx.Exec(rollupInsert+`WHERE transaction_records.warehouse_id = ?
+      AND transaction_records.completed_date >= date_trunc('day', ?::timestamptz)
+      AND transaction_records.completed_date < date_trunc('day', ?::timestamptz) + interval '1 day'`+rollupGroup,
+      warehouseID, firstDay, lastDay).Error; err != nil {
+      return fmt.Errorf("Failed to roll up activity for warehouse with id: '%s': %w", warehouseID, err)
+    }
+    return nil
+  })
+}
+
+func (r *rollupRepo) RefreshFile(fileID uuid.UUID) error {
+  var spans []struct {
+    WarehouseID uuid.UUID
+    FirstDate   time.Time
+    LastDate    time.Time
+  }
+  if err := r.db.Model(&models.TransactionRecord{}).
+    Select("warehouse_id, MIN(completed_date) AS first_date, MAX(completed_date) AS last_date").
+    Where("transaction_file_id = ?", fileID).
+    Group("warehouse_id").
+    Scan(&spans).Error; err != nil {
+    return fmt.Errorf("Failed to find record dates of transaction file with id: '%s': %w", fileID, err)
+  }
+  for _, sp := range spans {
+    if err := r.Refresh(sp.WarehouseID, sp.FirstDate, sp.LastDate); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+func (r *rollupRepo) Status(warehouseID uuid.UUID) (*RollupStatus, error) {
+  var st RollupStatus
+  if err := r.db.Model(&models.ActivityRollup{}).
+    Select("COUNT(*) AS rows, MIN(day) AS first_day, MAX(day) AS last_day, MAX(updated_at) AS updated_at").
+    Where("warehouse_id = ?", warehouseID).
+    Scan(&st).Error; err != nil {
+    return nil, fmt.Errorf("Failed to read activity rollup status: %w", err)
+  }
+  var span struct {
+    FirstDate *time.Time
+    LastDate  *time.Time
+  }
+  if err := r.db.Model(&models.TransactionRecord{}).
+    Select("MIN(completed_date) AS first_date, MAX(completed_date) AS last_date").
+    Where("warehouse_id = ?", warehouseID).
+    Scan(&span).Error; err != nil {
+    return nil, fmt.Errorf("Failed to read record dates: %w", err)
+  }
+  st.RecordFirstDate, st.RecordLastDate = span.FirstDate, span.LastDate
+  return &st, nil
+}
+
+func (r *rollupRepo) BuildMissing() (int64, error) {
+  res := r.db.Exec(rollupInsert + `WHERE NOT EXISTS (
+    SELECT 1 FROM activity_rollups ar WHERE ar.warehouse_id = transaction_records.warehouse_id
+  )` + rollupGroup)
+  if res.Error != nil {
+    return 0, fmt.Errorf("Failed to build activity rollups: %w", res.Error)
+  }
+  return res.RowsAffected, nil
+}
+
+// rollupScope is the activity_rollups counterpart of analyticsRepo.scope; the
+// days are matched against f's dates.
+func rollupScope(db *gorm.DB, f AnalyticsFilter) *gorm.DB {
+  dbq := db.Model(&models.ActivityRollup{})
+  if f.CompanyID != uuid.Nil {
+    dbq = dbq.Where("activity_rollups.company_id = ?", f.CompanyID)
+  }
+  if f.WarehouseID != uuid.Nil {
+    dbq = dbq.Where("activity_rollups.warehouse_id = ?", f.WarehouseID)
+  }
+  if f.ItemID != uuid.Nil {
+    dbq = dbq.Where("activity_rollups.item_id = ?", f.ItemID)
+  }
+  if !f.StartDate.IsZero() {
+    dbq = dbq.Where("activity_rollups.day >= ?", f.StartDate)
+  }
+  if !f.EndDate.IsZero() {
+    dbq = dbq.Where("activity_rollups.day < ?", f.EndDate)
+  }
+  if len(f.RawTypes) > 0 {
+    dbq = dbq.Where("activity_rollups.transaction_type IN ?", f.RawTypes)
+  }
+  return dbq
+}
+
+func (r *rollupRepo) ListTransactionTypes(f AnalyticsFilter) ([]string, error) {
+  var types []string
+  if err := rollupScope(r.db, f).Distinct("activity_rollups.transaction_type").
+    Pluck("activity_rollups.transaction_type", &types).Error; err != nil {
+    return nil, fmt.Errorf("Failed to list transaction types: %w", err)
+  }
+  return types, nil
+}
+
+func (r *rollupRepo) LocationHeat(f AnalyticsFilter, depth int) ([]HeatNode, error) {
+  join := "LEFT JOIN activity_rollups ar ON ar.location_id = l.id AND ar.transaction_type IN ?"
+  args := []interface{}{f.RawTypes}
+  if !f.StartDate.IsZero() {
+    join += " AND ar.day >= ?"
+    args = append(args, f.StartDate)
+  }
+  if !f.EndDate.IsZero() {
+    join += " AND ar.day < ?"
+    args = append(args, f.EndDate)
+  }
+  aggregates := `COUNT(DISTINCT l.id) AS locations,
+    COALESCE(SUM(ar.lines), 0) AS lines, COALESCE(SUM(ar.units), 0) AS units,
+    COUNT(DISTINCT ar.item_id) AS items,
+    MIN(NULLIF(l.coord_x, 0)) AS min_x, MAX(NULLIF(l.coord_x, 0)) AS max_x,
+    MIN(NULLIF(l.coord_y, 0)) AS min_y, MAX(NULLIF(l.coord_y, 0)) AS max_y`
+  dbq := r.db.Table("locations l").Joins(join, args...).Where("l.warehouse_id = ?", f.WarehouseID)
+  if depth > 0 {
+    dbq = dbq.Select("array_to_string((string_to_array(l.location_path, '/'))[1:?], '/') AS node, "+aggregates, depth).
+      Group("1").Order("1")
+  } else {
+    dbq = dbq.Select("l.location_path AS node, l.id AS location_id, MAX(NULLIF(l.level_height_cm, 0)) AS level_height_cm, "+aggregates).
+      Group("l.id, l.location_path").Order("l.location_path")
+  }
+  var nodes []HeatNode
+  if err := dbq.Scan(&nodes).Error; err != nil {
+    return nil, fmt.Errorf("Failed to aggregate location activity: %w", err)
+  }
+  return nodes, nil
+}
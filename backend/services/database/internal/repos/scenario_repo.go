@@ -0,0 +1,67 @@
+package repos
+
+import (
+  "fmt"
+
+  "gorm.io/gorm"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+type ScenarioFilter struct {
+  CompanyID     uuid.UUID
+  WarehouseID   uuid.UUID
+  SortField     string
+  SortDir       string
+}
+
+type ScenarioRepo interface {
+  Create(scenario *models.SlottingScenario) error
+  GetByID(scenarioID uuid.UUID) (*models.SlottingScenario, error)
+  Delete(scenarioID uuid.UUID) error
+  ListScenarios(f ScenarioFilter) ([]*models.SlottingScenario, error)
+}
+
+type scenarioRepo struct {
+  db *gorm.DB
+}
+
+func NewScenarioRepo(db *gorm.DB) ScenarioRepo {
+  return &scenarioRepo{db: db}
+}
+
+func (r *scenarioRepo) Create(scenario *models.SlottingScenario) error {
+  if err := r.db.Create(scenario).Error; err != nil {
+    return fmt.Errorf("Failed to create slotting scenario: %w", err)
+  }
+  return nil
+}
+
+func (r *scenarioRepo) GetByID(scenarioID uuid.UUID) (*models.SlottingScenario, error) {
+  var scenario models.SlottingScenario
+  if err := r.db.First(&scenario, "id = ?", scenarioID).Error; err != nil {
+    return nil, fmt.Errorf("Slotting scenario not found with id: '%s': %w", scenarioID, err)
+  }
+  return &scenario, nil
+}
+
+func (r *scenarioRepo) Delete(scenarioID uuid.UUID) error {
+  return r.db.Delete(&models.SlottingScenario{}, "id = ?", scenarioID).Error
+}
+
+func (r *scenarioRepo) ListScenarios(f ScenarioFilter) ([]*models.SlottingScenario, error) {
+  dbq := r.db.Model(&models.SlottingScenario{})
+  if f.CompanyID != uuid.Nil {
+    dbq = dbq.Where("company_id = ?", f.CompanyID)
+  }
+  if f.WarehouseID != uuid.Nil {
+    dbq = dbq.Where("warehouse_id = ?", f.WarehouseID)
+  }
+  allowed := []string{"name", "travel_m", "ergo_score", "moves_required", "move_hours", "capacity_violations", "created_at"}
+  dbq = applySorting(dbq, f.SortField, f.SortDir, allowed)
+  var scenarios []*models.SlottingScenario
+  if err := dbq.Find(&scenarios).Error; err != nil {
+    return nil, err
+  }
+  return scenarios, nil
+}
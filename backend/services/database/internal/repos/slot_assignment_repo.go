@@ -0,0 +1,167 @@
+package repos
+
+import (
+  "fmt"
+  "time"
+
+  "gorm.io/gorm"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+type SlotAssignmentFilter struct {
+  CompanyID       uuid.UUID
+  WarehouseID     uuid.UUID
+  ItemID          uuid.UUID
+  LocationID      uuid.UUID
+  AssignmentType  string
+  AsOf            time.Time // assignments in effect at this moment
+  OpenOnly        bool
+  StartDate       time.Time // assignments overlapping [StartDate, EndDate]
+  EndDate         time.Time
+  SortField       string
+  SortDir         string
+}
+
+type SlotAssignmentRepo interface {
+  // Open starts an assignment and links the item to the location. An open
+  // assignment of the pair is kept, reaching back to EffectiveFrom if earlier.
+  Open(a *models.SlotAssignment) error
+  // Close ends the pair's open assignment at `at` and unlinks the item.
+  Close(locationID, itemID uuid.UUID, at time.Time, source string, closedByID *uuid.UUID) error
+  ListAssignments(f SlotAssignmentFilter) ([]*models.SlotAssignment, error)
+  // Backfill opens an assignment for every item link that has none, starting
+  // at the pair's first transaction. It returns how many were opened.
+  Backfill() (int64, error)
+}
+
+type slotAssignmentRepo struct {
+  db *gorm.DB
+}
+
+func NewSlotAssignmentRepo(db *gorm.DB) SlotAssignmentRepo {
+  return &slotAssignmentRepo{db: db}
+}
+
+func (r *slotAssignmentRepo) Open(a *models.SlotAssignment) error {
+  if a.EffectiveFrom.IsZero() {
+    a.EffectiveFrom = time.Now()
+  }
+  return r.db.Transaction(func(tx *gorm.DB) error {
+    var open models.SlotAssignment
+    err := tx.Where("location_id = ? AND item_id = ? AND effective_to IS NULL", a.LocationID, a.ItemID).
+      First(&open).Error
+    switch {
+    case err == nil:
+      if a.EffectiveFrom.Before(open.EffectiveFrom) {
+        // never reach back over the pair's previous assignment
+        var lastClosed *time.Time
+        if err := tx.Model(&models.SlotAssignment{}).
+          Where("location_id = ? AND item_id = ? AND effective_to IS NOT NULL", a.LocationID, a.ItemID).
+          Select("MAX(effective_to)").Scan(&lastClosed).Error; err != nil {
+          return fmt.Errorf("Failed to check closed slot assignments: %w", err)
+        }
+        from := a.EffectiveFrom
+        if lastClosed != nil && from.Before(*lastClosed) {
+          from = *lastClosed
+        }
+        if from.Before(open.EffectiveFrom) {
+          if err := tx.Model(&open).Update("effective_from", from).Error; err != nil {
+            return fmt.Errorf("Failed to extend slot assignment with id: '%s': %w", open.ID, err)
+          }
+        }
+      }
+      *a = open
+    case err == gorm.ErrRecordNotFound:
+      if err := tx.Create(a).Error; err != nil {
+        return fmt.Errorf("Failed to create slot assignment: %w", err)
+      }
+    default:
+      return fmt.Errorf("Failed to check open slot assignment: %w", err)
+    }
+    if err := tx.Exec("INSERT INTO items_locations (item_id, location_id) VALUES (?, ?) ON CONFLICT DO NOTHING", a.ItemID, a.LocationID).Error; err != nil {
+      return fmt.Errorf("Failed to link item to location: %w", err)
+    }
+    return nil
+  })
+}
+
+func (r *slotAssignmentRepo) Close(locationID, itemID uuid.UUID, at time.Time, source string, closedByID *uuid.UUID) error {
+  if at.IsZero() {
+    at = time.Now()
+  }
+  return r.db.Transaction(func(tx *gorm.DB) error {
+    if err := tx.Model(&models.SlotAssignment{}).
+      Where("location_id = ? AND item_id = ? AND effective_to IS NULL", locationID, itemID).
+      Updates(map[string]interface{}{
+        "effective_to": gorm.Expr("GREATEST(effective_from, ?)", at),
+        "close_source": source,
+        "closed_by_id": closedByID,
+        "updated_at":   time.Now(),
+      }).Error; err != nil {
+      return fmt.Errorf("Failed to close slot assignment: %w", err)
+    }
+    if err := tx.Exec("DELETE FROM items_locations WHERE item_id = ? AND location_id = ?", itemID, locationID).Error; err != nil {
+      return fmt.Errorf("Failed to unlink item (ID %s) from location (ID %s): %w", itemID, locationID, err)
+    }
+    return nil
+  })
+}
+
+func (r *slotAssignmentRepo) ListAssignments(f SlotAssignmentFilter) ([]*models.SlotAssignment, error) {
+  dbq := r.db.Model(&models.SlotAssignment{}).Preload("Item").Preload("Location")
+  if f.CompanyID != uuid.Nil {
+    dbq = dbq.Where("company_id = ?", f.CompanyID)
+  }
+  if f.WarehouseID != uuid.Nil {
+    dbq = dbq.Where("warehouse_id = ?", f.WarehouseID)
+  }
+  if f.ItemID != uuid.Nil {
+    dbq = dbq.Where("item_id = ?", f.ItemID)
+  }
+  if f.LocationID != uuid.Nil {
+    dbq = dbq.Where("location_id = ?", f.LocationID)
+  }
+  if f.AssignmentType != "" {
+    dbq = dbq.Where("assignment_type = ?", f.AssignmentType)
+  }
+  if !f.AsOf.IsZero() {
+    dbq = dbq.Where("effective_from <= ? AND (effective_to IS NULL OR effective_to > ?)", f.AsOf, f.AsOf)
+  }
+  if f.OpenOnly {
+    dbq = dbq.Where("effective_to IS NULL")
+  }
+  if !f.StartDate.IsZero() {
+    dbq = dbq.Where("(effective_to IS NULL OR effective_to > ?)", f.StartDate)
+  }
+  if !f.EndDate.IsZero() {
+    dbq = dbq.Where("effective_from <= ?", f.EndDate)
+  }
+  allowed := []string{"assignment_type", "effective_from", "effective_to", "created_at"}
+  dbq = applySorting(dbq, f.SortField, f.SortDir, allowed)
+  var assignments []*models.SlotAssignment
+  if err := dbq.Find(&assignments).Error; err != nil {
+    return nil, err
+  }
+  return assignments, nil
+}
+
+func (r *slotAssignmentRepo) Backfill() (int64, error) {
+  res := r.db.Exec(`INSERT INTO slot_assignments (company_id, warehouse_id, item_id, location_id, assignment_type, effective_from, open_source)
+    SELECT w.company_id, l.warehouse_id, il.item_id, il.location_id,
+      CASE WHEN l.slot_role IN (?, ?) THEN l.slot_role ELSE ? END,
+      COALESCE((SELECT MIN(tr.completed_date) FROM transaction_records tr WHERE tr.item_id = il.item_id AND tr.location_id = il.location_id), l.created_at),
+      ?
+    FROM items_locations il
+    JOIN locations l ON l.id = il.location_id
+    JOIN warehouses w ON w.id = l.warehouse_id
+    WHERE NOT EXISTS (
+      SELECT 1 FROM slot_assignments sa
+      WHERE sa.item_id = il.item_id AND sa.location_id = il.location_id AND sa.effective_to IS NULL
+    )`, constants.SlotRoleReserve, constants.SlotRoleOverflow, constants.AssignmentPrimary, constants.AssignmentSourceBackfill)
+  if res.Error != nil {
+    return 0, fmt.Errorf("Failed to backfill slot assignments: %w", res.Error)
+  }
+  return res.RowsAffected, nil
+}
@@ -0,0 +1,118 @@
+package repos
+
+import (
+  "fmt"
+
+  "gorm.io/gorm"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+type SlotPlanFilter struct {
+  CompanyID     uuid.UUID
+  WarehouseID   uuid.UUID
+  ZoneID        uuid.UUID
+  SortField     string
+  SortDir       string
+}
+
+type SlotPlanEntryFilter struct {
+  PlanID        uuid.UUID
+  ItemID        uuid.UUID
+  MovesOnly     bool // entries whose target differs from the current slot
+  SortField     string
+  SortDir       string
+}
+
+type SlotPlanRepo interface {
+  // Create stores the plan and its entries in one transaction.
+  Create(plan models.SlotPlan, entries []*models.SlotPlanEntry) (*models.SlotPlan, error)
+  GetByID(planID uuid.UUID) (*models.SlotPlan, error)
+  Delete(planID uuid.UUID) error
+  ListSlotPlans(f SlotPlanFilter) ([]*models.SlotPlan, error)
+  ListEntries(f SlotPlanEntryFilter) ([]*models.SlotPlanEntry, error)
+}
+
+type slotPlanRepo struct {
+  db *gorm.DB
+}
+
+func NewSlotPlanRepo(db *gorm.DB) SlotPlanRepo {
+  return &slotPlanRepo{db: db}
+}
+
+func (r *slotPlanRepo) Create(plan models.SlotPlan, entries []*models.SlotPlanEntry) (*models.SlotPlan, error) {
+  err := r.db.Transaction(func(tx *gorm.DB) error {
+    plan.Entries = nil
+    if err := tx.Create(&plan).Error; err != nil {
+      return err
+    }
+    for _, e := range entries {
+      e.PlanID = &plan.ID
+    }
+    if len(entries) == 0 {
+      return nil
+    }
+    return tx.CreateInBatches(entries, 500).Error
+  })
+  if err != nil {
+    return nil, fmt.Errorf("Failed to create slot plan: %w", err)
+  }
+  return &plan, nil
+}
+
+func (r *slotPlanRepo) GetByID(planID uuid.UUID) (*models.SlotPlan, error) {
+  var plan models.SlotPlan
+  if err := r.db.First(&plan, "id = ?", planID).Error; err != nil {
+    return nil, fmt.Errorf("Slot plan not found with id: '%s': %w", planID, err)
+  }
+  return &plan, nil
+}
+
+func (r *slotPlanRepo) Delete(planID uuid.UUID) error {
+  return r.db.Delete(&models.SlotPlan{}, "id = ?", planID).Error
+}
+
+func (r *slotPlanRepo) ListSlotPlans(f SlotPlanFilter) ([]*models.SlotPlan, error) {
+  dbq := r.db.Model(&models.SlotPlan{})
+  if f.CompanyID != uuid.Nil {
+    dbq = dbq.Where("company_id = ?", f.CompanyID)
+  }
+  if f.WarehouseID != uuid.Nil {
+    dbq = dbq.Where("warehouse_id = ?", f.WarehouseID)
+  }
+  if f.ZoneID != uuid.Nil {
+    dbq = dbq.Where("zone_id = ?", f.ZoneID)
+  }
+  allowed := []string{"item_count", "move_count", "plan_travel_m", "plan_ergo_score", "created_at"}
+  dbq = applySorting(dbq, f.SortField, f.SortDir, allowed)
+  var plans []*models.SlotPlan
+  if err := dbq.Find(&plans).Error; err != nil {
+    return nil, err
+  }
+  return plans, nil
+}
+
+func (r *slotPlanRepo) ListEntries(f SlotPlanEntryFilter) ([]*models.SlotPlanEntry, error) {
+  dbq := r.db.Model(&models.SlotPlanEntry{}).
+    Preload("Item").Preload("FromLocation").Preload("ToLocation")
+  if f.PlanID != uuid.Nil {
+    dbq = dbq.Where("plan_id = ?", f.PlanID)
+  }
+  if f.ItemID != uuid.Nil {
+    dbq = dbq.Where("item_id = ?", f.ItemID)
+  }
+  if f.MovesOnly {
+    dbq = dbq.Where("to_location_id IS NOT NULL AND to_location_id IS DISTINCT FROM from_location_id")
+  }
+  if f.SortField == "" {
+    f.SortField, f.SortDir = "lines", "desc"
+  }
+  allowed := []string{"lines", "from_score", "to_score"}
+  dbq = applySorting(dbq, f.SortField, f.SortDir, allowed)
+  var entries []*models.SlotPlanEntry
+  if err := dbq.Find(&entries).Error; err != nil {
+    return nil, err
+  }
+  return entries, nil
+}
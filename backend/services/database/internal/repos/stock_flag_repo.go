@@ -0,0 +1,93 @@
+package repos
+
+import (
+  "fmt"
+  "time"
+
+  "gorm.io/gorm"
+  "gorm.io/gorm/clause"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+type StockFlagRepo interface {
+  ListByWarehouse(warehouseID uuid.UUID) ([]*models.StockFlag, error)
+  // Upsert creates the item's flag or sets its status; it counts as flagged
+  // anew only when the status changed. Concurrent scans meet on the
+  // warehouse and item key instead of failing on it.
+  Upsert(flag *models.StockFlag) error
+  Delete(flagID uuid.UUID) error
+  // GetSetting returns the warehouse's thresholds, nil when it has none.
+  GetSetting(warehouseID uuid.UUID) (*models.SlowStockSetting, error)
+  // UpsertSetting creates the warehouse's thresholds or replaces them.
+  UpsertSetting(st *models.SlowStockSetting) error
+  DeleteSetting(warehouseID uuid.UUID) error
+}
+
+type stockFlagRepo struct {
+  db *gorm.DB
+}
+
+func NewStockFlagRepo(db *gorm.DB) StockFlagRepo {
+  return &stockFlagRepo{db: db}
+}
+
+func (r *stockFlagRepo) ListByWarehouse(warehouseID uuid.UUID) ([]*models.StockFlag, error) {
+  var flags []*models.StockFlag
+  if err := r.db.Where("warehouse_id = ?", warehouseID).Find(&flags).Error; err != nil {
+    return nil, fmt.Errorf("Failed to list stock flags for warehouse with id: '%s': %w", warehouseID, err)
+  }
+  return flags, nil
+}
+
+func (r *stockFlagRepo) Upsert(flag *models.StockFlag) error {
+  now := time.Now()
+  flag.FlaggedAt, flag.UpdatedAt = now, now
+  err := r.db.Clauses(clause.OnConflict{
+    Columns:   []clause.Column{{Name: "warehouse_id"}, {Name: "item_id"}},
+    DoUpdates: clause.Assignments(map[string]interface{}{
+      "status":     gorm.Expr("excluded.status"),
+      "flagged_at": gorm.Expr("CASE WHEN stock_flags.status = excluded.status THEN stock_flags.flagged_at ELSE excluded.flagged_at END"),
+      "updated_at": gorm.Expr("excluded.updated_at"),
+    }),
+  }).Create(flag).Error
+  if err != nil {
+    return fmt.Errorf("Failed to save stock flag: %w", err)
+  }
+  return nil
+}
+
+func (r *stockFlagRepo) Delete(flagID uuid.UUID) error {
+  return r.db.Delete(&models.StockFlag{}, "id = ?", flagID).Error
+}
+
+func (r *stockFlagRepo) GetSetting(warehouseID uuid.UUID) (*models.SlowStockSetting, error) {
+  var st models.SlowStockSetting
+  err := r.db.Where("warehouse_id = ?", warehouseID).First(&st).Error
+  switch {
+  case err == gorm.ErrRecordNotFound:
+    return nil, nil
+  case err != nil:
+    return nil, fmt.Errorf("Failed to get slow stock setting for warehouse with id: '%s': %w", warehouseID, err)
+  }
+  return &st, nil
+}
+
+func (r *stockFlagRepo) UpsertSetting(st *models.SlowStockSetting) error {
+  st.UpdatedAt = time.Now()
+  err := r.db.Clauses(clause.OnConflict{
+    Columns:   []clause.Column{{Name: "warehouse_id"}},
+    DoUpdates: clause.AssignmentColumns([]string{"dead_days", "window_days", "drop_pct", "min_prior_lines", "updated_at"}),
+  }).Create(st).Error
+  if err != nil {
+    return fmt.Errorf("Failed to save slow stock setting: %w", err)
+  }
+  return nil
+}
+
+func (r *stockFlagRepo) DeleteSetting(warehouseID uuid.UUID) error {
+  if err := r.db.Where("warehouse_id = ?", warehouseID).Delete(&models.SlowStockSetting{}).Error; err != nil {
+    return fmt.Errorf("Failed to delete slow stock setting: %w", err)
+  }
+  return nil
+}
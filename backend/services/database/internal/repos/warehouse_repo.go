@@ -20,16 +20,28 @@ type WarehouseFilter struct {
     SortDir         string
 }
 
+// WarehouseCloneResult counts what Clone copied into the new warehouse.
+type WarehouseCloneResult struct {
+    WarehouseID     uuid.UUID   `json:"warehouse_id"`
+    Locations       int         `json:"locations"`
+    Zones           int         `json:"zones"`
+    ItemLinks       int         `json:"item_links"`
+}
+
 type WRepo interface {
     //GENERAL CRUD
     Create(warehouse models.Warehouse) (*models.Warehouse, error)
     UpdateName(warehouseID uuid.UUID, newName string) error
+    UpdateDepot(warehouseID uuid.UUID, x, y float64) error
+    UpdateGoLiveDate(warehouseID uuid.UUID, goLive *time.Time) error
     GetByID(warehouseID uuid.UUID) (*models.Warehouse, error)
     Delete(warehouseID uuid.UUID) error
     //LINK & UNLINK TO ITEMS
     LinkToItem(warehouseID, itemID uuid.UUID) error
     UnlinkFromItem(warehouseID, itemID uuid.UUID) error
     ListWarehouses(f WarehouseFilter) ([]*models.Warehouse, error)
+    //CLONE STRUCTURE (LOCATIONS, ZONES, OPTIONALLY ITEM LINKS) INTO A NEW WAREHOUSE
+    Clone(sourceID uuid.UUID, target models.Warehouse, includeItems bool, progress func(done, total int)) (*WarehouseCloneResult, error)
 }
 
 type wRepo struct {
@@ -53,6 +65,18 @@ func (r *wRepo) UpdateName(warehouseID uuid.UUID, newName string) error {
         Update("name", newName).Error
 }
 
+func (r *wRepo) UpdateDepot(warehouseID uuid.UUID, x, y float64) error {
+    return r.db.Model(&models.Warehouse{}).
+        Where("id = ?", warehouseID).
+        Updates(map[string]interface{}{"depot_x": x, "depot_y": y}).Error
+}
+
+func (r *wRepo) UpdateGoLiveDate(warehouseID uuid.UUID, goLive *time.Time) error {
+    return r.db.Model(&models.Warehouse{}).
+        Where("id = ?", warehouseID).
+        Update("go_live_date", goLive).Error
+}
+
 func (r *wRepo) GetByID(warehouseID uuid.UUID) (*models.Warehouse, error) {
     var wh models.Warehouse
     if err := r.db.First(&wh, "id = ?", warehouseID).Error; err != nil {
@@ -109,6 +133,99 @@ func (r *wRepo) UnlinkFromItem(warehouseID, itemID uuid.UUID) error {
     return nil
 }
 
+// Clone creates target and copies the source location hierarchy with its slot
+// attributes and zones in one transaction, so a failed clone leaves nothing
+// behind. Transactions, files and item-location links are never copied.
+func (r *wRepo) Clone(sourceID uuid.UUID, target models.Warehouse, includeItems bool, progress func(done, total int)) (*WarehouseCloneResult, error) {
+    res := &WarehouseCloneResult{}
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        var locCount, zoneCount int64
+        if err := tx.Model(&models.Location{}).Where("warehouse_id = ?", sourceID).Count(&locCount).Error; err != nil {
+            return fmt.Errorf("Failed to count source locations: %w", err)
+        }
+        if err := tx.Model(&models.Zone{}).Where("warehouse_id = ?", sourceID).Count(&zoneCount).Error; err != nil {
+            return fmt.Errorf("Failed to count source zones: %w", err)
+        }
+        total := int(locCount + zoneCount)
+        progress(0, total)
+
+        var source models.Warehouse
+        if err := tx.First(&source, "id = ?", sourceID).Error; err != nil {
+            return fmt.Errorf("Failed to find source warehouse: %w", err)
+        }
+        target.DepotX, target.DepotY = source.DepotX, source.DepotY
+        if err := tx.Create(&target).Error; err != nil {
+            return fmt.Errorf("Failed to create warehouse: %w", err)
+        }
+        res.WarehouseID = target.ID
+
+        var batch []*models.Location
+        err := tx.Model(&models.Location{}).Where("warehouse_id = ?", sourceID).
+            FindInBatches(&batch, 500, func(btx *gorm.DB, _ int) error {
+                copies := make([]*models.Location, 0, len(batch))
+                for _, loc := range batch {
+                    copies = append(copies, &models.Location{
+                        WarehouseID:      &target.ID,
+                        LocationPath:     loc.LocationPath,
+                        LocationNamePath: loc.LocationNamePath,
+                        Status:           loc.Status,
+                        StatusReason:     loc.StatusReason,
+                        SlotRole:         loc.SlotRole,
+                        SlotType:         loc.SlotType,
+                        Aisle:            loc.Aisle,
+                        CoordX:           loc.CoordX,
+                        CoordY:           loc.CoordY,
+                        LevelHeightCm:    loc.LevelHeightCm,
+                        SlotWidthCm:      loc.SlotWidthCm,
+                        SlotDepthCm:      loc.SlotDepthCm,
+                        SlotHeightCm:     loc.SlotHeightCm,
+                        MaxWeightKg:      loc.MaxWeightKg,
+                    })
+                }
+                if err := tx.Create(&copies).Error; err != nil {
+                    return fmt.Errorf("Failed to copy locations: %w", err)
+                }
+                res.Locations += len(copies)
+                progress(res.Locations, total)
+                return nil
+            }).Error
+        if err != nil {
+            return err
+        }
+
+        var zones []*models.Zone
+        if err := tx.Where("warehouse_id = ?", sourceID).Find(&zones).Error; err != nil {
+            return fmt.Errorf("Failed to load source zones: %w", err)
+        }
+        for _, z := range zones {
+            z.ID = uuid.Nil
+            z.WarehouseID = &target.ID
+            z.CreatedAt = time.Time{}
+            z.UpdatedAt = time.Time{}
+        }
+        if len(zones) > 0 {
+            if err := tx.Create(&zones).Error; err != nil {
+                return fmt.Errorf("Failed to copy zones: %w", err)
+            }
+        }
+        res.Zones = len(zones)
+        progress(res.Locations+res.Zones, total)
+
+        if includeItems {
+            q := tx.Exec("INSERT INTO items_warehouses (warehouse_id, item_id) SELECT ?, item_id FROM items_warehouses WHERE warehouse_id = ? ON CONFLICT DO NOTHING", target.ID, sourceID)
+            if q.Error != nil {
+                return fmt.Errorf("Failed to copy item links: %w", q.Error)
+            }
+            res.ItemLinks = int(q.RowsAffected)
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return res, nil
+}
+
 func (r *wRepo) ListWarehouses(f WarehouseFilter) ([]*models.Warehouse, error) {
     dbq := r.db.Model(&models.Warehouse{}).Select("DISTINCT warehouses.*")
     if f.CompanyID != uuid.Nil {
@@ -0,0 +1,64 @@
+package repos
+
+import (
+  "fmt"
+
+  "gorm.io/gorm"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+type ZRepo interface {
+  Create(zone models.Zone) (*models.Zone, error)
+  Update(zone models.Zone) error
+  GetByID(zoneID uuid.UUID) (*models.Zone, error)
+  Delete(zoneID uuid.UUID) error
+  ListByWarehouse(warehouseID uuid.UUID) ([]*models.Zone, error)
+}
+
+type zRepo struct {
+  db *gorm.DB
+}
+
+func NewZRepo(db *gorm.DB) ZRepo {
+  return &zRepo{db: db}
+}
+
+func (r *zRepo) Create(zone models.Zone) (*models.Zone, error) {
+  if err := r.db.Create(&zone).Error; err != nil {
+    return nil, fmt.Errorf("Failed to create zone: %w", err)
+  }
+  return &zone, nil
+}
+
+func (r *zRepo) Update(zone models.Zone) error {
+  // Select("*") so limits can be cleared back to zero
+  if err := r.db.Model(&models.Zone{}).Where("id = ?", zone.ID).
+    Select("*").Omit("id", "company_id", "warehouse_id", "created_at").Updates(&zone).Error; err != nil {
+    return fmt.Errorf("Failed to update zone with id: '%s': %w", zone.ID, err)
+  }
+  return nil
+}
+
+func (r *zRepo) GetByID(zoneID uuid.UUID) (*models.Zone, error) {
+  var zone models.Zone
+  if err := r.db.First(&zone, "id = ?", zoneID).Error; err != nil {
+    return nil, fmt.Errorf("Zone not found with id: '%s': %w", zoneID, err)
+  }
+  return &zone, nil
+}
+
+func (r *zRepo) Delete(zoneID uuid.UUID) error {
+  if err := r.db.Delete(&models.Zone{}, "id = ?", zoneID).Error; err != nil {
+    return fmt.Errorf("Failed to delete zone: %w", err)
+  }
+  return nil
+}
+
+func (r *zRepo) ListByWarehouse(warehouseID uuid.UUID) ([]*models.Zone, error) {
+  var zones []*models.Zone
+  if err := r.db.Where("warehouse_id = ?", warehouseID).Order("path_prefix ASC").Find(&zones).Error; err != nil {
+    return nil, fmt.Errorf("Failed to list zones: %w", err)
+  }
+  return zones, nil
+}
@@ -0,0 +1,104 @@
+package repos
+
+import (
+  "fmt"
+  "time"
+
+  "gorm.io/gorm"
+  "gorm.io/gorm/clause"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+type ZoneViolationFilter struct {
+  CompanyID       uuid.UUID
+  WarehouseID     uuid.UUID
+  ZoneID          uuid.UUID
+  LocationID      uuid.UUID
+  ItemID          uuid.UUID
+  Rule            string
+  Source          string
+  IncludeResolved bool
+  SortField       string
+  SortDir         string
+}
+
+type ZVRepo interface {
+  Upsert(v models.ZoneViolation) error
+  // ResolveLink marks open violations for a location/item pair resolved,
+  // except those whose "zone_id:rule" key is in keep (rules that still fail).
+  ResolveLink(locationID, itemID uuid.UUID, keep []string) error
+  ListViolations(f ZoneViolationFilter) ([]*models.ZoneViolation, error)
+}
+
+type zvRepo struct {
+  db *gorm.DB
+}
+
+func NewZVRepo(db *gorm.DB) ZVRepo {
+  return &zvRepo{db: db}
+}
+
+func (r *zvRepo) Upsert(v models.ZoneViolation) error {
+  err := r.db.Clauses(clause.OnConflict{
+    Columns:   []clause.Column{{Name: "zone_id"}, {Name: "location_id"}, {Name: "item_id"}, {Name: "rule"}},
+    DoUpdates: clause.Assignments(map[string]interface{}{
+      "detail":              v.Detail,
+      "source":              v.Source,
+      "transaction_file_id": v.TransactionFileID,
+      "resolved_at":         nil,
+      "updated_at":          time.Now(),
+    }),
+  }).Create(&v).Error
+  if err != nil {
+    return fmt.Errorf("Failed to save zone violation: %w", err)
+  }
+  return nil
+}
+
+func (r *zvRepo) ResolveLink(locationID, itemID uuid.UUID, keep []string) error {
+  dbq := r.db.Model(&models.ZoneViolation{}).
+    Where("location_id = ? AND item_id = ? AND resolved_at IS NULL", locationID, itemID)
+  if len(keep) > 0 {
+    dbq = dbq.Where("(zone_id::text || ':' || rule) NOT IN ?", keep)
+  }
+  if err := dbq.Update("resolved_at", time.Now()).Error; err != nil {
+    return fmt.Errorf("Failed to resolve zone violations: %w", err)
+  }
+  return nil
+}
+
+func (r *zvRepo) ListViolations(f ZoneViolationFilter) ([]*models.ZoneViolation, error) {
+  dbq := r.db.Model(&models.ZoneViolation{}).Preload("Zone").Preload("Location").Preload("Item")
+  if f.CompanyID != uuid.Nil {
+    dbq = dbq.Where("zone_violations.company_id = ?", f.CompanyID)
+  }
+  if f.WarehouseID != uuid.Nil {
+    dbq = dbq.Where("zone_violations.warehouse_id = ?", f.WarehouseID)
+  }
+  if f.ZoneID != uuid.Nil {
+    dbq = dbq.Where("zone_violations.zone_id = ?", f.ZoneID)
+  }
+  if f.LocationID != uuid.Nil {
+    dbq = dbq.Where("zone_violations.location_id = ?", f.LocationID)
+  }
+  if f.ItemID != uuid.Nil {
+    dbq = dbq.Where("zone_violations.item_id = ?", f.ItemID)
+  }
+  if f.Rule != "" {
+    dbq = dbq.Where("zone_violations.rule = ?", f.Rule)
+  }
+  if f.Source != "" {
+    dbq = dbq.Where("zone_violations.source = ?", f.Source)
+  }
+  if !f.IncludeResolved {
+    dbq = dbq.Where("zone_violations.resolved_at IS NULL")
+  }
+  allowed := []string{"rule", "source", "created_at", "updated_at"}
+  dbq = applySorting(dbq, f.SortField, f.SortDir, allowed)
+  var out []*models.ZoneViolation
+  if err := dbq.Find(&out).Error; err != nil {
+    return nil, err
+  }
+  return out, nil
+}
@@ -0,0 +1,756 @@
+package services
+
+import (
+  "fmt"
+  "math"
+  "sort"
+  "strings"
+  "time"
+
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+// VelocityQuery selects the window and transaction type categories (see
+// constants.TransactionTypes) to analyse. Without types only picks count.
+type VelocityQuery struct {
+  CompanyID        uuid.UUID
+  WarehouseID      uuid.UUID
+  StartDate        time.Time
+  EndDate          time.Time
+  TransactionTypes []string
+  SortField        string
+  SortDir          string
+  Limit            int
+  Offset           int
+}
+
+type ItemVelocity struct {
+  ItemID         uuid.UUID        `json:"item_id"`
+  ItemName       string           `json:"item_name"`
+  Lines          int64            `json:"lines"`
+  Units          int64            `json:"units"`
+  Orders         int64            `json:"orders"`
+  ActiveDays     int              `json:"active_days"`
+  AvgDailyLines  float64          `json:"avg_daily_lines"`
+  PeakDailyLines int64            `json:"peak_daily_lines"`
+  AvgDailyUnits  float64          `json:"avg_daily_units"`
+  PeakDailyUnits int64            `json:"peak_daily_units"`
+  LinesByType    map[string]int64 `json:"lines_by_type"`
+  UnitsByType    map[string]int64 `json:"units_by_type"`
+}
+
+type VelocityReport struct {
+  WarehouseID      uuid.UUID       `json:"warehouse_id"`
+  StartDate        time.Time       `json:"start_date"`
+  EndDate          time.Time       `json:"end_date"`
+  Days             int             `json:"days"`
+  TransactionTypes []string        `json:"transaction_types"`
+  TotalItems       int             `json:"total_items"`
+  Rows             []*ItemVelocity `json:"rows"`
+}
+
+// AffinityQuery scopes a co-pick analysis of pick lines grouped by order.
+// Pairs need MinCoOrders shared orders and at least MinLift to count; orders
+// with more than MaxOrderItems distinct items (bulk or replenishment runs)
+// are left out. Clusters grow up to MaxClusterSize items.
+type AffinityQuery struct {
+  CompanyID      uuid.UUID
+  WarehouseID    uuid.UUID
+  StartDate      time.Time
+  EndDate        time.Time
+  MinCoOrders    int
+  MinLift        float64
+  MaxOrderItems  int
+  MaxClusterSize int
+  Limit          int
+  Offset         int
+}
+
+// ItemPair is an association rule between two items: support is the share of
+// orders with both, confidence the share of one item's orders that also have
+// the other, and lift how much more often they meet than by chance.
+type ItemPair struct {
+  ItemAID      uuid.UUID `json:"item_a_id"`
+  ItemAName    string    `json:"item_a_name"`
+  ItemBID      uuid.UUID `json:"item_b_id"`
+  ItemBName    string    `json:"item_b_name"`
+  CoOrders     int64     `json:"co_orders"`
+  Support      float64   `json:"support"`
+  ConfidenceAB float64   `json:"confidence_ab"`
+  ConfidenceBA float64   `json:"confidence_ba"`
+  Lift         float64   `json:"lift"`
+}
+
+type ClusterItem struct {
+  ItemID   uuid.UUID `json:"item_id"`
+  ItemName string    `json:"item_name"`
+  Orders   int64     `json:"orders"`
+}
+
+type AffinityCluster struct {
+  Cluster  int            `json:"cluster"`
+  Items    []*ClusterItem `json:"items"`
+  CoOrders int64          `json:"co_orders"` // summed over the pairs inside the cluster
+  AvgLift  float64        `json:"avg_lift"`
+}
+
+type AffinityReport struct {
+  WarehouseID   uuid.UUID          `json:"warehouse_id"`
+  StartDate     time.Time          `json:"start_date"`
+  EndDate       time.Time          `json:"end_date"`
+  Orders        int                `json:"orders"`
+  SkippedOrders int                `json:"skipped_orders"`
+  TotalPairs    int                `json:"total_pairs"`
+  Pairs         []*ItemPair        `json:"pairs"`
+  Clusters      []*AffinityCluster `json:"clusters"`
+}
+
+// OrderProfileQuery scopes an order profile. TransactionTypes select the
+// lines that make up orders (default pick); the type mix covers all types.
+type OrderProfileQuery struct {
+  CompanyID        uuid.UUID
+  WarehouseID      uuid.UUID
+  StartDate        time.Time
+  EndDate          time.Time
+  TransactionTypes []string
+}
+
+// ProfileBucket is one bar of a distribution; Count is orders or lines
+// depending on the distribution.
+type ProfileBucket struct {
+  Label string  `json:"label"`
+  Count int64   `json:"count"`
+  Share float64 `json:"share"`
+}
+
+type TypeShare struct {
+  TransactionType string  `json:"transaction_type"`
+  Lines           int64   `json:"lines"`
+  Units           int64   `json:"units"`
+  Share           float64 `json:"share"` // of lines
+}
+
+type OrderProfile struct {
+  WarehouseID         uuid.UUID        `json:"warehouse_id"`
+  StartDate           time.Time        `json:"start_date"`
+  EndDate             time.Time        `json:"end_date"`
+  TransactionTypes    []string         `json:"transaction_types"`
+  Orders              int64            `json:"orders"`
+  Lines               int64            `json:"lines"`
+  Units               int64            `json:"units"`
+  LinesWithoutOrder   int64            `json:"lines_without_order"`
+  AvgLinesPerOrder    float64          `json:"avg_lines_per_order"`
+  MedianLinesPerOrder float64          `json:"median_lines_per_order"`
+  MaxLinesPerOrder    int64            `json:"max_lines_per_order"`
+  AvgUnitsPerLine     float64          `json:"avg_units_per_line"`
+  AvgUnitsPerOrder    float64          `json:"avg_units_per_order"`
+  SingleLineOrders    int64            `json:"single_line_orders"`
+  MultiLineOrders     int64            `json:"multi_line_orders"`
+  SingleLineShare     float64          `json:"single_line_share"`
+  MultiLineShare      float64          `json:"multi_line_share"`
+  LinesPerOrder       []*ProfileBucket `json:"lines_per_order"`   // orders
+  UnitsPerLine        []*ProfileBucket `json:"units_per_line"`    // lines
+  OrdersByHour        []*ProfileBucket `json:"orders_by_hour"`    // UTC hour of the first line
+  OrdersByWeekday     []*ProfileBucket `json:"orders_by_weekday"` // Monday first
+  TypeMix             []*TypeShare     `json:"type_mix"`
+}
+
+type AnalyticsSvc interface {
+  // ItemVelocity aggregates transaction records per item over [StartDate, EndDate].
+  ItemVelocity(q VelocityQuery) (*VelocityReport, error)
+  // ItemAffinity finds item pairs picked on the same orders and groups them
+  // into clusters of items that belong together.
+  ItemAffinity(q AffinityQuery) (*AffinityReport, error)
+  // OrderProfile breaks orders down by size, time and transaction type.
+  OrderProfile(q OrderProfileQuery) (*OrderProfile, error)
+}
+
+type analyticsSvc struct {
+  repo            repos.AnalyticsRepo
+}
+
+func NewAnalyticsSvc(repo repos.AnalyticsRepo) AnalyticsSvc {
+  return &analyticsSvc{repo: repo}
+}
+
+const (
+  defaultVelocityWindowDays  = 90
+  defaultAffinityMinCoOrders = 2
+  defaultAffinityMinLift     = 1.0
+  defaultAffinityMaxItems    = 50
+  defaultAffinityClusterSize = 8
+)
+
+func (s *analyticsSvc) ItemVelocity(q VelocityQuery) (*VelocityReport, error) {
+  if q.WarehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  start, end, err := analyticsWindow(q.StartDate, q.EndDate)
+  if err != nil {
+    return nil, err
+  }
+  types, err := normalizeTypeFilter(q.TransactionTypes)
+  if err != nil {
+    return nil, err
+  }
+  f := repos.AnalyticsFilter{CompanyID: q.CompanyID, WarehouseID: q.WarehouseID, StartDate: start, EndDate: end}
+  if f.RawTypes, err = rawTypesFor(s.repo, f, types); err != nil {
+    return nil, err
+  }
+  days := int(end.Sub(start).Hours() / 24)
+  report := &VelocityReport{
+    WarehouseID:      q.WarehouseID,
+    StartDate:        start,
+    EndDate:          end.AddDate(0, 0, -1),
+    Days:             days,
+    TransactionTypes: types,
+    Rows:             []*ItemVelocity{},
+  }
+  if len(f.RawTypes) == 0 {
+    return report, nil
+  }
+
+  activity, err := s.repo.ItemDailyActivity(f)
+  if err != nil {
+    return nil, err
+  }
+  summaries, err := s.repo.ItemSummaries(f)
+  if err != nil {
+    return nil, err
+  }
+
+  byItem := make(map[uuid.UUID]*ItemVelocity)
+  daily := make(map[uuid.UUID]map[time.Time]int64)
+  dailyUnits := make(map[uuid.UUID]map[time.Time]int64)
+  for _, o := range summaries {
+    byItem[o.ItemID] = &ItemVelocity{
+      ItemID:      o.ItemID,
+      ItemName:    o.ItemName,
+      Orders:      o.Orders,
+      LinesByType: make(map[string]int64),
+      UnitsByType: make(map[string]int64),
+    }
+    daily[o.ItemID] = make(map[time.Time]int64)
+    dailyUnits[o.ItemID] = make(map[time.Time]int64)
+  }
+  for _, a := range activity {
+    v, ok := byItem[a.ItemID]
+    if !ok {
+      continue
+    }
+    category := constants.NormalizeTransactionType(a.TransactionType)
+    v.Lines += a.Lines
+    v.Units += a.Units
+    v.LinesByType[category] += a.Lines
+    v.UnitsByType[category] += a.Units
+    daily[a.ItemID][a.Day] += a.Lines
+    dailyUnits[a.ItemID][a.Day] += a.Units
+  }
+  for id, v := range byItem {
+    v.ActiveDays = len(daily[id])
+    for _, lines := range daily[id] {
+      if lines > v.PeakDailyLines {
+        v.PeakDailyLines = lines
+      }
+    }
+    for _, units := range dailyUnits[id] {
+      if units > v.PeakDailyUnits {
+        v.PeakDailyUnits = units
+      }
+    }
+    if days > 0 {
+      v.AvgDailyLines = float64(v.Lines) / float64(days)
+      v.AvgDailyUnits = float64(v.Units) / float64(days)
+    }
+    report.Rows = append(report.Rows, v)
+  }
+
+  sortVelocity(report.Rows, q.SortField, q.SortDir)
+  report.TotalItems = len(report.Rows)
+  report.Rows = paginate(report.Rows, q.Limit, q.Offset)
+  return report, nil
+}
+
+func (s *analyticsSvc) ItemAffinity(q AffinityQuery) (*AffinityReport, error) {
+  if q.WarehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  if q.MinCoOrders < 0 || q.MinLift < 0 || q.MaxOrderItems < 0 || q.MaxClusterSize < 0 {
+    return nil, fmt.Errorf("affinity parameters cannot be negative")
+  }
+  if q.MinCoOrders == 0 {
+    q.MinCoOrders = defaultAffinityMinCoOrders
+  }
+  if q.MinLift == 0 {
+    q.MinLift = defaultAffinityMinLift
+  }
+  if q.MaxOrderItems == 0 {
+    q.MaxOrderItems = defaultAffinityMaxItems
+  }
+  if q.MaxClusterSize == 0 {
+    q.MaxClusterSize = defaultAffinityClusterSize
+  }
+  start, end, err := analyticsWindow(q.StartDate, q.EndDate)
+  if err != nil {
+    return nil, err
+  }
+  f := repos.AnalyticsFilter{CompanyID: q.CompanyID, WarehouseID: q.WarehouseID, StartDate: start, EndDate: end}
+  if f.RawTypes, err = rawTypesFor(s.repo, f, []string{constants.TransactionTypePick}); err != nil {
+    return nil, err
+  }
+  report := &AffinityReport{
+    WarehouseID: q.WarehouseID,
+    StartDate:   start,
+    EndDate:     end.AddDate(0, 0, -1),
+    Pairs:       []*ItemPair{},
+    Clusters:    []*AffinityCluster{},
+  }
+  if len(f.RawTypes) == 0 {
+    return report, nil
+  }
+  lines, err := s.repo.PickLines(f)
+  if err != nil {
+    return nil, err
+  }
+  summaries, err := s.repo.ItemSummaries(f)
+  if err != nil {
+    return nil, err
+  }
+  names := make(map[uuid.UUID]string, len(summaries))
+  for _, o := range summaries {
+    names[o.ItemID] = o.ItemName
+  }
+
+  type pairKey [2]uuid.UUID
+  itemOrders := make(map[uuid.UUID]int64)
+  coOrders := make(map[pairKey]int64)
+  for _, items := range orderItemSets(lines) {
+    if len(items) > q.MaxOrderItems {
+      report.SkippedOrders++
+      continue
+    }
+    report.Orders++
+    for i, a := range items {
+      itemOrders[a]++
+      for _, b := range items[i+1:] {
+        coOrders[pairKey{a, b}]++
+      }
+    }
+  }
+  if report.Orders == 0 {
+    return report, nil
+  }
+
+  n := float64(report.Orders)
+  for k, co := range coOrders {
+    if co < int64(q.MinCoOrders) {
+      continue
+    }
+    a, b := float64(itemOrders[k[0]]), float64(itemOrders[k[1]])
+    pair := &ItemPair{
+      ItemAID:      k[0],
+      ItemAName:    names[k[0]],
+      ItemBID:      k[1],
+      ItemBName:    names[k[1]],
+      CoOrders:     co,
+      Support:      float64(co) / n,
+      ConfidenceAB: float64(co) / a,
+      ConfidenceBA: float64(co) / b,
+      Lift:         float64(co) * n / (a * b),
+    }
+    if pair.Lift < q.MinLift {
+      continue
+    }
+    if pair.ItemBName < pair.ItemAName {
+      pair.ItemAID, pair.ItemBID = pair.ItemBID, pair.ItemAID
+      pair.ItemAName, pair.ItemBName = pair.ItemBName, pair.ItemAName
+      pair.ConfidenceAB, pair.ConfidenceBA = pair.ConfidenceBA, pair.ConfidenceAB
+    }
+    report.Pairs = append(report.Pairs, pair)
+  }
+  sort.Slice(report.Pairs, func(i, j int) bool {
+    a, b := report.Pairs[i], report.Pairs[j]
+    if a.Lift != b.Lift {
+      return a.Lift > b.Lift
+    }
+    if a.CoOrders != b.CoOrders {
+      return a.CoOrders > b.CoOrders
+    }
+    return a.ItemAName+a.ItemBName < b.ItemAName+b.ItemBName
+  })
+  report.Clusters = affinityClusters(report.Pairs, itemOrders, names, q.MaxClusterSize)
+  report.TotalPairs = len(report.Pairs)
+  report.Pairs = paginate(report.Pairs, q.Limit, q.Offset)
+  return report, nil
+}
+
+// profileRange is a distribution bucket of [min, max].
+type profileRange struct {
+  label    string
+  min, max int64
+}
+
+var (
+  linesPerOrderBuckets = []profileRange{
+    {"1", 1, 1}, {"2", 2, 2}, {"3", 3, 3}, {"4", 4, 4}, {"5", 5, 5},
+    {"6-10", 6, 10}, {"11-20", 11, 20}, {"21-50", 21, 50}, {"51+", 51, math.MaxInt64},
+  }
+  unitsPerLineBuckets = []profileRange{
+    {"0", 0, 0}, {"1", 1, 1}, {"2", 2, 2}, {"3-5", 3, 5}, {"6-10", 6, 10},
+    {"11-24", 11, 24}, {"25-99", 25, 99}, {"100+", 100, math.MaxInt64},
+  }
+)
+
+func (s *analyticsSvc) OrderProfile(q OrderProfileQuery) (*OrderProfile, error) {
+  if q.WarehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  start, end, err := analyticsWindow(q.StartDate, q.EndDate)
+  if err != nil {
+    return nil, err
+  }
+  types, err := normalizeTypeFilter(q.TransactionTypes)
+  if err != nil {
+    return nil, err
+  }
+  all := repos.AnalyticsFilter{CompanyID: q.CompanyID, WarehouseID: q.WarehouseID, StartDate: start, EndDate: end}
+  profile := &OrderProfile{
+    WarehouseID:      q.WarehouseID,
+    StartDate:        start,
+    EndDate:          end.AddDate(0, 0, -1),
+    TransactionTypes: types,
+    TypeMix:          []*TypeShare{},
+  }
+
+  totals, err := s.repo.TypeTotals(all)
+  if err != nil {
+    return nil, err
+  }
+  mix := make(map[string]*TypeShare)
+  var allLines int64
+  for _, t := range totals {
+    category := constants.NormalizeTransactionType(t.TransactionType)
+    m, ok := mix[category]
+    if !ok {
+      m = &TypeShare{TransactionType: category}
+      mix[category] = m
+      profile.TypeMix = append(profile.TypeMix, m)
+    }
+    m.Lines += t.Lines
+    m.Units += t.Units
+    allLines += t.Lines
+  }
+  for _, m := range profile.TypeMix {
+    m.Share = float64(m.Lines) / float64(allLines)
+  }
+  sort.Slice(profile.TypeMix, func(i, j int) bool {
+    if profile.TypeMix[i].Lines != profile.TypeMix[j].Lines {
+      return profile.TypeMix[i].Lines > profile.TypeMix[j].Lines
+    }
+    return profile.TypeMix[i].TransactionType < profile.TypeMix[j].TransactionType
+  })
+
+  f := all
+  if f.RawTypes, err = rawTypesFor(s.repo, f, types); err != nil {
+    return nil, err
+  }
+  var orders []repos.OrderSummary
+  var units []repos.UnitCount
+  if len(f.RawTypes) > 0 {
+    if orders, err = s.repo.OrderSummaries(f); err != nil {
+      return nil, err
+    }
+    if profile.LinesWithoutOrder, err = s.repo.CountLinesWithoutOrder(f); err != nil {
+      return nil, err
+    }
+    if units, err = s.repo.LineUnitCounts(f); err != nil {
+      return nil, err
+    }
+  }
+
+  linesPerOrder := make([]int64, len(linesPerOrderBuckets))
+  hours := make([]int64, 24)
+  weekdays := make([]int64, 7)
+  sizes := make([]int64, 0, len(orders))
+  for _, o := range orders {
+    profile.Orders++
+    profile.Lines += o.Lines
+    profile.Units += o.Units
+    if o.Lines == 1 {
+      profile.SingleLineOrders++
+    } else {
+      profile.MultiLineOrders++
+    }
+    if o.Lines > profile.MaxLinesPerOrder {
+      profile.MaxLinesPerOrder = o.Lines
+    }
+    sizes = append(sizes, o.Lines)
+    linesPerOrder[bucketOf(linesPerOrderBuckets, o.Lines)]++
+    at := o.CompletedAt.UTC()
+    hours[at.Hour()]++
+    weekdays[(int(at.Weekday())+6)%7]++
+  }
+  if profile.Orders > 0 {
+    n := float64(profile.Orders)
+    profile.AvgLinesPerOrder = float64(profile.Lines) / n
+    profile.AvgUnitsPerOrder = float64(profile.Units) / n
+    profile.SingleLineShare = float64(profile.SingleLineOrders) / n
+    profile.MultiLineShare = float64(profile.MultiLineOrders) / n
+    sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+    mid := len(sizes) / 2
+    profile.MedianLinesPerOrder = float64(sizes[mid])
+    if len(sizes)%2 == 0 {
+      profile.MedianLinesPerOrder = float64(sizes[mid-1]+sizes[mid]) / 2
+    }
+  }
+
+  unitsPerLine := make([]int64, len(unitsPerLineBuckets))
+  var lines, lineUnits int64
+  for _, u := range units {
+    unitsPerLine[bucketOf(unitsPerLineBuckets, u.Units)] += u.Lines
+    lines += u.Lines
+    lineUnits += u.Units * u.Lines
+  }
+  if lines > 0 {
+    profile.AvgUnitsPerLine = float64(lineUnits) / float64(lines)
+  }
+
+  profile.LinesPerOrder = profileBuckets(linesPerOrderBuckets, linesPerOrder, profile.Orders)
+  profile.UnitsPerLine = profileBuckets(unitsPerLineBuckets, unitsPerLine, lines)
+  for h, n := range hours {
+    profile.OrdersByHour = append(profile.OrdersByHour, &ProfileBucket{Label: fmt.Sprintf("%02d", h), Count: n})
+  }
+  for d, n := range weekdays {
+    profile.OrdersByWeekday = append(profile.OrdersByWeekday, &ProfileBucket{Label: time.Weekday((d + 1) % 7).String(), Count: n})
+  }
+  for _, b := range append(profile.OrdersByHour, profile.OrdersByWeekday...) {
+    if profile.Orders > 0 {
+      b.Share = float64(b.Count) / float64(profile.Orders)
+    }
+  }
+  return profile, nil
+}
+
+func bucketOf(ranges []profileRange, v int64) int {
+  for i, r := range ranges {
+    if v >= r.min && v <= r.max {
+      return i
+    }
+  }
+  return 0
+}
+
+func profileBuckets(ranges []profileRange, counts []int64, total int64) []*ProfileBucket {
+  out := make([]*ProfileBucket, len(ranges))
+  for i, r := range ranges {
+    out[i] = &ProfileBucket{Label: r.label, Count: counts[i]}
+    if total > 0 {
+      out[i].Share = float64(counts[i]) / float64(total)
+    }
+  }
+  return out
+}
+
+// orderItemSets turns lines ordered by order name into the distinct items of
+// each order, sorted so a pair is always keyed the same way. Lines without an
+// order name cannot be related to others and are dropped.
+func orderItemSets(lines []repos.PickLine) [][]uuid.UUID {
+  var sets [][]uuid.UUID
+  var seen map[uuid.UUID]bool
+  last := ""
+  for _, l := range lines {
+    if l.OrderName == "" {
+      continue
+    }
+    if seen == nil || l.OrderName != last {
+      seen = make(map[uuid.UUID]bool)
+      sets = append(sets, nil)
+      last = l.OrderName
+    }
+    if !seen[l.ItemID] {
+      seen[l.ItemID] = true
+      sets[len(sets)-1] = append(sets[len(sets)-1], l.ItemID)
+    }
+  }
+  for _, items := range sets {
+    sort.Slice(items, func(i, j int) bool { return items[i].String() < items[j].String() })
+  }
+  return sets
+}
+
+// affinityClusters merges items greedily along the strongest pairs (pairs is
+// sorted best first) while clusters stay within maxSize items.
+func affinityClusters(pairs []*ItemPair, itemOrders map[uuid.UUID]int64, names map[uuid.UUID]string, maxSize int) []*AffinityCluster {
+  parent := make(map[uuid.UUID]uuid.UUID)
+  size := make(map[uuid.UUID]int)
+  var find func(uuid.UUID) uuid.UUID
+  find = func(id uuid.UUID) uuid.UUID {
+    if _, ok := parent[id]; !ok {
+      parent[id], size[id] = id, 1
+    }
+    if parent[id] != id {
+      parent[id] = find(parent[id])
+    }
+    return parent[id]
+  }
+  for _, p := range pairs {
+    a, b := find(p.ItemAID), find(p.ItemBID)
+    if a == b || size[a]+size[b] > maxSize {
+      continue
+    }
+    parent[b] = a
+    size[a] += size[b]
+  }
+
+  byRoot := make(map[uuid.UUID]*AffinityCluster)
+  pairCount := make(map[uuid.UUID]int)
+  var clusters []*AffinityCluster
+  for _, p := range pairs {
+    root := find(p.ItemAID)
+    if root != find(p.ItemBID) {
+      continue
+    }
+    c, ok := byRoot[root]
+    if !ok {
+      c = &AffinityCluster{}
+      byRoot[root] = c
+      clusters = append(clusters, c)
+    }
+    c.CoOrders += p.CoOrders
+    c.AvgLift += p.Lift
+    pairCount[root]++
+  }
+  for id := range parent {
+    if c, ok := byRoot[find(id)]; ok {
+      c.Items = append(c.Items, &ClusterItem{ItemID: id, ItemName: names[id], Orders: itemOrders[id]})
+    }
+  }
+  for root, c := range byRoot {
+    c.AvgLift /= float64(pairCount[root])
+    sort.Slice(c.Items, func(i, j int) bool {
+      if c.Items[i].Orders != c.Items[j].Orders {
+        return c.Items[i].Orders > c.Items[j].Orders
+      }
+      return c.Items[i].ItemName < c.Items[j].ItemName
+    })
+  }
+  sort.SliceStable(clusters, func(i, j int) bool { return clusters[i].CoOrders > clusters[j].CoOrders })
+  for i, c := range clusters {
+    c.Cluster = i + 1
+  }
+  if clusters == nil {
+    clusters = []*AffinityCluster{}
+  }
+  return clusters
+}
+
+// typeLister is a source of the raw transaction types in range: the records
+// themselves or their rollups.
+type typeLister interface {
+  ListTransactionTypes(f repos.AnalyticsFilter) ([]string, error)
+}
+
+// rawTypesFor lists the raw transaction_type values in range that normalize
+// to one of the requested categories.
+func rawTypesFor(repo typeLister, f repos.AnalyticsFilter, categories []string) ([]string, error) {
+  all, err := repo.ListTransactionTypes(f)
+  if err != nil {
+    return nil, err
+  }
+  want := make(map[string]bool, len(categories))
+  for _, c := range categories {
+    want[c] = true
+  }
+  var raw []string
+  for _, t := range all {
+    if want[constants.NormalizeTransactionType(t)] {
+      raw = append(raw, t)
+    }
+  }
+  return raw, nil
+}
+
+// analyticsWindow turns the inclusive day range into [start, end) midnights,
+// defaulting to the last defaultVelocityWindowDays days.
+func analyticsWindow(start, end time.Time) (time.Time, time.Time, error) {
+  if end.IsZero() {
+    end = time.Now().UTC()
+  }
+  end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+  if start.IsZero() {
+    start = end.AddDate(0, 0, -defaultVelocityWindowDays)
+  }
+  start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+  if !start.Before(end) {
+    return start, end, fmt.Errorf("start date must not be after end date")
+  }
+  return start, end, nil
+}
+
+func normalizeTypeFilter(types []string) ([]string, error) {
+  if len(types) == 0 {
+    return []string{constants.TransactionTypePick}, nil
+  }
+  seen := make(map[string]bool)
+  var out []string
+  for _, t := range types {
+    t = strings.ToLower(strings.TrimSpace(t))
+    if t == "" || seen[t] {
+      continue
+    }
+    if !constants.TransactionTypes[t] {
+      return nil, fmt.Errorf("invalid transaction type '%s'", t)
+    }
+    seen[t] = true
+    out = append(out, t)
+  }
+  sort.Strings(out)
+  return out, nil
+}
+
+func sortVelocity(rows []*ItemVelocity, field, dir string) {
+  less := func(a, b *ItemVelocity) bool { return a.Lines < b.Lines }
+  switch field {
+  case "units":
+    less = func(a, b *ItemVelocity) bool { return a.Units < b.Units }
+  case "orders":
+    less = func(a, b *ItemVelocity) bool { return a.Orders < b.Orders }
+  case "active_days":
+    less = func(a, b *ItemVelocity) bool { return a.ActiveDays < b.ActiveDays }
+  case "avg_daily_lines":
+    less = func(a, b *ItemVelocity) bool { return a.AvgDailyLines < b.AvgDailyLines }
+  case "peak_daily_lines":
+    less = func(a, b *ItemVelocity) bool { return a.PeakDailyLines < b.PeakDailyLines }
+  case "avg_daily_units":
+    less = func(a, b *ItemVelocity) bool { return a.AvgDailyUnits < b.AvgDailyUnits }
+  case "peak_daily_units":
+    less = func(a, b *ItemVelocity) bool { return a.PeakDailyUnits < b.PeakDailyUnits }
+  case "item_name":
+    less = func(a, b *ItemVelocity) bool { return a.ItemName < b.ItemName }
+  }
+  asc := dir == "asc" || dir == "ASC"
+  // name first so ties come out in a stable, readable order
+  sort.Slice(rows, func(i, j int) bool { return rows[i].ItemName < rows[j].ItemName })
+  sort.SliceStable(rows, func(i, j int) bool {
+    if asc {
+      return less(rows[i], rows[j])
+    }
+    return less(rows[j], rows[i])
+  })
+}
+
+func paginate[T any](rows []T, limit, offset int) []T {
+  if offset < 0 {
+    offset = 0
+  }
+  if offset >= len(rows) {
+    return rows[:0]
+  }
+  rows = rows[offset:]
+  if limit > 0 && limit < len(rows) {
+    rows = rows[:limit]
+  }
+  return rows
+}
@@ -10,10 +10,13 @@ import (
   "github.com/google/uuid"
   "golang.org/x/crypto/bcrypt"
 
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
   "github.com/yungbote/slotter/backend/services/database/internal/models"
   "github.com/yungbote/slotter/backend/services/database/internal/repos"
   "github.com/yungbote/slotter/backend/services/database/internal/events"
   "github.com/yungbote/slotter/backend/services/database/internal/services/avatar"
+  "github.com/yungbote/slotter/backend/services/database/internal/services/export"
+  "github.com/yungbote/slotter/backend/services/database/internal/services/label"
   "github.com/yungbote/slotter/backend/services/database/internal/services/s3"
 )
 
@@ -37,10 +40,19 @@ type AppSvc interface {
 
   //Warehouse
   CreateWarehouse(ctx context.Context, userID uuid.UUID, createWarehouseName string) error
-  GetWarehouseByID(ctx context.Context, warehouseID uuid.UUID) (*models.Warehouse, error)
+  GetWarehouseByID(ctx context.Context, userID, warehouseID uuid.UUID) (*models.Warehouse, error)
   UpdateWarehouseName(ctx context.Context, userID uuid.UUID, newWarehouseName string) error
   DeleteWarehouse(ctx context.Context, userID uuid.UUID, warehouseID uuid.UUID) error
   ListWarehouses(ctx context.Context, userID uuid.UUID, f repos.WarehouseFilter) ([]*models.Warehouse, error)
+  CloneWarehouse(ctx context.Context, userID, sourceID uuid.UUID, newName string, includeItems bool) (*models.Job, error)
+  UpdateWarehouseDepot(ctx context.Context, userID, warehouseID uuid.UUID, x, y float64) error
+  // UpdateWarehouseGoLive sets or clears the go-live date and rescans the
+  // warehouse's exceptions against it.
+  UpdateWarehouseGoLive(ctx context.Context, userID, warehouseID uuid.UUID, goLive *time.Time) error
+
+  //Job
+  GetJob(ctx context.Context, userID, jobID uuid.UUID) (*models.Job, error)
+  ListJobs(ctx context.Context, userID uuid.UUID, f repos.JobFilter) ([]*models.Job, error)
 
   //Location
   CreateLocation(ctx context.Context, userID, warehouseID uuid.UUID, locationPath, locationNamePath string) error
@@ -48,6 +60,46 @@ type AppSvc interface {
   GetLocationByPath(ctx context.Context, userID, warehouseID uuid.UUID, locationPath string) (*models.Location, error)
   DeleteLocation(ctx context.Context, userID, locationID uuid.UUID) error
   ListLocations(ctx context.Context, userID uuid.UUID, f repos.LocationFilter) ([]*models.Location, error)
+  MoveLocation(ctx context.Context, userID, locationID uuid.UUID, newPath, newNamePath string) (*models.Location, error)
+  MergeLocations(ctx context.Context, userID, sourceID, targetID uuid.UUID) (*models.Location, error)
+  ListLocationAliases(ctx context.Context, userID, locationID uuid.UUID) ([]*models.LocationAlias, error)
+  UpdateLocationStatus(ctx context.Context, userID, locationID uuid.UUID, status, reason string) (*models.Location, error)
+  LinkItemToLocation(ctx context.Context, userID, locationID, itemID uuid.UUID, assignmentType string, effectiveFrom time.Time) ([]*models.ZoneViolation, error)
+  UnlinkItemFromLocation(ctx context.Context, userID, locationID, itemID uuid.UUID, effectiveTo time.Time) error
+  UpdateLocationSlot(ctx context.Context, userID, locationID uuid.UUID, attrs models.Location) (*models.Location, error)
+  UpdateWarehouseSlots(ctx context.Context, userID, warehouseID uuid.UUID, slots []models.Location) (int, error)
+
+  //Zone
+  CreateZone(ctx context.Context, userID, warehouseID uuid.UUID, zone models.Zone) (*models.Zone, error)
+  UpdateZone(ctx context.Context, userID, zoneID uuid.UUID, zone models.Zone) (*models.Zone, error)
+  DeleteZone(ctx context.Context, userID, zoneID uuid.UUID) error
+  ListZones(ctx context.Context, userID, warehouseID uuid.UUID) ([]*models.Zone, error)
+  RecheckZoneViolations(ctx context.Context, userID, warehouseID uuid.UUID) (int, error)
+  ListZoneViolations(ctx context.Context, userID uuid.UUID, f repos.ZoneViolationFilter) ([]*models.ZoneViolation, error)
+
+  //Slotting
+  RecommendSlotPlan(ctx context.Context, userID, warehouseID uuid.UUID, p SlotPlanParams) (*models.SlotPlan, error)
+  GetSlotPlan(ctx context.Context, userID, planID uuid.UUID) (*models.SlotPlan, error)
+  DeleteSlotPlan(ctx context.Context, userID, planID uuid.UUID) error
+  ListSlotPlans(ctx context.Context, userID uuid.UUID, f repos.SlotPlanFilter) ([]*models.SlotPlan, error)
+  ListSlotPlanEntries(ctx context.Context, userID, planID uuid.UUID, f repos.SlotPlanEntryFilter) ([]*models.SlotPlanEntry, error)
+  GetSlotPlanMoves(ctx context.Context, userID, planID uuid.UUID, p MoveListParams) (*MoveList, error)
+  AcceptSlotPlanMoves(ctx context.Context, userID, planID uuid.UUID, p MoveListParams) ([]*models.MoveTask, error)
+  ListMoveTasks(ctx context.Context, userID uuid.UUID, f repos.MoveTaskFilter) ([]*models.MoveTask, error)
+  GetMoveTask(ctx context.Context, userID, taskID uuid.UUID) (*models.MoveTask, error)
+  ConfirmMoveTask(ctx context.Context, userID, taskID uuid.UUID) ([]*models.ZoneViolation, error)
+  CancelMoveTask(ctx context.Context, userID, taskID uuid.UUID) error
+  ReplaySlotPlan(ctx context.Context, userID, planID uuid.UUID, p ReplayParams) (*ReplayReport, error)
+  ExportSlotPlanReplay(ctx context.Context, userID, planID uuid.UUID, p ReplayParams) ([]byte, string, error)
+  GetPickFaceSizing(ctx context.Context, userID, warehouseID uuid.UUID, q SizingQuery) (*SizingReport, error)
+  ExportPickFaceSizing(ctx context.Context, userID, warehouseID uuid.UUID, q SizingQuery) ([]byte, string, error)
+  CreateScenario(ctx context.Context, userID, warehouseID uuid.UUID, p ScenarioParams) (*models.SlottingScenario, error)
+  GetScenario(ctx context.Context, userID, scenarioID uuid.UUID) (*models.SlottingScenario, error)
+  DeleteScenario(ctx context.Context, userID, scenarioID uuid.UUID) error
+  ListScenarios(ctx context.Context, userID uuid.UUID, f repos.ScenarioFilter) ([]*models.SlottingScenario, error)
+  CloneScenario(ctx context.Context, userID, scenarioID uuid.UUID, name, description string, plan *SlotPlanParams) (*models.SlottingScenario, error)
+  CompareScenarios(ctx context.Context, userID uuid.UUID, scenarioIDs []uuid.UUID) (*ScenarioComparison, error)
+  ListSlotAssignments(ctx context.Context, userID, warehouseID uuid.UUID, f repos.SlotAssignmentFilter) ([]*models.SlotAssignment, error)
 
   //TransactionFile
   UploadTransactionFile()
@@ -76,6 +128,69 @@ type AppSvc interface {
 
   //Item
   ListItems(ctx context.Context, userID uuid.UUID, f repos.ItemFilter) ([]*models.Item, error)
+  UpdateItemAttributes(ctx context.Context, userID, itemID uuid.UUID, attrs models.Item) (*models.Item, error)
+
+  //Labels
+  CreateLabelTemplate(ctx context.Context, userID uuid.UUID, tpl models.LabelTemplate) (*models.LabelTemplate, error)
+  UpdateLabelTemplate(ctx context.Context, userID, templateID uuid.UUID, tpl models.LabelTemplate) (*models.LabelTemplate, error)
+  DeleteLabelTemplate(ctx context.Context, userID, templateID uuid.UUID) error
+  ListLabelTemplates(ctx context.Context, userID uuid.UUID) ([]*models.LabelTemplate, error)
+  GenerateLocationLabels(ctx context.Context, userID, warehouseID, templateID uuid.UUID, format string, locationIDs []uuid.UUID, subtreePath string) (*models.LabelBatch, error)
+  GenerateItemLabels(ctx context.Context, userID, templateID uuid.UUID, format string, itemIDs []uuid.UUID) (*models.LabelBatch, error)
+  GetLabelBatch(ctx context.Context, userID, batchID uuid.UUID) (*models.LabelBatch, error)
+  ListLabelBatches(ctx context.Context, userID uuid.UUID, f repos.LabelBatchFilter) ([]*models.LabelBatch, error)
+
+  //Analytics
+  GetItemVelocity(ctx context.Context, userID, warehouseID uuid.UUID, q VelocityQuery) (*VelocityReport, error)
+  ExportItemVelocity(ctx context.Context, userID, warehouseID uuid.UUID, q VelocityQuery) ([]byte, string, error)
+  GetItemAffinity(ctx context.Context, userID, warehouseID uuid.UUID, q AffinityQuery) (*AffinityReport, error)
+  GetOrderProfile(ctx context.Context, userID, warehouseID uuid.UUID, q OrderProfileQuery) (*OrderProfile, error)
+  ExportOrderProfile(ctx context.Context, userID, warehouseID uuid.UUID, q OrderProfileQuery) ([]byte, string, error)
+  GetSlowStock(ctx context.Context, userID, warehouseID uuid.UUID, q SlowStockQuery) (*SlowStockReport, error)
+  ScanSlowStock(ctx context.Context, userID, warehouseID uuid.UUID, q SlowStockQuery) (*SlowStockReport, []*StockFlagChange, error)
+  GetSlowStockSetting(ctx context.Context, userID, warehouseID uuid.UUID) (*models.SlowStockSetting, error)
+  SetSlowStockSetting(ctx context.Context, userID, warehouseID uuid.UUID, st models.SlowStockSetting) (*models.SlowStockSetting, error)
+  ResetSlowStockSetting(ctx context.Context, userID, warehouseID uuid.UUID) (*models.SlowStockSetting, error)
+  GetHeatmap(ctx context.Context, userID, warehouseID uuid.UUID, q HeatmapQuery) (*Heatmap, error)
+  GetRollupStatus(ctx context.Context, userID, warehouseID uuid.UUID) (*repos.RollupStatus, error)
+  RebuildRollups(ctx context.Context, userID, warehouseID uuid.UUID) (*models.Job, error)
+  GetProductivity(ctx context.Context, userID, warehouseID uuid.UUID, q ProductivityQuery) (*ProductivityReport, error)
+  ExportProductivity(ctx context.Context, userID, warehouseID uuid.UUID, q ProductivityQuery) ([]byte, string, error)
+  ListLaborStandards(ctx context.Context, userID, warehouseID uuid.UUID) ([]*models.LaborStandard, error)
+  SetLaborStandard(ctx context.Context, userID, warehouseID uuid.UUID, std models.LaborStandard) (*models.LaborStandard, error)
+  DeleteLaborStandard(ctx context.Context, userID, warehouseID uuid.UUID, transactionType string) error
+  ScanTransactionExceptions(ctx context.Context, userID, warehouseID uuid.UUID, q ExceptionQuery) (*ExceptionScan, error)
+  ListTransactionExceptions(ctx context.Context, userID uuid.UUID, f repos.ExceptionFilter) ([]*models.TransactionException, error)
+  AcknowledgeTransactionExceptions(ctx context.Context, userID, warehouseID uuid.UUID, exceptionIDs []uuid.UUID, note string) (int64, error)
+  GetErgonomicModel(ctx context.Context, userID, warehouseID uuid.UUID) (*models.ErgonomicModel, error)
+  SetErgonomicModel(ctx context.Context, userID, warehouseID uuid.UUID, m models.ErgonomicModel) (*models.ErgonomicModel, error)
+  ResetErgonomicModel(ctx context.Context, userID, warehouseID uuid.UUID) (*models.ErgonomicModel, error)
+  GetErgonomics(ctx context.Context, userID, warehouseID uuid.UUID, q ErgonomicQuery) (*ErgonomicReport, error)
+  ExportErgonomics(ctx context.Context, userID, warehouseID uuid.UUID, q ErgonomicQuery) ([]byte, string, error)
+  SuggestPutaway(ctx context.Context, userID, warehouseID uuid.UUID, q PutawayQuery) (*PutawaySuggestion, error)
+  SuggestRecordPutaway(ctx context.Context, userID, recordID uuid.UUID, limit int) (*PutawaySuggestion, error)
+  ListReplenishmentSettings(ctx context.Context, userID, warehouseID uuid.UUID) ([]*models.ReplenishmentSetting, error)
+  SetReplenishmentSetting(ctx context.Context, userID, warehouseID uuid.UUID, st models.ReplenishmentSetting) (*models.ReplenishmentSetting, error)
+  DeleteReplenishmentSetting(ctx context.Context, userID, warehouseID, locationID, itemID uuid.UUID) error
+  GetReplenishmentStatus(ctx context.Context, userID, warehouseID uuid.UUID) (*ReplenishmentRun, error)
+  EvaluateReplenishment(ctx context.Context, userID, warehouseID uuid.UUID) (*ReplenishmentRun, error)
+  ListReplenishmentQueue(ctx context.Context, userID uuid.UUID, f repos.ReplenishmentTaskFilter) ([]*models.ReplenishmentTask, error)
+  GetReplenishmentTask(ctx context.Context, userID, taskID uuid.UUID) (*models.ReplenishmentTask, error)
+  CompleteReplenishmentTask(ctx context.Context, userID, taskID uuid.UUID) error
+  CancelReplenishmentTask(ctx context.Context, userID, taskID uuid.UUID) error
+  GetCapacity(ctx context.Context, userID, warehouseID uuid.UUID, q CapacityQuery) (*CapacityReport, error)
+  ExportCapacity(ctx context.Context, userID, warehouseID uuid.UUID, q CapacityQuery) ([]byte, string, error)
+  RunClassification(ctx context.Context, userID, warehouseID uuid.UUID, p ClassificationParams) (*models.ClassificationRun, error)
+  GetClassificationRun(ctx context.Context, userID, runID uuid.UUID) (*models.ClassificationRun, error)
+  DeleteClassificationRun(ctx context.Context, userID, runID uuid.UUID) error
+  ListClassificationRuns(ctx context.Context, userID uuid.UUID, f repos.ClassificationRunFilter) ([]*models.ClassificationRun, error)
+  ListItemClassifications(ctx context.Context, userID, runID uuid.UUID, f repos.ItemClassificationFilter) ([]*models.ItemClassification, error)
+  CompareClassificationRuns(ctx context.Context, userID, fromRunID, toRunID uuid.UUID) (*ClassChangeReport, error)
+  RunForecast(ctx context.Context, userID, warehouseID uuid.UUID, p ForecastParams) (*models.ForecastRun, error)
+  GetForecastRun(ctx context.Context, userID, runID uuid.UUID) (*models.ForecastRun, error)
+  DeleteForecastRun(ctx context.Context, userID, runID uuid.UUID) error
+  ListForecastRuns(ctx context.Context, userID uuid.UUID, f repos.ForecastRunFilter) ([]*models.ForecastRun, error)
+  ListItemForecasts(ctx context.Context, userID, runID uuid.UUID, f repos.ItemForecastFilter) ([]*models.ItemForecast, error)
 
   //Utility
   generateUserAvatar(ctx context.Context, firstName string, lastName string) (string, error)
@@ -90,9 +205,28 @@ type appSvc struct {
   tfsvc           TFSvc
   trsvc           TRSvc
   isvc            ISvc
+  zsvc            ZSvc
+  ltsvc           LTSvc
+  lbsvc           LBSvc
+  jsvc            JSvc
+  asvc            AnalyticsSvc
+  clsvc           ClassificationSvc
+  fcsvc           ForecastSvc
+  slsvc           SlottingSvc
+  scsvc           ScenarioSvc
+  simsvc          SimulationSvc
+  sssvc           SlowStockSvc
+  rlsvc           RollupSvc
+  pdsvc           ProductivitySvc
+  exsvc           ExceptionSvc
+  ergsvc          ErgonomicSvc
+  pwsvc           PutawaySvc
+  rpsvc           ReplenishmentSvc
+  cpsvc           CapacitySvc
   
   avatarsvc       avatar.AvatarService
   s3svc           s3.S3Service
+  labelsvc        label.LabelService
 
   tokensvc        TokenService
   refreshTokenSvc RefreshTokenService
@@ -104,8 +238,8 @@ type appSvc struct {
   parsersvc       ParserService
 }
 
-func NewAppSvc(csvc CSvc, usvc USvc, wsvc WSvc, lsvc LSvc, tfsvc TFSvc, trsvc TRSvc, isvc ISvc, avatarsvc avatar.AvatarService, s3svc s3.S3Service, tokensvc TokenService, refreshTokenSvc RefreshTokenService, oauthsvc auth.OAuthService, pub events.PubSubPublisher, uact repos.UserActionRepo, parsersvc ParserService) AppSvc {
-  return &appSvc{csvc: csvc, usvc: usvc, wsvc: wsvc, lsvc: lsvc, tfsvc: tfsvc, trsvc: trsvc, isvc: isvc, avatarsvc: avatarsvc, s3svc: s3svc, tokensvc: tokensvc, refreshTokenSvc: refreshTokenSvc, oauthsvc: oauthsvc, pub: pub, uact: uact, parsersvc: parsersvc}
+func NewAppSvc(csvc CSvc, usvc USvc, wsvc WSvc, lsvc LSvc, tfsvc TFSvc, trsvc TRSvc, isvc ISvc, zsvc ZSvc, ltsvc LTSvc, lbsvc LBSvc, jsvc JSvc, asvc AnalyticsSvc, clsvc ClassificationSvc, fcsvc ForecastSvc, slsvc SlottingSvc, scsvc ScenarioSvc, simsvc SimulationSvc, sssvc SlowStockSvc, rlsvc RollupSvc, pdsvc ProductivitySvc, exsvc ExceptionSvc, ergsvc ErgonomicSvc, pwsvc PutawaySvc, rpsvc ReplenishmentSvc, cpsvc CapacitySvc, avatarsvc avatar.AvatarService, s3svc s3.S3Service, labelsvc label.LabelService, tokensvc TokenService, refreshTokenSvc RefreshTokenService, oauthsvc auth.OAuthService, pub events.PubSubPublisher, uact repos.UserActionRepo, parsersvc ParserService) AppSvc {
+  return &appSvc{csvc: csvc, usvc: usvc, wsvc: wsvc, lsvc: lsvc, tfsvc: tfsvc, trsvc: trsvc, isvc: isvc, zsvc: zsvc, ltsvc: ltsvc, lbsvc: lbsvc, jsvc: jsvc, asvc: asvc, clsvc: clsvc, fcsvc: fcsvc, slsvc: slsvc, scsvc: scsvc, simsvc: simsvc, sssvc: sssvc, rlsvc: rlsvc, pdsvc: pdsvc, exsvc: exsvc, ergsvc: ergsvc, pwsvc: pwsvc, rpsvc: rpsvc, cpsvc: cpsvc, avatarsvc: avatarsvc, s3svc: s3svc, labelsvc: labelsvc, tokensvc: tokensvc, refreshTokenSvc: refreshTokenSvc, oauthsvc: oauthsvc, pub: pub, uact: uact, parsersvc: parsersvc}
 }
 
 func (s *appSvc) RegisterUserLocal(ctx context.Context, email, password, firstName, lastName string, createCompanyName string, companyID uuid.UUID) (*models.User, string, string, error) {
@@ -322,6 +456,25 @@ func (s *appSvc) CreateWarehouse(ctx context.Context, userID uuid.UUID, warehous
   return created, nil
 }
 
+// GetWarehouseByID loads the warehouse, refusing one outside the user's company.
+func (s *appSvc) GetWarehouseByID(ctx context.Context, userID, warehouseID uuid.UUID) (*models.Warehouse, error) {
+  if warehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  wh, err := s.wsvc.GetWarehouseByID(warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  if wh.CompanyID == nil || user.CompanyID == nil || *wh.CompanyID != *user.CompanyID {
+    return nil, fmt.Errorf("warehouse does not belong to user's company")
+  }
+  return wh, nil
+}
+
 func (s *appSvc) UpdateWarehouseName(ctx context.Context, userID, warehouseID uuid.UUID) error {
   if newName == "" {
     return fmt.Errorf("new warehouse name is required")
@@ -363,6 +516,94 @@ func (s *appSvc) ListWarehouses(ctx context.Context, userID uuid.UUID, f repos.W
   return s.wsvc.ListWarehouses(f)
 }
 
+// CloneWarehouse queues a background job that copies sourceID's structure into
+// a new warehouse of the same company. Poll the returned job for progress.
+func (s *appSvc) CloneWarehouse(ctx context.Context, userID, sourceID uuid.UUID, newName string, includeItems bool) (*models.Job, error) {
+  if newName == "" {
+    return nil, fmt.Errorf("warehouse name is required")
+  }
+  src, err := s.GetWarehouseByID(ctx, userID, sourceID)
+  if err != nil {
+    return nil, err
+  }
+  params := map[string]interface{}{"source_warehouse_id": src.ID, "name": newName, "include_items": includeItems}
+  job, err := s.jsvc.CreateJob(*src.CompanyID, userID, constants.JobTypeWarehouseClone, params)
+  if err != nil {
+    return nil, err
+  }
+  companyID := *src.CompanyID
+  s.jsvc.Run(job, func(ctx context.Context, progress JobProgress) (interface{}, error) {
+    target := models.Warehouse{Name: newName, CompanyID: &companyID}
+    res, err := s.wsvc.CloneWarehouse(src.ID, target, includeItems, func(done, total int) {
+      if done == 0 {
+        progress.SetTotal(total)
+      }
+      progress.Advance(done)
+    })
+    if err != nil {
+      return nil, err
+    }
+    _ = s.pub.PublishCompanyEvent(companyID, "WAREHOUSE_CREATED", map[string]interface{}{"warehouse_id": res.WarehouseID, "warehouse_name": newName, "created_by": userID, "cloned_from": src.ID})
+    return res, nil
+  })
+  _ = s.pub.PublishCompanyEvent(companyID, "WAREHOUSE_CLONE_STARTED", map[string]interface{}{"job_id": job.ID, "source_warehouse_id": src.ID, "name": newName, "started_by": userID})
+  return job, nil
+}
+
+func (s *appSvc) UpdateWarehouseDepot(ctx context.Context, userID, warehouseID uuid.UUID, x, y float64) error {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return err
+  }
+  if err := s.wsvc.UpdateDepot(wh.ID, x, y); err != nil {
+    return fmt.Errorf("failed to update depot: %w", err)
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "WAREHOUSE_DEPOT_UPDATED", map[string]interface{}{"warehouse_id": wh.ID, "depot_x": x, "depot_y": y, "updated_by": userID})
+  return nil
+}
+
+func (s *appSvc) UpdateWarehouseGoLive(ctx context.Context, userID, warehouseID uuid.UUID, goLive *time.Time) error {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return err
+  }
+  if err := s.wsvc.UpdateGoLiveDate(wh.ID, goLive); err != nil {
+    return fmt.Errorf("failed to update go-live date: %w", err)
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "WAREHOUSE_GO_LIVE_UPDATED", map[string]interface{}{"warehouse_id": wh.ID, "go_live_date": goLive, "updated_by": userID})
+  if _, err := s.ScanTransactionExceptions(ctx, userID, wh.ID, ExceptionQuery{}); err != nil {
+    return fmt.Errorf("failed to rescan exceptions: %w", err)
+  }
+  return nil
+}
+
+func (s *appSvc) GetJob(ctx context.Context, userID, jobID uuid.UUID) (*models.Job, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  job, err := s.jsvc.GetJobByID(jobID)
+  if err != nil {
+    return nil, err
+  }
+  if job.CompanyID == nil || user.CompanyID == nil || *job.CompanyID != *user.CompanyID {
+    return nil, fmt.Errorf("job does not belong to user's company")
+  }
+  return job, nil
+}
+
+func (s *appSvc) ListJobs(ctx context.Context, userID uuid.UUID, f repos.JobFilter) ([]*models.Job, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  if user.CompanyID == nil {
+    return nil, fmt.Errorf("user has no company")
+  }
+  f.CompanyID = *user.CompanyID
+  return s.jsvc.ListJobs(f)
+}
+
 func (s *appSvc) CreateLocation(ctx context.Context, userID, warehouseID uuid.UUID, locationPath, locationNamePath string) (*models.Location, error) {
   wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
   if err != nil {
@@ -453,6 +694,318 @@ func (s *appSvc) ListLocations(ctx context.Context, userID uuid.UUID, f repos.Lo
   return s.lsvc.ListLocations(f)
 }
 
+func (s *appSvc) MoveLocation(ctx context.Context, userID, locationID uuid.UUID, newPath, newNamePath string) (*models.Location, error) {
+  loc, err := s.GetLocationByID(ctx, userID, locationID)
+  if err != nil {
+    return nil, err
+  }
+  wh, err := s.wsvc.GetWarehouseByID(*loc.WarehouseID)
+  if err != nil {
+    return nil, fmt.Errorf("failed to get warehouse: %w", err)
+  }
+  moved, err := s.lsvc.MoveLocation(loc.ID, newPath, newNamePath)
+  if err != nil {
+    return nil, fmt.Errorf("failed to move location: %w", err)
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "LOCATION_MOVED", map[string]interface{}{"location_id": loc.ID, "warehouse_id": wh.ID, "old_location_path": loc.LocationPath, "new_location_path": moved.LocationPath, "moved_by": userID})
+  return moved, nil
+}
+
+func (s *appSvc) MergeLocations(ctx context.Context, userID, sourceID, targetID uuid.UUID) (*models.Location, error) {
+  source, err := s.GetLocationByID(ctx, userID, sourceID)
+  if err != nil {
+    return nil, err
+  }
+  if _, err := s.GetLocationByID(ctx, userID, targetID); err != nil {
+    return nil, err
+  }
+  wh, err := s.wsvc.GetWarehouseByID(*source.WarehouseID)
+  if err != nil {
+    return nil, fmt.Errorf("failed to get warehouse: %w", err)
+  }
+  merged, err := s.lsvc.MergeLocations(sourceID, targetID)
+  if err != nil {
+    return nil, fmt.Errorf("failed to merge locations: %w", err)
+  }
+  // the source's links now sit in target's zones
+  links, err := s.lsvc.ListItemLinks(wh.ID)
+  if err != nil {
+    return nil, fmt.Errorf("failed to list item links: %w", err)
+  }
+  for _, link := range links {
+    if link.LocationID != merged.ID {
+      continue
+    }
+    item, err := s.isvc.GetItemByID(link.ItemID)
+    if err != nil {
+      return nil, err
+    }
+    if _, err := s.checkZoneLink(merged, item, constants.ViolationSourceLink); err != nil {
+      return nil, err
+    }
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "LOCATION_MERGED", map[string]interface{}{"source_location_id": sourceID, "source_location_path": source.LocationPath, "target_location_id": merged.ID, "warehouse_id": wh.ID, "merged_by": userID})
+  return merged, nil
+}
+
+func (s *appSvc) ListLocationAliases(ctx context.Context, userID, locationID uuid.UUID) ([]*models.LocationAlias, error) {
+  loc, err := s.GetLocationByID(ctx, userID, locationID)
+  if err != nil {
+    return nil, err
+  }
+  return s.lsvc.ListLocationAliases(loc.ID)
+}
+
+func (s *appSvc) UpdateLocationStatus(ctx context.Context, userID, locationID uuid.UUID, status, reason string) (*models.Location, error) {
+  loc, err := s.GetLocationByID(ctx, userID, locationID)
+  if err != nil {
+    return nil, err
+  }
+  wh, err := s.wsvc.GetWarehouseByID(*loc.WarehouseID)
+  if err != nil {
+    return nil, fmt.Errorf("failed to get warehouse: %w", err)
+  }
+  if err := s.lsvc.UpdateLocationStatus(loc.ID, status, reason, userID); err != nil {
+    return nil, fmt.Errorf("failed to update location status: %w", err)
+  }
+  updated, err := s.lsvc.GetLocationByID(loc.ID)
+  if err != nil {
+    return nil, err
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "LOCATION_STATUS_CHANGED", map[string]interface{}{"location_id": loc.ID, "warehouse_id": wh.ID, "location_path": loc.LocationPath, "old_status": loc.Status, "new_status": status, "reason": reason, "changed_by": userID})
+  return updated, nil
+}
+
+func (s *appSvc) UpdateLocationSlot(ctx context.Context, userID, locationID uuid.UUID, attrs models.Location) (*models.Location, error) {
+  loc, err := s.GetLocationByID(ctx, userID, locationID)
+  if err != nil {
+    return nil, err
+  }
+  wh, err := s.wsvc.GetWarehouseByID(*loc.WarehouseID)
+  if err != nil {
+    return nil, fmt.Errorf("failed to get warehouse: %w", err)
+  }
+  if err := s.lsvc.UpdateSlotAttributes(loc.ID, attrs); err != nil {
+    return nil, err
+  }
+  updated, err := s.lsvc.GetLocationByID(loc.ID)
+  if err != nil {
+    return nil, err
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "LOCATION_SLOT_UPDATED", map[string]interface{}{"location_id": loc.ID, "warehouse_id": wh.ID, "location_path": loc.LocationPath, "updated_by": userID})
+  return updated, nil
+}
+
+// UpdateWarehouseSlots sets slot attributes for many locations at once, each
+// identified by its LocationPath. It stops at the first unknown path.
+func (s *appSvc) UpdateWarehouseSlots(ctx context.Context, userID, warehouseID uuid.UUID, slots []models.Location) (int, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return 0, err
+  }
+  updated := 0
+  for _, attrs := range slots {
+    loc, err := s.lsvc.GetLocationByPath(*wh.CompanyID, wh.ID, attrs.LocationPath)
+    if err != nil {
+      return updated, fmt.Errorf("location '%s': %w", attrs.LocationPath, err)
+    }
+    if err := s.lsvc.UpdateSlotAttributes(loc.ID, attrs); err != nil {
+      return updated, fmt.Errorf("location '%s': %w", attrs.LocationPath, err)
+    }
+    updated++
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "LOCATION_SLOTS_UPDATED", map[string]interface{}{"warehouse_id": wh.ID, "count": updated, "updated_by": userID})
+  return updated, nil
+}
+
+func (s *appSvc) LinkItemToLocation(ctx context.Context, userID, locationID, itemID uuid.UUID, assignmentType string, effectiveFrom time.Time) ([]*models.ZoneViolation, error) {
+  loc, err := s.GetLocationByID(ctx, userID, locationID)
+  if err != nil {
+    return nil, err
+  }
+  item, err := s.isvc.GetItemByID(itemID)
+  if err != nil {
+    return nil, err
+  }
+  wh, err := s.wsvc.GetWarehouseByID(*loc.WarehouseID)
+  if err != nil {
+    return nil, fmt.Errorf("failed to get warehouse: %w", err)
+  }
+  if item.CompanyID == nil || *item.CompanyID != *wh.CompanyID {
+    return nil, fmt.Errorf("item does not belong to user's company")
+  }
+  assignment, err := s.lsvc.AssignItem(models.SlotAssignment{
+    CompanyID:      wh.CompanyID,
+    ItemID:         &item.ID,
+    LocationID:     &loc.ID,
+    AssignmentType: assignmentType,
+    EffectiveFrom:  effectiveFrom,
+    OpenSource:     constants.AssignmentSourceManual,
+    OpenedByID:     &userID,
+  })
+  if err != nil {
+    return nil, fmt.Errorf("failed to link item to location: %w", err)
+  }
+  _ = s.wsvc.LinkToItem(wh.ID, item.ID)
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "LOCATION_ITEM_LINKED", map[string]interface{}{"location_id": loc.ID, "item_id": item.ID, "warehouse_id": wh.ID, "assignment_id": assignment.ID, "assignment_type": assignment.AssignmentType, "linked_by": userID})
+  return s.checkZoneLink(loc, item, constants.ViolationSourceLink)
+}
+
+// UnlinkItemFromLocation closes the item's slot assignment at effectiveTo, or
+// now when zero.
+func (s *appSvc) UnlinkItemFromLocation(ctx context.Context, userID, locationID, itemID uuid.UUID, effectiveTo time.Time) error {
+  loc, err := s.GetLocationByID(ctx, userID, locationID)
+  if err != nil {
+    return err
+  }
+  wh, err := s.wsvc.GetWarehouseByID(*loc.WarehouseID)
+  if err != nil {
+    return fmt.Errorf("failed to get warehouse: %w", err)
+  }
+  if err := s.lsvc.ReleaseItem(loc.ID, itemID, effectiveTo, constants.AssignmentSourceManual, &userID); err != nil {
+    return fmt.Errorf("failed to unlink item from location: %w", err)
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "LOCATION_ITEM_UNLINKED", map[string]interface{}{"location_id": loc.ID, "item_id": itemID, "warehouse_id": wh.ID, "unlinked_by": userID})
+  return nil
+}
+
+func (s *appSvc) CreateZone(ctx context.Context, userID, warehouseID uuid.UUID, zone models.Zone) (*models.Zone, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  zone.ID = uuid.Nil
+  zone.CompanyID = wh.CompanyID
+  zone.WarehouseID = &wh.ID
+  created, err := s.zsvc.CreateZone(zone)
+  if err != nil {
+    return nil, err
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "ZONE_CREATED", map[string]interface{}{"zone_id": created.ID, "warehouse_id": wh.ID, "name": created.Name, "path_prefix": created.PathPrefix, "created_by": userID})
+  return created, nil
+}
+
+func (s *appSvc) UpdateZone(ctx context.Context, userID, zoneID uuid.UUID, zone models.Zone) (*models.Zone, error) {
+  existing, err := s.getZone(ctx, userID, zoneID)
+  if err != nil {
+    return nil, err
+  }
+  zone.ID = existing.ID
+  zone.CompanyID = existing.CompanyID
+  zone.WarehouseID = existing.WarehouseID
+  if err := s.zsvc.UpdateZone(zone); err != nil {
+    return nil, err
+  }
+  updated, err := s.zsvc.GetZoneByID(existing.ID)
+  if err != nil {
+    return nil, err
+  }
+  _ = s.pub.PublishCompanyEvent(*existing.CompanyID, "ZONE_UPDATED", map[string]interface{}{"zone_id": existing.ID, "warehouse_id": existing.WarehouseID, "updated_by": userID})
+  return updated, nil
+}
+
+func (s *appSvc) DeleteZone(ctx context.Context, userID, zoneID uuid.UUID) error {
+  zone, err := s.getZone(ctx, userID, zoneID)
+  if err != nil {
+    return err
+  }
+  if err := s.zsvc.DeleteZone(zone.ID); err != nil {
+    return fmt.Errorf("failed to delete zone: %w", err)
+  }
+  _ = s.pub.PublishCompanyEvent(*zone.CompanyID, "ZONE_DELETED", map[string]interface{}{"zone_id": zone.ID, "warehouse_id": zone.WarehouseID, "deleted_by": userID})
+  return nil
+}
+
+func (s *appSvc) ListZones(ctx context.Context, userID, warehouseID uuid.UUID) ([]*models.Zone, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  return s.zsvc.ListZones(wh.ID)
+}
+
+// RecheckZoneViolations re-evaluates every item-location link in a warehouse,
+// e.g. after zones or item attributes changed. Returns the open violation count.
+func (s *appSvc) RecheckZoneViolations(ctx context.Context, userID, warehouseID uuid.UUID) (int, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return 0, err
+  }
+  links, err := s.lsvc.ListItemLinks(wh.ID)
+  if err != nil {
+    return 0, err
+  }
+  locs := make(map[uuid.UUID]*models.Location)
+  items := make(map[uuid.UUID]*models.Item)
+  total := 0
+  for _, link := range links {
+    loc, ok := locs[link.LocationID]
+    if !ok {
+      if loc, err = s.lsvc.GetLocationByID(link.LocationID); err != nil {
+        return total, err
+      }
+      locs[link.LocationID] = loc
+    }
+    item, ok := items[link.ItemID]
+    if !ok {
+      if item, err = s.isvc.GetItemByID(link.ItemID); err != nil {
+        return total, err
+      }
+      items[link.ItemID] = item
+    }
+    found, err := s.zsvc.CheckLink(loc, item, constants.ViolationSourceLink, nil)
+    if err != nil {
+      return total, err
+    }
+    total += len(found)
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "ZONE_VIOLATIONS_RECHECKED", map[string]interface{}{"warehouse_id": wh.ID, "links_checked": len(links), "violation_count": total, "requested_by": userID})
+  return total, nil
+}
+
+func (s *appSvc) ListZoneViolations(ctx context.Context, userID uuid.UUID, f repos.ZoneViolationFilter) ([]*models.ZoneViolation, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  if user.CompanyID == nil {
+    return nil, fmt.Errorf("user has no company")
+  }
+  f.CompanyID = *user.CompanyID
+  return s.zsvc.ListViolations(f)
+}
+
+func (s *appSvc) getZone(ctx context.Context, userID, zoneID uuid.UUID) (*models.Zone, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  zone, err := s.zsvc.GetZoneByID(zoneID)
+  if err != nil {
+    return nil, err
+  }
+  if zone.CompanyID == nil || user.CompanyID == nil || *zone.CompanyID != *user.CompanyID {
+    return nil, fmt.Errorf("zone does not belong to user's company")
+  }
+  return zone, nil
+}
+
+// checkZoneLink records zone violations for a new link and announces them.
+func (s *appSvc) checkZoneLink(loc *models.Location, item *models.Item, source string) ([]*models.ZoneViolation, error) {
+  found, err := s.zsvc.CheckLink(loc, item, source, nil)
+  if err != nil {
+    return nil, fmt.Errorf("failed to check zone rules: %w", err)
+  }
+  if len(found) > 0 && item.CompanyID != nil {
+    rules := make([]string, 0, len(found))
+    for _, v := range found {
+      rules = append(rules, v.Rule)
+    }
+    _ = s.pub.PublishCompanyEvent(*item.CompanyID, "ZONE_VIOLATION_DETECTED", map[string]interface{}{"location_id": loc.ID, "item_id": item.ID, "warehouse_id": loc.WarehouseID, "rules": rules, "source": source})
+  }
+  return found, nil
+}
+
 func (s *appSvc) UploadTransactionFile(ctx context.Context, userID, warehouseID uuid.UUID, fileName string, data []byte) (*models.TransactionFile, error) {
   user, err := s.usvc.GetUserByID(userID)
   if err != nil || user == nil {
@@ -484,6 +1037,33 @@ func (s *appSvc) UploadTransactionFile(ctx context.Context, userID, warehouseID
   if err != nil {
     return nil, fmt.Errorf("failed to parse transaction file: %w", err)
   }
+  fileID := createdFile.ID
+  companyID := *user.CompanyID
+  params := map[string]interface{}{"warehouse_id": warehouseID, "transaction_file_id": fileID}
+  if _, err := s.refreshRollupsInBackground(companyID, userID, params, func() error {
+    if err := s.rlsvc.RefreshFile(fileID); err != nil {
+      return err
+    }
+    // the outlier check reads the rollups, so it runs once they are current
+    scan, err := s.exsvc.Scan(ExceptionQuery{CompanyID: companyID, WarehouseID: warehouseID})
+    if err != nil {
+      return err
+    }
+    s.publishExceptionScan(companyID, scan, userID)
+    run, err := s.rpsvc.Evaluate(companyID, warehouseID, true)
+    if err != nil {
+      return err
+    }
+    s.publishReplenishmentRun(companyID, run, userID)
+    _, changes, err := s.sssvc.Scan(SlowStockQuery{CompanyID: companyID, WarehouseID: warehouseID})
+    if err != nil {
+      return err
+    }
+    s.publishStockFlagChanges(companyID, warehouseID, changes)
+    return nil
+  }); err != nil {
+    return nil, fmt.Errorf("failed to queue rollup refresh: %w", err)
+  }
   _ = s.pub.PublishCompanyEvent(*user.CompanyID, "TRANSACTION_FILE_UPLOADED", map[string]interface{}{"transaction_file_id": createdFile.ID, "records_created": recordsCreated, "uploaded_by": userID, "file_path_url": url})
   return createdFile, nil
 }
@@ -564,7 +1144,7 @@ func (s *appSvc) CreateTransactionRecord(ctx context.Context, userID, warehouseI
   if err != nil {
     return nil, fmt.Errorf("warehouse invalid: %w", err)
   }
-  loc, err := s.lsvc.GetLocationByPath(*wh.WarehouseID, locationPath)
+  loc, err := s.lsvc.GetLocationByPath(wh.ID, locationPath)
   if err != nil || loc == nil {
     newLoc := models.Location{
       WarehouseID:      &wh.ID,
@@ -588,7 +1168,9 @@ func (s *appSvc) CreateTransactionRecord(ctx context.Context, userID, warehouseI
     }
     _ = s.wsvc.LinkToItem(wh.ID, item.ID)
   }
-  _ = s.lsvc.LinkToItem(loc.ID, item.ID)
+  if _, err := s.lsvc.AssignItem(models.SlotAssignment{CompanyID: user.CompanyID, ItemID: &item.ID, LocationID: &loc.ID, EffectiveFrom: completedDate, OpenSource: constants.AssignmentSourceManual, OpenedByID: &userID}); err == nil {
+    _, _ = s.checkZoneLink(loc, item, constants.ViolationSourceLink)
+  }
 
   rec := models.TransactionRecord{
     CompanyID:            user.CompanyID,
@@ -601,15 +1183,57 @@ func (s *appSvc) CreateTransactionRecord(ctx context.Context, userID, warehouseI
     TransactionQuantity:  transactionQ,
     CompletedDate:        completedDate,
     CompletedQuantity:    completedQ,
+    CompletedBy:          user.Email,
   }
   createdRec, err := s.trsvc.CreateTransactionRecord(rec)
   if err != nil {
     return nil, fmt.Errorf("failed to create transaction record: %w", err)
   }
+  if err := s.rlsvc.Refresh(wh.ID, completedDate, completedDate); err != nil {
+    return nil, fmt.Errorf("failed to roll up transaction record: %w", err)
+  }
   _ = s.pub.PublishCompanyEvent(*user.CompanyID, "TRANSACTION_RECORD_CREATED", map[string]interface{}{"record_id": createdRec.ID, "warehouse_id": wh.ID, "location_id": loc.ID, "item_id": item.ID, "user_id": userID})
+  switch constants.NormalizeTransactionType(transactionType) {
+  case constants.TransactionTypeReceive:
+    s.publishPutawaySuggestion(createdRec)
+  case constants.TransactionTypePick:
+    if run, err := s.rpsvc.EvaluateFace(*user.CompanyID, wh.ID, loc.ID, item.ID); err == nil {
+      s.publishReplenishmentRun(*user.CompanyID, run, userID)
+    }
+  }
   return createdRec, nil
 }
 
+// publishPutawaySuggestion announces where a receipt could be put away. The
+// suggestion is best effort and never fails the receipt.
+func (s *appSvc) publishPutawaySuggestion(rec *models.TransactionRecord) {
+  suggestion, err := s.pwsvc.Suggest(receiptPutawayQuery(rec, 0))
+  if err != nil {
+    return
+  }
+  _ = s.pub.PublishCompanyEvent(*rec.CompanyID, "PUTAWAY_SUGGESTED", map[string]interface{}{"record_id": rec.ID, "warehouse_id": rec.WarehouseID, "item_id": rec.ItemID, "quantity": suggestion.Quantity, "unplaced": suggestion.Unplaced, "candidates": suggestion.Candidates})
+}
+
+// receiptPutawayQuery asks where a receipt's units go, away from the location
+// it was received at.
+func receiptPutawayQuery(rec *models.TransactionRecord, limit int) PutawayQuery {
+  quantity := rec.CompletedQuantity
+  if quantity == 0 {
+    quantity = rec.TransactionQuantity
+  }
+  q := PutawayQuery{CompanyID: *rec.CompanyID, Quantity: int64(quantity), Limit: limit}
+  if rec.WarehouseID != nil {
+    q.WarehouseID = *rec.WarehouseID
+  }
+  if rec.ItemID != nil {
+    q.ItemID = *rec.ItemID
+  }
+  if rec.LocationID != nil {
+    q.ExcludeLocationID = *rec.LocationID
+  }
+  return q
+}
+
 func (s *appSvc) GetTransactionRecordByID(ctx context.Context, userID, recordID uuid.UUID) (*models.TransactionRecord, error) {
   user, err := s.usvc.GetUserByID(userID)
   if err != nil {
@@ -633,6 +1257,9 @@ func (s *appSvc) UpdateTransactionRecordOrderName(ctx context.Context, userID, r
   if err := s.trsvc.UpdateTransactionRecordOrderName(recordID, newOrderName); err != nil {
     return err
   }
+  if err := s.refreshRecordRollups(rec); err != nil {
+    return err
+  }
   _ = s.pub.PublishCompanyEvent(*rec.CompanyID, "TRANSACTION_RECORD_UPDATED", map[string]interface{}{"record_id": recordID, "updated_by": userID, "order_name": newOrderName})
   return nil
 }
@@ -657,6 +1284,9 @@ func (s *appSvc) UpdateTransactionRecordTransactionQuantity(ctx context.Context,
   if err := s.trsvc.UpdateTransactionRecordTransactionQuantity(recordID, newTQuantity); err != nil {
     return err
   }
+  if err := s.refreshRecordRollups(rec); err != nil {
+    return err
+  }
   _ = s.pub.PublishCompanyEvent(*rec.CompanyID, "TRANSACTION_RECORD_UPDATED", map[string]interface{}{"record_id": recordID, "updated_by": userID, "transaction_quantity": newTQuantity})
   return nil
 }
@@ -669,6 +1299,9 @@ func (s *appSvc) UpdateTransactionRecordCompletedQuantity(ctx context.Context, u
   if err := s.trsvc.UpdateTransactionRecordCompletedQuantity(recordID, newCQuantity); err != nil {
     return err
   }
+  if err := s.refreshRecordRollups(rec); err != nil {
+    return err
+  }
   _ = s.pub.PublishCompanyEvent(*rec.CompanyID, "TRANSACTION_RECORD_UPDATED", map[string]interface{}{"record_id": recordID, "updated_by": userID, "completed_quantity": newCQuantity})
   return nil
 }
@@ -681,6 +1314,9 @@ func (s *appSvc) UpdateTransactionRecordCompletedDate(ctx context.Context, userI
   if err := s.trsvc.UpdateTransactionRecordCompletedDate(recordID, newDate); err != nil {
     return err
   }
+  if err := s.refreshRecordRollups(rec, newDate); err != nil {
+    return err
+  }
   _ = s.pub.PublishCompanyEvent(*rec.CompanyID, "TRANSACTION_RECORD_UPDATED", map[string]interface{}{"record_id": recordID, "updated_by": userID, "completed_date": newDate})
   return nil
 }
@@ -696,10 +1332,27 @@ func (s *appSvc) UpdateTransactionRecordTransactionType(ctx context.Context, use
   if err := s.trsvc.UpdateTransactionRecordTransactionType(recordID, newType); err != nil {
     return err
   }
+  if err := s.refreshRecordRollups(rec); err != nil {
+    return err
+  }
   _ = s.pub.PublishCompanyEvent(*rec.CompanyID, "TRANSACTION_RECORD_UPDATED", map[string]interface{}{"record_id": recordID, "updated_by": userID, "transaction_type": newType})
   return nil
 }
 
+// refreshRecordRollups rebuilds the rollups of the day rec was completed on,
+// and of extraDays, after an edit of the record.
+func (s *appSvc) refreshRecordRollups(rec *models.TransactionRecord, extraDays ...time.Time) error {
+  if rec.WarehouseID == nil {
+    return nil
+  }
+  for _, day := range append([]time.Time{rec.CompletedDate}, extraDays...) {
+    if err := s.rlsvc.Refresh(*rec.WarehouseID, day, day); err != nil {
+      return fmt.Errorf("failed to roll up transaction record: %w", err)
+    }
+  }
+  return nil
+}
+
 func (s *appSvc) ListTransactionRecords(ctx context.Context, userID uuid.UUID, f repos.TransactionRecordFilter) ([]*models.TransactionRecord, error) {
   user, err := s.usvc.GetUserByID(userID)
   if err != nil || user == nil {
@@ -840,6 +1493,1565 @@ func (s *appSvc) ListItems(ctx context.Context, userID uuid.UUID, f repos.ItemFi
   return s.isvc.ListItems(f)
 }
 
+func (s *appSvc) UpdateItemAttributes(ctx context.Context, userID, itemID uuid.UUID, attrs models.Item) (*models.Item, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("invalid user")
+  }
+  item, err := s.isvc.GetItemByID(itemID)
+  if err != nil {
+    return nil, err
+  }
+  if item.CompanyID == nil || user.CompanyID == nil || *item.CompanyID != *user.CompanyID {
+    return nil, fmt.Errorf("item does not belong to user's company")
+  }
+  if err := s.isvc.UpdateItemAttributes(item.ID, attrs); err != nil {
+    return nil, err
+  }
+  updated, err := s.isvc.GetItemByID(item.ID)
+  if err != nil {
+    return nil, err
+  }
+  _ = s.pub.PublishCompanyEvent(*item.CompanyID, "ITEM_ATTRIBUTES_UPDATED", map[string]interface{}{"item_id": item.ID, "updated_by": userID})
+  return updated, nil
+}
+
+func (s *appSvc) CreateLabelTemplate(ctx context.Context, userID uuid.UUID, tpl models.LabelTemplate) (*models.LabelTemplate, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  if user.CompanyID == nil {
+    return nil, fmt.Errorf("user has no company")
+  }
+  tpl.ID = uuid.Nil
+  tpl.CompanyID = user.CompanyID
+  created, err := s.ltsvc.CreateLabelTemplate(tpl)
+  if err != nil {
+    return nil, err
+  }
+  _ = s.pub.PublishCompanyEvent(*user.CompanyID, "LABEL_TEMPLATE_CREATED", map[string]interface{}{"template_id": created.ID, "name": created.Name, "created_by": userID})
+  return created, nil
+}
+
+func (s *appSvc) UpdateLabelTemplate(ctx context.Context, userID, templateID uuid.UUID, tpl models.LabelTemplate) (*models.LabelTemplate, error) {
+  existing, err := s.getLabelTemplate(ctx, userID, templateID)
+  if err != nil {
+    return nil, err
+  }
+  tpl.ID = existing.ID
+  tpl.CompanyID = existing.CompanyID
+  if err := s.ltsvc.UpdateLabelTemplate(tpl); err != nil {
+    return nil, err
+  }
+  updated, err := s.ltsvc.GetLabelTemplateByID(existing.ID)
+  if err != nil {
+    return nil, err
+  }
+  _ = s.pub.PublishCompanyEvent(*existing.CompanyID, "LABEL_TEMPLATE_UPDATED", map[string]interface{}{"template_id": existing.ID, "updated_by": userID})
+  return updated, nil
+}
+
+func (s *appSvc) DeleteLabelTemplate(ctx context.Context, userID, templateID uuid.UUID) error {
+  tpl, err := s.getLabelTemplate(ctx, userID, templateID)
+  if err != nil {
+    return err
+  }
+  if err := s.ltsvc.DeleteLabelTemplate(tpl.ID); err != nil {
+    return fmt.Errorf("failed to delete label template: %w", err)
+  }
+  _ = s.pub.PublishCompanyEvent(*tpl.CompanyID, "LABEL_TEMPLATE_DELETED", map[string]interface{}{"template_id": tpl.ID, "deleted_by": userID})
+  return nil
+}
+
+func (s *appSvc) ListLabelTemplates(ctx context.Context, userID uuid.UUID) ([]*models.LabelTemplate, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  if user.CompanyID == nil {
+    return nil, fmt.Errorf("user has no company")
+  }
+  return s.ltsvc.ListLabelTemplates(*user.CompanyID)
+}
+
+// GenerateLocationLabels renders labels for the given locations, or for every
+// location under subtreePath when no ids are passed.
+func (s *appSvc) GenerateLocationLabels(ctx context.Context, userID, warehouseID, templateID uuid.UUID, format string, locationIDs []uuid.UUID, subtreePath string) (*models.LabelBatch, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  tpl, err := s.resolveLabelTemplate(ctx, userID, templateID)
+  if err != nil {
+    return nil, err
+  }
+  var locs []*models.Location
+  if len(locationIDs) > 0 {
+    for _, id := range locationIDs {
+      loc, err := s.GetLocationByID(ctx, userID, id)
+      if err != nil {
+        return nil, err
+      }
+      if loc.WarehouseID == nil || *loc.WarehouseID != wh.ID {
+        return nil, fmt.Errorf("location %s is not in warehouse %s", id, wh.ID)
+      }
+      locs = append(locs, loc)
+    }
+  } else {
+    if subtreePath == "" {
+      return nil, fmt.Errorf("location ids or a subtree path are required")
+    }
+    locs, err = s.lsvc.ListLocations(repos.LocationFilter{WarehouseID: wh.ID, PathPrefix: subtreePath, SortField: "location_path", SortDir: "asc"})
+    if err != nil {
+      return nil, fmt.Errorf("failed to list locations: %w", err)
+    }
+  }
+  if len(locs) == 0 {
+    return nil, fmt.Errorf("no locations matched")
+  }
+  labels := make([]label.Label, 0, len(locs))
+  for _, loc := range locs {
+    labels = append(labels, label.Label{Data: loc.LocationPath, Text: loc.LocationPath, Caption: loc.LocationNamePath})
+  }
+  return s.createLabelBatch(ctx, userID, wh.CompanyID, &wh.ID, tpl, "location", format, labels)
+}
+
+func (s *appSvc) GenerateItemLabels(ctx context.Context, userID, templateID uuid.UUID, format string, itemIDs []uuid.UUID) (*models.LabelBatch, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  if user.CompanyID == nil {
+    return nil, fmt.Errorf("user has no company")
+  }
+  if len(itemIDs) == 0 {
+    return nil, fmt.Errorf("item ids are required")
+  }
+  tpl, err := s.resolveLabelTemplate(ctx, userID, templateID)
+  if err != nil {
+    return nil, err
+  }
+  labels := make([]label.Label, 0, len(itemIDs))
+  for _, id := range itemIDs {
+    item, err := s.isvc.GetItemByID(id)
+    if err != nil {
+      return nil, err
+    }
+    if item.CompanyID == nil || *item.CompanyID != *user.CompanyID {
+      return nil, fmt.Errorf("item %s does not belong to user's company", id)
+    }
+    labels = append(labels, label.Label{Data: item.Name, Text: item.Name})
+  }
+  return s.createLabelBatch(ctx, userID, user.CompanyID, nil, tpl, "item", format, labels)
+}
+
+func (s *appSvc) GetLabelBatch(ctx context.Context, userID, batchID uuid.UUID) (*models.LabelBatch, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  batch, err := s.lbsvc.GetLabelBatchByID(batchID)
+  if err != nil {
+    return nil, err
+  }
+  if batch.CompanyID == nil || user.CompanyID == nil || *batch.CompanyID != *user.CompanyID {
+    return nil, fmt.Errorf("label batch does not belong to user's company")
+  }
+  return batch, nil
+}
+
+func (s *appSvc) ListLabelBatches(ctx context.Context, userID uuid.UUID, f repos.LabelBatchFilter) ([]*models.LabelBatch, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  if user.CompanyID == nil {
+    return nil, fmt.Errorf("user has no company")
+  }
+  f.CompanyID = *user.CompanyID
+  return s.lbsvc.ListLabelBatches(f)
+}
+
+func (s *appSvc) getLabelTemplate(ctx context.Context, userID, templateID uuid.UUID) (*models.LabelTemplate, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  tpl, err := s.ltsvc.GetLabelTemplateByID(templateID)
+  if err != nil {
+    return nil, err
+  }
+  if tpl.CompanyID == nil || user.CompanyID == nil || *tpl.CompanyID != *user.CompanyID {
+    return nil, fmt.Errorf("label template does not belong to user's company")
+  }
+  return tpl, nil
+}
+
+// resolveLabelTemplate falls back to the built-in template when no id is given.
+func (s *appSvc) resolveLabelTemplate(ctx context.Context, userID, templateID uuid.UUID) (*models.LabelTemplate, error) {
+  if templateID == uuid.Nil {
+    tpl := label.DefaultTemplate()
+    return &tpl, nil
+  }
+  return s.getLabelTemplate(ctx, userID, templateID)
+}
+
+func (s *appSvc) createLabelBatch(ctx context.Context, userID uuid.UUID, companyID, warehouseID *uuid.UUID, tpl *models.LabelTemplate, subject, format string, labels []label.Label) (*models.LabelBatch, error) {
+  format = strings.ToLower(format)
+  baseName := fmt.Sprintf("labels/%s/%s-%s", companyID, subject, time.Now().UTC().Format("20060102-150405"))
+  fileName, url, err := s.labelsvc.RenderAndUpload(ctx, *tpl, format, baseName, labels)
+  if err != nil {
+    return nil, fmt.Errorf("failed to render labels: %w", err)
+  }
+  batch := models.LabelBatch{
+    CompanyID:        companyID,
+    WarehouseID:      warehouseID,
+    CreatedByID:      &userID,
+    Subject:          subject,
+    Format:           format,
+    LabelCount:       len(labels),
+    FileName:         fileName,
+    FilePathURL:      url,
+  }
+  if tpl.ID != uuid.Nil {
+    batch.TemplateID = &tpl.ID
+  }
+  created, err := s.lbsvc.CreateLabelBatch(batch)
+  if err != nil {
+    return nil, err
+  }
+  _ = s.pub.PublishCompanyEvent(*companyID, "LABEL_BATCH_CREATED", map[string]interface{}{"batch_id": created.ID, "subject": subject, "format": format, "label_count": created.LabelCount, "file_path_url": url, "created_by": userID})
+  return created, nil
+}
+
+func (s *appSvc) GetItemVelocity(ctx context.Context, userID, warehouseID uuid.UUID, q VelocityQuery) (*VelocityReport, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  q.CompanyID = *wh.CompanyID
+  q.WarehouseID = wh.ID
+  return s.asvc.ItemVelocity(q)
+}
+
+// ExportItemVelocity returns the full (unpaginated) velocity report as an XLSX
+// workbook together with a download file name.
+func (s *appSvc) ExportItemVelocity(ctx context.Context, userID, warehouseID uuid.UUID, q VelocityQuery) ([]byte, string, error) {
+  q.Limit, q.Offset = 0, 0
+  report, err := s.GetItemVelocity(ctx, userID, warehouseID, q)
+  if err != nil {
+    return nil, "", err
+  }
+  headers := []string{"Item", "Lines", "Units", "Orders", "Active Days", "Avg Daily Lines", "Peak Daily Lines", "Avg Daily Units", "Peak Daily Units"}
+  for _, t := range report.TransactionTypes {
+    headers = append(headers, "Lines ("+t+")", "Units ("+t+")")
+  }
+  rows := make([][]interface{}, 0, len(report.Rows))
+  for _, v := range report.Rows {
+    row := []interface{}{v.ItemName, v.Lines, v.Units, v.Orders, v.ActiveDays, v.AvgDailyLines, v.PeakDailyLines, v.AvgDailyUnits, v.PeakDailyUnits}
+    for _, t := range report.TransactionTypes {
+      row = append(row, v.LinesByType[t], v.UnitsByType[t])
+    }
+    rows = append(rows, row)
+  }
+  data, err := export.XLSX(export.Sheet{Name: "Velocity", Headers: headers, Rows: rows})
+  if err != nil {
+    return nil, "", err
+  }
+  fileName := fmt.Sprintf("velocity_%s_%s.xlsx", report.StartDate.Format("20060102"), report.EndDate.Format("20060102"))
+  return data, fileName, nil
+}
+
+func (s *appSvc) GetItemAffinity(ctx context.Context, userID, warehouseID uuid.UUID, q AffinityQuery) (*AffinityReport, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  q.CompanyID = *wh.CompanyID
+  q.WarehouseID = wh.ID
+  return s.asvc.ItemAffinity(q)
+}
+
+func (s *appSvc) GetOrderProfile(ctx context.Context, userID, warehouseID uuid.UUID, q OrderProfileQuery) (*OrderProfile, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  q.CompanyID = *wh.CompanyID
+  q.WarehouseID = wh.ID
+  return s.asvc.OrderProfile(q)
+}
+
+func (s *appSvc) ExportOrderProfile(ctx context.Context, userID, warehouseID uuid.UUID, q OrderProfileQuery) ([]byte, string, error) {
+  p, err := s.GetOrderProfile(ctx, userID, warehouseID, q)
+  if err != nil {
+    return nil, "", err
+  }
+  summary := export.Sheet{
+    Name:    "Summary",
+    Headers: []string{"Metric", "Value"},
+    Rows: [][]interface{}{
+      {"Transaction Types", strings.Join(p.TransactionTypes, ", ")},
+      {"Orders", p.Orders},
+      {"Lines", p.Lines},
+      {"Units", p.Units},
+      {"Lines Without Order", p.LinesWithoutOrder},
+      {"Avg Lines per Order", p.AvgLinesPerOrder},
+      {"Median Lines per Order", p.MedianLinesPerOrder},
+      {"Max Lines per Order", p.MaxLinesPerOrder},
+      {"Avg Units per Line", p.AvgUnitsPerLine},
+      {"Avg Units per Order", p.AvgUnitsPerOrder},
+      {"Single-Line Orders", p.SingleLineOrders},
+      {"Multi-Line Orders", p.MultiLineOrders},
+      {"Single-Line Share", p.SingleLineShare},
+      {"Multi-Line Share", p.MultiLineShare},
+    },
+  }
+  bucketSheet := func(name, label, count string, buckets []*ProfileBucket) export.Sheet {
+    sheet := export.Sheet{Name: name, Headers: []string{label, count, "Share"}}
+    for _, b := range buckets {
+      sheet.Rows = append(sheet.Rows, []interface{}{b.Label, b.Count, b.Share})
+    }
+    return sheet
+  }
+  mix := export.Sheet{Name: "Transaction Types", Headers: []string{"Type", "Lines", "Units", "Share"}}
+  for _, t := range p.TypeMix {
+    mix.Rows = append(mix.Rows, []interface{}{t.TransactionType, t.Lines, t.Units, t.Share})
+  }
+  data, err := export.XLSX(
+    summary,
+    bucketSheet("Lines per Order", "Lines", "Orders", p.LinesPerOrder),
+    bucketSheet("Units per Line", "Units", "Lines", p.UnitsPerLine),
+    bucketSheet("Hour of Day", "Hour (UTC)", "Orders", p.OrdersByHour),
+    bucketSheet("Day of Week", "Day", "Orders", p.OrdersByWeekday),
+    mix,
+  )
+  if err != nil {
+    return nil, "", err
+  }
+  fileName := fmt.Sprintf("order_profile_%s_%s.xlsx", p.StartDate.Format("20060102"), p.EndDate.Format("20060102"))
+  return data, fileName, nil
+}
+
+func (s *appSvc) GetSlowStock(ctx context.Context, userID, warehouseID uuid.UUID, q SlowStockQuery) (*SlowStockReport, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  q.CompanyID = *wh.CompanyID
+  q.WarehouseID = wh.ID
+  return s.sssvc.Report(q)
+}
+
+// ScanSlowStock refreshes the stored flags and publishes an event for every
+// item that became dead or slow, changed between the two, or recovered.
+func (s *appSvc) ScanSlowStock(ctx context.Context, userID, warehouseID uuid.UUID, q SlowStockQuery) (*SlowStockReport, []*StockFlagChange, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, nil, err
+  }
+  q.CompanyID = *wh.CompanyID
+  q.WarehouseID = wh.ID
+  report, changes, err := s.sssvc.Scan(q)
+  if err != nil {
+    return nil, nil, err
+  }
+  s.publishStockFlagChanges(*wh.CompanyID, wh.ID, changes)
+  return report, changes, nil
+}
+
+// publishStockFlagChanges announces each flag change of a slow stock scan.
+func (s *appSvc) publishStockFlagChanges(companyID, warehouseID uuid.UUID, changes []*StockFlagChange) {
+  for _, c := range changes {
+    if c.Status == "" {
+      _ = s.pub.PublishCompanyEvent(companyID, "STOCK_FLAG_CLEARED", map[string]interface{}{"warehouse_id": warehouseID, "item_id": c.ItemID, "from_status": c.FromStatus})
+      continue
+    }
+    _ = s.pub.PublishCompanyEvent(companyID, "STOCK_FLAGGED", map[string]interface{}{"warehouse_id": warehouseID, "item_id": c.ItemID, "item_name": c.ItemName, "status": c.Status, "from_status": c.FromStatus})
+  }
+}
+
+func (s *appSvc) GetSlowStockSetting(ctx context.Context, userID, warehouseID uuid.UUID) (*models.SlowStockSetting, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  st, err := s.sssvc.GetSetting(wh.ID)
+  if err != nil {
+    return nil, err
+  }
+  st.CompanyID = wh.CompanyID
+  return st, nil
+}
+
+func (s *appSvc) SetSlowStockSetting(ctx context.Context, userID, warehouseID uuid.UUID, st models.SlowStockSetting) (*models.SlowStockSetting, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  st.ID = uuid.Nil
+  st.CompanyID = wh.CompanyID
+  st.WarehouseID = &wh.ID
+  if err := s.sssvc.SetSetting(&st); err != nil {
+    return nil, err
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "SLOW_STOCK_SETTING_UPDATED", map[string]interface{}{"warehouse_id": wh.ID, "setting": st, "updated_by": userID})
+  return &st, nil
+}
+
+func (s *appSvc) ResetSlowStockSetting(ctx context.Context, userID, warehouseID uuid.UUID) (*models.SlowStockSetting, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  if err := s.sssvc.ResetSetting(wh.ID); err != nil {
+    return nil, err
+  }
+  st, err := s.sssvc.GetSetting(wh.ID)
+  if err != nil {
+    return nil, err
+  }
+  st.CompanyID = wh.CompanyID
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "SLOW_STOCK_SETTING_UPDATED", map[string]interface{}{"warehouse_id": wh.ID, "setting": st, "updated_by": userID})
+  return st, nil
+}
+
+func (s *appSvc) GetHeatmap(ctx context.Context, userID, warehouseID uuid.UUID, q HeatmapQuery) (*Heatmap, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  q.CompanyID = *wh.CompanyID
+  q.WarehouseID = wh.ID
+  return s.rlsvc.Heatmap(q)
+}
+
+func (s *appSvc) GetRollupStatus(ctx context.Context, userID, warehouseID uuid.UUID) (*repos.RollupStatus, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  return s.rlsvc.Status(wh.ID)
+}
+
+// RebuildRollups queues a background job that rebuilds all of the warehouse's
+// activity rollups from its records. Poll the returned job for progress.
+func (s *appSvc) RebuildRollups(ctx context.Context, userID, warehouseID uuid.UUID) (*models.Job, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  job, err := s.jsvc.CreateJob(*wh.CompanyID, userID, constants.JobTypeRollupRebuild, map[string]interface{}{"warehouse_id": wh.ID})
+  if err != nil {
+    return nil, err
+  }
+  companyID := *wh.CompanyID
+  s.jsvc.Run(job, func(ctx context.Context, progress JobProgress) (interface{}, error) {
+    st, err := s.rlsvc.Rebuild(wh.ID, func(done, total int) {
+      if done == 0 {
+        progress.SetTotal(total)
+      }
+      progress.Advance(done)
+    })
+    if err != nil {
+      return nil, err
+    }
+    _ = s.pub.PublishCompanyEvent(companyID, "ROLLUPS_REBUILT", map[string]interface{}{"warehouse_id": wh.ID, "rows": st.Rows})
+    return st, nil
+  })
+  _ = s.pub.PublishCompanyEvent(companyID, "ROLLUP_REBUILD_STARTED", map[string]interface{}{"job_id": job.ID, "warehouse_id": wh.ID, "started_by": userID})
+  return job, nil
+}
+
+// refreshRollupsInBackground runs refresh as a rollup refresh job so imports
+// return before their records are rolled up.
+func (s *appSvc) refreshRollupsInBackground(companyID, userID uuid.UUID, params map[string]interface{}, refresh func() error) (*models.Job, error) {
+  job, err := s.jsvc.CreateJob(companyID, userID, constants.JobTypeRollupRefresh, params)
+  if err != nil {
+    return nil, err
+  }
+  s.jsvc.Run(job, func(ctx context.Context, progress JobProgress) (interface{}, error) {
+    progress.SetTotal(1)
+    if err := refresh(); err != nil {
+      return nil, err
+    }
+    progress.Advance(1)
+    return nil, nil
+  })
+  return job, nil
+}
+
+func (s *appSvc) GetProductivity(ctx context.Context, userID, warehouseID uuid.UUID, q ProductivityQuery) (*ProductivityReport, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  q.CompanyID = *wh.CompanyID
+  q.WarehouseID = wh.ID
+  return s.pdsvc.Report(q)
+}
+
+func (s *appSvc) ExportProductivity(ctx context.Context, userID, warehouseID uuid.UUID, q ProductivityQuery) ([]byte, string, error) {
+  r, err := s.GetProductivity(ctx, userID, warehouseID, q)
+  if err != nil {
+    return nil, "", err
+  }
+  performance := func(v *float64) interface{} {
+    if v == nil {
+      return ""
+    }
+    return *v
+  }
+  summary := export.Sheet{
+    Name:    "Summary",
+    Headers: []string{"Metric", "Value"},
+    Rows: [][]interface{}{
+      {"Transaction Types", strings.Join(r.TransactionTypes, ", ")},
+      {"Idle Minutes", r.IdleMinutes},
+      {"Lines", r.Totals.Lines},
+      {"Units", r.Totals.Units},
+      {"Operators", r.Totals.Operators},
+      {"Active Hours", r.Totals.ActiveHours},
+      {"Lines per Hour", r.Totals.LinesPerHour},
+      {"Units per Hour", r.Totals.UnitsPerHour},
+      {"Standard Hours", r.Totals.StandardHours},
+      {"Performance", performance(r.Totals.Performance)},
+      {"Lines Without Operator", r.UnattributedLines},
+    },
+  }
+  rowSheet := func(name, key string, rows []*ProductivityRow) export.Sheet {
+    sheet := export.Sheet{Name: name, Headers: []string{key, "Lines", "Units", "Operators", "Active Hours", "Lines per Hour", "Units per Hour", "Standard Hours", "Performance", "Median Gap (s)", "P90 Gap (s)"}}
+    for _, row := range rows {
+      sheet.Rows = append(sheet.Rows, []interface{}{row.Key, row.Lines, row.Units, row.Operators, row.ActiveHours, row.LinesPerHour, row.UnitsPerHour, row.StandardHours, performance(row.Performance), row.MedianGapSeconds, row.P90GapSeconds})
+    }
+    return sheet
+  }
+  gaps := export.Sheet{Name: "Time Between Lines", Headers: []string{"Type", "Gap", "Lines", "Share"}}
+  for _, d := range r.Gaps {
+    t := d.TransactionType
+    if t == "" {
+      t = "all"
+    }
+    for _, b := range d.Buckets {
+      gaps.Rows = append(gaps.Rows, []interface{}{t, b.Label, b.Count, b.Share})
+    }
+  }
+  standards := export.Sheet{Name: "Labor Standards", Headers: []string{"Type", "Seconds per Line", "Seconds per Unit"}}
+  for _, std := range r.Standards {
+    standards.Rows = append(standards.Rows, []interface{}{std.TransactionType, std.SecondsPerLine, std.SecondsPerUnit})
+  }
+  data, err := export.XLSX(
+    summary,
+    rowSheet("Operators", "Operator", r.ByOperator),
+    rowSheet("Zones", "Zone", r.ByZone),
+    rowSheet("Hour of Day", "Hour (UTC)", r.ByHour),
+    rowSheet("Transaction Types", "Type", r.ByType),
+    gaps,
+    standards,
+  )
+  if err != nil {
+    return nil, "", err
+  }
+  fileName := fmt.Sprintf("productivity_%s_%s.xlsx", r.StartDate.Format("20060102"), r.EndDate.Format("20060102"))
+  return data, fileName, nil
+}
+
+func (s *appSvc) ListLaborStandards(ctx context.Context, userID, warehouseID uuid.UUID) ([]*models.LaborStandard, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  return s.pdsvc.ListStandards(wh.ID)
+}
+
+func (s *appSvc) SetLaborStandard(ctx context.Context, userID, warehouseID uuid.UUID, std models.LaborStandard) (*models.LaborStandard, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  std.CompanyID = wh.CompanyID
+  std.WarehouseID = &wh.ID
+  saved, err := s.pdsvc.SetStandard(std)
+  if err != nil {
+    return nil, err
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "LABOR_STANDARD_UPDATED", map[string]interface{}{"warehouse_id": wh.ID, "transaction_type": saved.TransactionType, "seconds_per_line": saved.SecondsPerLine, "seconds_per_unit": saved.SecondsPerUnit, "updated_by": userID})
+  return saved, nil
+}
+
+func (s *appSvc) DeleteLaborStandard(ctx context.Context, userID, warehouseID uuid.UUID, transactionType string) error {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return err
+  }
+  if err := s.pdsvc.DeleteStandard(wh.ID, transactionType); err != nil {
+    return err
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "LABOR_STANDARD_DELETED", map[string]interface{}{"warehouse_id": wh.ID, "transaction_type": transactionType, "deleted_by": userID})
+  return nil
+}
+
+func (s *appSvc) ScanTransactionExceptions(ctx context.Context, userID, warehouseID uuid.UUID, q ExceptionQuery) (*ExceptionScan, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  q.CompanyID = *wh.CompanyID
+  q.WarehouseID = wh.ID
+  scan, err := s.exsvc.Scan(q)
+  if err != nil {
+    return nil, err
+  }
+  s.publishExceptionScan(*wh.CompanyID, scan, userID)
+  return scan, nil
+}
+
+// publishExceptionScan announces the exceptions a scan newly found or resolved.
+func (s *appSvc) publishExceptionScan(companyID uuid.UUID, scan *ExceptionScan, requestedBy uuid.UUID) {
+  if len(scan.New) == 0 && scan.Resolved == 0 {
+    return
+  }
+  byType := make(map[string]int)
+  ids := make([]uuid.UUID, 0, len(scan.New))
+  for _, e := range scan.New {
+    byType[e.ExceptionType]++
+    ids = append(ids, e.ID)
+  }
+  _ = s.pub.PublishCompanyEvent(companyID, "TRANSACTION_EXCEPTIONS_DETECTED", map[string]interface{}{"warehouse_id": scan.WarehouseID, "new_count": len(scan.New), "new_by_type": byType, "exception_ids": ids, "resolved_count": scan.Resolved, "requested_by": requestedBy})
+}
+
+func (s *appSvc) ListTransactionExceptions(ctx context.Context, userID uuid.UUID, f repos.ExceptionFilter) ([]*models.TransactionException, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  if user.CompanyID == nil {
+    return nil, fmt.Errorf("user has no company")
+  }
+  f.CompanyID = *user.CompanyID
+  return s.exsvc.ListExceptions(f)
+}
+
+func (s *appSvc) AcknowledgeTransactionExceptions(ctx context.Context, userID, warehouseID uuid.UUID, exceptionIDs []uuid.UUID, note string) (int64, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return 0, err
+  }
+  n, err := s.exsvc.Acknowledge(wh.ID, exceptionIDs, userID, note)
+  if err != nil {
+    return 0, err
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "TRANSACTION_EXCEPTIONS_ACKNOWLEDGED", map[string]interface{}{"warehouse_id": wh.ID, "exception_ids": exceptionIDs, "acknowledged": n, "note": note, "acknowledged_by": userID})
+  return n, nil
+}
+
+func (s *appSvc) SuggestPutaway(ctx context.Context, userID, warehouseID uuid.UUID, q PutawayQuery) (*PutawaySuggestion, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  q.CompanyID = *wh.CompanyID
+  q.WarehouseID = wh.ID
+  return s.pwsvc.Suggest(q)
+}
+
+func (s *appSvc) SuggestRecordPutaway(ctx context.Context, userID, recordID uuid.UUID, limit int) (*PutawaySuggestion, error) {
+  rec, err := s.GetTransactionRecordByID(ctx, userID, recordID)
+  if err != nil {
+    return nil, err
+  }
+  switch constants.NormalizeTransactionType(rec.TransactionType) {
+  case constants.TransactionTypeReceive, constants.TransactionTypePutaway:
+  default:
+    return nil, fmt.Errorf("record is not a receipt")
+  }
+  return s.pwsvc.Suggest(receiptPutawayQuery(rec, limit))
+}
+
+func (s *appSvc) ListReplenishmentSettings(ctx context.Context, userID, warehouseID uuid.UUID) ([]*models.ReplenishmentSetting, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  return s.rpsvc.ListSettings(wh.ID)
+}
+
+func (s *appSvc) SetReplenishmentSetting(ctx context.Context, userID, warehouseID uuid.UUID, st models.ReplenishmentSetting) (*models.ReplenishmentSetting, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  st.ID = uuid.Nil
+  st.CompanyID = wh.CompanyID
+  st.WarehouseID = &wh.ID
+  if err := s.rpsvc.SetSetting(&st); err != nil {
+    return nil, err
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "REPLENISHMENT_SETTING_UPDATED", map[string]interface{}{"warehouse_id": wh.ID, "location_id": st.LocationID, "item_id": st.ItemID, "method": st.Method, "min_units": st.MinUnits, "max_units": st.MaxUnits, "min_days": st.MinDays, "max_days": st.MaxDays, "updated_by": userID})
+  return &st, nil
+}
+
+func (s *appSvc) DeleteReplenishmentSetting(ctx context.Context, userID, warehouseID, locationID, itemID uuid.UUID) error {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return err
+  }
+  if err := s.rpsvc.DeleteSetting(wh.ID, locationID, itemID); err != nil {
+    return err
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "REPLENISHMENT_SETTING_DELETED", map[string]interface{}{"warehouse_id": wh.ID, "location_id": locationID, "item_id": itemID, "deleted_by": userID})
+  return nil
+}
+
+func (s *appSvc) GetReplenishmentStatus(ctx context.Context, userID, warehouseID uuid.UUID) (*ReplenishmentRun, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  return s.rpsvc.Evaluate(*wh.CompanyID, wh.ID, false)
+}
+
+func (s *appSvc) EvaluateReplenishment(ctx context.Context, userID, warehouseID uuid.UUID) (*ReplenishmentRun, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  run, err := s.rpsvc.Evaluate(*wh.CompanyID, wh.ID, true)
+  if err != nil {
+    return nil, err
+  }
+  s.publishReplenishmentRun(*wh.CompanyID, run, userID)
+  return run, nil
+}
+
+// publishReplenishmentRun announces the replenishment tasks a run raised.
+func (s *appSvc) publishReplenishmentRun(companyID uuid.UUID, run *ReplenishmentRun, requestedBy uuid.UUID) {
+  if len(run.Created) == 0 {
+    return
+  }
+  tasks := make([]map[string]interface{}, 0, len(run.Created))
+  for _, t := range run.Created {
+    tasks = append(tasks, map[string]interface{}{"task_id": t.ID, "item_id": t.ItemID, "from_location_id": t.FromLocationID, "to_location_id": t.ToLocationID, "quantity": t.Quantity, "on_hand": t.OnHand, "min_units": t.MinUnits, "days_of_supply": t.DaysOfSupply})
+  }
+  _ = s.pub.PublishCompanyEvent(companyID, "REPLENISHMENT_TASKS_CREATED", map[string]interface{}{"warehouse_id": run.WarehouseID, "count": len(run.Created), "tasks": tasks, "requested_by": requestedBy})
+}
+
+func (s *appSvc) ListReplenishmentQueue(ctx context.Context, userID uuid.UUID, f repos.ReplenishmentTaskFilter) ([]*models.ReplenishmentTask, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  if user.CompanyID == nil {
+    return nil, fmt.Errorf("user has no company")
+  }
+  f.CompanyID = *user.CompanyID
+  switch f.Status {
+  case "":
+    f.Status = constants.ReplenishTaskOpen
+  case "all":
+    f.Status = ""
+  }
+  return s.rpsvc.ListTasks(f)
+}
+
+func (s *appSvc) GetReplenishmentTask(ctx context.Context, userID, taskID uuid.UUID) (*models.ReplenishmentTask, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  task, err := s.rpsvc.GetTask(taskID)
+  if err != nil {
+    return nil, err
+  }
+  if task.CompanyID == nil || user.CompanyID == nil || *task.CompanyID != *user.CompanyID {
+    return nil, fmt.Errorf("replenishment task does not belong to user's company")
+  }
+  return task, nil
+}
+
+func (s *appSvc) CompleteReplenishmentTask(ctx context.Context, userID, taskID uuid.UUID) error {
+  task, err := s.GetReplenishmentTask(ctx, userID, taskID)
+  if err != nil {
+    return err
+  }
+  if task.Status != constants.ReplenishTaskOpen {
+    return fmt.Errorf("replenishment task is %s", task.Status)
+  }
+  if err := s.rpsvc.CompleteTask(task.ID, userID); err != nil {
+    return err
+  }
+  _ = s.pub.PublishCompanyEvent(*task.CompanyID, "REPLENISHMENT_TASK_COMPLETED", map[string]interface{}{"task_id": task.ID, "warehouse_id": task.WarehouseID, "item_id": task.ItemID, "from_location_id": task.FromLocationID, "to_location_id": task.ToLocationID, "quantity": task.Quantity, "completed_by": userID})
+  return nil
+}
+
+func (s *appSvc) CancelReplenishmentTask(ctx context.Context, userID, taskID uuid.UUID) error {
+  task, err := s.GetReplenishmentTask(ctx, userID, taskID)
+  if err != nil {
+    return err
+  }
+  if task.Status != constants.ReplenishTaskOpen {
+    return fmt.Errorf("replenishment task is %s", task.Status)
+  }
+  if err := s.rpsvc.CancelTask(task.ID); err != nil {
+    return err
+  }
+  _ = s.pub.PublishCompanyEvent(*task.CompanyID, "REPLENISHMENT_TASK_CANCELLED", map[string]interface{}{"task_id": task.ID, "warehouse_id": task.WarehouseID, "cancelled_by": userID})
+  return nil
+}
+
+func (s *appSvc) GetCapacity(ctx context.Context, userID, warehouseID uuid.UUID, q CapacityQuery) (*CapacityReport, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  q.CompanyID = *wh.CompanyID
+  q.WarehouseID = wh.ID
+  return s.cpsvc.Report(q)
+}
+
+// ExportCapacity writes the capacity report with a Charts sheet plotting the
+// utilization trend, utilization by zone, each zone's trend and the projection.
+func (s *appSvc) ExportCapacity(ctx context.Context, userID, warehouseID uuid.UUID, q CapacityQuery) ([]byte, string, error) {
+  r, err := s.GetCapacity(ctx, userID, warehouseID, q)
+  if err != nil {
+    return nil, "", err
+  }
+  opt := func(v interface{}) interface{} {
+    switch v := v.(type) {
+    case *float64:
+      if v != nil {
+        return *v
+      }
+    case *time.Time:
+      if v != nil {
+        return v.Format("2006-01-02")
+      }
+    case *uuid.UUID:
+      if v != nil {
+        return v.String()
+      }
+    }
+    return ""
+  }
+  t, proj := r.Totals, r.Totals.Projection
+  summary := export.Sheet{
+    Name:    "Summary",
+    Headers: []string{"Metric", "Value"},
+    Rows: [][]interface{}{
+      {"Slot Role", r.SlotRole},
+      {"Slots", t.Slots},
+      {"Occupied Slots", t.OccupiedSlots},
+      {"Slot Utilization %", t.SlotUtilizationPct},
+      {"Cube (m3)", t.CubeM3},
+      {"Occupied Cube (m3)", t.OccupiedCubeM3},
+      {"Cube Utilization %", t.CubeUtilizationPct},
+      {"Slots Without Dimensions", t.UnknownCubeSlots},
+      {"Stored Cube (m3)", t.StoredCubeM3},
+      {"Fill %", opt(t.FillPct)},
+      {"Unmeasured Slots", t.UnmeasuredSlots},
+      {"Target Utilization %", r.TargetPct},
+      {"Horizon (days)", r.HorizonDays},
+      {"Forecast Run", opt(r.ForecastRunID)},
+      {"Demand Growth", opt(proj.DemandGrowth)},
+      {"Slot Utilization Trend (pts/day)", proj.SlotPctPerDay},
+      {"Cube Utilization Trend (pts/day)", proj.CubePctPerDay},
+      {"Run Out by Trend", opt(proj.RunOutByTrend)},
+      {"Run Out by Forecast", opt(proj.RunOutByForecast)},
+    },
+  }
+  zones := export.Sheet{Name: "Zones", Headers: []string{"Zone", "Slots", "Occupied Slots", "Slot Utilization %", "Cube (m3)", "Occupied Cube (m3)", "Cube Utilization %", "Slots Without Dimensions", "Stored Cube (m3)", "Fill %", "Unmeasured Slots", "Demand Growth", "Run Out by Trend", "Run Out by Forecast"}}
+  for _, z := range r.ByZone {
+    zones.Rows = append(zones.Rows, []interface{}{z.Zone, z.Slots, z.OccupiedSlots, z.SlotUtilizationPct, z.CubeM3, z.OccupiedCubeM3, z.CubeUtilizationPct, z.UnknownCubeSlots, z.StoredCubeM3, opt(z.FillPct), z.UnmeasuredSlots, opt(z.Projection.DemandGrowth), opt(z.Projection.RunOutByTrend), opt(z.Projection.RunOutByForecast)})
+  }
+  trend := export.Sheet{Name: "Trend", Headers: []string{"Date", "Slots", "Occupied Slots", "Slot Utilization %", "Cube (m3)", "Occupied Cube (m3)", "Cube Utilization %"}}
+  for _, p := range t.Trend {
+    trend.Rows = append(trend.Rows, []interface{}{p.Date.Format("2006-01-02"), p.Slots, p.OccupiedSlots, p.SlotUtilizationPct, p.CubeM3, p.OccupiedCubeM3, p.CubeUtilizationPct})
+  }
+  zoneTrend := export.Sheet{Name: "Zone Trend", Headers: []string{"Date"}}
+  for _, z := range r.ByZone {
+    zoneTrend.Headers = append(zoneTrend.Headers, z.Zone+" Slot Utilization %")
+  }
+  for i, p := range t.Trend {
+    row := []interface{}{p.Date.Format("2006-01-02")}
+    for _, z := range r.ByZone {
+      row = append(row, z.Trend[i].SlotUtilizationPct)
+    }
+    zoneTrend.Rows = append(zoneTrend.Rows, row)
+  }
+  projection := export.Sheet{Name: "Projection", Headers: []string{"Date", "Target %", "Trend Slot %", "Trend Cube %"}}
+  if proj.DemandGrowth != nil {
+    projection.Headers = append(projection.Headers, "Forecast Slot %", "Forecast Cube %")
+  }
+  for _, p := range proj.Points {
+    row := []interface{}{p.Date.Format("2006-01-02"), r.TargetPct, p.TrendSlotPct, p.TrendCubePct}
+    if proj.DemandGrowth != nil {
+      row = append(row, opt(p.ForecastSlotPct), opt(p.ForecastCubePct))
+    }
+    projection.Rows = append(projection.Rows, row)
+  }
+
+  w, err := export.NewWorkbook()
+  if err != nil {
+    return nil, "", err
+  }
+  for _, sheet := range []export.Sheet{summary, {Name: "Charts"}, zones, trend, zoneTrend, projection} {
+    if err := w.AddSheet(sheet); err != nil {
+      return nil, "", err
+    }
+  }
+  zoneSeries := make([]int, len(r.ByZone))
+  for i := range zoneSeries {
+    zoneSeries[i] = i + 2
+  }
+  projSeries := []int{2, 3, 4}
+  if proj.DemandGrowth != nil {
+    projSeries = append(projSeries, 5, 6)
+  }
+  charts := []export.Chart{
+    {Type: export.ChartLine, Title: "Utilization Trend", DataSheet: trend.Name, Category: 1, Series: []int{4, 7}, Rows: len(trend.Rows), YAxisTitle: "%", Cell: "A1"},
+    {Type: export.ChartColumn, Title: "Utilization by Zone", DataSheet: zones.Name, Category: 1, Series: []int{4, 7}, Rows: len(zones.Rows), YAxisTitle: "%", Cell: "M1"},
+    {Type: export.ChartLine, Title: "Slot Utilization Trend by Zone", DataSheet: zoneTrend.Name, Category: 1, Series: zoneSeries, Rows: len(zoneTrend.Rows), YAxisTitle: "%", Cell: "A20"},
+    {Type: export.ChartLine, Title: "Utilization Projection", DataSheet: projection.Name, Category: 1, Series: projSeries, Rows: len(projection.Rows), YAxisTitle: "%", Cell: "M20"},
+  }
+  for _, c := range charts {
+    c.Sheet = "Charts"
+    if err := w.AddChart(c); err != nil {
+      return nil, "", err
+    }
+  }
+  data, err := w.Bytes()
+  if err != nil {
+    return nil, "", err
+  }
+  fileName := fmt.Sprintf("capacity_%s_%s.xlsx", r.StartDate.Format("20060102"), r.EndDate.Format("20060102"))
+  return data, fileName, nil
+}
+
+func (s *appSvc) GetErgonomicModel(ctx context.Context, userID, warehouseID uuid.UUID) (*models.ErgonomicModel, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  m, err := s.ergsvc.GetModel(wh.ID)
+  if err != nil {
+    return nil, err
+  }
+  m.CompanyID = wh.CompanyID
+  return m, nil
+}
+
+func (s *appSvc) SetErgonomicModel(ctx context.Context, userID, warehouseID uuid.UUID, m models.ErgonomicModel) (*models.ErgonomicModel, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  m.ID = uuid.Nil
+  m.CompanyID = wh.CompanyID
+  m.WarehouseID = &wh.ID
+  if err := s.ergsvc.SetModel(&m); err != nil {
+    return nil, err
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "ERGONOMIC_MODEL_UPDATED", map[string]interface{}{"warehouse_id": wh.ID, "model": m, "updated_by": userID})
+  return &m, nil
+}
+
+func (s *appSvc) ResetErgonomicModel(ctx context.Context, userID, warehouseID uuid.UUID) (*models.ErgonomicModel, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  if err := s.ergsvc.ResetModel(wh.ID); err != nil {
+    return nil, err
+  }
+  m, err := s.ergsvc.GetModel(wh.ID)
+  if err != nil {
+    return nil, err
+  }
+  m.CompanyID = wh.CompanyID
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "ERGONOMIC_MODEL_UPDATED", map[string]interface{}{"warehouse_id": wh.ID, "model": m, "updated_by": userID})
+  return m, nil
+}
+
+func (s *appSvc) GetErgonomics(ctx context.Context, userID, warehouseID uuid.UUID, q ErgonomicQuery) (*ErgonomicReport, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  q.CompanyID = *wh.CompanyID
+  q.WarehouseID = wh.ID
+  return s.ergsvc.Report(q)
+}
+
+func (s *appSvc) ExportErgonomics(ctx context.Context, userID, warehouseID uuid.UUID, q ErgonomicQuery) ([]byte, string, error) {
+  q.Limit, q.Offset = 0, 0
+  r, err := s.GetErgonomics(ctx, userID, warehouseID, q)
+  if err != nil {
+    return nil, "", err
+  }
+  score := func(v *float64) interface{} {
+    if v == nil {
+      return ""
+    }
+    return *v
+  }
+  bands := []string{constants.ErgoBandBelowKnee, constants.ErgoBandKneeToWaist, constants.ErgoBandGolden, constants.ErgoBandAboveShoulder, constants.ErgoBandUnknown}
+  m := r.Model
+  summary := export.Sheet{
+    Name:    "Summary",
+    Headers: []string{"Metric", "Value"},
+    Rows: [][]interface{}{
+      {"Transaction Types", strings.Join(r.TransactionTypes, ", ")},
+      {"Ergonomic Score", score(r.Totals.Score)},
+      {"Slots", r.Totals.Slots},
+      {"Lines", r.Totals.Lines},
+      {"Units", r.Totals.Units},
+      {"Awkward Lines", r.Totals.AwkwardLines},
+      {"Heavy Fast Awkward Lines", r.Totals.HeavyFastAwkwardLines},
+      {"Heavy Fast Awkward Items", r.Totals.HeavyFastAwkwardItems},
+      {"Unslotted Items", r.UnslottedItems},
+      {"Unslotted Lines", r.UnslottedLines},
+      {"Knee Height (cm)", m.KneeHeightCm},
+      {"Waist Height (cm)", m.WaistHeightCm},
+      {"Shoulder Height (cm)", m.ShoulderHeightCm},
+      {"Reach Depth (cm)", m.ReachDepthCm},
+      {"Max Reach Depth (cm)", m.MaxReachDepthCm},
+      {"Height Weight", m.HeightWeight},
+      {"Reach Weight", m.ReachWeight},
+      {"Heavy Item (kg)", m.HeavyItemKg},
+      {"Fast Mover Share", m.FastMoverShare},
+    },
+  }
+  for _, b := range bands {
+    summary.Rows = append(summary.Rows, []interface{}{"Lines " + b, r.Totals.LinesByBand[b]})
+  }
+  zones := export.Sheet{Name: "Zones", Headers: []string{"Zone", "Slots", "Lines", "Units", "Score", "Awkward Lines", "Heavy Fast Awkward Lines", "Heavy Fast Awkward Items"}}
+  for _, b := range bands {
+    zones.Headers = append(zones.Headers, "Lines "+b)
+  }
+  for _, z := range r.ByZone {
+    row := []interface{}{z.Zone, z.Slots, z.Lines, z.Units, score(z.Score), z.AwkwardLines, z.HeavyFastAwkwardLines, z.HeavyFastAwkwardItems}
+    for _, b := range bands {
+      row = append(row, z.LinesByBand[b])
+    }
+    zones.Rows = append(zones.Rows, row)
+  }
+  items := export.Sheet{Name: "Items", Headers: []string{"Item", "Unit Weight (kg)", "Lines", "Units", "Heavy", "Fast Mover", "Score", "Awkward Lines"}}
+  slots := export.Sheet{Name: "Slots", Headers: []string{"Item", "Location", "Zone", "Band", "Score", "Lines"}}
+  for _, it := range r.Items {
+    items.Rows = append(items.Rows, []interface{}{it.ItemName, it.UnitWeightKg, it.Lines, it.Units, it.Heavy, it.FastMover, it.Score, it.AwkwardLines})
+    for _, sl := range it.Slots {
+      slots.Rows = append(slots.Rows, []interface{}{it.ItemName, sl.LocationPath, sl.Zone, sl.Band, sl.Score, sl.Lines})
+    }
+  }
+  data, err := export.XLSX(summary, zones, items, slots)
+  if err != nil {
+    return nil, "", err
+  }
+  fileName := fmt.Sprintf("ergonomics_%s_%s.xlsx", r.StartDate.Format("20060102"), r.EndDate.Format("20060102"))
+  return data, fileName, nil
+}
+
+func (s *appSvc) RunClassification(ctx context.Context, userID, warehouseID uuid.UUID, p ClassificationParams) (*models.ClassificationRun, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  p.CompanyID = *wh.CompanyID
+  p.WarehouseID = wh.ID
+  p.CreatedByID = userID
+  run, items, err := s.clsvc.Classify(p)
+  if err != nil {
+    return nil, err
+  }
+  counts := make(map[string]int)
+  for _, it := range items {
+    counts[it.ABCClass+it.XYZClass]++
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "CLASSIFICATION_COMPLETED", map[string]interface{}{"warehouse_id": wh.ID, "run_id": run.ID, "basis": run.Basis, "item_count": run.ItemCount, "class_counts": counts, "run_by": userID})
+  return run, nil
+}
+
+func (s *appSvc) GetClassificationRun(ctx context.Context, userID, runID uuid.UUID) (*models.ClassificationRun, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  run, err := s.clsvc.GetRunByID(runID)
+  if err != nil {
+    return nil, err
+  }
+  if run.CompanyID == nil || user.CompanyID == nil || *run.CompanyID != *user.CompanyID {
+    return nil, fmt.Errorf("classification run does not belong to user's company")
+  }
+  return run, nil
+}
+
+func (s *appSvc) DeleteClassificationRun(ctx context.Context, userID, runID uuid.UUID) error {
+  run, err := s.GetClassificationRun(ctx, userID, runID)
+  if err != nil {
+    return err
+  }
+  return s.clsvc.DeleteRun(run.ID)
+}
+
+func (s *appSvc) ListClassificationRuns(ctx context.Context, userID uuid.UUID, f repos.ClassificationRunFilter) ([]*models.ClassificationRun, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  if user.CompanyID == nil {
+    return nil, fmt.Errorf("user has no company")
+  }
+  f.CompanyID = *user.CompanyID
+  return s.clsvc.ListRuns(f)
+}
+
+func (s *appSvc) ListItemClassifications(ctx context.Context, userID, runID uuid.UUID, f repos.ItemClassificationFilter) ([]*models.ItemClassification, error) {
+  run, err := s.GetClassificationRun(ctx, userID, runID)
+  if err != nil {
+    return nil, err
+  }
+  f.RunID = run.ID
+  return s.clsvc.ListItems(f)
+}
+
+func (s *appSvc) CompareClassificationRuns(ctx context.Context, userID, fromRunID, toRunID uuid.UUID) (*ClassChangeReport, error) {
+  if _, err := s.GetClassificationRun(ctx, userID, fromRunID); err != nil {
+    return nil, err
+  }
+  if _, err := s.GetClassificationRun(ctx, userID, toRunID); err != nil {
+    return nil, err
+  }
+  return s.clsvc.CompareRuns(fromRunID, toRunID)
+}
+
+func (s *appSvc) RunForecast(ctx context.Context, userID, warehouseID uuid.UUID, p ForecastParams) (*models.ForecastRun, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  p.CompanyID = *wh.CompanyID
+  p.WarehouseID = wh.ID
+  p.CreatedByID = userID
+  run, items, err := s.fcsvc.Forecast(p)
+  if err != nil {
+    return nil, err
+  }
+  methods := make(map[string]int)
+  for _, it := range items {
+    methods[it.Method]++
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "FORECAST_COMPLETED", map[string]interface{}{"warehouse_id": wh.ID, "run_id": run.ID, "granularity": run.Granularity, "item_count": run.ItemCount, "method_counts": methods, "wape": run.WAPE, "run_by": userID})
+  return run, nil
+}
+
+func (s *appSvc) GetForecastRun(ctx context.Context, userID, runID uuid.UUID) (*models.ForecastRun, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  run, err := s.fcsvc.GetRunByID(runID)
+  if err != nil {
+    return nil, err
+  }
+  if run.CompanyID == nil || user.CompanyID == nil || *run.CompanyID != *user.CompanyID {
+    return nil, fmt.Errorf("forecast run does not belong to user's company")
+  }
+  return run, nil
+}
+
+func (s *appSvc) DeleteForecastRun(ctx context.Context, userID, runID uuid.UUID) error {
+  run, err := s.GetForecastRun(ctx, userID, runID)
+  if err != nil {
+    return err
+  }
+  return s.fcsvc.DeleteRun(run.ID)
+}
+
+func (s *appSvc) ListForecastRuns(ctx context.Context, userID uuid.UUID, f repos.ForecastRunFilter) ([]*models.ForecastRun, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  if user.CompanyID == nil {
+    return nil, fmt.Errorf("user has no company")
+  }
+  f.CompanyID = *user.CompanyID
+  return s.fcsvc.ListRuns(f)
+}
+
+func (s *appSvc) ListItemForecasts(ctx context.Context, userID, runID uuid.UUID, f repos.ItemForecastFilter) ([]*models.ItemForecast, error) {
+  run, err := s.GetForecastRun(ctx, userID, runID)
+  if err != nil {
+    return nil, err
+  }
+  f.RunID = run.ID
+  return s.fcsvc.ListItems(f)
+}
+
+func (s *appSvc) RecommendSlotPlan(ctx context.Context, userID, warehouseID uuid.UUID, p SlotPlanParams) (*models.SlotPlan, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  p.CompanyID = *wh.CompanyID
+  p.WarehouseID = wh.ID
+  p.CreatedByID = userID
+  plan, err := s.slsvc.Recommend(p)
+  if err != nil {
+    return nil, err
+  }
+  _ = s.pub.PublishCompanyEvent(*wh.CompanyID, "SLOT_PLAN_CREATED", map[string]interface{}{"warehouse_id": wh.ID, "plan_id": plan.ID, "item_count": plan.ItemCount, "move_count": plan.MoveCount, "created_by": userID})
+  return plan, nil
+}
+
+func (s *appSvc) GetSlotPlan(ctx context.Context, userID, planID uuid.UUID) (*models.SlotPlan, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  plan, err := s.slsvc.GetPlanByID(planID)
+  if err != nil {
+    return nil, err
+  }
+  if plan.CompanyID == nil || user.CompanyID == nil || *plan.CompanyID != *user.CompanyID {
+    return nil, fmt.Errorf("slot plan does not belong to user's company")
+  }
+  return plan, nil
+}
+
+func (s *appSvc) DeleteSlotPlan(ctx context.Context, userID, planID uuid.UUID) error {
+  plan, err := s.GetSlotPlan(ctx, userID, planID)
+  if err != nil {
+    return err
+  }
+  return s.slsvc.DeletePlan(plan.ID)
+}
+
+func (s *appSvc) ListSlotPlans(ctx context.Context, userID uuid.UUID, f repos.SlotPlanFilter) ([]*models.SlotPlan, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  if user.CompanyID == nil {
+    return nil, fmt.Errorf("user has no company")
+  }
+  f.CompanyID = *user.CompanyID
+  return s.slsvc.ListPlans(f)
+}
+
+func (s *appSvc) ListSlotPlanEntries(ctx context.Context, userID, planID uuid.UUID, f repos.SlotPlanEntryFilter) ([]*models.SlotPlanEntry, error) {
+  plan, err := s.GetSlotPlan(ctx, userID, planID)
+  if err != nil {
+    return nil, err
+  }
+  f.PlanID = plan.ID
+  return s.slsvc.ListEntries(f)
+}
+
+func (s *appSvc) GetSlotPlanMoves(ctx context.Context, userID, planID uuid.UUID, p MoveListParams) (*MoveList, error) {
+  plan, err := s.GetSlotPlan(ctx, userID, planID)
+  if err != nil {
+    return nil, err
+  }
+  return s.slsvc.MoveList(plan.ID, p)
+}
+
+func (s *appSvc) AcceptSlotPlanMoves(ctx context.Context, userID, planID uuid.UUID, p MoveListParams) ([]*models.MoveTask, error) {
+  plan, err := s.GetSlotPlan(ctx, userID, planID)
+  if err != nil {
+    return nil, err
+  }
+  tasks, err := s.slsvc.AcceptMoveList(plan.ID, userID, p)
+  if err != nil {
+    return nil, err
+  }
+  _ = s.pub.PublishCompanyEvent(*plan.CompanyID, "MOVE_TASKS_CREATED", map[string]interface{}{"warehouse_id": plan.WarehouseID, "plan_id": plan.ID, "task_count": len(tasks), "created_by": userID})
+  return tasks, nil
+}
+
+func (s *appSvc) ListMoveTasks(ctx context.Context, userID uuid.UUID, f repos.MoveTaskFilter) ([]*models.MoveTask, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  if user.CompanyID == nil {
+    return nil, fmt.Errorf("user has no company")
+  }
+  f.CompanyID = *user.CompanyID
+  return s.slsvc.ListMoveTasks(f)
+}
+
+func (s *appSvc) GetMoveTask(ctx context.Context, userID, taskID uuid.UUID) (*models.MoveTask, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  task, err := s.slsvc.GetMoveTask(taskID)
+  if err != nil {
+    return nil, err
+  }
+  if task.CompanyID == nil || user.CompanyID == nil || *task.CompanyID != *user.CompanyID {
+    return nil, fmt.Errorf("move task does not belong to user's company")
+  }
+  return task, nil
+}
+
+// ConfirmMoveTask records the move as done: the item's assignment to the
+// source slot is closed and one to the target slot opened, of the same type,
+// together with the task. Tasks of a group confirm in sequence.
+func (s *appSvc) ConfirmMoveTask(ctx context.Context, userID, taskID uuid.UUID) ([]*models.ZoneViolation, error) {
+  task, err := s.GetMoveTask(ctx, userID, taskID)
+  if err != nil {
+    return nil, err
+  }
+  if task.Status != constants.MoveTaskStatusOpen {
+    return nil, fmt.Errorf("move task is %s", task.Status)
+  }
+  if task.ToLocation == nil || task.Item == nil {
+    return nil, fmt.Errorf("move task target location or item no longer exists")
+  }
+  moved := models.SlotAssignment{
+    CompanyID:  task.CompanyID,
+    ItemID:     &task.Item.ID,
+    LocationID: &task.ToLocation.ID,
+    OpenSource: constants.AssignmentSourceMoveTask,
+    OpenedByID: &userID,
+  }
+  if task.FromLocationID != nil {
+    open, err := s.lsvc.ListAssignments(repos.SlotAssignmentFilter{WarehouseID: *task.WarehouseID, LocationID: *task.FromLocationID, ItemID: task.Item.ID, OpenOnly: true})
+    if err != nil {
+      return nil, err
+    }
+    if len(open) > 0 {
+      moved.AssignmentType = open[0].AssignmentType
+    }
+  }
+  prepared, err := s.lsvc.PrepareAssignment(moved)
+  if err != nil {
+    return nil, fmt.Errorf("failed to link item to location: %w", err)
+  }
+  if err := s.slsvc.CompleteMoveTask(task, prepared, userID); err != nil {
+    return nil, err
+  }
+  _ = s.pub.PublishCompanyEvent(*task.CompanyID, "MOVE_TASK_COMPLETED", map[string]interface{}{"task_id": task.ID, "plan_id": task.PlanID, "warehouse_id": task.WarehouseID, "item_id": task.Item.ID, "from_location_id": task.FromLocationID, "to_location_id": task.ToLocation.ID, "completed_by": userID})
+  return s.checkZoneLink(task.ToLocation, task.Item, constants.ViolationSourceLink)
+}
+
+func (s *appSvc) CancelMoveTask(ctx context.Context, userID, taskID uuid.UUID) error {
+  task, err := s.GetMoveTask(ctx, userID, taskID)
+  if err != nil {
+    return err
+  }
+  if task.Status != constants.MoveTaskStatusOpen {
+    return fmt.Errorf("move task is %s", task.Status)
+  }
+  if err := s.slsvc.CancelMoveTask(task.ID); err != nil {
+    return err
+  }
+  _ = s.pub.PublishCompanyEvent(*task.CompanyID, "MOVE_TASK_CANCELLED", map[string]interface{}{"task_id": task.ID, "plan_id": task.PlanID, "warehouse_id": task.WarehouseID, "cancelled_by": userID})
+  return nil
+}
+
+func (s *appSvc) ReplaySlotPlan(ctx context.Context, userID, planID uuid.UUID, p ReplayParams) (*ReplayReport, error) {
+  plan, err := s.GetSlotPlan(ctx, userID, planID)
+  if err != nil {
+    return nil, err
+  }
+  return s.simsvc.ReplayPlan(plan.ID, p)
+}
+
+func (s *appSvc) ExportSlotPlanReplay(ctx context.Context, userID, planID uuid.UUID, p ReplayParams) ([]byte, string, error) {
+  report, err := s.ReplaySlotPlan(ctx, userID, planID, p)
+  if err != nil {
+    return nil, "", err
+  }
+  cur, prop := report.Current, report.Proposed
+  summary := export.Sheet{
+    Name:    "Summary",
+    Headers: []string{"Metric", "Current", "Proposed", "Change"},
+    Rows: [][]interface{}{
+      {"Orders", cur.Orders, prop.Orders, prop.Orders - cur.Orders},
+      {"Lines", cur.Lines, prop.Lines, prop.Lines - cur.Lines},
+      {"Unrouted Lines", cur.UnroutedLines, prop.UnroutedLines, prop.UnroutedLines - cur.UnroutedLines},
+      {"Travel (m)", cur.TravelM, prop.TravelM, prop.TravelM - cur.TravelM},
+      {"Avg Travel per Order (m)", cur.AvgTravelPerOrderM, prop.AvgTravelPerOrderM, prop.AvgTravelPerOrderM - cur.AvgTravelPerOrderM},
+      {"Pick Hours", cur.PickHours, prop.PickHours, prop.PickHours - cur.PickHours},
+      {"Lines per Hour", cur.LinesPerHour, prop.LinesPerHour, report.LinesPerHourGain},
+    },
+  }
+  orders := export.Sheet{
+    Name:    "Orders",
+    Headers: []string{"Order", "Completed At", "Lines", "Current Travel (m)", "Proposed Travel (m)", "Saved (m)"},
+  }
+  for _, o := range report.Orders {
+    orders.Rows = append(orders.Rows, []interface{}{o.OrderName, o.CompletedAt, o.Lines, o.CurrentTravelM, o.ProposedTravelM, o.CurrentTravelM - o.ProposedTravelM})
+  }
+  hotspots := export.Sheet{
+    Name:    "Hotspots",
+    Headers: []string{"Scenario", "Aisle", "Visits", "Peak Hour", "Peak Hour Visits"},
+  }
+  for _, sc := range []*ReplayScenario{cur, prop} {
+    for _, h := range sc.Hotspots {
+      hotspots.Rows = append(hotspots.Rows, []interface{}{sc.Name, h.Aisle, h.Visits, h.PeakHour, h.PeakHourVisits})
+    }
+  }
+  data, err := export.XLSX(summary, orders, hotspots)
+  if err != nil {
+    return nil, "", err
+  }
+  fileName := fmt.Sprintf("replay_%s_%s_%s.xlsx", report.Routing, report.StartDate.Format("20060102"), report.EndDate.Format("20060102"))
+  return data, fileName, nil
+}
+
+func (s *appSvc) GetPickFaceSizing(ctx context.Context, userID, warehouseID uuid.UUID, q SizingQuery) (*SizingReport, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  q.CompanyID = *wh.CompanyID
+  q.WarehouseID = wh.ID
+  return s.slsvc.PickFaceSizing(q)
+}
+
+func (s *appSvc) ExportPickFaceSizing(ctx context.Context, userID, warehouseID uuid.UUID, q SizingQuery) ([]byte, string, error) {
+  q.Limit, q.Offset = 0, 0
+  report, err := s.GetPickFaceSizing(ctx, userID, warehouseID, q)
+  if err != nil {
+    return nil, "", err
+  }
+  days := func(v *float64) interface{} {
+    if v == nil {
+      return ""
+    }
+    return *v
+  }
+  sizing := export.Sheet{
+    Name: "Sizing",
+    Headers: []string{"Item", "Slots", "Slot Type", "Capacity (units)", "Avg Daily Units", "Peak Daily Units", "Days of Supply", "Peak Days of Supply", "Status",
+      "Target Units", "Recommended Slot Type", "Recommended W (cm)", "Recommended D (cm)", "Recommended H (cm)", "Recommended Slots", "Recommended Capacity", "Recommended Days of Supply"},
+  }
+  for _, r := range report.Rows {
+    capacity := interface{}(r.CapacityUnits)
+    if !r.CapacityKnown {
+      capacity = ""
+    }
+    sizing.Rows = append(sizing.Rows, []interface{}{r.ItemName, r.Slots, r.SlotType, capacity, r.AvgDailyUnits, r.PeakDailyUnits, days(r.DaysOfSupply), days(r.PeakDaysOfSupply), r.Status,
+      r.TargetUnits, r.RecommendedSlotType, r.RecommendedWidthCm, r.RecommendedDepthCm, r.RecommendedHeightCm, r.RecommendedSlots, r.RecommendedCapacity, days(r.RecommendedDaysOfSupply)})
+  }
+  profiles := export.Sheet{Name: "Slot Profiles", Headers: []string{"Slot Type", "Width (cm)", "Depth (cm)", "Height (cm)", "Max Weight (kg)", "Slots"}}
+  for _, p := range report.Profiles {
+    profiles.Rows = append(profiles.Rows, []interface{}{p.SlotType, p.WidthCm, p.DepthCm, p.HeightCm, p.MaxWeightKg, p.Slots})
+  }
+  data, err := export.XLSX(sizing, profiles)
+  if err != nil {
+    return nil, "", err
+  }
+  fileName := fmt.Sprintf("pick_face_sizing_%s_%s.xlsx", report.StartDate.Format("20060102"), report.EndDate.Format("20060102"))
+  return data, fileName, nil
+}
+
+func (s *appSvc) CreateScenario(ctx context.Context, userID, warehouseID uuid.UUID, p ScenarioParams) (*models.SlottingScenario, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  p.CompanyID = *wh.CompanyID
+  p.WarehouseID = wh.ID
+  p.CreatedByID = userID
+  scenario, err := s.scsvc.Create(p)
+  if err != nil {
+    return nil, err
+  }
+  s.publishScenarioCreated(scenario, userID)
+  return scenario, nil
+}
+
+func (s *appSvc) GetScenario(ctx context.Context, userID, scenarioID uuid.UUID) (*models.SlottingScenario, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  scenario, err := s.scsvc.GetByID(scenarioID)
+  if err != nil {
+    return nil, err
+  }
+  if scenario.CompanyID == nil || user.CompanyID == nil || *scenario.CompanyID != *user.CompanyID {
+    return nil, fmt.Errorf("scenario does not belong to user's company")
+  }
+  return scenario, nil
+}
+
+func (s *appSvc) DeleteScenario(ctx context.Context, userID, scenarioID uuid.UUID) error {
+  scenario, err := s.GetScenario(ctx, userID, scenarioID)
+  if err != nil {
+    return err
+  }
+  return s.scsvc.Delete(scenario.ID)
+}
+
+func (s *appSvc) ListScenarios(ctx context.Context, userID uuid.UUID, f repos.ScenarioFilter) ([]*models.SlottingScenario, error) {
+  user, err := s.usvc.GetUserByID(userID)
+  if err != nil || user == nil {
+    return nil, fmt.Errorf("unauthorized user")
+  }
+  if user.CompanyID == nil {
+    return nil, fmt.Errorf("user has no company")
+  }
+  f.CompanyID = *user.CompanyID
+  return s.scsvc.List(f)
+}
+
+func (s *appSvc) CloneScenario(ctx context.Context, userID, scenarioID uuid.UUID, name, description string, plan *SlotPlanParams) (*models.SlottingScenario, error) {
+  src, err := s.GetScenario(ctx, userID, scenarioID)
+  if err != nil {
+    return nil, err
+  }
+  scenario, err := s.scsvc.Clone(src.ID, userID, name, description, plan)
+  if err != nil {
+    return nil, err
+  }
+  s.publishScenarioCreated(scenario, userID)
+  return scenario, nil
+}
+
+func (s *appSvc) CompareScenarios(ctx context.Context, userID uuid.UUID, scenarioIDs []uuid.UUID) (*ScenarioComparison, error) {
+  for _, id := range scenarioIDs {
+    if _, err := s.GetScenario(ctx, userID, id); err != nil {
+      return nil, err
+    }
+  }
+  return s.scsvc.Compare(scenarioIDs)
+}
+
+// ListSlotAssignments lists a warehouse's slot assignments; with f.AsOf set it
+// is the slot map on that date.
+func (s *appSvc) ListSlotAssignments(ctx context.Context, userID, warehouseID uuid.UUID, f repos.SlotAssignmentFilter) ([]*models.SlotAssignment, error) {
+  wh, err := s.GetWarehouseByID(ctx, userID, warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  f.CompanyID = *wh.CompanyID
+  f.WarehouseID = wh.ID
+  return s.lsvc.ListAssignments(f)
+}
+
+func (s *appSvc) publishScenarioCreated(scenario *models.SlottingScenario, userID uuid.UUID) {
+  _ = s.pub.PublishCompanyEvent(*scenario.CompanyID, "SCENARIO_CREATED", map[string]interface{}{
+    "warehouse_id":        scenario.WarehouseID,
+    "scenario_id":         scenario.ID,
+    "name":                scenario.Name,
+    "plan_id":             scenario.PlanID,
+    "cloned_from_id":      scenario.ClonedFromID,
+    "travel_m":            scenario.TravelM,
+    "ergo_score":          scenario.ErgoScore,
+    "moves_required":      scenario.MovesRequired,
+    "capacity_violations": scenario.CapacityViolations,
+    "created_by":          userID,
+  })
+}
+
 func (s *appSvc) generateUserAvatar(ctx context.Context, firstName, lastName string) (string, error) {
   seed := fmt.Sprintf("%s-%s", strings.ToLower(firstName), strings.ToLower(lastName))
   return s.avatarsvc.GenerateAndUploadAvatar(ctx, "adventurer", seed)
@@ -0,0 +1,177 @@
+package services
+
+import (
+  "context"
+  "strings"
+  "testing"
+
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+type fakeUSvc struct {
+  USvc
+  user *models.User
+}
+
+func (f *fakeUSvc) GetUserByID(userID uuid.UUID) (*models.User, error) {
+  return f.user, nil
+}
+
+type fakeLRepo struct {
+  repos.LRepo
+  locs map[uuid.UUID]*models.Location
+}
+
+func (f *fakeLRepo) GetByID(locationID uuid.UUID) (*models.Location, error) {
+  return f.locs[locationID], nil
+}
+
+type fakeAssignRepo struct {
+  repos.SlotAssignmentRepo
+  open    []*models.SlotAssignment
+  filters []repos.SlotAssignmentFilter
+}
+
+func (f *fakeAssignRepo) ListAssignments(filter repos.SlotAssignmentFilter) ([]*models.SlotAssignment, error) {
+  f.filters = append(f.filters, filter)
+  var out []*models.SlotAssignment
+  for _, a := range f.open {
+    if (filter.LocationID == uuid.Nil || *a.LocationID == filter.LocationID) && (filter.ItemID == uuid.Nil || *a.ItemID == filter.ItemID) {
+      out = append(out, a)
+    }
+  }
+  return out, nil
+}
+
+type fakeMoveTaskRepo struct {
+  repos.MoveTaskRepo
+  tasks map[uuid.UUID]*models.MoveTask
+  moved *models.SlotAssignment
+}
+
+func (f *fakeMoveTaskRepo) GetByID(taskID uuid.UUID) (*models.MoveTask, error) {
+  return f.tasks[taskID], nil
+}
+
+func (f *fakeMoveTaskRepo) Complete(task *models.MoveTask, moved *models.SlotAssignment, completedBy uuid.UUID) error {
+  f.tasks[task.ID].Status = constants.MoveTaskStatusDone
+  f.moved = moved
+  return nil
+}
+
+type fakeZSvc struct {
+  ZSvc
+}
+
+func (fakeZSvc) CheckLink(location *models.Location, item *models.Item, source string, fileID *uuid.UUID) ([]*models.ZoneViolation, error) {
+  return nil, nil
+}
+
+type fakePub struct {
+  actions []string
+}
+
+func (f *fakePub) PublishCompanyEvent(companyID uuid.UUID, action string, payload interface{}) error {
+  f.actions = append(f.actions, action)
+  return nil
+}
+
+func (f *fakePub) PublishUserEvent(userID uuid.UUID, action string, payload interface{}) error {
+  return nil
+}
+
+// TestConfirmMoveTask confirms a task shaped like the ones AcceptMoveList
+// creates, which always carry the source slot.
+func TestConfirmMoveTask(t *testing.T) {
+  companyID, warehouseID, userID := uuid.New(), uuid.New(), uuid.New()
+  item := &models.Item{ID: uuid.New(), CompanyID: &companyID}
+  from := &models.Location{ID: uuid.New(), WarehouseID: &warehouseID, SlotRole: constants.SlotRolePick}
+  to := &models.Location{ID: uuid.New(), WarehouseID: &warehouseID, SlotRole: constants.SlotRolePick}
+
+  tests := []struct {
+    name    string
+    status  string
+    wantErr string
+  }{
+    {name: "open task moves the assignment", status: constants.MoveTaskStatusOpen},
+    {name: "done task is refused", status: constants.MoveTaskStatusDone, wantErr: "move task is done"},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      planID := uuid.New()
+      task := &models.MoveTask{
+        ID:             uuid.New(),
+        CompanyID:      &companyID,
+        WarehouseID:    &warehouseID,
+        PlanID:         &planID,
+        ItemID:         &item.ID,
+        Item:           item,
+        FromLocationID: &from.ID,
+        FromLocation:   from,
+        ToLocationID:   &to.ID,
+        ToLocation:     to,
+        GroupNo:        1,
+        Kind:           constants.MoveKindMove,
+        Sequence:       1,
+        Status:         tt.status,
+      }
+      assignRepo := &fakeAssignRepo{open: []*models.SlotAssignment{{
+        CompanyID:      &companyID,
+        WarehouseID:    &warehouseID,
+        ItemID:         &item.ID,
+        LocationID:     &from.ID,
+        AssignmentType: constants.AssignmentOverflow,
+      }}}
+      mtrepo := &fakeMoveTaskRepo{tasks: map[uuid.UUID]*models.MoveTask{task.ID: task}}
+      pub := &fakePub{}
+      s := &appSvc{
+        usvc:  &fakeUSvc{user: &models.User{ID: userID, CompanyID: &companyID}},
+        lsvc:  NewLSvc(&fakeLRepo{locs: map[uuid.UUID]*models.Location{from.ID: from, to.ID: to}}, nil, assignRepo),
+        slsvc: &slottingSvc{mtrepo: mtrepo},
+        zsvc:  fakeZSvc{},
+        pub:   pub,
+      }
+
+      _, err := s.ConfirmMoveTask(context.Background(), userID, task.ID)
+      if tt.wantErr != "" {
+        if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+          t.Fatalf("got error %v, want %q", err, tt.wantErr)
+        }
+        if mtrepo.moved != nil {
+          t.Fatalf("refused task still moved the item")
+        }
+        return
+      }
+      if err != nil {
+        t.Fatalf("ConfirmMoveTask: %v", err)
+      }
+      for _, f := range assignRepo.filters {
+        if f.WarehouseID != warehouseID {
+          t.Errorf("assignment lookup without the task's warehouse: %+v", f)
+        }
+      }
+      m := mtrepo.moved
+      if m == nil {
+        t.Fatal("task was not completed")
+      }
+      if *m.LocationID != to.ID || *m.ItemID != item.ID || *m.WarehouseID != warehouseID {
+        t.Errorf("moved to %v item %v warehouse %v", *m.LocationID, *m.ItemID, *m.WarehouseID)
+      }
+      if m.AssignmentType != constants.AssignmentOverflow {
+        t.Errorf("assignment type %q, want the source slot's %q", m.AssignmentType, constants.AssignmentOverflow)
+      }
+      if m.OpenSource != constants.AssignmentSourceMoveTask || m.OpenedByID == nil || *m.OpenedByID != userID {
+        t.Errorf("open source %q by %v", m.OpenSource, m.OpenedByID)
+      }
+      if task.Status != constants.MoveTaskStatusDone {
+        t.Errorf("task status %q", task.Status)
+      }
+      if len(pub.actions) != 1 || pub.actions[0] != "MOVE_TASK_COMPLETED" {
+        t.Errorf("published %v", pub.actions)
+      }
+    })
+  }
+}
@@ -0,0 +1,518 @@
+package services
+
+import (
+  "fmt"
+  "math"
+  "sort"
+  "time"
+
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+// CapacityQuery measures slot and cube utilization now and, from the slot
+// map history, at every Granularity period over [StartDate, EndDate]. The
+// projection runs HorizonDays ahead both on the utilization trend and on the
+// demand growth of ForecastRunID (the latest run when nil) for the items now
+// slotted, and dates when utilization reaches TargetPct. Decommissioned
+// locations are left out; SlotRole keeps one role only.
+type CapacityQuery struct {
+  CompanyID     uuid.UUID
+  WarehouseID   uuid.UUID
+  StartDate     time.Time
+  EndDate       time.Time
+  Granularity   string
+  SlotRole      string
+  ForecastRunID uuid.UUID
+  HorizonDays   int
+  TargetPct     float64
+}
+
+// CapacityPoint is utilization at the close of Date. Slots count the
+// locations that existed then; cube covers slots with all three dimensions.
+type CapacityPoint struct {
+  Date               time.Time `json:"date"`
+  Slots              int       `json:"slots"`
+  OccupiedSlots      int       `json:"occupied_slots"`
+  SlotUtilizationPct float64   `json:"slot_utilization_pct"`
+  CubeM3             float64   `json:"cube_m3"`
+  OccupiedCubeM3     float64   `json:"occupied_cube_m3"`
+  CubeUtilizationPct float64   `json:"cube_utilization_pct"`
+}
+
+type CapacityProjectionPoint struct {
+  Date            time.Time `json:"date"`
+  TrendSlotPct    float64   `json:"trend_slot_pct"`
+  TrendCubePct    float64   `json:"trend_cube_pct"`
+  ForecastSlotPct *float64  `json:"forecast_slot_pct"`
+  ForecastCubePct *float64  `json:"forecast_cube_pct"`
+}
+
+// CapacityProjection extends current utilization linearly by the least
+// squares trend of the history and, separately, in step with forecast
+// demand. DemandGrowth is forecast over historical daily units of the items
+// slotted; it is nil without a forecast covering them. A run-out date is
+// nil when utilization is not heading for the target.
+type CapacityProjection struct {
+  SlotPctPerDay    float64                    `json:"slot_pct_per_day"`
+  CubePctPerDay    float64                    `json:"cube_pct_per_day"`
+  DemandGrowth     *float64                   `json:"demand_growth"`
+  RunOutByTrend    *time.Time                 `json:"run_out_by_trend"`
+  RunOutByForecast *time.Time                 `json:"run_out_by_forecast"`
+  Points           []*CapacityProjectionPoint `json:"points"`
+}
+
+// CapacityTotals describe a zone or the whole warehouse. StoredCubeM3 fills
+// occupied slots by estimated on-hand over what fits of each item; FillPct is
+// it over the cube of the slots where that could be estimated, the others
+// counting as UnmeasuredSlots.
+type CapacityTotals struct {
+  Zone               string              `json:"zone,omitempty"`
+  Slots              int                 `json:"slots"`
+  OccupiedSlots      int                 `json:"occupied_slots"`
+  SlotUtilizationPct float64             `json:"slot_utilization_pct"`
+  CubeM3             float64             `json:"cube_m3"`
+  OccupiedCubeM3     float64             `json:"occupied_cube_m3"`
+  CubeUtilizationPct float64             `json:"cube_utilization_pct"`
+  UnknownCubeSlots   int                 `json:"unknown_cube_slots"`
+  StoredCubeM3       float64             `json:"stored_cube_m3"`
+  FillPct            *float64            `json:"fill_pct"`
+  UnmeasuredSlots    int                 `json:"unmeasured_slots"`
+  Trend              []*CapacityPoint    `json:"trend"`
+  Projection         *CapacityProjection `json:"projection"`
+  measuredCube       float64
+  items              map[uuid.UUID]bool
+}
+
+type CapacityReport struct {
+  WarehouseID   uuid.UUID         `json:"warehouse_id"`
+  StartDate     time.Time         `json:"start_date"`
+  EndDate       time.Time         `json:"end_date"`
+  Granularity   string            `json:"granularity"`
+  SlotRole      string            `json:"slot_role,omitempty"`
+  HorizonDays   int               `json:"horizon_days"`
+  TargetPct     float64           `json:"target_pct"`
+  ForecastRunID *uuid.UUID        `json:"forecast_run_id"`
+  Totals        *CapacityTotals   `json:"totals"`
+  ByZone        []*CapacityTotals `json:"by_zone"`
+}
+
+type CapacitySvc interface {
+  Report(q CapacityQuery) (*CapacityReport, error)
+}
+
+type capacitySvc struct {
+  lrepo           repos.LRepo
+  zrepo           repos.ZRepo
+  sarepo          repos.SlotAssignmentRepo
+  arepo           repos.AnalyticsRepo
+  fsvc            ForecastSvc
+}
+
+func NewCapacitySvc(lrepo repos.LRepo, zrepo repos.ZRepo, sarepo repos.SlotAssignmentRepo, arepo repos.AnalyticsRepo, fsvc ForecastSvc) CapacitySvc {
+  return &capacitySvc{lrepo: lrepo, zrepo: zrepo, sarepo: sarepo, arepo: arepo, fsvc: fsvc}
+}
+
+const (
+  defaultCapacityWindowDays  = 182
+  defaultCapacityHorizonDays = 182
+  maxCapacityHorizonDays     = 730
+  defaultCapacityTargetPct   = 90
+  maxCapacityRunOutDays      = 3650
+)
+
+func (s *capacitySvc) Report(q CapacityQuery) (*CapacityReport, error) {
+  if q.WarehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  if q.StartDate.IsZero() {
+    end := q.EndDate
+    if end.IsZero() {
+      end = time.Now().UTC()
+    }
+    q.StartDate = end.AddDate(0, 0, 1-defaultCapacityWindowDays)
+  }
+  start, end, err := analyticsWindow(q.StartDate, q.EndDate)
+  if err != nil {
+    return nil, err
+  }
+  if q.Granularity == "" {
+    q.Granularity = constants.ForecastGranularityWeek
+  }
+  if !constants.ForecastGranularities[q.Granularity] {
+    return nil, fmt.Errorf("invalid granularity '%s'", q.Granularity)
+  }
+  if q.SlotRole != "" && !constants.SlotRoles[q.SlotRole] {
+    return nil, fmt.Errorf("invalid slot role '%s'", q.SlotRole)
+  }
+  if q.HorizonDays == 0 {
+    q.HorizonDays = defaultCapacityHorizonDays
+  }
+  if q.HorizonDays < 0 || q.HorizonDays > maxCapacityHorizonDays {
+    return nil, fmt.Errorf("horizon must be between 1 and %d days", maxCapacityHorizonDays)
+  }
+  if q.TargetPct == 0 {
+    q.TargetPct = defaultCapacityTargetPct
+  }
+  if q.TargetPct < 0 || q.TargetPct > 100 {
+    return nil, fmt.Errorf("target must be between 0 and 100 percent")
+  }
+  report := &CapacityReport{
+    WarehouseID: q.WarehouseID,
+    StartDate:   start,
+    EndDate:     end.AddDate(0, 0, -1),
+    Granularity: q.Granularity,
+    SlotRole:    q.SlotRole,
+    HorizonDays: q.HorizonDays,
+    TargetPct:   q.TargetPct,
+    Totals:      newCapacityTotals(""),
+    ByZone:      []*CapacityTotals{},
+  }
+
+  all, err := s.lrepo.ListLocations(repos.LocationFilter{WarehouseID: q.WarehouseID})
+  if err != nil {
+    return nil, fmt.Errorf("failed to load locations: %w", err)
+  }
+  zones, err := s.zrepo.ListByWarehouse(q.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+  var locs []*models.Location
+  zoneOf := make(map[uuid.UUID]*CapacityTotals)
+  byZone := make(map[string]*CapacityTotals)
+  for _, loc := range all {
+    role := loc.SlotRole
+    if role == "" {
+      role = constants.SlotRolePick
+    }
+    if loc.Status == constants.LocationStatusDecommissioned || (q.SlotRole != "" && role != q.SlotRole) {
+      continue
+    }
+    name := zoneForPath(zones, loc.LocationPath)
+    if byZone[name] == nil {
+      byZone[name] = newCapacityTotals(name)
+      report.ByZone = append(report.ByZone, byZone[name])
+    }
+    zoneOf[loc.ID] = byZone[name]
+    locs = append(locs, loc)
+  }
+  sort.Slice(report.ByZone, func(i, j int) bool { return report.ByZone[i].Zone < report.ByZone[j].Zone })
+
+  history, err := s.sarepo.ListAssignments(repos.SlotAssignmentFilter{WarehouseID: q.WarehouseID, StartDate: start, EndDate: end})
+  if err != nil {
+    return nil, fmt.Errorf("failed to load slot assignments: %w", err)
+  }
+  var assignments []*models.SlotAssignment
+  for _, a := range history {
+    if a.LocationID != nil && zoneOf[*a.LocationID] != nil {
+      assignments = append(assignments, a)
+    }
+  }
+
+  // utilization at the close of each period, the last one ending the window
+  step := forecastPeriodDays(q.Granularity)
+  var samples []time.Time
+  for at := start.AddDate(0, 0, step); at.Before(end); at = at.AddDate(0, 0, step) {
+    samples = append(samples, at)
+  }
+  samples = append(samples, end)
+  for _, at := range samples {
+    occupied := make(map[uuid.UUID]bool)
+    for _, a := range assignments {
+      if a.EffectiveFrom.Before(at) && (a.EffectiveTo == nil || a.EffectiveTo.After(at)) {
+        occupied[*a.LocationID] = true
+      }
+    }
+    day := at.AddDate(0, 0, -1)
+    total := &CapacityPoint{Date: day}
+    points := make(map[*CapacityTotals]*CapacityPoint, len(report.ByZone))
+    for _, z := range report.ByZone {
+      points[z] = &CapacityPoint{Date: day}
+    }
+    for _, loc := range locs {
+      if !loc.CreatedAt.Before(at) {
+        continue
+      }
+      for _, p := range []*CapacityPoint{total, points[zoneOf[loc.ID]]} {
+        p.add(loc, occupied[loc.ID])
+      }
+    }
+    report.Totals.Trend = append(report.Totals.Trend, total.finish())
+    for _, z := range report.ByZone {
+      z.Trend = append(z.Trend, points[z].finish())
+    }
+  }
+
+  // the slot map now, with the estimated stock held in it
+  flows, err := s.arepo.LocationItemUnits(repos.AnalyticsFilter{CompanyID: q.CompanyID, WarehouseID: q.WarehouseID})
+  if err != nil {
+    return nil, err
+  }
+  stock := netOnHand(flows)
+  held := make(map[uuid.UUID][]*models.Item)
+  for _, a := range assignments {
+    if a.EffectiveTo == nil && a.Item != nil {
+      held[*a.LocationID] = append(held[*a.LocationID], a.Item)
+    }
+  }
+  for _, loc := range locs {
+    items := held[loc.ID]
+    for _, t := range []*CapacityTotals{report.Totals, zoneOf[loc.ID]} {
+      t.addSlot(loc, items, stock[loc.ID])
+    }
+  }
+
+  growth, err := s.demandGrowth(q, report)
+  if err != nil {
+    return nil, err
+  }
+  today := report.EndDate
+  for _, t := range append([]*CapacityTotals{report.Totals}, report.ByZone...) {
+    t.finish()
+    t.Projection = projectCapacity(t, growth(t.items), today, step, q.HorizonDays, q.TargetPct)
+  }
+  return report, nil
+}
+
+// demandGrowth picks the forecast run and returns, per set of items, their
+// forecast daily units over historical ones spread evenly across the days
+// from the middle of the run's history to the middle of its horizon.
+func (s *capacitySvc) demandGrowth(q CapacityQuery, report *CapacityReport) (func(items map[uuid.UUID]bool) *capacityGrowth, error) {
+  none := func(map[uuid.UUID]bool) *capacityGrowth { return nil }
+  var run *models.ForecastRun
+  if q.ForecastRunID != uuid.Nil {
+    r, err := s.fsvc.GetRunByID(q.ForecastRunID)
+    if err != nil {
+      return nil, err
+    }
+    if r.WarehouseID == nil || *r.WarehouseID != q.WarehouseID {
+      return nil, fmt.Errorf("forecast run belongs to another warehouse")
+    }
+    run = r
+  } else {
+    runs, err := s.fsvc.ListRuns(repos.ForecastRunFilter{WarehouseID: q.WarehouseID})
+    if err != nil {
+      return nil, err
+    }
+    if len(runs) == 0 {
+      return none, nil
+    }
+    run = runs[0]
+  }
+  report.ForecastRunID = &run.ID
+  forecasts, err := s.fsvc.ListItems(repos.ItemForecastFilter{RunID: run.ID})
+  if err != nil {
+    return nil, err
+  }
+  historyDays := run.EndDate.Sub(run.StartDate).Hours()/24 + 1
+  horizonDays := float64(run.Horizon * forecastPeriodDays(run.Granularity))
+  if historyDays <= 0 || horizonDays <= 0 {
+    return none, nil
+  }
+  byItem := make(map[uuid.UUID]*models.ItemForecast, len(forecasts))
+  for _, f := range forecasts {
+    byItem[*f.ItemID] = f
+  }
+  span := (historyDays + horizonDays) / 2
+  return func(items map[uuid.UUID]bool) *capacityGrowth {
+    var history, forecast float64
+    for itemID := range items {
+      if f, ok := byItem[itemID]; ok {
+        history += float64(f.HistoryUnits) / historyDays
+        forecast += f.ForecastUnits / horizonDays
+      }
+    }
+    if history <= 0 {
+      return nil
+    }
+    ratio := forecast / history
+    return &capacityGrowth{ratio: ratio, perDay: (ratio - 1) / span}
+  }, nil
+}
+
+type capacityGrowth struct {
+  ratio  float64
+  perDay float64 // relative change in demand per day
+}
+
+func newCapacityTotals(zone string) *CapacityTotals {
+  return &CapacityTotals{Zone: zone, Trend: []*CapacityPoint{}, items: make(map[uuid.UUID]bool)}
+}
+
+// slotCubeM3 is the slot's volume, 0 when a dimension is unknown.
+func slotCubeM3(loc *models.Location) float64 {
+  if loc.SlotWidthCm <= 0 || loc.SlotDepthCm <= 0 || loc.SlotHeightCm <= 0 {
+    return 0
+  }
+  return loc.SlotWidthCm * loc.SlotDepthCm * loc.SlotHeightCm / 1e6
+}
+
+func (p *CapacityPoint) add(loc *models.Location, occupied bool) {
+  cube := slotCubeM3(loc)
+  p.Slots++
+  p.CubeM3 += cube
+  if occupied {
+    p.OccupiedSlots++
+    p.OccupiedCubeM3 += cube
+  }
+}
+
+func (p *CapacityPoint) finish() *CapacityPoint {
+  p.SlotUtilizationPct = pct(float64(p.OccupiedSlots), float64(p.Slots))
+  p.CubeUtilizationPct = pct(p.OccupiedCubeM3, p.CubeM3)
+  return p
+}
+
+// addSlot counts loc with the items slotted there. The slot is filled by the
+// share of each item's fit the on-hand takes up; it stays unmeasured when an
+// item in stock has no fit against its dimensions.
+func (t *CapacityTotals) addSlot(loc *models.Location, items []*models.Item, onHand map[uuid.UUID]int64) {
+  cube := slotCubeM3(loc)
+  t.Slots++
+  t.CubeM3 += cube
+  if cube == 0 {
+    t.UnknownCubeSlots++
+  }
+  if len(items) == 0 {
+    return
+  }
+  t.OccupiedSlots++
+  t.OccupiedCubeM3 += cube
+  for _, item := range items {
+    t.items[item.ID] = true
+  }
+  fill := 0.0
+  for _, item := range items {
+    n := onHand[item.ID]
+    if n == 0 {
+      continue
+    }
+    capacity, ok := slotCapacity(loc, item)
+    if !ok || capacity == 0 {
+      t.UnmeasuredSlots++
+      return
+    }
+    fill += float64(n) / float64(capacity)
+  }
+  if cube == 0 {
+    t.UnmeasuredSlots++
+    return
+  }
+  t.StoredCubeM3 += min(fill, 1) * cube
+  t.measuredCube += cube
+}
+
+func (t *CapacityTotals) finish() {
+  t.SlotUtilizationPct = pct(float64(t.OccupiedSlots), float64(t.Slots))
+  t.CubeUtilizationPct = pct(t.OccupiedCubeM3, t.CubeM3)
+  if t.measuredCube > 0 {
+    fill := 100 * t.StoredCubeM3 / t.measuredCube
+    t.FillPct = &fill
+  }
+}
+
+func pct(part, whole float64) float64 {
+  if whole <= 0 {
+    return 0
+  }
+  return 100 * part / whole
+}
+
+// projectCapacity projects t from today in step day periods over horizon days.
+func projectCapacity(t *CapacityTotals, growth *capacityGrowth, today time.Time, step, horizon int, target float64) *CapacityProjection {
+  p := &CapacityProjection{Points: []*CapacityProjectionPoint{}}
+  p.SlotPctPerDay, p.CubePctPerDay = capacitySlopes(t.Trend)
+  hasCube := t.CubeM3 > 0
+  p.RunOutByTrend = earliestRunOut(today, target,
+    runOutCandidate{t.SlotUtilizationPct, p.SlotPctPerDay, true},
+    runOutCandidate{t.CubeUtilizationPct, p.CubePctPerDay, hasCube})
+  if growth != nil {
+    ratio := growth.ratio
+    p.DemandGrowth = &ratio
+    p.RunOutByForecast = earliestRunOut(today, target,
+      runOutCandidate{t.SlotUtilizationPct, t.SlotUtilizationPct * growth.perDay, true},
+      runOutCandidate{t.CubeUtilizationPct, t.CubeUtilizationPct * growth.perDay, hasCube})
+  }
+  for d := step; d <= horizon; d += step {
+    days := float64(d)
+    pt := &CapacityProjectionPoint{
+      Date:         today.AddDate(0, 0, d),
+      TrendSlotPct: max(t.SlotUtilizationPct+p.SlotPctPerDay*days, 0),
+      TrendCubePct: max(t.CubeUtilizationPct+p.CubePctPerDay*days, 0),
+    }
+    if growth != nil {
+      scale := max(1+growth.perDay*days, 0)
+      slot, cube := t.SlotUtilizationPct*scale, t.CubeUtilizationPct*scale
+      pt.ForecastSlotPct, pt.ForecastCubePct = &slot, &cube
+    }
+    p.Points = append(p.Points, pt)
+  }
+  return p
+}
+
+// capacitySlopes fits slot and cube utilization of the trend to a least
+// squares line, in percentage points per day.
+func capacitySlopes(trend []*CapacityPoint) (float64, float64) {
+  if len(trend) < 2 {
+    return 0, 0
+  }
+  n := float64(len(trend))
+  var sx, sy, sz float64
+  xs := make([]float64, len(trend))
+  for i, p := range trend {
+    xs[i] = p.Date.Sub(trend[0].Date).Hours() / 24
+    sx += xs[i]
+    sy += p.SlotUtilizationPct
+    sz += p.CubeUtilizationPct
+  }
+  mx, my, mz := sx/n, sy/n, sz/n
+  var sxx, sxy, sxz float64
+  for i, p := range trend {
+    dx := xs[i] - mx
+    sxx += dx * dx
+    sxy += dx * (p.SlotUtilizationPct - my)
+    sxz += dx * (p.CubeUtilizationPct - mz)
+  }
+  if sxx == 0 {
+    return 0, 0
+  }
+  return sxy / sxx, sxz / sxx
+}
+
+type runOutCandidate struct {
+  current float64
+  perDay  float64
+  known   bool
+}
+
+// earliestRunOut dates when the first of the candidates reaches target, nil
+// when none does within maxCapacityRunOutDays.
+func earliestRunOut(today time.Time, target float64, candidates ...runOutCandidate) *time.Time {
+  best := -1
+  for _, c := range candidates {
+    if !c.known {
+      continue
+    }
+    days := 0
+    if c.current < target {
+      if c.perDay <= 0 {
+        continue
+      }
+      need := (target - c.current) / c.perDay
+      if need > maxCapacityRunOutDays {
+        continue
+      }
+      days = int(math.Ceil(need))
+    }
+    if best < 0 || days < best {
+      best = days
+    }
+  }
+  if best < 0 {
+    return nil
+  }
+  at := today.AddDate(0, 0, best)
+  return &at
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+  "math"
+  "testing"
+  "time"
+)
+
+func TestCapacitySlopes(t *testing.T) {
+  start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+  point := func(day int, slot, cube float64) *CapacityPoint {
+    return &CapacityPoint{Date: start.AddDate(0, 0, day), SlotUtilizationPct: slot, CubeUtilizationPct: cube}
+  }
+  tests := []struct {
+    name               string
+    trend              []*CapacityPoint
+    wantSlot, wantCube float64
+  }{
+    {name: "empty"},
+    {name: "single point", trend: []*CapacityPoint{point(0, 50, 40)}},
+    {
+      name:     "straight lines",
+      trend:    []*CapacityPoint{point(0, 50, 40), point(1, 51, 39.5), point(2, 52, 39)},
+      wantSlot: 1,
+      wantCube: -0.5,
+    },
+    {
+      name:     "uneven spacing",
+      trend:    []*CapacityPoint{point(0, 50, 40), point(7, 57, 40), point(14, 64, 40)},
+      wantSlot: 1,
+    },
+    {
+      // deviations from the means are x -1.5, -.5, .5, 1.5 and y -2, 0, -1, 3: 7 / 5
+      name:     "noisy",
+      trend:    []*CapacityPoint{point(0, 60, 30), point(1, 62, 30), point(2, 61, 30), point(3, 65, 30)},
+      wantSlot: 1.4,
+    },
+    {name: "same day", trend: []*CapacityPoint{point(0, 50, 40), point(0, 60, 50)}},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      slot, cube := capacitySlopes(tt.trend)
+      if math.Abs(slot-tt.wantSlot) > 1e-9 || math.Abs(cube-tt.wantCube) > 1e-9 {
+        t.Errorf("capacitySlopes = %v, %v, want %v, %v", slot, cube, tt.wantSlot, tt.wantCube)
+      }
+    })
+  }
+}
+
+func TestEarliestRunOut(t *testing.T) {
+  today := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+  tests := []struct {
+    name       string
+    candidates []runOutCandidate
+    wantDays   int // -1 when it never runs out
+  }{
+    {name: "no candidates", wantDays: -1},
+    {name: "exact days", candidates: []runOutCandidate{{current: 80, perDay: 2, known: true}}, wantDays: 5},
+    {name: "part day rounds up", candidates: []runOutCandidate{{current: 80, perDay: 3, known: true}}, wantDays: 4},
+    {name: "already full", candidates: []runOutCandidate{{current: 95, perDay: -1, known: true}}, wantDays: 0},
+    {name: "shrinking", candidates: []runOutCandidate{{current: 80, perDay: -1, known: true}}, wantDays: -1},
+    {name: "flat", candidates: []runOutCandidate{{current: 80, known: true}}, wantDays: -1},
+    {name: "beyond the cap", candidates: []runOutCandidate{{current: 10, perDay: 0.01, known: true}}, wantDays: -1},
+    {name: "unknown is ignored", candidates: []runOutCandidate{{current: 89, perDay: 1}}, wantDays: -1},
+    {
+      name: "earliest candidate wins",
+      candidates: []runOutCandidate{
+        {current: 60, perDay: 1, known: true},
+        {current: 70, perDay: 5, known: true},
+        {current: 89, perDay: 10},
+      },
+      wantDays: 4,
+    },
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      got := earliestRunOut(today, 90, tt.candidates...)
+      if tt.wantDays < 0 {
+        if got != nil {
+          t.Errorf("earliestRunOut = %v, want nil", got)
+        }
+        return
+      }
+      if want := today.AddDate(0, 0, tt.wantDays); got == nil || !got.Equal(want) {
+        t.Errorf("earliestRunOut = %v, want %v", got, want)
+      }
+    })
+  }
+}
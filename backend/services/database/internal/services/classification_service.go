@@ -0,0 +1,405 @@
+package services
+
+import (
+  "fmt"
+  "math"
+  "sort"
+  "strings"
+  "time"
+
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+// ClassificationParams configures a run. Cutoffs are the A, B and C shares of
+// the basis total in percent and must add up to 100; an item is X when the
+// coefficient of variation of its daily units is at most XMaxCV, Y up to
+// YMaxCV and Z above that. With ForecastRunID the run's forecast over its
+// horizon stands in for history and the window and types come from it.
+type ClassificationParams struct {
+  CompanyID        uuid.UUID
+  WarehouseID      uuid.UUID
+  CreatedByID      uuid.UUID
+  Basis            string
+  Cutoffs          []float64
+  XMaxCV           float64
+  YMaxCV           float64
+  StartDate        time.Time
+  EndDate          time.Time
+  TransactionTypes []string
+  ForecastRunID    uuid.UUID
+}
+
+type ClassChange struct {
+  ItemID   uuid.UUID `json:"item_id"`
+  ItemName string    `json:"item_name"`
+  FromABC  string    `json:"from_abc"`
+  ToABC    string    `json:"to_abc"`
+  FromXYZ  string    `json:"from_xyz"`
+  ToXYZ    string    `json:"to_xyz"`
+}
+
+// ClassChangeReport compares two runs of the same warehouse. The matrices
+// count items by from-class -> to-class; "-" marks an item missing from a run.
+type ClassChangeReport struct {
+  FromRun   *models.ClassificationRun `json:"from_run"`
+  ToRun     *models.ClassificationRun `json:"to_run"`
+  ABCMatrix map[string]map[string]int `json:"abc_matrix"`
+  XYZMatrix map[string]map[string]int `json:"xyz_matrix"`
+  Changes   []ClassChange             `json:"changes"`
+}
+
+type ClassificationSvc interface {
+  Classify(p ClassificationParams) (*models.ClassificationRun, []*models.ItemClassification, error)
+  GetRunByID(runID uuid.UUID) (*models.ClassificationRun, error)
+  LatestRun(warehouseID uuid.UUID) (*models.ClassificationRun, error)
+  DeleteRun(runID uuid.UUID) error
+  ListRuns(f repos.ClassificationRunFilter) ([]*models.ClassificationRun, error)
+  ListItems(f repos.ItemClassificationFilter) ([]*models.ItemClassification, error)
+  CompareRuns(fromRunID, toRunID uuid.UUID) (*ClassChangeReport, error)
+}
+
+type classificationSvc struct {
+  repo            repos.ClassificationRepo
+  arepo           repos.AnalyticsRepo
+  fsvc            ForecastSvc
+}
+
+func NewClassificationSvc(repo repos.ClassificationRepo, arepo repos.AnalyticsRepo, fsvc ForecastSvc) ClassificationSvc {
+  return &classificationSvc{repo: repo, arepo: arepo, fsvc: fsvc}
+}
+
+var defaultABCCutoffs = []float64{80, 15, 5}
+
+const (
+  defaultXMaxCV = 0.5
+  defaultYMaxCV = 1.0
+)
+
+func (s *classificationSvc) Classify(p ClassificationParams) (*models.ClassificationRun, []*models.ItemClassification, error) {
+  if p.CompanyID == uuid.Nil || p.WarehouseID == uuid.Nil {
+    return nil, nil, fmt.Errorf("invalid warehouse")
+  }
+  if err := normalizeClassificationParams(&p); err != nil {
+    return nil, nil, err
+  }
+  start, end, err := analyticsWindow(p.StartDate, p.EndDate)
+  if err != nil {
+    return nil, nil, err
+  }
+  types, err := normalizeTypeFilter(p.TransactionTypes)
+  if err != nil {
+    return nil, nil, err
+  }
+  f := repos.AnalyticsFilter{CompanyID: p.CompanyID, WarehouseID: p.WarehouseID, StartDate: start, EndDate: end}
+  if f.RawTypes, err = rawTypesFor(s.arepo, f, types); err != nil {
+    return nil, nil, err
+  }
+
+  var items []*models.ItemClassification
+  var forecastRunID *uuid.UUID
+  if p.ForecastRunID != uuid.Nil {
+    fr, err := s.fsvc.GetRunByID(p.ForecastRunID)
+    if err != nil {
+      return nil, nil, err
+    }
+    if fr.WarehouseID == nil || *fr.WarehouseID != p.WarehouseID {
+      return nil, nil, fmt.Errorf("forecast run belongs to another warehouse")
+    }
+    forecasts, err := s.fsvc.ListItems(repos.ItemForecastFilter{RunID: fr.ID})
+    if err != nil {
+      return nil, nil, err
+    }
+    days := fr.Horizon * forecastPeriodDays(fr.Granularity)
+    start = fr.EndDate.AddDate(0, 0, 1)
+    end = start.AddDate(0, 0, days)
+    types = nil
+    if fr.TransactionTypes != "" {
+      types = strings.Split(fr.TransactionTypes, ",")
+    }
+    summaries := make([]repos.ItemSummary, 0, len(forecasts))
+    demand := make(map[uuid.UUID]*itemDemand, len(forecasts))
+    for _, it := range forecasts {
+      sum := repos.ItemSummary{ItemID: *it.ItemID}
+      if it.Item != nil {
+        sum.ItemName = it.Item.Name
+        sum.CubeCm3 = it.Item.LengthCm * it.Item.WidthCm * it.Item.HeightCm
+      }
+      summaries = append(summaries, sum)
+      demand[*it.ItemID] = &itemDemand{lines: it.ForecastLines, units: it.ForecastUnits, daily: forecastDailyUnits(fr, it)}
+    }
+    items = classifyDemand(p, summaries, demand, float64(days))
+    forecastRunID = &fr.ID
+  } else if len(f.RawTypes) > 0 {
+    activity, err := s.arepo.ItemDailyActivity(f)
+    if err != nil {
+      return nil, nil, err
+    }
+    summaries, err := s.arepo.ItemSummaries(f)
+    if err != nil {
+      return nil, nil, err
+    }
+    days := end.Sub(start).Hours() / 24
+    items = classifyItems(p, summaries, activity, days)
+  }
+
+  run := models.ClassificationRun{
+    CompanyID:        &p.CompanyID,
+    WarehouseID:      &p.WarehouseID,
+    Basis:            p.Basis,
+    TransactionTypes: strings.Join(types, ","),
+    ACutoff:          p.Cutoffs[0],
+    BCutoff:          p.Cutoffs[1],
+    CCutoff:          p.Cutoffs[2],
+    XMaxCV:           p.XMaxCV,
+    YMaxCV:           p.YMaxCV,
+    StartDate:        start,
+    EndDate:          end.AddDate(0, 0, -1),
+    ItemCount:        len(items),
+    ForecastRunID:    forecastRunID,
+  }
+  if p.CreatedByID != uuid.Nil {
+    run.CreatedByID = &p.CreatedByID
+  }
+  for _, it := range items {
+    it.CompanyID = &p.CompanyID
+    it.WarehouseID = &p.WarehouseID
+  }
+  created, err := s.repo.CreateRun(run, items)
+  if err != nil {
+    return nil, nil, err
+  }
+  return created, items, nil
+}
+
+func (s *classificationSvc) GetRunByID(runID uuid.UUID) (*models.ClassificationRun, error) {
+  if runID == uuid.Nil {
+    return nil, fmt.Errorf("invalid runID")
+  }
+  return s.repo.GetRunByID(runID)
+}
+
+func (s *classificationSvc) LatestRun(warehouseID uuid.UUID) (*models.ClassificationRun, error) {
+  if warehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  return s.repo.LatestRun(warehouseID)
+}
+
+func (s *classificationSvc) DeleteRun(runID uuid.UUID) error {
+  if runID == uuid.Nil {
+    return fmt.Errorf("invalid runID")
+  }
+  return s.repo.DeleteRun(runID)
+}
+
+func (s *classificationSvc) ListRuns(f repos.ClassificationRunFilter) ([]*models.ClassificationRun, error) {
+  return s.repo.ListRuns(f)
+}
+
+func (s *classificationSvc) ListItems(f repos.ItemClassificationFilter) ([]*models.ItemClassification, error) {
+  if f.RunID == uuid.Nil {
+    return nil, fmt.Errorf("invalid runID")
+  }
+  return s.repo.ListItems(f)
+}
+
+func (s *classificationSvc) CompareRuns(fromRunID, toRunID uuid.UUID) (*ClassChangeReport, error) {
+  from, err := s.GetRunByID(fromRunID)
+  if err != nil {
+    return nil, err
+  }
+  to, err := s.GetRunByID(toRunID)
+  if err != nil {
+    return nil, err
+  }
+  if from.WarehouseID == nil || to.WarehouseID == nil || *from.WarehouseID != *to.WarehouseID {
+    return nil, fmt.Errorf("runs belong to different warehouses")
+  }
+  fromItems, err := s.repo.ListItems(repos.ItemClassificationFilter{RunID: from.ID})
+  if err != nil {
+    return nil, err
+  }
+  toItems, err := s.repo.ListItems(repos.ItemClassificationFilter{RunID: to.ID})
+  if err != nil {
+    return nil, err
+  }
+
+  type pair struct{ from, to *models.ItemClassification }
+  pairs := make(map[uuid.UUID]*pair)
+  for _, it := range fromItems {
+    pairs[*it.ItemID] = &pair{from: it}
+  }
+  for _, it := range toItems {
+    if p, ok := pairs[*it.ItemID]; ok {
+      p.to = it
+    } else {
+      pairs[*it.ItemID] = &pair{to: it}
+    }
+  }
+
+  report := &ClassChangeReport{
+    FromRun:   from,
+    ToRun:     to,
+    ABCMatrix: make(map[string]map[string]int),
+    XYZMatrix: make(map[string]map[string]int),
+    Changes:   []ClassChange{},
+  }
+  count := func(m map[string]map[string]int, a, b string) {
+    if m[a] == nil {
+      m[a] = make(map[string]int)
+    }
+    m[a][b]++
+  }
+  for id, p := range pairs {
+    c := ClassChange{ItemID: id, FromABC: "-", ToABC: "-", FromXYZ: "-", ToXYZ: "-"}
+    if p.from != nil {
+      c.FromABC, c.FromXYZ = p.from.ABCClass, p.from.XYZClass
+      if p.from.Item != nil {
+        c.ItemName = p.from.Item.Name
+      }
+    }
+    if p.to != nil {
+      c.ToABC, c.ToXYZ = p.to.ABCClass, p.to.XYZClass
+      if p.to.Item != nil {
+        c.ItemName = p.to.Item.Name
+      }
+    }
+    count(report.ABCMatrix, c.FromABC, c.ToABC)
+    count(report.XYZMatrix, c.FromXYZ, c.ToXYZ)
+    if c.FromABC != c.ToABC || c.FromXYZ != c.ToXYZ {
+      report.Changes = append(report.Changes, c)
+    }
+  }
+  sort.Slice(report.Changes, func(i, j int) bool { return report.Changes[i].ItemName < report.Changes[j].ItemName })
+  return report, nil
+}
+
+func normalizeClassificationParams(p *ClassificationParams) error {
+  if p.Basis == "" {
+    p.Basis = constants.ABCBasisLines
+  }
+  if !constants.ABCBases[p.Basis] {
+    return fmt.Errorf("invalid classification basis '%s'", p.Basis)
+  }
+  if len(p.Cutoffs) == 0 {
+    p.Cutoffs = defaultABCCutoffs
+  }
+  if len(p.Cutoffs) != 3 {
+    return fmt.Errorf("cutoffs must have exactly three values (A, B, C)")
+  }
+  total := 0.0
+  for _, c := range p.Cutoffs {
+    if c <= 0 {
+      return fmt.Errorf("cutoffs must be positive")
+    }
+    total += c
+  }
+  if math.Abs(total-100) > 0.01 {
+    return fmt.Errorf("cutoffs must add up to 100, got %.2f", total)
+  }
+  if p.XMaxCV == 0 {
+    p.XMaxCV = defaultXMaxCV
+  }
+  if p.YMaxCV == 0 {
+    p.YMaxCV = defaultYMaxCV
+  }
+  if p.XMaxCV < 0 || p.YMaxCV <= p.XMaxCV {
+    return fmt.Errorf("XYZ thresholds must satisfy 0 <= x_max_cv < y_max_cv")
+  }
+  return nil
+}
+
+// classifyItems ranks items by their basis value for ABC and measures daily
+// demand variability over the whole window (idle days count as zero) for XYZ.
+func classifyItems(p ClassificationParams, summaries []repos.ItemSummary, activity []repos.ItemDailyActivity, days float64) []*models.ItemClassification {
+  demand := make(map[uuid.UUID]*itemDemand)
+  for _, a := range activity {
+    x, ok := demand[a.ItemID]
+    if !ok {
+      x = &itemDemand{daily: make(map[time.Time]float64)}
+      demand[a.ItemID] = x
+    }
+    x.lines += float64(a.Lines)
+    x.units += float64(a.Units)
+    x.daily[a.Day] += float64(a.Units)
+  }
+  return classifyDemand(p, summaries, demand, days)
+}
+
+// itemDemand is an item's lines and units over the window and its units by
+// day; forecasts make them fractional.
+type itemDemand struct {
+  lines, units float64
+  daily        map[time.Time]float64
+}
+
+func classifyDemand(p ClassificationParams, summaries []repos.ItemSummary, demand map[uuid.UUID]*itemDemand, days float64) []*models.ItemClassification {
+
+  items := make([]*models.ItemClassification, 0, len(summaries))
+  total := 0.0
+  for _, sum := range summaries {
+    x := demand[sum.ItemID]
+    if x == nil {
+      continue
+    }
+    itemID := sum.ItemID
+    it := &models.ItemClassification{ItemID: &itemID, Lines: int64(math.Round(x.lines)), Units: int64(math.Round(x.units))}
+    switch p.Basis {
+    case constants.ABCBasisUnits:
+      it.Value = x.units
+    case constants.ABCBasisCube:
+      it.Value = x.units * sum.CubeCm3
+    default:
+      it.Value = x.lines
+    }
+    total += it.Value
+
+    mean := x.units / days
+    sq := 0.0
+    for _, u := range x.daily {
+      sq += u * u
+    }
+    if variance := sq/days - mean*mean; mean > 0 && variance > 0 {
+      it.CV = math.Sqrt(variance) / mean
+    }
+    it.MeanDailyUnits = mean
+    switch {
+    case mean <= 0:
+      it.XYZClass = constants.XYZClassZ
+    case it.CV <= p.XMaxCV:
+      it.XYZClass = constants.XYZClassX
+    case it.CV <= p.YMaxCV:
+      it.XYZClass = constants.XYZClassY
+    default:
+      it.XYZClass = constants.XYZClassZ
+    }
+    items = append(items, it)
+  }
+
+  sort.SliceStable(items, func(i, j int) bool { return items[i].Value > items[j].Value })
+  cum := 0.0
+  for _, it := range items {
+    // classed by the share covered before the item, so a single dominant
+    // item still lands in A
+    before := cum
+    if total > 0 {
+      it.Share = it.Value / total * 100
+    }
+    cum += it.Share
+    it.CumulativeShare = cum
+    switch {
+    case it.Value <= 0:
+      it.ABCClass = constants.ABCClassC
+    case before < p.Cutoffs[0]:
+      it.ABCClass = constants.ABCClassA
+    case before < p.Cutoffs[0]+p.Cutoffs[1]:
+      it.ABCClass = constants.ABCClassB
+    default:
+      it.ABCClass = constants.ABCClassC
+    }
+  }
+  return items
+}
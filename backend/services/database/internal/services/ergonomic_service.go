@@ -0,0 +1,475 @@
+package services
+
+import (
+  "fmt"
+  "math"
+  "sort"
+  "time"
+
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+// ErgonomicQuery scores the current slot map against the pick frequency of
+// TransactionTypes (default pick) over [StartDate, EndDate]. An item's lines
+// are split evenly over its active pick slots, over all its slots when it
+// has none. The filters and paging apply to the item rows only.
+type ErgonomicQuery struct {
+  CompanyID        uuid.UUID
+  WarehouseID      uuid.UUID
+  StartDate        time.Time
+  EndDate          time.Time
+  TransactionTypes []string
+  HeavyFastOnly    bool // heavy fast movers only
+  AwkwardOnly      bool // items with lines below knee or above shoulder only
+  SortField        string
+  SortDir          string
+  Limit            int
+  Offset           int
+}
+
+type ErgonomicSlot struct {
+  LocationID   uuid.UUID `json:"location_id"`
+  LocationPath string    `json:"location_path"`
+  Zone         string    `json:"zone"`
+  Band         string    `json:"band"`
+  Score        float64   `json:"score"`
+  Lines        float64   `json:"lines"`
+}
+
+type ErgonomicItem struct {
+  ItemID       uuid.UUID        `json:"item_id"`
+  ItemName     string           `json:"item_name"`
+  UnitWeightKg float64          `json:"unit_weight_kg"`
+  Lines        int64            `json:"lines"`
+  Units        int64            `json:"units"`
+  Heavy        bool             `json:"heavy"`
+  FastMover    bool             `json:"fast_mover"`
+  Score        float64          `json:"score"` // pick line weighted slot score
+  AwkwardLines float64          `json:"awkward_lines"`
+  Slots        []*ErgonomicSlot `json:"slots"`
+}
+
+// ErgonomicTotals are pick line weighted over the slots of a zone or of the
+// whole slot map. Score is nil without lines.
+type ErgonomicTotals struct {
+  Zone                  string             `json:"zone,omitempty"`
+  Slots                 int                `json:"slots"`
+  Lines                 float64            `json:"lines"`
+  Units                 float64            `json:"units"`
+  Score                 *float64           `json:"score"`
+  LinesByBand           map[string]float64 `json:"lines_by_band"`
+  AwkwardLines          float64            `json:"awkward_lines"`
+  HeavyFastAwkwardLines float64            `json:"heavy_fast_awkward_lines"`
+  HeavyFastAwkwardItems int                `json:"heavy_fast_awkward_items"`
+  weighted              float64
+}
+
+type ErgonomicReport struct {
+  WarehouseID      uuid.UUID              `json:"warehouse_id"`
+  StartDate        time.Time              `json:"start_date"`
+  EndDate          time.Time              `json:"end_date"`
+  TransactionTypes []string               `json:"transaction_types"`
+  Model            *models.ErgonomicModel `json:"model"`
+  Totals           *ErgonomicTotals       `json:"totals"`
+  UnslottedItems   int                    `json:"unslotted_items"`
+  UnslottedLines   int64                  `json:"unslotted_lines"`
+  ByZone           []*ErgonomicTotals     `json:"by_zone"`
+  TotalItems       int                    `json:"total_items"`
+  Items            []*ErgonomicItem       `json:"items"`
+}
+
+type ErgonomicSvc interface {
+  // GetModel returns the warehouse's ergonomic model, the defaults (with a
+  // nil ID) when it has none.
+  GetModel(warehouseID uuid.UUID) (*models.ErgonomicModel, error)
+  SetModel(m *models.ErgonomicModel) error
+  // ResetModel drops the warehouse's model, returning it to the defaults.
+  ResetModel(warehouseID uuid.UUID) error
+  Report(q ErgonomicQuery) (*ErgonomicReport, error)
+}
+
+type ergonomicSvc struct {
+  repo            repos.ErgonomicRepo
+  arepo           repos.AnalyticsRepo
+  lrepo           repos.LRepo
+  irepo           repos.IRepo
+  zrepo           repos.ZRepo
+}
+
+func NewErgonomicSvc(repo repos.ErgonomicRepo, arepo repos.AnalyticsRepo, lrepo repos.LRepo, irepo repos.IRepo, zrepo repos.ZRepo) ErgonomicSvc {
+  return &ergonomicSvc{repo: repo, arepo: arepo, lrepo: lrepo, irepo: irepo, zrepo: zrepo}
+}
+
+// defaultErgonomicModel favours waist to shoulder height and shallow slots,
+// the golden zone bounds being constants.GoldenZoneMinCm and MaxCm.
+func defaultErgonomicModel(warehouseID uuid.UUID) *models.ErgonomicModel {
+  return &models.ErgonomicModel{
+    WarehouseID:        &warehouseID,
+    KneeHeightCm:       50,
+    WaistHeightCm:      constants.GoldenZoneMinCm,
+    ShoulderHeightCm:   constants.GoldenZoneMaxCm,
+    BelowKneeScore:     0.4,
+    KneeToWaistScore:   0.8,
+    GoldenScore:        1,
+    AboveShoulderScore: 0.5,
+    ReachDepthCm:       60,
+    MaxReachDepthCm:    120,
+    DeepReachScore:     0.5,
+    HeightWeight:       0.75,
+    ReachWeight:        0.25,
+    HeavyItemKg:        10,
+    FastMoverShare:     0.2,
+  }
+}
+
+func (s *ergonomicSvc) GetModel(warehouseID uuid.UUID) (*models.ErgonomicModel, error) {
+  if warehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  m, err := s.repo.GetByWarehouse(warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  if m == nil {
+    return defaultErgonomicModel(warehouseID), nil
+  }
+  return m, nil
+}
+
+func (s *ergonomicSvc) SetModel(m *models.ErgonomicModel) error {
+  if m.CompanyID == nil || m.WarehouseID == nil {
+    return fmt.Errorf("invalid warehouse")
+  }
+  if m.KneeHeightCm <= 0 || m.WaistHeightCm <= m.KneeHeightCm || m.ShoulderHeightCm <= m.WaistHeightCm {
+    return fmt.Errorf("heights must rise from knee to waist to shoulder")
+  }
+  for _, score := range []float64{m.BelowKneeScore, m.KneeToWaistScore, m.GoldenScore, m.AboveShoulderScore, m.DeepReachScore} {
+    if score < 0 || score > 1 {
+      return fmt.Errorf("scores must be between 0 and 1")
+    }
+  }
+  if m.ReachDepthCm <= 0 || m.MaxReachDepthCm <= m.ReachDepthCm {
+    return fmt.Errorf("max reach depth must exceed a positive reach depth")
+  }
+  if m.HeightWeight < 0 || m.ReachWeight < 0 {
+    return fmt.Errorf("weights cannot be negative")
+  }
+  if m.HeightWeight == 0 && m.ReachWeight == 0 {
+    return fmt.Errorf("height and reach weight cannot both be zero")
+  }
+  if m.HeavyItemKg <= 0 {
+    return fmt.Errorf("heavy item weight must be positive")
+  }
+  if m.FastMoverShare <= 0 || m.FastMoverShare > 1 {
+    return fmt.Errorf("fast mover share must be above 0 and at most 1")
+  }
+  return s.repo.Upsert(m)
+}
+
+func (s *ergonomicSvc) ResetModel(warehouseID uuid.UUID) error {
+  if warehouseID == uuid.Nil {
+    return fmt.Errorf("invalid warehouseID")
+  }
+  return s.repo.Delete(warehouseID)
+}
+
+// ergonomicBand places the slot floor in a height band of m. Without a
+// height the Level=<n> segment of the name path stands in, level 2 being
+// waist high.
+func ergonomicBand(m *models.ErgonomicModel, loc *models.Location) string {
+  h := loc.LevelHeightCm
+  if h <= 0 {
+    switch level := locationLevel(loc); {
+    case level == 0:
+      return constants.ErgoBandUnknown
+    case level == 1:
+      return constants.ErgoBandKneeToWaist
+    case level == 2:
+      return constants.ErgoBandGolden
+    default:
+      return constants.ErgoBandAboveShoulder
+    }
+  }
+  switch {
+  case h < m.KneeHeightCm:
+    return constants.ErgoBandBelowKnee
+  case h < m.WaistHeightCm:
+    return constants.ErgoBandKneeToWaist
+  case h <= m.ShoulderHeightCm:
+    return constants.ErgoBandGolden
+  default:
+    return constants.ErgoBandAboveShoulder
+  }
+}
+
+// ergonomicScore rates a slot 0..1 under m, with its height band. An unknown
+// band rates neutral and an unknown depth leaves reach out of the score.
+func ergonomicScore(m *models.ErgonomicModel, loc *models.Location) (float64, string) {
+  band := ergonomicBand(m, loc)
+  height := 0.5
+  switch band {
+  case constants.ErgoBandBelowKnee:
+    height = m.BelowKneeScore
+  case constants.ErgoBandKneeToWaist:
+    height = m.KneeToWaistScore
+  case constants.ErgoBandGolden:
+    height = m.GoldenScore
+  case constants.ErgoBandAboveShoulder:
+    height = m.AboveShoulderScore
+  }
+  d := loc.SlotDepthCm
+  if d <= 0 || m.ReachWeight == 0 {
+    return height, band
+  }
+  reach := 1.0
+  switch {
+  case d >= m.MaxReachDepthCm:
+    reach = m.DeepReachScore
+  case d > m.ReachDepthCm:
+    reach = 1 - (1-m.DeepReachScore)*(d-m.ReachDepthCm)/(m.MaxReachDepthCm-m.ReachDepthCm)
+  }
+  return (m.HeightWeight*height + m.ReachWeight*reach) / (m.HeightWeight + m.ReachWeight), band
+}
+
+func newErgonomicTotals(zone string) *ErgonomicTotals {
+  return &ErgonomicTotals{Zone: zone, LinesByBand: make(map[string]float64)}
+}
+
+func (t *ErgonomicTotals) add(slot *ErgonomicSlot, units float64, heavyFast bool) {
+  t.Lines += slot.Lines
+  t.Units += units
+  t.weighted += slot.Lines * slot.Score
+  t.LinesByBand[slot.Band] += slot.Lines
+  if constants.ErgoBandsAwkward[slot.Band] {
+    t.AwkwardLines += slot.Lines
+    if heavyFast {
+      t.HeavyFastAwkwardLines += slot.Lines
+    }
+  }
+}
+
+func (t *ErgonomicTotals) finish() {
+  if t.Lines > 0 {
+    score := t.weighted / t.Lines
+    t.Score = &score
+  }
+}
+
+func (s *ergonomicSvc) Report(q ErgonomicQuery) (*ErgonomicReport, error) {
+  if q.WarehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  start, end, err := analyticsWindow(q.StartDate, q.EndDate)
+  if err != nil {
+    return nil, err
+  }
+  types, err := normalizeTypeFilter(q.TransactionTypes)
+  if err != nil {
+    return nil, err
+  }
+  model, err := s.GetModel(q.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+  report := &ErgonomicReport{
+    WarehouseID:      q.WarehouseID,
+    StartDate:        start,
+    EndDate:          end.AddDate(0, 0, -1),
+    TransactionTypes: types,
+    Model:            model,
+    Totals:           newErgonomicTotals(""),
+    ByZone:           []*ErgonomicTotals{},
+    Items:            []*ErgonomicItem{},
+  }
+
+  f := repos.AnalyticsFilter{CompanyID: q.CompanyID, WarehouseID: q.WarehouseID, StartDate: start, EndDate: end}
+  if f.RawTypes, err = rawTypesFor(s.arepo, f, types); err != nil {
+    return nil, err
+  }
+  lines := make(map[uuid.UUID]int64)
+  units := make(map[uuid.UUID]int64)
+  if len(f.RawTypes) > 0 {
+    activity, err := s.arepo.ItemDailyActivity(f)
+    if err != nil {
+      return nil, err
+    }
+    for _, a := range activity {
+      lines[a.ItemID] += a.Lines
+      units[a.ItemID] += a.Units
+    }
+  }
+
+  links, err := s.lrepo.ListItemLinks(q.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+  locs, err := s.lrepo.ListLocations(repos.LocationFilter{WarehouseID: q.WarehouseID})
+  if err != nil {
+    return nil, fmt.Errorf("failed to load locations: %w", err)
+  }
+  zones, err := s.zrepo.ListByWarehouse(q.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+  locByID := make(map[uuid.UUID]*models.Location, len(locs))
+  for _, loc := range locs {
+    locByID[loc.ID] = loc
+  }
+  slots := make(map[uuid.UUID][]*models.Location)
+  picks := make(map[uuid.UUID][]*models.Location)
+  for _, l := range links {
+    loc, ok := locByID[l.LocationID]
+    if !ok {
+      continue
+    }
+    slots[l.ItemID] = append(slots[l.ItemID], loc)
+    if loc.Status == constants.LocationStatusActive && (loc.SlotRole == "" || loc.SlotRole == constants.SlotRolePick) {
+      picks[l.ItemID] = append(picks[l.ItemID], loc)
+    }
+  }
+
+  // Fast movers are the top FastMoverShare of picked items by lines, ties
+  // with the last of them included.
+  ranked := make([]int64, 0, len(lines))
+  itemIDs := make([]uuid.UUID, 0, len(lines))
+  for itemID, n := range lines {
+    if n > 0 {
+      ranked = append(ranked, n)
+      itemIDs = append(itemIDs, itemID)
+    }
+  }
+  sort.Slice(ranked, func(i, j int) bool { return ranked[i] > ranked[j] })
+  fastLines := int64(math.MaxInt64)
+  if k := int(math.Ceil(model.FastMoverShare * float64(len(ranked)))); k > 0 {
+    fastLines = ranked[k-1]
+  }
+  var items []*models.Item
+  if len(itemIDs) > 0 {
+    if items, err = s.irepo.ListItems(repos.ItemFilter{IDs: itemIDs}); err != nil {
+      return nil, fmt.Errorf("failed to load items: %w", err)
+    }
+  }
+
+  byZone := make(map[string]*ErgonomicTotals)
+  zoneTotals := func(zone string) *ErgonomicTotals {
+    t, ok := byZone[zone]
+    if !ok {
+      t = newErgonomicTotals(zone)
+      byZone[zone] = t
+    }
+    return t
+  }
+  zoneOf := make(map[uuid.UUID]string, len(locs))
+  counted := make(map[uuid.UUID]bool)
+  for _, itemSlots := range slots {
+    for _, loc := range itemSlots {
+      zone := zoneForPath(zones, loc.LocationPath)
+      zoneOf[loc.ID] = zone
+      if !counted[loc.ID] {
+        counted[loc.ID] = true
+        zoneTotals(zone).Slots++
+        report.Totals.Slots++
+      }
+    }
+  }
+
+  var rows []*ErgonomicItem
+  for _, it := range items {
+    n := lines[it.ID]
+    scored := picks[it.ID]
+    if len(scored) == 0 {
+      scored = slots[it.ID]
+    }
+    if len(scored) == 0 {
+      report.UnslottedItems++
+      report.UnslottedLines += n
+      continue
+    }
+    row := &ErgonomicItem{
+      ItemID:       it.ID,
+      ItemName:     it.Name,
+      UnitWeightKg: it.UnitWeightKg,
+      Lines:        n,
+      Units:        units[it.ID],
+      Heavy:        it.UnitWeightKg >= model.HeavyItemKg,
+      FastMover:    n >= fastLines,
+    }
+    heavyFast := row.Heavy && row.FastMover
+    share := 1 / float64(len(scored))
+    touched := make(map[string]bool)
+    for _, loc := range scored {
+      score, band := ergonomicScore(model, loc)
+      slot := &ErgonomicSlot{
+        LocationID:   loc.ID,
+        LocationPath: loc.LocationPath,
+        Zone:         zoneOf[loc.ID],
+        Band:         band,
+        Score:        score,
+        Lines:        float64(n) * share,
+      }
+      row.Slots = append(row.Slots, slot)
+      row.Score += score * share
+      slotUnits := float64(row.Units) * share
+      report.Totals.add(slot, slotUnits, heavyFast)
+      zoneTotals(slot.Zone).add(slot, slotUnits, heavyFast)
+      if constants.ErgoBandsAwkward[band] {
+        row.AwkwardLines += slot.Lines
+        if heavyFast && !touched[slot.Zone] {
+          touched[slot.Zone] = true
+          zoneTotals(slot.Zone).HeavyFastAwkwardItems++
+        }
+      }
+    }
+    if heavyFast && row.AwkwardLines > 0 {
+      report.Totals.HeavyFastAwkwardItems++
+    }
+    rows = append(rows, row)
+  }
+
+  report.Totals.finish()
+  for _, t := range byZone {
+    t.finish()
+    report.ByZone = append(report.ByZone, t)
+  }
+  sort.Slice(report.ByZone, func(i, j int) bool { return report.ByZone[i].Zone < report.ByZone[j].Zone })
+  finishErgonomicItems(report, rows, q)
+  return report, nil
+}
+
+// finishErgonomicItems applies the item filters, sorting and pagination of q.
+func finishErgonomicItems(report *ErgonomicReport, rows []*ErgonomicItem, q ErgonomicQuery) {
+  kept := rows[:0]
+  for _, row := range rows {
+    if q.HeavyFastOnly && !(row.Heavy && row.FastMover) {
+      continue
+    }
+    if q.AwkwardOnly && row.AwkwardLines == 0 {
+      continue
+    }
+    kept = append(kept, row)
+  }
+  less := func(a, b *ErgonomicItem) bool { return a.Lines < b.Lines }
+  switch q.SortField {
+  case "score":
+    less = func(a, b *ErgonomicItem) bool { return a.Score < b.Score }
+  case "awkward_lines":
+    less = func(a, b *ErgonomicItem) bool { return a.AwkwardLines < b.AwkwardLines }
+  case "unit_weight_kg":
+    less = func(a, b *ErgonomicItem) bool { return a.UnitWeightKg < b.UnitWeightKg }
+  case "item_name":
+    less = func(a, b *ErgonomicItem) bool { return a.ItemName < b.ItemName }
+  }
+  asc := q.SortDir == "asc" || q.SortDir == "ASC"
+  sort.Slice(kept, func(i, j int) bool { return kept[i].ItemName < kept[j].ItemName })
+  sort.SliceStable(kept, func(i, j int) bool {
+    if asc {
+      return less(kept[i], kept[j])
+    }
+    return less(kept[j], kept[i])
+  })
+  report.TotalItems = len(kept)
+  report.Items = paginate(kept, q.Limit, q.Offset)
+}
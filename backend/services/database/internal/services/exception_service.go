@@ -0,0 +1,252 @@
+package services
+
+import (
+  "fmt"
+  "math"
+  "sort"
+  "time"
+
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+// ExceptionQuery tunes the volume outlier check: an item's daily pick units
+// over [StartDate, EndDate], idle days counting as zero, are an outlier at
+// ZThreshold standard deviations above its mean. Items need MinActiveDays
+// days with picks to be checked. The record rules always cover every record
+// of the warehouse.
+type ExceptionQuery struct {
+  CompanyID     uuid.UUID
+  WarehouseID   uuid.UUID
+  StartDate     time.Time
+  EndDate       time.Time
+  ZThreshold    float64
+  MinActiveDays int
+}
+
+type ExceptionScan struct {
+  WarehouseID   uuid.UUID                      `json:"warehouse_id"`
+  StartDate     time.Time                      `json:"start_date"`
+  EndDate       time.Time                      `json:"end_date"`
+  ZThreshold    float64                        `json:"z_threshold"`
+  MinActiveDays int                            `json:"min_active_days"`
+  Open          map[string]int                 `json:"open"` // per exception type, as found by this scan
+  New           []*models.TransactionException `json:"new"`
+  Resolved      int64                          `json:"resolved"`
+}
+
+type ExceptionSvc interface {
+  // Scan finds the warehouse's exceptions, stores new ones and resolves
+  // those whose record no longer qualifies.
+  Scan(q ExceptionQuery) (*ExceptionScan, error)
+  ListExceptions(f repos.ExceptionFilter) ([]*models.TransactionException, error)
+  Acknowledge(warehouseID uuid.UUID, exceptionIDs []uuid.UUID, userID uuid.UUID, note string) (int64, error)
+}
+
+type exceptionSvc struct {
+  repo            repos.ExceptionRepo
+  arepo           repos.AnalyticsRepo
+}
+
+func NewExceptionSvc(repo repos.ExceptionRepo, arepo repos.AnalyticsRepo) ExceptionSvc {
+  return &exceptionSvc{repo: repo, arepo: arepo}
+}
+
+const (
+  defaultExceptionZ             = 3.0
+  defaultExceptionMinActiveDays = 5
+)
+
+func (s *exceptionSvc) Scan(q ExceptionQuery) (*ExceptionScan, error) {
+  if q.WarehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  if q.ZThreshold < 0 || q.MinActiveDays < 0 {
+    return nil, fmt.Errorf("exception thresholds cannot be negative")
+  }
+  if q.ZThreshold == 0 {
+    q.ZThreshold = defaultExceptionZ
+  }
+  if q.MinActiveDays == 0 {
+    q.MinActiveDays = defaultExceptionMinActiveDays
+  }
+  start, end, err := analyticsWindow(q.StartDate, q.EndDate)
+  if err != nil {
+    return nil, err
+  }
+  picks := []string{constants.TransactionTypePick}
+  pickTypes, err := rawTypesFor(s.arepo, repos.AnalyticsFilter{CompanyID: q.CompanyID, WarehouseID: q.WarehouseID}, picks)
+  if err != nil {
+    return nil, err
+  }
+
+  found := make(map[string][]*models.TransactionException)
+  add := func(exceptionType string, recordID, itemID, locationID uuid.UUID, day time.Time, value float64, detail string) {
+    found[exceptionType] = append(found[exceptionType], &models.TransactionException{
+      CompanyID:           &q.CompanyID,
+      WarehouseID:         &q.WarehouseID,
+      TransactionRecordID: &recordID,
+      ExceptionType:       exceptionType,
+      ItemID:              &itemID,
+      LocationID:          &locationID,
+      Day:                 time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC),
+      Value:               value,
+      Detail:              detail,
+    })
+  }
+
+  checks, err := s.repo.SuspectRecords(q.WarehouseID, pickTypes)
+  if err != nil {
+    return nil, err
+  }
+  isPick := make(map[string]bool, len(pickTypes))
+  for _, t := range pickTypes {
+    isPick[t] = true
+  }
+  now := time.Now()
+  for _, c := range checks {
+    day := c.CompletedDate.UTC()
+    if c.CompletedQuantity > c.TransactionQuantity {
+      add(constants.ExceptionOverCompleted, c.RecordID, c.ItemID, c.LocationID, day, float64(c.CompletedQuantity),
+        fmt.Sprintf("completed %d of %d", c.CompletedQuantity, c.TransactionQuantity))
+    }
+    if c.TransactionQuantity <= 0 || c.CompletedQuantity < 0 {
+      add(constants.ExceptionNonPositiveQty, c.RecordID, c.ItemID, c.LocationID, day, float64(min(c.TransactionQuantity, c.CompletedQuantity)),
+        fmt.Sprintf("transaction quantity %d, completed quantity %d", c.TransactionQuantity, c.CompletedQuantity))
+    }
+    switch {
+    case c.CompletedDate.After(now):
+      add(constants.ExceptionFutureDate, c.RecordID, c.ItemID, c.LocationID, day, math.Ceil(c.CompletedDate.Sub(now).Hours()/24),
+        fmt.Sprintf("completed %s, in the future", day.Format("2006-01-02")))
+    case c.WarehouseGoLive != nil && c.CompletedDate.Before(*c.WarehouseGoLive):
+      live := c.WarehouseGoLive.UTC()
+      add(constants.ExceptionBeforeWarehouse, c.RecordID, c.ItemID, c.LocationID, day, math.Ceil(live.Sub(c.CompletedDate).Hours()/24),
+        fmt.Sprintf("completed %s, before the warehouse went live on %s", day.Format("2006-01-02"), live.Format("2006-01-02")))
+    }
+    if isPick[c.TransactionType] {
+      switch {
+      case constants.LocationStatusesClosed[c.LocationStatus]:
+        add(constants.ExceptionClosedLocation, c.RecordID, c.ItemID, c.LocationID, day, 0,
+          fmt.Sprintf("pick from a %s location", c.LocationStatus))
+      case c.SlotRole != constants.SlotRolePick:
+        add(constants.ExceptionNonPickFace, c.RecordID, c.ItemID, c.LocationID, day, 0,
+          fmt.Sprintf("pick from a %s slot", c.SlotRole))
+      }
+    }
+  }
+
+  f := repos.AnalyticsFilter{CompanyID: q.CompanyID, WarehouseID: q.WarehouseID, StartDate: start, EndDate: end}
+  if f.RawTypes, err = rawTypesFor(s.arepo, f, picks); err != nil {
+    return nil, err
+  }
+  if len(f.RawTypes) > 0 {
+    activity, err := s.arepo.ItemDailyActivity(f)
+    if err != nil {
+      return nil, err
+    }
+    daily := make(map[uuid.UUID]map[time.Time]int64)
+    for _, a := range activity {
+      if daily[a.ItemID] == nil {
+        daily[a.ItemID] = make(map[time.Time]int64)
+      }
+      daily[a.ItemID][a.Day] += a.Units
+    }
+    days := end.Sub(start).Hours() / 24
+    for itemID, byDay := range daily {
+      if len(byDay) < q.MinActiveDays {
+        continue
+      }
+      var sum float64
+      for _, u := range byDay {
+        sum += float64(u)
+      }
+      mean := sum / days
+      variance := (days - float64(len(byDay))) * mean * mean
+      for _, u := range byDay {
+        variance += (float64(u) - mean) * (float64(u) - mean)
+      }
+      sd := math.Sqrt(variance / days)
+      if sd == 0 {
+        continue
+      }
+      for day, u := range byDay {
+        z := (float64(u) - mean) / sd
+        if z < q.ZThreshold {
+          continue
+        }
+        rec, err := s.repo.LargestRecord(q.WarehouseID, itemID, day, f.RawTypes)
+        if err != nil {
+          return nil, err
+        }
+        add(constants.ExceptionVolumeOutlier, rec.ID, itemID, *rec.LocationID, day, z,
+          fmt.Sprintf("%d units picked against a daily mean of %.1f", u, mean))
+      }
+    }
+  }
+
+  open, err := s.repo.ListOpen(q.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+  known := make(map[string]bool, len(open))
+  for _, e := range open {
+    known[e.ExceptionType+":"+e.TransactionRecordID.String()] = true
+  }
+  scan := &ExceptionScan{
+    WarehouseID:   q.WarehouseID,
+    StartDate:     start,
+    EndDate:       end.AddDate(0, 0, -1),
+    ZThreshold:    q.ZThreshold,
+    MinActiveDays: q.MinActiveDays,
+    Open:          make(map[string]int),
+    New:           []*models.TransactionException{},
+  }
+  types := make([]string, 0, len(constants.ExceptionTypes))
+  for t := range constants.ExceptionTypes {
+    types = append(types, t)
+  }
+  sort.Strings(types)
+  for _, t := range types {
+    keep := make([]uuid.UUID, 0, len(found[t]))
+    for _, e := range found[t] {
+      isNew := !known[t+":"+e.TransactionRecordID.String()]
+      if err := s.repo.Upsert(e); err != nil {
+        return nil, err
+      }
+      if isNew {
+        scan.New = append(scan.New, e)
+      }
+      keep = append(keep, *e.TransactionRecordID)
+    }
+    var from, to time.Time
+    if t == constants.ExceptionVolumeOutlier {
+      from, to = start, end
+    }
+    resolved, err := s.repo.ResolveStale(q.WarehouseID, t, keep, from, to)
+    if err != nil {
+      return nil, err
+    }
+    scan.Resolved += resolved
+    scan.Open[t] = len(found[t])
+  }
+  return scan, nil
+}
+
+func (s *exceptionSvc) ListExceptions(f repos.ExceptionFilter) ([]*models.TransactionException, error) {
+  if f.ExceptionType != "" && !constants.ExceptionTypes[f.ExceptionType] {
+    return nil, fmt.Errorf("invalid exception type '%s'", f.ExceptionType)
+  }
+  return s.repo.ListExceptions(f)
+}
+
+func (s *exceptionSvc) Acknowledge(warehouseID uuid.UUID, exceptionIDs []uuid.UUID, userID uuid.UUID, note string) (int64, error) {
+  if warehouseID == uuid.Nil {
+    return 0, fmt.Errorf("invalid warehouseID")
+  }
+  if len(exceptionIDs) == 0 {
+    return 0, fmt.Errorf("no exceptions to acknowledge")
+  }
+  return s.repo.Acknowledge(warehouseID, exceptionIDs, userID, note)
+}
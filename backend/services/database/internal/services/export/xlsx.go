@@ -0,0 +1,169 @@
+package export
+
+import (
+  "fmt"
+
+  "github.com/xuri/excelize/v2"
+)
+
+const XLSXContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// Sheet is one worksheet of a tabular report. Rows hold plain values
+// (strings, numbers, times); each row should have len(Headers) cells.
+type Sheet struct {
+  Name    string
+  Headers []string
+  Rows    [][]interface{}
+}
+
+// Workbook wraps an excelize file so reports can add sheets, then charts or
+// other decorations, before writing it out.
+type Workbook struct {
+  f      *excelize.File
+  header int
+  first  bool
+}
+
+func NewWorkbook() (*Workbook, error) {
+  f := excelize.NewFile()
+  header, err := f.NewStyle(&excelize.Style{
+    Font: &excelize.Font{Bold: true},
+    Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#E7E6E6"}},
+  })
+  if err != nil {
+    return nil, fmt.Errorf("failed to create header style: %w", err)
+  }
+  return &Workbook{f: f, header: header, first: true}, nil
+}
+
+// File exposes the underlying excelize file for report specific additions.
+func (w *Workbook) File() *excelize.File {
+  return w.f
+}
+
+// AddSheet writes a header row, the rows, a frozen header pane and an
+// autofilter over the table.
+func (w *Workbook) AddSheet(s Sheet) error {
+  if w.first {
+    if err := w.f.SetSheetName("Sheet1", s.Name); err != nil {
+      return fmt.Errorf("failed to name sheet: %w", err)
+    }
+    w.first = false
+  } else if _, err := w.f.NewSheet(s.Name); err != nil {
+    return fmt.Errorf("failed to add sheet %s: %w", s.Name, err)
+  }
+  if len(s.Headers) == 0 {
+    return nil
+  }
+  headers := make([]interface{}, len(s.Headers))
+  for i, h := range s.Headers {
+    headers[i] = h
+  }
+  if err := w.f.SetSheetRow(s.Name, "A1", &headers); err != nil {
+    return fmt.Errorf("failed to write header: %w", err)
+  }
+  for i, row := range s.Rows {
+    cell, _ := excelize.CoordinatesToCellName(1, i+2)
+    if err := w.f.SetSheetRow(s.Name, cell, &row); err != nil {
+      return fmt.Errorf("failed to write row %d: %w", i+1, err)
+    }
+  }
+
+  lastCol, _ := excelize.ColumnNumberToName(len(s.Headers))
+  if err := w.f.SetCellStyle(s.Name, "A1", lastCol+"1", w.header); err != nil {
+    return fmt.Errorf("failed to style header: %w", err)
+  }
+  if err := w.f.SetColWidth(s.Name, "A", lastCol, 16); err != nil {
+    return fmt.Errorf("failed to size columns: %w", err)
+  }
+  if err := w.f.SetPanes(s.Name, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+    return fmt.Errorf("failed to freeze header: %w", err)
+  }
+  lastRow := len(s.Rows) + 1
+  if err := w.f.AutoFilter(s.Name, fmt.Sprintf("A1:%s%d", lastCol, lastRow), nil); err != nil {
+    return fmt.Errorf("failed to add filter: %w", err)
+  }
+  return nil
+}
+
+const (
+  ChartLine   = "line"
+  ChartColumn = "column"
+)
+
+// Chart plots columns of a sheet written by AddSheet. Columns are 1-based;
+// each series takes its name from the header row and Rows data rows below.
+type Chart struct {
+  Type       string // ChartLine or ChartColumn
+  Title      string
+  DataSheet  string
+  Category   int   // x axis labels
+  Series     []int // plotted columns
+  Rows       int
+  YAxisTitle string
+  Sheet      string // sheet the chart is drawn on, DataSheet when empty
+  Cell       string // top left corner of the chart
+}
+
+// AddChart draws c. Charts without data rows are skipped.
+func (w *Workbook) AddChart(c Chart) error {
+  if c.Rows == 0 || len(c.Series) == 0 {
+    return nil
+  }
+  kind := excelize.Line
+  if c.Type == ChartColumn {
+    kind = excelize.Col
+  }
+  ref := func(col, from, to int) string {
+    name, _ := excelize.ColumnNumberToName(col)
+    if from == to {
+      return fmt.Sprintf("'%s'!$%s$%d", c.DataSheet, name, from)
+    }
+    return fmt.Sprintf("'%s'!$%s$%d:$%s$%d", c.DataSheet, name, from, name, to)
+  }
+  chart := &excelize.Chart{
+    Type:         kind,
+    Title:        []excelize.RichTextRun{{Text: c.Title}},
+    Legend:       excelize.ChartLegend{Position: "bottom"},
+    Dimension:    excelize.ChartDimension{Width: 720, Height: 360},
+    YAxis:        excelize.ChartAxis{MajorGridLines: true, Title: []excelize.RichTextRun{{Text: c.YAxisTitle}}},
+    ShowBlanksAs: "gap",
+  }
+  for _, col := range c.Series {
+    chart.Series = append(chart.Series, excelize.ChartSeries{
+      Name:       ref(col, 1, 1),
+      Categories: ref(c.Category, 2, c.Rows+1),
+      Values:     ref(col, 2, c.Rows+1),
+    })
+  }
+  sheet := c.Sheet
+  if sheet == "" {
+    sheet = c.DataSheet
+  }
+  if err := w.f.AddChart(sheet, c.Cell, chart); err != nil {
+    return fmt.Errorf("failed to add chart %s: %w", c.Title, err)
+  }
+  return nil
+}
+
+func (w *Workbook) Bytes() ([]byte, error) {
+  buf, err := w.f.WriteToBuffer()
+  if err != nil {
+    return nil, fmt.Errorf("failed to write workbook: %w", err)
+  }
+  return buf.Bytes(), nil
+}
+
+// XLSX is the common case: a workbook of plain tables.
+func XLSX(sheets ...Sheet) ([]byte, error) {
+  w, err := NewWorkbook()
+  if err != nil {
+    return nil, err
+  }
+  for _, s := range sheets {
+    if err := w.AddSheet(s); err != nil {
+      return nil, err
+    }
+  }
+  return w.Bytes()
+}
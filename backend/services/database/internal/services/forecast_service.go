@@ -0,0 +1,532 @@
+package services
+
+import (
+  "encoding/json"
+  "fmt"
+  "math"
+  "strings"
+  "time"
+
+  "github.com/google/uuid"
+  "gorm.io/datatypes"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+// ForecastParams configures a run. Units per item are bucketed by Granularity
+// over [StartDate, EndDate]; the last HoldoutPeriods are forecast from the
+// rest to score the model before it is refitted on everything and projected
+// Horizon periods ahead. SeasonLength is in periods (7 days, 52 weeks by
+// default); 1 turns seasonality off.
+type ForecastParams struct {
+  CompanyID        uuid.UUID
+  WarehouseID      uuid.UUID
+  CreatedByID      uuid.UUID
+  Granularity      string
+  SeasonLength     int
+  Horizon          int
+  HoldoutPeriods   int
+  StartDate        time.Time
+  EndDate          time.Time
+  TransactionTypes []string
+}
+
+type ForecastSvc interface {
+  Forecast(p ForecastParams) (*models.ForecastRun, []*models.ItemForecast, error)
+  GetRunByID(runID uuid.UUID) (*models.ForecastRun, error)
+  LatestRun(warehouseID uuid.UUID) (*models.ForecastRun, error)
+  DeleteRun(runID uuid.UUID) error
+  ListRuns(f repos.ForecastRunFilter) ([]*models.ForecastRun, error)
+  ListItems(f repos.ItemForecastFilter) ([]*models.ItemForecast, error)
+  // ForecastVelocity shapes a run's forecast like ItemVelocity over the
+  // horizon so velocity based features can run on it instead of history.
+  ForecastVelocity(runID uuid.UUID) (*VelocityReport, error)
+}
+
+type forecastSvc struct {
+  repo            repos.ForecastRepo
+  arepo           repos.AnalyticsRepo
+}
+
+func NewForecastSvc(repo repos.ForecastRepo, arepo repos.AnalyticsRepo) ForecastSvc {
+  return &forecastSvc{repo: repo, arepo: arepo}
+}
+
+const (
+  defaultForecastDayWindow   = 182
+  defaultForecastDaySeason   = 7
+  defaultForecastDayHorizon  = 28
+  defaultForecastDayHoldout  = 14
+  defaultForecastWeekWindow  = 784 // two seasons plus the holdout, so Holt-Winters is scored
+  defaultForecastWeekSeason  = 52
+  defaultForecastWeekHorizon = 13
+  defaultForecastWeekHoldout = 8
+  maxForecastPeriods         = 1100
+  forecastTrendDamping       = 0.95
+)
+
+// smoothing parameters tried when fitting; the trend gets a finer low end
+// since sparse item demand rarely carries a strong trend
+var (
+  forecastLevelGrid = []float64{0.05, 0.1, 0.2, 0.3, 0.5, 0.8}
+  forecastTrendGrid = []float64{0.01, 0.05, 0.1, 0.2}
+)
+
+func (s *forecastSvc) Forecast(p ForecastParams) (*models.ForecastRun, []*models.ItemForecast, error) {
+  if p.CompanyID == uuid.Nil || p.WarehouseID == uuid.Nil {
+    return nil, nil, fmt.Errorf("invalid warehouse")
+  }
+  window, err := normalizeForecastParams(&p)
+  if err != nil {
+    return nil, nil, err
+  }
+  _, end, err := analyticsWindow(time.Time{}, p.EndDate)
+  if err != nil {
+    return nil, nil, err
+  }
+  start := p.StartDate
+  if start.IsZero() {
+    start = end.AddDate(0, 0, -window)
+  }
+  if start, end, err = analyticsWindow(start, end.AddDate(0, 0, -1)); err != nil {
+    return nil, nil, err
+  }
+  periodDays := forecastPeriodDays(p.Granularity)
+  periods := int(end.Sub(start).Hours()/24) / periodDays
+  if periods < 2 {
+    return nil, nil, fmt.Errorf("forecast history must cover at least two %ss", p.Granularity)
+  }
+  if periods > maxForecastPeriods {
+    return nil, nil, fmt.Errorf("forecast history cannot exceed %d %ss", maxForecastPeriods, p.Granularity)
+  }
+  // whole periods only, ending on the last day of history
+  start = end.AddDate(0, 0, -periods*periodDays)
+
+  types, err := normalizeTypeFilter(p.TransactionTypes)
+  if err != nil {
+    return nil, nil, err
+  }
+  f := repos.AnalyticsFilter{CompanyID: p.CompanyID, WarehouseID: p.WarehouseID, StartDate: start, EndDate: end}
+  if f.RawTypes, err = rawTypesFor(s.arepo, f, types); err != nil {
+    return nil, nil, err
+  }
+
+  run := models.ForecastRun{
+    CompanyID:        &p.CompanyID,
+    WarehouseID:      &p.WarehouseID,
+    Granularity:      p.Granularity,
+    SeasonLength:     p.SeasonLength,
+    Horizon:          p.Horizon,
+    HoldoutPeriods:   p.HoldoutPeriods,
+    TransactionTypes: strings.Join(types, ","),
+    StartDate:        start,
+    EndDate:          end.AddDate(0, 0, -1),
+  }
+  if p.CreatedByID != uuid.Nil {
+    run.CreatedByID = &p.CreatedByID
+  }
+
+  var items []*models.ItemForecast
+  if len(f.RawTypes) > 0 {
+    activity, err := s.arepo.ItemDailyActivity(f)
+    if err != nil {
+      return nil, nil, err
+    }
+    type history struct {
+      lines, units int64
+      series       []float64
+      daily        map[time.Time]int64
+    }
+    byItem := make(map[uuid.UUID]*history)
+    var order []uuid.UUID
+    for _, a := range activity {
+      idx := int(a.Day.Sub(start).Hours()/24) / periodDays
+      if idx < 0 || idx >= periods {
+        continue
+      }
+      h, ok := byItem[a.ItemID]
+      if !ok {
+        h = &history{series: make([]float64, periods), daily: make(map[time.Time]int64)}
+        byItem[a.ItemID] = h
+        order = append(order, a.ItemID)
+      }
+      h.lines += a.Lines
+      h.units += a.Units
+      h.series[idx] += float64(a.Units)
+      h.daily[a.Day] += a.Units
+    }
+
+    days := float64(periods * periodDays)
+    horizonDays := float64(p.Horizon * periodDays)
+    var absErr, actual, bias, evaluated float64
+    for _, id := range order {
+      h := byItem[id]
+      fit := fitForecast(h.series, p.SeasonLength, p.Horizon, p.HoldoutPeriods)
+      itemID := id
+      it := &models.ItemForecast{
+        CompanyID:    &p.CompanyID,
+        WarehouseID:  &p.WarehouseID,
+        ItemID:       &itemID,
+        Method:       fit.method,
+        Alpha:        fit.alpha,
+        Beta:         fit.beta,
+        Gamma:        fit.gamma,
+        HistoryLines: h.lines,
+        HistoryUnits: h.units,
+      }
+      points := make([]float64, len(fit.points))
+      for i, v := range fit.points {
+        points[i] = math.Round(v*1000) / 1000
+        it.ForecastUnits += v
+      }
+      raw, err := json.Marshal(points)
+      if err != nil {
+        return nil, nil, err
+      }
+      it.Points = datatypes.JSON(raw)
+      if h.units > 0 {
+        it.ForecastLines = it.ForecastUnits * float64(h.lines) / float64(h.units)
+      }
+      it.AvgDailyUnits = it.ForecastUnits / horizonDays
+      // a smoothed forecast has no daily spikes of its own, so the peak
+      // keeps the item's historical peak-to-average ratio
+      if avg := float64(h.units) / days; avg > 0 && it.AvgDailyUnits > 0 {
+        var peak int64
+        for _, u := range h.daily {
+          if u > peak {
+            peak = u
+          }
+        }
+        it.PeakDailyUnits = int64(math.Ceil(it.AvgDailyUnits * math.Max(1, float64(peak)/avg)))
+      }
+      if fit.evaluated {
+        it.EvaluatedMethod = fit.method
+        it.MAE, it.WAPE, it.Bias = forecastAccuracy(fit.absErr, fit.actual, fit.bias, float64(p.HoldoutPeriods))
+        absErr += fit.absErr
+        actual += fit.actual
+        bias += fit.bias
+        evaluated += float64(p.HoldoutPeriods)
+      }
+      items = append(items, it)
+    }
+    if evaluated > 0 {
+      run.MAE, run.WAPE, run.Bias = forecastAccuracy(absErr, actual, bias, evaluated)
+    }
+  }
+  run.ItemCount = len(items)
+
+  created, err := s.repo.CreateRun(run, items)
+  if err != nil {
+    return nil, nil, err
+  }
+  return created, items, nil
+}
+
+func (s *forecastSvc) GetRunByID(runID uuid.UUID) (*models.ForecastRun, error) {
+  if runID == uuid.Nil {
+    return nil, fmt.Errorf("invalid runID")
+  }
+  return s.repo.GetRunByID(runID)
+}
+
+func (s *forecastSvc) LatestRun(warehouseID uuid.UUID) (*models.ForecastRun, error) {
+  return s.repo.LatestRun(warehouseID)
+}
+
+func (s *forecastSvc) DeleteRun(runID uuid.UUID) error {
+  if runID == uuid.Nil {
+    return fmt.Errorf("invalid runID")
+  }
+  return s.repo.DeleteRun(runID)
+}
+
+func (s *forecastSvc) ListRuns(f repos.ForecastRunFilter) ([]*models.ForecastRun, error) {
+  return s.repo.ListRuns(f)
+}
+
+func (s *forecastSvc) ListItems(f repos.ItemForecastFilter) ([]*models.ItemForecast, error) {
+  if f.RunID == uuid.Nil {
+    return nil, fmt.Errorf("invalid runID")
+  }
+  if f.Method != "" && !constants.ForecastMethods[f.Method] {
+    return nil, fmt.Errorf("invalid forecast method '%s'", f.Method)
+  }
+  return s.repo.ListItems(f)
+}
+
+func (s *forecastSvc) ForecastVelocity(runID uuid.UUID) (*VelocityReport, error) {
+  run, err := s.GetRunByID(runID)
+  if err != nil {
+    return nil, err
+  }
+  items, err := s.repo.ListItems(repos.ItemForecastFilter{RunID: run.ID})
+  if err != nil {
+    return nil, err
+  }
+  periodDays := forecastPeriodDays(run.Granularity)
+  days := run.Horizon * periodDays
+  report := &VelocityReport{
+    StartDate:  run.EndDate.AddDate(0, 0, 1),
+    EndDate:    run.EndDate.AddDate(0, 0, days),
+    Days:       days,
+    TotalItems: len(items),
+    Rows:       []*ItemVelocity{},
+  }
+  if run.WarehouseID != nil {
+    report.WarehouseID = *run.WarehouseID
+  }
+  if run.TransactionTypes != "" {
+    report.TransactionTypes = strings.Split(run.TransactionTypes, ",")
+  }
+  for _, it := range items {
+    v := &ItemVelocity{
+      ItemID:         *it.ItemID,
+      Lines:          int64(math.Round(it.ForecastLines)),
+      Units:          int64(math.Round(it.ForecastUnits)),
+      AvgDailyUnits:  it.AvgDailyUnits,
+      PeakDailyUnits: it.PeakDailyUnits,
+      LinesByType:    make(map[string]int64),
+      UnitsByType:    make(map[string]int64),
+    }
+    if it.Item != nil {
+      v.ItemName = it.Item.Name
+    }
+    if days > 0 {
+      v.AvgDailyLines = it.ForecastLines / float64(days)
+    }
+    if it.AvgDailyUnits > 0 {
+      v.PeakDailyLines = int64(math.Ceil(v.AvgDailyLines * float64(it.PeakDailyUnits) / it.AvgDailyUnits))
+    }
+    var points []float64
+    _ = json.Unmarshal(it.Points, &points)
+    for _, u := range points {
+      if u >= 0.5 {
+        v.ActiveDays += periodDays
+      }
+    }
+    report.Rows = append(report.Rows, v)
+  }
+  sortVelocity(report.Rows, "", "")
+  return report, nil
+}
+
+// forecastDailyUnits spreads an item's forecast points over the days of the
+// horizon, evenly within a week when the run is weekly.
+func forecastDailyUnits(run *models.ForecastRun, it *models.ItemForecast) map[time.Time]float64 {
+  var points []float64
+  _ = json.Unmarshal(it.Points, &points)
+  periodDays := forecastPeriodDays(run.Granularity)
+  first := run.EndDate.AddDate(0, 0, 1)
+  daily := make(map[time.Time]float64)
+  for i, u := range points {
+    if u <= 0 {
+      continue
+    }
+    for d := 0; d < periodDays; d++ {
+      daily[first.AddDate(0, 0, i*periodDays+d)] = u / float64(periodDays)
+    }
+  }
+  return daily
+}
+
+// normalizeForecastParams fills in the granularity's defaults and returns the
+// default history window in days.
+func normalizeForecastParams(p *ForecastParams) (int, error) {
+  if p.Granularity == "" {
+    p.Granularity = constants.ForecastGranularityDay
+  }
+  if !constants.ForecastGranularities[p.Granularity] {
+    return 0, fmt.Errorf("invalid forecast granularity '%s'", p.Granularity)
+  }
+  if p.SeasonLength < 0 || p.Horizon < 0 || p.HoldoutPeriods < 0 {
+    return 0, fmt.Errorf("forecast periods cannot be negative")
+  }
+  window, season, horizon, holdout := defaultForecastDayWindow, defaultForecastDaySeason, defaultForecastDayHorizon, defaultForecastDayHoldout
+  if p.Granularity == constants.ForecastGranularityWeek {
+    window, season, horizon, holdout = defaultForecastWeekWindow, defaultForecastWeekSeason, defaultForecastWeekHorizon, defaultForecastWeekHoldout
+  }
+  if p.SeasonLength == 0 {
+    p.SeasonLength = season
+  }
+  if p.Horizon == 0 {
+    p.Horizon = horizon
+  }
+  if p.HoldoutPeriods == 0 {
+    p.HoldoutPeriods = holdout
+  }
+  if p.Horizon > maxForecastPeriods {
+    return 0, fmt.Errorf("forecast horizon cannot exceed %d periods", maxForecastPeriods)
+  }
+  return window, nil
+}
+
+func forecastPeriodDays(granularity string) int {
+  if granularity == constants.ForecastGranularityWeek {
+    return 7
+  }
+  return 1
+}
+
+func forecastAccuracy(absErr, actual, bias, periods float64) (mae, wape, pctBias *float64) {
+  m := absErr / periods
+  mae = &m
+  if actual > 0 {
+    w := absErr / actual * 100
+    b := bias / actual * 100
+    wape, pctBias = &w, &b
+  }
+  return mae, wape, pctBias
+}
+
+// forecastFit is a fitted model's forecast plus its holdout errors summed
+// over the holdout periods.
+type forecastFit struct {
+  method               string
+  alpha, beta, gamma   float64
+  points               []float64
+  evaluated            bool
+  absErr, actual, bias float64
+}
+
+// fitForecast scores a model fitted on the series without its last holdout
+// periods, then refits on the whole series and forecasts horizon periods.
+// The holdout is skipped when the shorter series would be fitted with another
+// method than the whole one, so the scores always describe the stored model.
+func fitForecast(series []float64, season, horizon, holdout int) forecastFit {
+  total := 0.0
+  for _, y := range series {
+    total += y
+  }
+  if total == 0 {
+    return forecastFit{method: constants.ForecastMethodNone, points: make([]float64, horizon)}
+  }
+  fit := forecastFit{}
+  if holdout > 0 && len(series)-holdout >= 2 && smoothingMethod(len(series)-holdout, season) == smoothingMethod(len(series), season) {
+    train := series[:len(series)-holdout]
+    test := series[len(series)-holdout:]
+    m := fitSmoothing(train, season)
+    pred := m.forecast(holdout)
+    for i, a := range test {
+      fit.absErr += math.Abs(pred[i] - a)
+      fit.bias += pred[i] - a
+      fit.actual += a
+    }
+    fit.evaluated = true
+  }
+  m := fitSmoothing(series, season)
+  fit.method, fit.alpha, fit.beta, fit.gamma = m.method, m.alpha, m.beta, m.gamma
+  fit.points = m.forecast(horizon)
+  return fit
+}
+
+// smoothing is exponential smoothing state after running through a series:
+// a level, a damped trend and additive seasonal offsets indexed by period
+// modulo the season length.
+type smoothing struct {
+  method             string
+  alpha, beta, gamma float64
+  level, trend       float64
+  seasonal           []float64
+  n                  int
+  sse                float64
+}
+
+// fitSmoothing grid searches the smoothing parameters for the lowest one
+// step ahead squared error. Holt-Winters needs two full seasons of history;
+// shorter series fall back to a trend only, then to a level only.
+func fitSmoothing(y []float64, season int) *smoothing {
+  var best *smoothing
+  try := func(m *smoothing) {
+    if best == nil || m.sse < best.sse {
+      best = m
+    }
+  }
+  switch smoothingMethod(len(y), season) {
+  case constants.ForecastMethodHoltWinters:
+    for _, a := range forecastLevelGrid {
+      for _, b := range forecastTrendGrid {
+        for _, g := range forecastLevelGrid {
+          try(runSmoothing(y, season, a, b, g))
+        }
+      }
+    }
+  case constants.ForecastMethodHolt:
+    for _, a := range forecastLevelGrid {
+      for _, b := range forecastTrendGrid {
+        try(runSmoothing(y, 1, a, b, 0))
+      }
+    }
+  default:
+    for _, a := range forecastLevelGrid {
+      try(runSmoothing(y, 1, a, 0, 0))
+    }
+  }
+  return best
+}
+
+// smoothingMethod is the method fitSmoothing uses for n periods of history.
+func smoothingMethod(n, season int) string {
+  switch {
+  case season > 1 && n >= 2*season:
+    return constants.ForecastMethodHoltWinters
+  case n >= 4:
+    return constants.ForecastMethodHolt
+  default:
+    return constants.ForecastMethodSimple
+  }
+}
+
+func runSmoothing(y []float64, season int, alpha, beta, gamma float64) *smoothing {
+  m := &smoothing{alpha: alpha, beta: beta, gamma: gamma, n: len(y), seasonal: make([]float64, max(season, 1))}
+  first := 1
+  switch {
+  case season > 1:
+    m.method = constants.ForecastMethodHoltWinters
+    var s1, s2 float64
+    for i := 0; i < season; i++ {
+      s1 += y[i]
+      s2 += y[season+i]
+    }
+    m.level = s1 / float64(season)
+    m.trend = (s2 - s1) / float64(season*season)
+    for i := 0; i < season; i++ {
+      m.seasonal[i] = y[i] - m.level
+    }
+    first = season
+  case beta > 0:
+    m.method = constants.ForecastMethodHolt
+    m.level = y[0]
+    m.trend = y[1] - y[0]
+  default:
+    m.method = constants.ForecastMethodSimple
+    m.level = y[0]
+  }
+  for t := first; t < len(y); t++ {
+    k := t % len(m.seasonal)
+    s := m.seasonal[k]
+    damped := forecastTrendDamping * m.trend
+    e := y[t] - (m.level + damped + s)
+    m.sse += e * e
+    level := alpha*(y[t]-s) + (1-alpha)*(m.level+damped)
+    m.trend = beta*(level-m.level) + (1-beta)*damped
+    if season > 1 {
+      m.seasonal[k] = gamma*(y[t]-level) + (1-gamma)*s
+    }
+    m.level = level
+  }
+  return m
+}
+
+// forecast projects h periods past the end of the series, never below zero.
+func (m *smoothing) forecast(h int) []float64 {
+  out := make([]float64, h)
+  damp, phi := 0.0, 1.0
+  for i := 0; i < h; i++ {
+    phi *= forecastTrendDamping
+    damp += phi
+    v := m.level + damp*m.trend + m.seasonal[(m.n+i)%len(m.seasonal)]
+    out[i] = math.Max(0, v)
+  }
+  return out
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+  "math"
+  "testing"
+
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+)
+
+// weeklySeries is a series of n days with a weekday pattern on top of a linear trend.
+func weeklySeries(n int, slope float64) []float64 {
+  pattern := []float64{10, 20, 30, 40, 30, 20, 10}
+  y := make([]float64, n)
+  for t := range y {
+    y[t] = pattern[t%7] + slope*float64(t)
+  }
+  return y
+}
+
+func TestFitSmoothing(t *testing.T) {
+  tests := []struct {
+    name       string
+    series     []float64
+    season     int
+    wantMethod string
+    want       []float64 // next periods
+    tolerance  float64
+  }{
+    {
+      name:       "repeating week",
+      series:     weeklySeries(56, 0),
+      season:     7,
+      wantMethod: constants.ForecastMethodHoltWinters,
+      want:       []float64{10, 20, 30, 40, 30, 20, 10},
+      tolerance:  1e-6,
+    },
+    {
+      name:       "trending week",
+      series:     weeklySeries(84, 0.5),
+      season:     7,
+      wantMethod: constants.ForecastMethodHoltWinters,
+      want:       weeklySeries(91, 0.5)[84:],
+      tolerance:  2,
+    },
+    {
+      name:       "under two seasons",
+      series:     weeklySeries(13, 0),
+      season:     7,
+      wantMethod: constants.ForecastMethodHolt,
+    },
+    {
+      name:       "season turned off",
+      series:     []float64{5, 5, 5, 5, 5, 5},
+      season:     1,
+      wantMethod: constants.ForecastMethodHolt,
+      want:       []float64{5, 5, 5},
+      tolerance:  1e-9,
+    },
+    {
+      name:       "too short for a trend",
+      series:     []float64{4, 6, 5},
+      season:     7,
+      wantMethod: constants.ForecastMethodSimple,
+    },
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      m := fitSmoothing(tt.series, tt.season)
+      if m.method != tt.wantMethod {
+        t.Fatalf("method %q, want %q", m.method, tt.wantMethod)
+      }
+      if m.method != smoothingMethod(len(tt.series), tt.season) {
+        t.Errorf("method %q disagrees with smoothingMethod %q", m.method, smoothingMethod(len(tt.series), tt.season))
+      }
+      got := m.forecast(len(tt.want))
+      for i, want := range tt.want {
+        if math.Abs(got[i]-want) > tt.tolerance {
+          t.Errorf("period %d: forecast %.3f, want %.3f", i+1, got[i], want)
+        }
+      }
+    })
+  }
+}
+
+func TestFitForecast(t *testing.T) {
+  tests := []struct {
+    name          string
+    series        []float64
+    season        int
+    holdout       int
+    wantMethod    string
+    wantEvaluated bool
+    maxWape       float64
+  }{
+    {
+      name:       "no demand",
+      series:     make([]float64, 30),
+      season:     7,
+      holdout:    7,
+      wantMethod: constants.ForecastMethodNone,
+    },
+    {
+      name:          "holdout scored",
+      series:        weeklySeries(63, 0),
+      season:        7,
+      holdout:       7,
+      wantMethod:    constants.ForecastMethodHoltWinters,
+      wantEvaluated: true,
+      maxWape:       1e-6,
+    },
+    {
+      name:          "trending holdout scored",
+      series:        weeklySeries(91, 0.5),
+      season:        7,
+      holdout:       14,
+      wantMethod:    constants.ForecastMethodHoltWinters,
+      wantEvaluated: true,
+      maxWape:       0.1,
+    },
+    {
+      // without the holdout only Holt could be fitted, which is not the stored model
+      name:       "holdout would change the method",
+      series:     weeklySeries(16, 0),
+      season:     7,
+      holdout:    4,
+      wantMethod: constants.ForecastMethodHoltWinters,
+    },
+    {
+      name:       "no holdout",
+      series:     weeklySeries(28, 0),
+      season:     7,
+      wantMethod: constants.ForecastMethodHoltWinters,
+    },
+    {
+      name:       "holdout leaves too little",
+      series:     []float64{4, 6, 5},
+      season:     7,
+      holdout:    2,
+      wantMethod: constants.ForecastMethodSimple,
+    },
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      fit := fitForecast(tt.series, tt.season, 5, tt.holdout)
+      if fit.method != tt.wantMethod {
+        t.Errorf("method %q, want %q", fit.method, tt.wantMethod)
+      }
+      if len(fit.points) != 5 {
+        t.Errorf("got %d points, want 5", len(fit.points))
+      }
+      if fit.evaluated != tt.wantEvaluated {
+        t.Fatalf("evaluated %v, want %v", fit.evaluated, tt.wantEvaluated)
+      }
+      if !fit.evaluated {
+        if fit.absErr != 0 || fit.actual != 0 || fit.bias != 0 {
+          t.Errorf("unevaluated fit has errors %v, %v, %v", fit.absErr, fit.actual, fit.bias)
+        }
+        return
+      }
+      if fit.actual <= 0 {
+        t.Fatalf("holdout actual %v", fit.actual)
+      }
+      if wape := fit.absErr / fit.actual; wape > tt.maxWape {
+        t.Errorf("holdout wape %.4f, want at most %.4f", wape, tt.maxWape)
+      }
+      if math.Abs(fit.bias) > fit.absErr+1e-9 {
+        t.Errorf("bias %v exceeds absolute error %v", fit.bias, fit.absErr)
+      }
+    })
+  }
+}
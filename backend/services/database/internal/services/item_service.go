@@ -3,6 +3,7 @@ package services
 import (
   "fmt"
   "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
   "github.com/yungbote/slotter/backend/services/database/internal/models"
   "github.com/yungbote/slotter/backend/services/database/internal/repos"
 )
@@ -11,6 +12,7 @@ type ISvc interface {
   //GENERAL CRUD
   CreateItem(item models.Item) (*models.Item, error)
   UpdateItemName(itemID uuid.UUID, newName string) error
+  UpdateItemAttributes(itemID uuid.UUID, attrs models.Item) error
   GetItemByID(itemID uuid.UUID) (*models.Item, error)
   GetByItemNameAndCompanyID(companyID uuid.UUID, name string) (*models.Item, error)
   DeleteItem(itemID uuid.UUID) error
@@ -59,6 +61,25 @@ func (s *iSvc) UpdateItemName(itemID uuid.UUID, newName string) error {
   return nil
 }
 
+func (s *iSvc) UpdateItemAttributes(itemID uuid.UUID, attrs models.Item) error {
+  if itemID == uuid.Nil {
+    return fmt.Errorf("invalid itemID")
+  }
+  if attrs.UnitWeightKg < 0 || attrs.LengthCm < 0 || attrs.WidthCm < 0 || attrs.HeightCm < 0 {
+    return fmt.Errorf("item weight and dimensions cannot be negative")
+  }
+  if attrs.TemperatureClass == "" {
+    attrs.TemperatureClass = constants.TemperatureClassAmbient
+  }
+  if !constants.TemperatureClasses[attrs.TemperatureClass] {
+    return fmt.Errorf("invalid temperature class '%s'", attrs.TemperatureClass)
+  }
+  if err := s.repo.UpdateAttributes(itemID, attrs); err != nil {
+    return fmt.Errorf("Failed to update item attributes: %w", err)
+  }
+  return nil
+}
+
 func (s *iSvc) GetItemByID(itemID uuid.UUID) (*models.Item, error) {
   if itemID == uuid.Nil {
     return nil, fmt.Errorf("Invalid itemID")
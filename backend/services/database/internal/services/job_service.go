@@ -0,0 +1,132 @@
+package services
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+
+  "github.com/google/uuid"
+  "gorm.io/datatypes"
+
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/events"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+// JobProgress lets a running job report how far it got. SetTotal may be called
+// once the amount of work is known.
+type JobProgress interface {
+  SetTotal(total int)
+  Advance(done int)
+}
+
+// JobFunc does the actual work of a job; its result is stored as JSON.
+type JobFunc func(ctx context.Context, progress JobProgress) (interface{}, error)
+
+type JSvc interface {
+  CreateJob(companyID, createdByID uuid.UUID, jobType string, params interface{}) (*models.Job, error)
+  GetJobByID(jobID uuid.UUID) (*models.Job, error)
+  ListJobs(f repos.JobFilter) ([]*models.Job, error)
+  // Run executes fn in its own goroutine and records the outcome on the job.
+  // JOB_COMPLETED / JOB_FAILED company events are published when it ends.
+  Run(job *models.Job, fn JobFunc)
+}
+
+type jSvc struct {
+  repo            repos.JRepo
+  pub             events.PubSubPublisher
+}
+
+func NewJSvc(repo repos.JRepo, pub events.PubSubPublisher) JSvc {
+  return &jSvc{repo: repo, pub: pub}
+}
+
+func (s *jSvc) CreateJob(companyID, createdByID uuid.UUID, jobType string, params interface{}) (*models.Job, error) {
+  if companyID == uuid.Nil {
+    return nil, fmt.Errorf("invalid companyID")
+  }
+  if jobType == "" {
+    return nil, fmt.Errorf("job type is required")
+  }
+  raw, err := json.Marshal(params)
+  if err != nil {
+    return nil, fmt.Errorf("failed to encode job params: %w", err)
+  }
+  job := models.Job{
+    CompanyID:   &companyID,
+    Type:        jobType,
+    Status:      constants.JobStatusQueued,
+    Params:      datatypes.JSON(raw),
+  }
+  if createdByID != uuid.Nil {
+    job.CreatedByID = &createdByID
+  }
+  created, err := s.repo.Create(job)
+  if err != nil {
+    return nil, fmt.Errorf("failed to create job: %w", err)
+  }
+  return created, nil
+}
+
+func (s *jSvc) GetJobByID(jobID uuid.UUID) (*models.Job, error) {
+  if jobID == uuid.Nil {
+    return nil, fmt.Errorf("invalid jobID")
+  }
+  return s.repo.GetByID(jobID)
+}
+
+func (s *jSvc) ListJobs(f repos.JobFilter) ([]*models.Job, error) {
+  return s.repo.ListJobs(f)
+}
+
+func (s *jSvc) Run(job *models.Job, fn JobFunc) {
+  go s.run(*job, fn)
+}
+
+func (s *jSvc) run(job models.Job, fn JobFunc) {
+  var (
+    result interface{}
+    err    error
+  )
+  func() {
+    defer func() {
+      if r := recover(); r != nil {
+        err = fmt.Errorf("job panicked: %v", r)
+      }
+    }()
+    _ = s.repo.MarkRunning(job.ID)
+    result, err = fn(context.Background(), &jobProgress{repo: s.repo, jobID: job.ID})
+  }()
+
+  status, errMsg := constants.JobStatusSucceeded, ""
+  var raw []byte
+  if err == nil {
+    if raw, err = json.Marshal(result); err != nil {
+      err = fmt.Errorf("failed to encode job result: %w", err)
+    }
+  }
+  if err != nil {
+    status, errMsg, raw = constants.JobStatusFailed, err.Error(), nil
+  }
+  _ = s.repo.Finish(job.ID, status, datatypes.JSON(raw), errMsg)
+
+  event := "JOB_COMPLETED"
+  if status == constants.JobStatusFailed {
+    event = "JOB_FAILED"
+  }
+  _ = s.pub.PublishCompanyEvent(*job.CompanyID, event, map[string]interface{}{"job_id": job.ID, "job_type": job.Type, "status": status, "error": errMsg, "result": result})
+}
+
+type jobProgress struct {
+  repo  repos.JRepo
+  jobID uuid.UUID
+}
+
+func (p *jobProgress) SetTotal(total int) {
+  _ = p.repo.UpdateTotal(p.jobID, total)
+}
+
+func (p *jobProgress) Advance(done int) {
+  _ = p.repo.UpdateProgress(p.jobID, done)
+}
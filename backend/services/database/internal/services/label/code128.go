@@ -0,0 +1,80 @@
+package label
+
+import (
+  "fmt"
+)
+
+// code128Patterns holds the bar/space widths for every Code 128 symbol value.
+// Index 106 is the stop pattern (7 elements, 13 modules).
+var code128Patterns = [107]string{
+  "212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+  "221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+  "221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+  "212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+  "231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+  "231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+  "314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+  "112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+  "111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+  "214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+  "114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+const (
+  code128StartB = 104
+  code128StartC = 105
+  code128Stop   = 106
+)
+
+// encodeCode128 returns the module pattern (true = bar) for data, without quiet zones.
+// All-digit data of even length uses code set C; everything else uses code set B.
+func encodeCode128(data string) ([]bool, error) {
+  if data == "" {
+    return nil, fmt.Errorf("code128: empty data")
+  }
+  var values []int
+  if isEvenDigits(data) {
+    values = append(values, code128StartC)
+    for i := 0; i < len(data); i += 2 {
+      values = append(values, int(data[i]-'0')*10+int(data[i+1]-'0'))
+    }
+  } else {
+    values = append(values, code128StartB)
+    for i := 0; i < len(data); i++ {
+      c := data[i]
+      if c < 32 || c > 126 {
+        return nil, fmt.Errorf("code128: unsupported character %q", c)
+      }
+      values = append(values, int(c)-32)
+    }
+  }
+  checksum := values[0]
+  for i := 1; i < len(values); i++ {
+    checksum += values[i] * i
+  }
+  values = append(values, checksum%103, code128Stop)
+
+  var modules []bool
+  for _, v := range values {
+    bar := true
+    for _, w := range code128Patterns[v] {
+      for n := 0; n < int(w-'0'); n++ {
+        modules = append(modules, bar)
+      }
+      bar = !bar
+    }
+  }
+  return modules, nil
+}
+
+func isEvenDigits(s string) bool {
+  if len(s) < 4 || len(s)%2 != 0 {
+    return false
+  }
+  for i := 0; i < len(s); i++ {
+    if s[i] < '0' || s[i] > '9' {
+      return false
+    }
+  }
+  return true
+}
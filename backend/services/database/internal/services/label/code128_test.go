@@ -0,0 +1,75 @@
+package label
+
+import (
+  "strings"
+  "testing"
+)
+
+// widths renders a module pattern as run lengths, the form barcode tables use.
+func widths(modules []bool) string {
+  var b strings.Builder
+  run := 1
+  for i := 1; i <= len(modules); i++ {
+    if i < len(modules) && modules[i] == modules[i-1] {
+      run++
+      continue
+    }
+    b.WriteByte(byte('0' + run))
+    run = 1
+  }
+  return b.String()
+}
+
+func TestEncodeCode128(t *testing.T) {
+  tests := []struct {
+    name    string
+    data    string
+    want    string
+    wantErr bool
+  }{
+    {
+      // start B, P J J 1 2 3 C, checksum 55, stop
+      name: "code set B",
+      data: "PJJ123C",
+      want: "211214" + "313121" + "112133" + "112133" + "123221" + "223211" + "221132" + "131321" + "311321" + "2331112",
+    },
+    {
+      // start C, 12 34, checksum (105 + 12 + 2*34) % 103 = 82, stop
+      name: "even digits use code set C",
+      data: "1234",
+      want: "211232" + "112232" + "131123" + "121241" + "2331112",
+    },
+    {
+      // start B, 1 2 3, checksum (104 + 17 + 2*18 + 3*19) % 103 = 8, stop
+      name: "odd digits stay in code set B",
+      data: "123",
+      want: "211214" + "123221" + "223211" + "221132" + "132212" + "2331112",
+    },
+    {name: "empty", data: "", wantErr: true},
+    {name: "non printable", data: "A\tB", wantErr: true},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      modules, err := encodeCode128(tt.data)
+      if tt.wantErr {
+        if err == nil {
+          t.Fatalf("encodeCode128(%q) succeeded, want an error", tt.data)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatalf("encodeCode128(%q): %v", tt.data, err)
+      }
+      if got := widths(modules); got != tt.want {
+        t.Errorf("encodeCode128(%q) = %s, want %s", tt.data, got, tt.want)
+      }
+      if !modules[0] || !modules[len(modules)-1] {
+        t.Errorf("symbol must start and end with a bar")
+      }
+      // every symbol is 11 modules, the stop 13
+      if n := len(modules); (n-13)%11 != 0 {
+        t.Errorf("%d modules is not a whole number of symbols", n)
+      }
+    })
+  }
+}
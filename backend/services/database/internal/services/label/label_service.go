@@ -0,0 +1,154 @@
+package label
+
+import (
+  "context"
+  "fmt"
+  "strings"
+
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  s3service "github.com/yungbote/slotter/backend/services/database/internal/services/s3"
+)
+
+const (
+  FormatZPL = "zpl"
+  FormatPDF = "pdf"
+  FormatPNG = "png"
+
+  SymbologyCode128 = "code128"
+  SymbologyQR      = "qr"
+)
+
+var errLabelTooSmall = fmt.Errorf("label is too small for its text and margins")
+
+// Label is one printable label: Data goes into the barcode, Text and Caption
+// are printed underneath as human-readable lines.
+type Label struct {
+  Data    string
+  Text    string
+  Caption string
+}
+
+type LabelService interface {
+  // Render returns the encoded output and its file extension. PNG output is a
+  // zip archive holding one image per label.
+  Render(tpl models.LabelTemplate, format string, labels []Label) ([]byte, string, error)
+  RenderAndUpload(ctx context.Context, tpl models.LabelTemplate, format, baseName string, labels []Label) (string, string, error)
+}
+
+type labelService struct {
+  s3 s3service.S3Service
+}
+
+func NewLabelService(s3 s3service.S3Service) LabelService {
+  return &labelService{s3: s3}
+}
+
+// DefaultTemplate is used when a batch is requested without a saved template.
+func DefaultTemplate() models.LabelTemplate {
+  return models.LabelTemplate{
+    Name:       "Default",
+    Symbology:  SymbologyCode128,
+    WidthMM:    100,
+    HeightMM:   50,
+    DPI:        203,
+    TextSizeMM: 5,
+    PageSize:   "A4",
+    Columns:    2,
+    Rows:       5,
+    MarginMM:   10,
+    GapMM:      2,
+  }
+}
+
+// WithTemplateDefaults fills the unset fields of tpl from DefaultTemplate, as
+// the database defaults would on insert, so a template can be validated
+// before it is created.
+func WithTemplateDefaults(tpl models.LabelTemplate) models.LabelTemplate {
+  def := DefaultTemplate()
+  if tpl.Symbology == "" {
+    tpl.Symbology = def.Symbology
+  }
+  if tpl.WidthMM == 0 {
+    tpl.WidthMM = def.WidthMM
+  }
+  if tpl.HeightMM == 0 {
+    tpl.HeightMM = def.HeightMM
+  }
+  if tpl.DPI == 0 {
+    tpl.DPI = def.DPI
+  }
+  if tpl.TextSizeMM == 0 {
+    tpl.TextSizeMM = def.TextSizeMM
+  }
+  if tpl.PageSize == "" {
+    tpl.PageSize = def.PageSize
+  }
+  if tpl.Columns == 0 {
+    tpl.Columns = def.Columns
+  }
+  if tpl.Rows == 0 {
+    tpl.Rows = def.Rows
+  }
+  if tpl.MarginMM == 0 {
+    tpl.MarginMM = def.MarginMM
+  }
+  if tpl.GapMM == 0 {
+    tpl.GapMM = def.GapMM
+  }
+  return tpl
+}
+
+// ValidateTemplate checks the fields that the renderers rely on.
+func ValidateTemplate(tpl models.LabelTemplate) error {
+  if tpl.Symbology != SymbologyCode128 && tpl.Symbology != SymbologyQR {
+    return fmt.Errorf("unsupported symbology '%s'", tpl.Symbology)
+  }
+  if tpl.WidthMM <= 0 || tpl.HeightMM <= 0 {
+    return fmt.Errorf("label width and height must be positive")
+  }
+  if tpl.DPI <= 0 {
+    return fmt.Errorf("dpi must be positive")
+  }
+  if tpl.Columns <= 0 || tpl.Rows <= 0 {
+    return fmt.Errorf("columns and rows must be positive")
+  }
+  if _, _, ok := pageSizeMM(tpl.PageSize); !ok {
+    return fmt.Errorf("unsupported page size '%s'", tpl.PageSize)
+  }
+  return nil
+}
+
+func (l *labelService) Render(tpl models.LabelTemplate, format string, labels []Label) ([]byte, string, error) {
+  if len(labels) == 0 {
+    return nil, "", fmt.Errorf("no labels to render")
+  }
+  if err := ValidateTemplate(tpl); err != nil {
+    return nil, "", err
+  }
+  switch strings.ToLower(format) {
+  case FormatZPL:
+    data, err := renderZPL(tpl, labels)
+    return data, ".zpl", err
+  case FormatPDF:
+    data, err := renderPDF(tpl, labels)
+    return data, ".pdf", err
+  case FormatPNG:
+    data, err := renderPNGZip(tpl, labels)
+    return data, ".zip", err
+  }
+  return nil, "", fmt.Errorf("unsupported label format '%s'", format)
+}
+
+// RenderAndUpload renders labels and stores the result in S3, returning the file name and URL.
+func (l *labelService) RenderAndUpload(ctx context.Context, tpl models.LabelTemplate, format, baseName string, labels []Label) (string, string, error) {
+  data, ext, err := l.Render(tpl, format, labels)
+  if err != nil {
+    return "", "", err
+  }
+  fileName := baseName + ext
+  url, err := l.s3.UploadFile(ctx, fileName, data)
+  if err != nil {
+    return "", "", fmt.Errorf("failed to upload labels to s3: %w", err)
+  }
+  return fileName, url, nil
+}
@@ -0,0 +1,49 @@
+package label
+
+import (
+  "testing"
+
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+func TestTemplateDefaults(t *testing.T) {
+  tests := []struct {
+    name    string
+    tpl     models.LabelTemplate
+    check   func(models.LabelTemplate) bool
+    wantErr bool
+  }{
+    {
+      name:  "name only",
+      tpl:   models.LabelTemplate{Name: "Bins"},
+      check: func(tpl models.LabelTemplate) bool { return tpl == withName(DefaultTemplate(), "Bins") },
+    },
+    {
+      name: "set fields are kept",
+      tpl:  models.LabelTemplate{Name: "Totes", Symbology: SymbologyQR, WidthMM: 60, DPI: 300, PageSize: "LETTER", HideText: true},
+      check: func(tpl models.LabelTemplate) bool {
+        return tpl.Symbology == SymbologyQR && tpl.WidthMM == 60 && tpl.HeightMM == 50 && tpl.DPI == 300 && tpl.PageSize == "LETTER" && tpl.HideText
+      },
+    },
+    {name: "negative size still fails", tpl: models.LabelTemplate{Name: "Bad", WidthMM: -1}, wantErr: true},
+    {name: "unknown symbology still fails", tpl: models.LabelTemplate{Name: "Bad", Symbology: "ean13"}, wantErr: true},
+    {name: "unknown page still fails", tpl: models.LabelTemplate{Name: "Bad", PageSize: "A3"}, wantErr: true},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      tpl := WithTemplateDefaults(tt.tpl)
+      err := ValidateTemplate(tpl)
+      if (err != nil) != tt.wantErr {
+        t.Fatalf("ValidateTemplate error = %v, want error %v", err, tt.wantErr)
+      }
+      if tt.check != nil && !tt.check(tpl) {
+        t.Errorf("unexpected template %+v", tpl)
+      }
+    })
+  }
+}
+
+func withName(tpl models.LabelTemplate, name string) models.LabelTemplate {
+  tpl.Name = name
+  return tpl
+}
@@ -0,0 +1,149 @@
+package label
+
+import (
+  "math"
+  "strings"
+
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+// All layout coordinates are millimetres from the top-left corner of a label.
+
+type rect struct {
+  x, y, w, h float64
+}
+
+type textLine struct {
+  x, y, size float64 // y is the top of the line
+  text       string
+}
+
+type labelLayout struct {
+  barcode    rect   // area reserved for the barcode
+  bars       []rect // dark modules, already positioned inside barcode
+  modules    int    // total module count across the barcode including quiet zone
+  moduleMM   float64
+  text       []textLine
+}
+
+const (
+  code128QuietModules = 10
+  qrQuietModules      = 4
+  captionScale        = 0.7
+  lineSpacing         = 1.2
+)
+
+func labelPadding(tpl models.LabelTemplate) float64 {
+  return math.Min(tpl.WidthMM, tpl.HeightMM) * 0.05
+}
+
+// layoutLabel positions the barcode modules and the text lines for one label.
+func layoutLabel(tpl models.LabelTemplate, lbl Label) (labelLayout, error) {
+  pad := labelPadding(tpl)
+  var out labelLayout
+
+  textH := 0.0
+  if !tpl.HideText {
+    y := tpl.HeightMM - pad
+    if lbl.Caption != "" {
+      size := tpl.TextSizeMM * captionScale
+      y -= size
+      out.text = append(out.text, textLine{x: pad, y: y, size: size, text: printable(lbl.Caption)})
+      y -= size * (lineSpacing - 1)
+    }
+    if lbl.Text != "" {
+      y -= tpl.TextSizeMM
+      out.text = append([]textLine{{x: pad, y: y, size: tpl.TextSizeMM, text: printable(lbl.Text)}}, out.text...)
+    }
+    textH = tpl.HeightMM - pad - y
+    if textH > 0 {
+      textH += tpl.TextSizeMM * (lineSpacing - 1)
+    }
+  }
+  out.barcode = rect{x: pad, y: pad, w: tpl.WidthMM - 2*pad, h: tpl.HeightMM - 2*pad - textH}
+  if out.barcode.w <= 0 || out.barcode.h <= 0 {
+    return out, errLabelTooSmall
+  }
+
+  switch tpl.Symbology {
+  case SymbologyQR:
+    matrix, err := encodeQR(lbl.Data)
+    if err != nil {
+      return out, err
+    }
+    n := len(matrix) + 2*qrQuietModules
+    side := math.Min(out.barcode.w, out.barcode.h)
+    out.modules = n
+    out.moduleMM = side / float64(n)
+    ox := out.barcode.x + (out.barcode.w-side)/2 + qrQuietModules*out.moduleMM
+    oy := out.barcode.y + qrQuietModules*out.moduleMM
+    for y, row := range matrix {
+      // merge horizontal runs so PDF/PNG output stays small
+      for x := 0; x < len(row); {
+        if !row[x] {
+          x++
+          continue
+        }
+        start := x
+        for x < len(row) && row[x] {
+          x++
+        }
+        out.bars = append(out.bars, rect{
+          x: ox + float64(start)*out.moduleMM,
+          y: oy + float64(y)*out.moduleMM,
+          w: float64(x-start) * out.moduleMM,
+          h: out.moduleMM,
+        })
+      }
+    }
+  default:
+    modules, err := encodeCode128(lbl.Data)
+    if err != nil {
+      return out, err
+    }
+    out.modules = len(modules) + 2*code128QuietModules
+    out.moduleMM = out.barcode.w / float64(out.modules)
+    ox := out.barcode.x + code128QuietModules*out.moduleMM
+    for x := 0; x < len(modules); {
+      if !modules[x] {
+        x++
+        continue
+      }
+      start := x
+      for x < len(modules) && modules[x] {
+        x++
+      }
+      out.bars = append(out.bars, rect{
+        x: ox + float64(start)*out.moduleMM,
+        y: out.barcode.y,
+        w: float64(x-start) * out.moduleMM,
+        h: out.barcode.h,
+      })
+    }
+  }
+  return out, nil
+}
+
+// printable drops characters the built-in fonts cannot draw.
+func printable(s string) string {
+  return strings.Map(func(r rune) rune {
+    if r < 32 || r > 126 {
+      return '?'
+    }
+    return r
+  }, s)
+}
+
+func pageSizeMM(name string) (float64, float64, bool) {
+  switch strings.ToUpper(name) {
+  case "A4":
+    return 210, 297, true
+  case "LETTER":
+    return 215.9, 279.4, true
+  }
+  return 0, 0, false
+}
+
+func mmToDots(mm float64, dpi int) int {
+  return int(math.Round(mm * float64(dpi) / 25.4))
+}
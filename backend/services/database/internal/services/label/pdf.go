@@ -0,0 +1,81 @@
+package label
+
+import (
+  "bytes"
+  "fmt"
+  "strings"
+
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+const ptPerMM = 72 / 25.4
+
+// renderPDF lays labels out on sheets of Columns x Rows using the built-in
+// Helvetica font, so no font files need to be embedded.
+func renderPDF(tpl models.LabelTemplate, labels []Label) ([]byte, error) {
+  pageW, pageH, _ := pageSizeMM(tpl.PageSize)
+  perPage := tpl.Columns * tpl.Rows
+
+  var pages []string
+  var content strings.Builder
+  for i, lbl := range labels {
+    lay, err := layoutLabel(tpl, lbl)
+    if err != nil {
+      return nil, err
+    }
+    slot := i % perPage
+    ox := tpl.MarginMM + float64(slot%tpl.Columns)*(tpl.WidthMM+tpl.GapMM)
+    oy := tpl.MarginMM + float64(slot/tpl.Columns)*(tpl.HeightMM+tpl.GapMM)
+
+    content.WriteString("0 g\n")
+    for _, r := range lay.bars {
+      fmt.Fprintf(&content, "%.3f %.3f %.3f %.3f re\n",
+        (ox+r.x)*ptPerMM, (pageH-oy-r.y-r.h)*ptPerMM, r.w*ptPerMM, r.h*ptPerMM)
+    }
+    content.WriteString("f\n")
+    for _, line := range lay.text {
+      size := line.size * ptPerMM
+      // baseline sits roughly 80% of the cap-to-descender height below the line top
+      fmt.Fprintf(&content, "BT /F1 %.2f Tf %.3f %.3f Td (%s) Tj ET\n",
+        size, (ox+line.x)*ptPerMM, (pageH-oy-line.y-line.size*0.8)*ptPerMM, pdfEscape(line.text))
+    }
+
+    if slot == perPage-1 || i == len(labels)-1 {
+      pages = append(pages, content.String())
+      content.Reset()
+    }
+  }
+
+  var buf bytes.Buffer
+  var offsets []int
+  obj := func(body string) {
+    offsets = append(offsets, buf.Len())
+    fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+  }
+
+  buf.WriteString("%PDF-1.4\n")
+  kids := make([]string, len(pages))
+  for i := range pages {
+    kids[i] = fmt.Sprintf("%d 0 R", 4+i*2)
+  }
+  obj("<< /Type /Catalog /Pages 2 0 R >>")
+  obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+  obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+  for i, stream := range pages {
+    obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+      pageW*ptPerMM, pageH*ptPerMM, 5+i*2))
+    obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(stream), stream))
+  }
+
+  xref := buf.Len()
+  fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+  for _, off := range offsets {
+    fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+  }
+  fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+  return buf.Bytes(), nil
+}
+
+func pdfEscape(s string) string {
+  return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+}
@@ -0,0 +1,97 @@
+package label
+
+import (
+  "archive/zip"
+  "bytes"
+  "fmt"
+  "image"
+  "image/color"
+  "image/draw"
+  "image/png"
+  "math"
+
+  "golang.org/x/image/font"
+  "golang.org/x/image/font/basicfont"
+  "golang.org/x/image/math/fixed"
+
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+// renderPNGZip renders each label to a grayscale PNG at the template DPI and
+// bundles them into a zip archive.
+func renderPNGZip(tpl models.LabelTemplate, labels []Label) ([]byte, error) {
+  var buf bytes.Buffer
+  zw := zip.NewWriter(&buf)
+  for i, lbl := range labels {
+    img, err := renderPNG(tpl, lbl)
+    if err != nil {
+      return nil, err
+    }
+    w, err := zw.Create(fmt.Sprintf("label-%04d.png", i+1))
+    if err != nil {
+      return nil, fmt.Errorf("failed to add label to archive: %w", err)
+    }
+    if err := png.Encode(w, img); err != nil {
+      return nil, fmt.Errorf("failed to encode label png: %w", err)
+    }
+  }
+  if err := zw.Close(); err != nil {
+    return nil, fmt.Errorf("failed to finalize label archive: %w", err)
+  }
+  return buf.Bytes(), nil
+}
+
+func renderPNG(tpl models.LabelTemplate, lbl Label) (*image.Gray, error) {
+  lay, err := layoutLabel(tpl, lbl)
+  if err != nil {
+    return nil, err
+  }
+  dots := func(mm float64) int { return mmToDots(mm, tpl.DPI) }
+  img := image.NewGray(image.Rect(0, 0, dots(tpl.WidthMM), dots(tpl.HeightMM)))
+  draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+  for _, r := range lay.bars {
+    draw.Draw(img, image.Rect(dots(r.x), dots(r.y), dots(r.x+r.w), dots(r.y+r.h)), image.Black, image.Point{}, draw.Src)
+  }
+  for _, line := range lay.text {
+    drawText(img, line.text, dots(line.x), dots(line.y), dots(line.size))
+  }
+  return img, nil
+}
+
+// drawText renders with the fixed 7x13 bitmap face and scales it up by an
+// integer factor to approximate the requested height in pixels.
+func drawText(dst *image.Gray, text string, x, y, heightPx int) {
+  face := basicfont.Face7x13
+  scale := int(math.Round(float64(heightPx) / float64(face.Height)))
+  if scale < 1 {
+    scale = 1
+  }
+  width := font.MeasureString(face, text).Ceil()
+  src := image.NewGray(image.Rect(0, 0, width, face.Height))
+  draw.Draw(src, src.Bounds(), image.White, image.Point{}, draw.Src)
+  d := &font.Drawer{
+    Dst:  src,
+    Src:  image.Black,
+    Face: face,
+    Dot:  fixed.P(0, face.Ascent),
+  }
+  d.DrawString(text)
+
+  bounds := dst.Bounds()
+  for sy := 0; sy < face.Height; sy++ {
+    for sx := 0; sx < width; sx++ {
+      c := src.GrayAt(sx, sy)
+      if c.Y > 127 {
+        continue
+      }
+      for dy := 0; dy < scale; dy++ {
+        for dx := 0; dx < scale; dx++ {
+          px, py := x+sx*scale+dx, y+sy*scale+dy
+          if image.Pt(px, py).In(bounds) {
+            dst.SetGray(px, py, color.Gray{Y: 0})
+          }
+        }
+      }
+    }
+  }
+}
@@ -0,0 +1,418 @@
+package label
+
+import (
+  "fmt"
+)
+
+// QR codes are encoded in byte mode at error correction level M, versions 1-10
+// (up to 213 bytes), which comfortably covers location paths and item numbers.
+
+type qrVersion struct {
+  ecPerBlock int
+  blocks     []int // data codewords per block
+  align      []int
+}
+
+var qrVersionsM = []qrVersion{
+  {},
+  {10, []int{16}, nil},
+  {16, []int{28}, []int{6, 18}},
+  {26, []int{44}, []int{6, 22}},
+  {18, []int{32, 32}, []int{6, 26}},
+  {24, []int{43, 43}, []int{6, 30}},
+  {16, []int{27, 27, 27, 27}, []int{6, 34}},
+  {18, []int{31, 31, 31, 31}, []int{6, 22, 38}},
+  {22, []int{38, 38, 39, 39}, []int{6, 24, 42}},
+  {22, []int{36, 36, 36, 37, 37}, []int{6, 26, 46}},
+  {26, []int{43, 43, 43, 43, 44}, []int{6, 28, 50}},
+}
+
+const qrFormatBitsM = 0
+
+type qrCode struct {
+  size     int
+  modules  [][]bool
+  function [][]bool
+}
+
+// encodeQR returns the module matrix (true = dark) for data, without the quiet zone.
+func encodeQR(data string) ([][]bool, error) {
+  payload := []byte(data)
+  version := 0
+  for v := 1; v < len(qrVersionsM); v++ {
+    countBits := 8
+    if v >= 10 {
+      countBits = 16
+    }
+    if 4+countBits+len(payload)*8 <= qrDataCapacity(v)*8 {
+      version = v
+      break
+    }
+  }
+  if version == 0 {
+    return nil, fmt.Errorf("qr: data too long (%d bytes)", len(payload))
+  }
+
+  codewords := qrDataCodewords(version, payload)
+  final := qrAddECC(version, codewords)
+
+  size := version*4 + 17
+  q := &qrCode{size: size, modules: make([][]bool, size), function: make([][]bool, size)}
+  for i := range q.modules {
+    q.modules[i] = make([]bool, size)
+    q.function[i] = make([]bool, size)
+  }
+  q.drawFunctionPatterns(version)
+  q.drawCodewords(final)
+
+  best, bestPenalty := 0, -1
+  for mask := 0; mask < 8; mask++ {
+    q.applyMask(mask)
+    q.drawFormatBits(mask)
+    p := q.penalty()
+    if bestPenalty < 0 || p < bestPenalty {
+      best, bestPenalty = mask, p
+    }
+    q.applyMask(mask)
+  }
+  q.applyMask(best)
+  q.drawFormatBits(best)
+  return q.modules, nil
+}
+
+func qrDataCapacity(version int) int {
+  total := 0
+  for _, n := range qrVersionsM[version].blocks {
+    total += n
+  }
+  return total
+}
+
+// qrDataCodewords builds the byte-mode bit stream, terminator and padding.
+func qrDataCodewords(version int, payload []byte) []byte {
+  var bits []bool
+  appendBits := func(val, n int) {
+    for i := n - 1; i >= 0; i-- {
+      bits = append(bits, (val>>uint(i))&1 == 1)
+    }
+  }
+  appendBits(0x4, 4)
+  if version >= 10 {
+    appendBits(len(payload), 16)
+  } else {
+    appendBits(len(payload), 8)
+  }
+  for _, b := range payload {
+    appendBits(int(b), 8)
+  }
+  capacity := qrDataCapacity(version) * 8
+  for i := 0; i < 4 && len(bits) < capacity; i++ {
+    bits = append(bits, false)
+  }
+  for len(bits)%8 != 0 {
+    bits = append(bits, false)
+  }
+  out := make([]byte, 0, capacity/8)
+  for i := 0; i < len(bits); i += 8 {
+    var b byte
+    for j := 0; j < 8; j++ {
+      if bits[i+j] {
+        b |= 1 << uint(7-j)
+      }
+    }
+    out = append(out, b)
+  }
+  for pad := byte(0xEC); len(out) < capacity/8; pad ^= 0xEC ^ 0x11 {
+    out = append(out, pad)
+  }
+  return out
+}
+
+// qrAddECC splits data into blocks, appends Reed-Solomon ECC and interleaves.
+func qrAddECC(version int, data []byte) []byte {
+  v := qrVersionsM[version]
+  divisor := rsDivisor(v.ecPerBlock)
+  var dataBlocks, eccBlocks [][]byte
+  k, maxLen := 0, 0
+  for _, n := range v.blocks {
+    block := data[k : k+n]
+    k += n
+    dataBlocks = append(dataBlocks, block)
+    eccBlocks = append(eccBlocks, rsRemainder(block, divisor))
+    if n > maxLen {
+      maxLen = n
+    }
+  }
+  var out []byte
+  for i := 0; i < maxLen; i++ {
+    for _, block := range dataBlocks {
+      if i < len(block) {
+        out = append(out, block[i])
+      }
+    }
+  }
+  for i := 0; i < v.ecPerBlock; i++ {
+    for _, block := range eccBlocks {
+      out = append(out, block[i])
+    }
+  }
+  return out
+}
+
+func gfMul(x, y byte) byte {
+  var z int
+  for i := 7; i >= 0; i-- {
+    z = (z << 1) ^ ((z >> 7) * 0x11D)
+    z ^= int((y>>uint(i))&1) * int(x)
+  }
+  return byte(z)
+}
+
+func rsDivisor(degree int) []byte {
+  result := make([]byte, degree)
+  result[degree-1] = 1
+  root := byte(1)
+  for i := 0; i < degree; i++ {
+    for j := range result {
+      result[j] = gfMul(result[j], root)
+      if j+1 < len(result) {
+        result[j] ^= result[j+1]
+      }
+    }
+    root = gfMul(root, 0x02)
+  }
+  return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+  result := make([]byte, len(divisor))
+  for _, b := range data {
+    factor := b ^ result[0]
+    copy(result, result[1:])
+    result[len(result)-1] = 0
+    for i := range result {
+      result[i] ^= gfMul(divisor[i], factor)
+    }
+  }
+  return result
+}
+
+func (q *qrCode) set(x, y int, dark bool) {
+  q.modules[y][x] = dark
+  q.function[y][x] = true
+}
+
+func (q *qrCode) drawFunctionPatterns(version int) {
+  for i := 0; i < q.size; i++ {
+    q.set(6, i, i%2 == 0)
+    q.set(i, 6, i%2 == 0)
+  }
+  q.drawFinder(3, 3)
+  q.drawFinder(q.size-4, 3)
+  q.drawFinder(3, q.size-4)
+
+  align := qrVersionsM[version].align
+  last := len(align) - 1
+  for i, ax := range align {
+    for j, ay := range align {
+      if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+        continue
+      }
+      for dy := -2; dy <= 2; dy++ {
+        for dx := -2; dx <= 2; dx++ {
+          q.set(ax+dx, ay+dy, maxAbs(dx, dy) != 1)
+        }
+      }
+    }
+  }
+
+  q.drawFormatBits(0) // reserve the area; real bits are drawn after masking
+
+  if version >= 7 {
+    rem := version
+    for i := 0; i < 12; i++ {
+      rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+    }
+    bits := version<<12 | rem
+    for i := 0; i < 18; i++ {
+      dark := (bits>>uint(i))&1 == 1
+      a, b := q.size-11+i%3, i/3
+      q.set(a, b, dark)
+      q.set(b, a, dark)
+    }
+  }
+}
+
+func (q *qrCode) drawFinder(cx, cy int) {
+  for dy := -4; dy <= 4; dy++ {
+    for dx := -4; dx <= 4; dx++ {
+      x, y := cx+dx, cy+dy
+      if x < 0 || x >= q.size || y < 0 || y >= q.size {
+        continue
+      }
+      dist := maxAbs(dx, dy)
+      q.set(x, y, dist != 2 && dist != 4)
+    }
+  }
+}
+
+func (q *qrCode) drawFormatBits(mask int) {
+  data := qrFormatBitsM<<3 | mask
+  rem := data
+  for i := 0; i < 10; i++ {
+    rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+  }
+  bits := (data<<10 | rem) ^ 0x5412
+  bit := func(i int) bool { return (bits>>uint(i))&1 == 1 }
+
+  for i := 0; i <= 5; i++ {
+    q.set(8, i, bit(i))
+  }
+  q.set(8, 7, bit(6))
+  q.set(8, 8, bit(7))
+  q.set(7, 8, bit(8))
+  for i := 9; i < 15; i++ {
+    q.set(14-i, 8, bit(i))
+  }
+  for i := 0; i < 8; i++ {
+    q.set(q.size-1-i, 8, bit(i))
+  }
+  for i := 8; i < 15; i++ {
+    q.set(8, q.size-15+i, bit(i))
+  }
+  q.set(8, q.size-8, true)
+}
+
+// drawCodewords places data bits in the zig-zag order, skipping function modules.
+func (q *qrCode) drawCodewords(data []byte) {
+  i := 0
+  for right := q.size - 1; right >= 1; right -= 2 {
+    if right == 6 {
+      right = 5
+    }
+    for vert := 0; vert < q.size; vert++ {
+      for j := 0; j < 2; j++ {
+        x := right - j
+        upward := (right+1)&2 == 0
+        y := vert
+        if upward {
+          y = q.size - 1 - vert
+        }
+        if !q.function[y][x] && i < len(data)*8 {
+          q.modules[y][x] = (data[i>>3]>>uint(7-(i&7)))&1 == 1
+          i++
+        }
+      }
+    }
+  }
+}
+
+func (q *qrCode) applyMask(mask int) {
+  for y := 0; y < q.size; y++ {
+    for x := 0; x < q.size; x++ {
+      if q.function[y][x] {
+        continue
+      }
+      var invert bool
+      switch mask {
+      case 0:
+        invert = (x+y)%2 == 0
+      case 1:
+        invert = y%2 == 0
+      case 2:
+        invert = x%3 == 0
+      case 3:
+        invert = (x+y)%3 == 0
+      case 4:
+        invert = (x/3+y/2)%2 == 0
+      case 5:
+        invert = x*y%2+x*y%3 == 0
+      case 6:
+        invert = (x*y%2+x*y%3)%2 == 0
+      case 7:
+        invert = ((x+y)%2+x*y%3)%2 == 0
+      }
+      if invert {
+        q.modules[y][x] = !q.modules[y][x]
+      }
+    }
+  }
+}
+
+// penalty scores the symbol with the four standard mask evaluation rules.
+func (q *qrCode) penalty() int {
+  n := q.size
+  score := 0
+  at := func(x, y int, transpose bool) bool {
+    if transpose {
+      return q.modules[x][y]
+    }
+    return q.modules[y][x]
+  }
+  finderA := []bool{true, false, true, true, true, false, true, false, false, false, false}
+  finderB := []bool{false, false, false, false, true, false, true, true, true, false, true}
+  for _, transpose := range []bool{false, true} {
+    for y := 0; y < n; y++ {
+      run := 1
+      for x := 1; x <= n; x++ {
+        if x < n && at(x, y, transpose) == at(x-1, y, transpose) {
+          run++
+          continue
+        }
+        if run >= 5 {
+          score += 3 + run - 5
+        }
+        run = 1
+      }
+      for x := 0; x+len(finderA) <= n; x++ {
+        matchA, matchB := true, true
+        for k := range finderA {
+          v := at(x+k, y, transpose)
+          matchA = matchA && v == finderA[k]
+          matchB = matchB && v == finderB[k]
+        }
+        if matchA {
+          score += 40
+        }
+        if matchB {
+          score += 40
+        }
+      }
+    }
+  }
+  dark := 0
+  for y := 0; y < n; y++ {
+    for x := 0; x < n; x++ {
+      if q.modules[y][x] {
+        dark++
+      }
+      if x+1 < n && y+1 < n {
+        c := q.modules[y][x]
+        if c == q.modules[y][x+1] && c == q.modules[y+1][x] && c == q.modules[y+1][x+1] {
+          score += 3
+        }
+      }
+    }
+  }
+  total := n * n
+  k := (abs(dark*20-total*10)+total-1)/total - 1
+  if k > 0 {
+    score += k * 10
+  }
+  return score
+}
+
+func maxAbs(a, b int) int {
+  a, b = abs(a), abs(b)
+  if a > b {
+    return a
+  }
+  return b
+}
+
+func abs(a int) int {
+  if a < 0 {
+    return -a
+  }
+  return a
+}
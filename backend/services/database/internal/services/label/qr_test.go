@@ -0,0 +1,168 @@
+package label
+
+import (
+  "bytes"
+  "strings"
+  "testing"
+)
+
+// qrFormatM are the standard format information strings of error correction
+// level M for masks 0-7, most significant bit first.
+var qrFormatM = []string{
+  "101010000010010", "101000100100101", "101111001111100", "101101101001011",
+  "100010111111001", "100000011001110", "100111110010111", "100101010100000",
+}
+
+func TestRSRemainder(t *testing.T) {
+  // data and error correction codewords of the 1-M "HELLO WORLD" symbol
+  data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+  want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+  if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+    t.Errorf("rsRemainder = %v, want %v", got, want)
+  }
+}
+
+func TestQRDataCodewords(t *testing.T) {
+  // mode 0100, count 00000010, 'h' 'i', terminator, then 0xEC 0x11 padding
+  want := []byte{0x40, 0x26, 0x86, 0x90, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+  if got := qrDataCodewords(1, []byte("hi")); !bytes.Equal(got, want) {
+    t.Errorf("qrDataCodewords = % x, want % x", got, want)
+  }
+}
+
+func TestEncodeQR(t *testing.T) {
+  tests := []struct {
+    name     string
+    data     string
+    wantSize int
+    wantErr  bool
+  }{
+    {name: "version 1", data: "A-01-02-03", wantSize: 21},
+    {name: "version 1 full", data: strings.Repeat("x", 14), wantSize: 21},
+    {name: "version 2", data: strings.Repeat("x", 15), wantSize: 25},
+    {name: "version 7 carries version bits", data: strings.Repeat("x", 120), wantSize: 45},
+    {name: "version 10 full", data: strings.Repeat("x", 213), wantSize: 57},
+    {name: "too long", data: strings.Repeat("x", 214), wantErr: true},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      m, err := encodeQR(tt.data)
+      if tt.wantErr {
+        if err == nil {
+          t.Fatalf("encodeQR succeeded, want an error")
+        }
+        return
+      }
+      if err != nil {
+        t.Fatalf("encodeQR: %v", err)
+      }
+      n := len(m)
+      if n != tt.wantSize {
+        t.Fatalf("size %d, want %d", n, tt.wantSize)
+      }
+      for _, corner := range [][2]int{{0, 0}, {n - 7, 0}, {0, n - 7}} {
+        for dy := 0; dy < 7; dy++ {
+          for dx := 0; dx < 7; dx++ {
+            ring := max(abs(dx-3), abs(dy-3))
+            if m[corner[1]+dy][corner[0]+dx] != (ring != 2) {
+              t.Fatalf("finder pattern at %v broken at %d,%d", corner, dx, dy)
+            }
+          }
+        }
+      }
+      for i := 8; i < n-8; i++ {
+        if m[6][i] != (i%2 == 0) || m[i][6] != (i%2 == 0) {
+          t.Fatalf("timing pattern broken at %d", i)
+        }
+      }
+
+      // both copies of the format information name a valid level M mask
+      var first, second int
+      for i := 0; i <= 5; i++ {
+        first |= qrBit(m[i][8], i)
+      }
+      first |= qrBit(m[7][8], 6) | qrBit(m[8][8], 7) | qrBit(m[8][7], 8)
+      for i := 9; i < 15; i++ {
+        first |= qrBit(m[8][14-i], i)
+      }
+      for i := 0; i < 8; i++ {
+        second |= qrBit(m[8][n-1-i], i)
+      }
+      for i := 8; i < 15; i++ {
+        second |= qrBit(m[n-15+i][8], i)
+      }
+      if first != second {
+        t.Fatalf("format copies differ: %015b and %015b", first, second)
+      }
+      mask := -1
+      for i, f := range qrFormatM {
+        if f == formatBits(first) {
+          mask = i
+        }
+      }
+      if mask < 0 {
+        t.Fatalf("format %015b is not a level M format", first)
+      }
+
+      // unmasking and reading the zig-zag back gives the encoded codewords
+      version := (n - 17) / 4
+      q := &qrCode{size: n, modules: make([][]bool, n), function: make([][]bool, n)}
+      for i := range q.modules {
+        q.modules[i] = make([]bool, n)
+        q.function[i] = make([]bool, n)
+      }
+      q.drawFunctionPatterns(version)
+      for y := range m {
+        copy(q.modules[y], m[y])
+      }
+      q.applyMask(mask)
+      want := qrAddECC(version, qrDataCodewords(version, []byte(tt.data)))
+      if got := readCodewords(q, len(want)); !bytes.Equal(got, want) {
+        t.Errorf("codewords read back differ from the encoded ones")
+      }
+    })
+  }
+}
+
+func qrBit(dark bool, i int) int {
+  if dark {
+    return 1 << uint(i)
+  }
+  return 0
+}
+
+func formatBits(v int) string {
+  s := []byte(strings.Repeat("0", 15))
+  for i := 0; i < 15; i++ {
+    if v>>uint(i)&1 == 1 {
+      s[14-i] = '1'
+    }
+  }
+  return string(s)
+}
+
+// readCodewords walks the data modules in placement order.
+func readCodewords(q *qrCode, count int) []byte {
+  out := make([]byte, count)
+  i := 0
+  for right := q.size - 1; right >= 1; right -= 2 {
+    if right == 6 {
+      right = 5
+    }
+    for vert := 0; vert < q.size; vert++ {
+      for j := 0; j < 2; j++ {
+        x, y := right-j, vert
+        if (right+1)&2 == 0 {
+          y = q.size - 1 - vert
+        }
+        if !q.function[y][x] && i < count*8 {
+          if q.modules[y][x] {
+            out[i>>3] |= 1 << uint(7-(i&7))
+          }
+          i++
+        }
+      }
+    }
+  }
+  return out
+}
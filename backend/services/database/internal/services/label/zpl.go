@@ -0,0 +1,57 @@
+package label
+
+import (
+  "bytes"
+  "fmt"
+  "strings"
+
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+// renderZPL emits one ^XA..^XZ block per label. Barcodes use the printer's
+// native ^BC / ^BQ commands so they stay crisp at any print density.
+func renderZPL(tpl models.LabelTemplate, labels []Label) ([]byte, error) {
+  var buf bytes.Buffer
+  dots := func(mm float64) int { return mmToDots(mm, tpl.DPI) }
+  for _, lbl := range labels {
+    lay, err := layoutLabel(tpl, lbl)
+    if err != nil {
+      return nil, err
+    }
+    buf.WriteString("^XA\n^CI28\n")
+    fmt.Fprintf(&buf, "^PW%d\n^LL%d\n", dots(tpl.WidthMM), dots(tpl.HeightMM))
+
+    moduleDots := int(lay.moduleMM * float64(tpl.DPI) / 25.4)
+    if moduleDots < 1 {
+      moduleDots = 1
+    }
+    switch tpl.Symbology {
+    case SymbologyQR:
+      if moduleDots > 10 {
+        moduleDots = 10
+      }
+      side := moduleDots * lay.modules
+      x := dots(lay.barcode.x) + (dots(lay.barcode.w)-side)/2 + qrQuietModules*moduleDots
+      y := dots(lay.barcode.y) + qrQuietModules*moduleDots
+      fmt.Fprintf(&buf, "^FO%d,%d^BQN,2,%d^FH_^FDMA,%s^FS\n", x, y, moduleDots, zplEscape(lbl.Data))
+    default:
+      x := dots(lay.barcode.x) + code128QuietModules*moduleDots
+      fmt.Fprintf(&buf, "^BY%d\n^FO%d,%d^BCN,%d,N,N,N,A^FH_^FD%s^FS\n",
+        moduleDots, x, dots(lay.barcode.y), dots(lay.barcode.h), zplEscape(lbl.Data))
+    }
+
+    for _, line := range lay.text {
+      h := dots(line.size)
+      fmt.Fprintf(&buf, "^FO%d,%d^A0N,%d,%d^FB%d,1,0,L^FH_^FD%s^FS\n",
+        dots(line.x), dots(line.y), h, h, dots(tpl.WidthMM-2*line.x), zplEscape(line.text))
+    }
+    buf.WriteString("^XZ\n")
+  }
+  return buf.Bytes(), nil
+}
+
+// zplEscape hex-encodes the characters that ZPL treats as command prefixes
+// (used together with ^FH_).
+func zplEscape(s string) string {
+  return strings.NewReplacer("_", "_5F", "^", "_5E", "~", "_7E").Replace(s)
+}
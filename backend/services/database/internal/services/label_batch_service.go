@@ -0,0 +1,47 @@
+package services
+
+import (
+  "fmt"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+type LBSvc interface {
+  CreateLabelBatch(batch models.LabelBatch) (*models.LabelBatch, error)
+  GetLabelBatchByID(batchID uuid.UUID) (*models.LabelBatch, error)
+  ListLabelBatches(f repos.LabelBatchFilter) ([]*models.LabelBatch, error)
+}
+
+type lbSvc struct {
+  repo            repos.LBRepo
+}
+
+func NewLBSvc(repo repos.LBRepo) LBSvc {
+  return &lbSvc{repo: repo}
+}
+
+func (s *lbSvc) CreateLabelBatch(batch models.LabelBatch) (*models.LabelBatch, error) {
+  if batch.CompanyID == nil {
+    return nil, fmt.Errorf("label batch company is required")
+  }
+  if batch.FilePathURL == "" {
+    return nil, fmt.Errorf("label batch file url is required")
+  }
+  created, err := s.repo.Create(batch)
+  if err != nil {
+    return nil, fmt.Errorf("failed to create label batch: %w", err)
+  }
+  return created, nil
+}
+
+func (s *lbSvc) GetLabelBatchByID(batchID uuid.UUID) (*models.LabelBatch, error) {
+  if batchID == uuid.Nil {
+    return nil, fmt.Errorf("invalid batchID")
+  }
+  return s.repo.GetByID(batchID)
+}
+
+func (s *lbSvc) ListLabelBatches(f repos.LabelBatchFilter) ([]*models.LabelBatch, error) {
+  return s.repo.ListLabelBatches(f)
+}
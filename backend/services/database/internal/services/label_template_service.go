@@ -0,0 +1,77 @@
+package services
+
+import (
+  "fmt"
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+  "github.com/yungbote/slotter/backend/services/database/internal/services/label"
+)
+
+type LTSvc interface {
+  CreateLabelTemplate(tpl models.LabelTemplate) (*models.LabelTemplate, error)
+  UpdateLabelTemplate(tpl models.LabelTemplate) error
+  GetLabelTemplateByID(templateID uuid.UUID) (*models.LabelTemplate, error)
+  DeleteLabelTemplate(templateID uuid.UUID) error
+  ListLabelTemplates(companyID uuid.UUID) ([]*models.LabelTemplate, error)
+}
+
+type ltSvc struct {
+  repo            repos.LTRepo
+}
+
+func NewLTSvc(repo repos.LTRepo) LTSvc {
+  return &ltSvc{repo: repo}
+}
+
+func (s *ltSvc) CreateLabelTemplate(tpl models.LabelTemplate) (*models.LabelTemplate, error) {
+  if tpl.Name == "" {
+    return nil, fmt.Errorf("label template name is required")
+  }
+  if tpl.CompanyID == nil {
+    return nil, fmt.Errorf("label template company is required")
+  }
+  tpl = label.WithTemplateDefaults(tpl)
+  if err := label.ValidateTemplate(tpl); err != nil {
+    return nil, err
+  }
+  created, err := s.repo.Create(tpl)
+  if err != nil {
+    return nil, fmt.Errorf("failed to create label template: %w", err)
+  }
+  return created, nil
+}
+
+func (s *ltSvc) UpdateLabelTemplate(tpl models.LabelTemplate) error {
+  if tpl.ID == uuid.Nil || tpl.Name == "" {
+    return fmt.Errorf("invalid input to update label template")
+  }
+  if err := label.ValidateTemplate(tpl); err != nil {
+    return err
+  }
+  if err := s.repo.Update(tpl); err != nil {
+    return fmt.Errorf("failed to update label template: %w", err)
+  }
+  return nil
+}
+
+func (s *ltSvc) GetLabelTemplateByID(templateID uuid.UUID) (*models.LabelTemplate, error) {
+  if templateID == uuid.Nil {
+    return nil, fmt.Errorf("invalid templateID")
+  }
+  return s.repo.GetByID(templateID)
+}
+
+func (s *ltSvc) DeleteLabelTemplate(templateID uuid.UUID) error {
+  if templateID == uuid.Nil {
+    return fmt.Errorf("invalid templateID")
+  }
+  return s.repo.Delete(templateID)
+}
+
+func (s *ltSvc) ListLabelTemplates(companyID uuid.UUID) ([]*models.LabelTemplate, error) {
+  if companyID == uuid.Nil {
+    return nil, fmt.Errorf("invalid companyID")
+  }
+  return s.repo.ListByCompany(companyID)
+}
@@ -2,7 +2,9 @@ package services
 
 import (
   "fmt"
+  "time"
   "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
   "github.com/yungbote/slotter/backend/services/database/internal/models"
   "github.com/yungbote/slotter/backend/services/database/internal/repos"
 )
@@ -10,14 +12,31 @@ import (
 type LSvc interface {
   //GENERAL CRUD
   CreateLocation(location models.Location) (*models.Location, error)
-  UpdateLocationName(locationID uuid.UUID, newName string) error
-  UpdateLocationNumber(locationID uuid.UUID, newNumber string) error
+  UpdateLocationPath(locationID uuid.UUID, newPath string) error
+  UpdateLocationNamePath(locationID uuid.UUID, newNamePath string) error
+  UpdateLocationStatus(locationID uuid.UUID, status, reason string, changedByID uuid.UUID) error
+  UpdateSlotAttributes(locationID uuid.UUID, attrs models.Location) error
   GetLocationByID(locationID uuid.UUID) (*models.Location, error)
   GetLocationByPath(companyID, warehouseID uuid.UUID, locationPath string) (*models.Location, error)
   DeleteLocation(locationID uuid.UUID) error
+
+  MoveLocation(locationID uuid.UUID, newPath, newNamePath string) (*models.Location, error)
+  MergeLocations(sourceID, targetID uuid.UUID) (*models.Location, error)
+  ListLocationAliases(locationID uuid.UUID) ([]*models.LocationAlias, error)
   
   LinkToItem(locationID, itemID uuid.UUID) error
   UnlinkFromItem(locationID, itemID uuid.UUID) error
+  ListItemLinks(warehouseID uuid.UUID) ([]repos.ItemLocationLink, error)
+  // AssignItem opens a slot assignment and links the item. Without a type the
+  // location's slot role decides; a pick slot is overflow when the item
+  // already has a primary one in the warehouse.
+  AssignItem(a models.SlotAssignment) (*models.SlotAssignment, error)
+  // PrepareAssignment validates a and fills in the warehouse and type
+  // AssignItem would open it with, without opening it.
+  PrepareAssignment(a models.SlotAssignment) (*models.SlotAssignment, error)
+  // ReleaseItem closes the item's assignment to the location and unlinks it.
+  ReleaseItem(locationID, itemID uuid.UUID, at time.Time, source string, closedByID *uuid.UUID) error
+  ListAssignments(f repos.SlotAssignmentFilter) ([]*models.SlotAssignment, error)
   LinkToTransactionFile(locationID, fileID uuid.UUID) error
   UnlinkFromTransactionFile(locationID, fileID uuid.UUID) error
 
@@ -27,10 +46,12 @@ type LSvc interface {
 
 type lSvc struct {
   repo            repos.LRepo
+  aliasRepo       repos.LARepo
+  assignRepo      repos.SlotAssignmentRepo
 }
 
-func NewLSvc(repo repos.LRepo) LSvc {
-  return &lSvc{repo: repo}
+func NewLSvc(repo repos.LRepo, aliasRepo repos.LARepo, assignRepo repos.SlotAssignmentRepo) LSvc {
+  return &lSvc{repo: repo, aliasRepo: aliasRepo, assignRepo: assignRepo}
 }
 
 func (s *lSvc) CreateLocation(location models.Location) (*models.Location, error) {
@@ -68,6 +89,41 @@ func (s *lSvc) UpdateLocationNamePath(locationID uuid.UUID, newNamePath string)
   return nil
 }
 
+func (s *lSvc) UpdateLocationStatus(locationID uuid.UUID, status, reason string, changedByID uuid.UUID) error {
+  if locationID == uuid.Nil {
+    return fmt.Errorf("invalid locationID")
+  }
+  if !constants.LocationStatuses[status] {
+    return fmt.Errorf("invalid location status '%s'", status)
+  }
+  if status != constants.LocationStatusActive && reason == "" {
+    return fmt.Errorf("a reason is required to set a location %s", status)
+  }
+  if err := s.repo.UpdateStatus(locationID, status, reason, changedByID); err != nil {
+    return fmt.Errorf("Failed to update location status: %w", err)
+  }
+  return nil
+}
+
+func (s *lSvc) UpdateSlotAttributes(locationID uuid.UUID, attrs models.Location) error {
+  if locationID == uuid.Nil {
+    return fmt.Errorf("invalid locationID")
+  }
+  if attrs.SlotRole == "" {
+    attrs.SlotRole = constants.SlotRolePick
+  }
+  if !constants.SlotRoles[attrs.SlotRole] {
+    return fmt.Errorf("invalid slot role '%s'", attrs.SlotRole)
+  }
+  if attrs.LevelHeightCm < 0 || attrs.SlotWidthCm < 0 || attrs.SlotDepthCm < 0 || attrs.SlotHeightCm < 0 || attrs.MaxWeightKg < 0 {
+    return fmt.Errorf("slot dimensions cannot be negative")
+  }
+  if err := s.repo.UpdateSlotAttributes(locationID, attrs); err != nil {
+    return fmt.Errorf("Failed to update slot attributes: %w", err)
+  }
+  return nil
+}
+
 func (s *lSvc) GetLocationByID(locationID uuid.UUID) (*models.Location, error) {
   if locationID == uuid.Nil {
     return nil, fmt.Errorf("invalid locationID")
@@ -79,6 +135,23 @@ func (s *lSvc) GetLocationByID(locationID uuid.UUID) (*models.Location, error) {
   return location, nil
 }
 
+// GetLocationByPath resolves a path to its location, falling back to aliases
+// left behind by renames and merges.
+func (s *lSvc) GetLocationByPath(companyID, warehouseID uuid.UUID, locationPath string) (*models.Location, error) {
+  if warehouseID == uuid.Nil || locationPath == "" {
+    return nil, fmt.Errorf("invalid warehouseID or locationPath")
+  }
+  location, err := s.repo.GetByPath(warehouseID, locationPath)
+  if err == nil {
+    return location, nil
+  }
+  alias, aliasErr := s.aliasRepo.GetByPath(warehouseID, locationPath)
+  if aliasErr != nil {
+    return nil, fmt.Errorf("Failed to get location by path: %w", err)
+  }
+  return s.GetLocationByID(*alias.LocationID)
+}
+
 func (s *lSvc) DeleteLocation(locationID uuid.UUID) error {
   if locationID == uuid.Nil {
     return fmt.Errorf("Invalid locationID")
@@ -89,6 +162,61 @@ func (s *lSvc) DeleteLocation(locationID uuid.UUID) error {
   return nil
 }
 
+func (s *lSvc) MoveLocation(locationID uuid.UUID, newPath, newNamePath string) (*models.Location, error) {
+  if locationID == uuid.Nil || newPath == "" {
+    return nil, fmt.Errorf("invalid input to move location")
+  }
+  location, err := s.GetLocationByID(locationID)
+  if err != nil {
+    return nil, err
+  }
+  if newNamePath == "" {
+    newNamePath = location.LocationNamePath
+  }
+  if existing, err := s.repo.GetByPath(*location.WarehouseID, newPath); err == nil && existing.ID != locationID {
+    return nil, fmt.Errorf("location path '%s' is already in use, merge the locations instead", newPath)
+  }
+  if err := s.repo.Move(locationID, newPath, newNamePath); err != nil {
+    return nil, fmt.Errorf("Failed to move location: %w", err)
+  }
+  return s.GetLocationByID(locationID)
+}
+
+func (s *lSvc) MergeLocations(sourceID, targetID uuid.UUID) (*models.Location, error) {
+  if sourceID == uuid.Nil || targetID == uuid.Nil {
+    return nil, fmt.Errorf("invalid input to merge locations")
+  }
+  if sourceID == targetID {
+    return nil, fmt.Errorf("cannot merge a location into itself")
+  }
+  source, err := s.GetLocationByID(sourceID)
+  if err != nil {
+    return nil, err
+  }
+  target, err := s.GetLocationByID(targetID)
+  if err != nil {
+    return nil, err
+  }
+  if source.WarehouseID == nil || target.WarehouseID == nil || *source.WarehouseID != *target.WarehouseID {
+    return nil, fmt.Errorf("cannot merge locations from different warehouses")
+  }
+  if err := s.repo.Merge(sourceID, targetID); err != nil {
+    return nil, fmt.Errorf("Failed to merge locations: %w", err)
+  }
+  return s.GetLocationByID(targetID)
+}
+
+func (s *lSvc) ListLocationAliases(locationID uuid.UUID) ([]*models.LocationAlias, error) {
+  if locationID == uuid.Nil {
+    return nil, fmt.Errorf("Invalid LocationID")
+  }
+  aliases, err := s.aliasRepo.ListByLocation(locationID)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to list location aliases: %w", err)
+  }
+  return aliases, nil
+}
+
 func (s *lSvc) LinkToItem(locationID, itemID uuid.UUID) error {
   if locationID == uuid.Nil {
     return fmt.Errorf("Invalid LocationID")
@@ -109,6 +237,91 @@ func (s *lSvc) UnlinkFromItem(locationID, itemID uuid.UUID) error {
   return s.repo.UnlinkFromItem(locationID, itemID)
 }
 
+func (s *lSvc) ListItemLinks(warehouseID uuid.UUID) ([]repos.ItemLocationLink, error) {
+  if warehouseID == uuid.Nil {
+    return nil, fmt.Errorf("Invalid WarehouseID")
+  }
+  return s.repo.ListItemLinks(warehouseID)
+}
+
+func (s *lSvc) AssignItem(a models.SlotAssignment) (*models.SlotAssignment, error) {
+  prepared, err := s.PrepareAssignment(a)
+  if err != nil {
+    return nil, err
+  }
+  if err := s.assignRepo.Open(prepared); err != nil {
+    return nil, err
+  }
+  return prepared, nil
+}
+
+func (s *lSvc) PrepareAssignment(a models.SlotAssignment) (*models.SlotAssignment, error) {
+  if a.LocationID == nil || *a.LocationID == uuid.Nil {
+    return nil, fmt.Errorf("Invalid LocationID")
+  }
+  if a.ItemID == nil || *a.ItemID == uuid.Nil {
+    return nil, fmt.Errorf("Invalid ItemID")
+  }
+  if a.CompanyID == nil || *a.CompanyID == uuid.Nil {
+    return nil, fmt.Errorf("Invalid CompanyID")
+  }
+  if a.AssignmentType != "" && !constants.AssignmentTypes[a.AssignmentType] {
+    return nil, fmt.Errorf("invalid assignment type: %s", a.AssignmentType)
+  }
+  if a.OpenSource == "" {
+    a.OpenSource = constants.AssignmentSourceManual
+  }
+  loc, err := s.repo.GetByID(*a.LocationID)
+  if err != nil {
+    return nil, err
+  }
+  a.ID = uuid.Nil
+  a.WarehouseID = loc.WarehouseID
+  a.EffectiveTo = nil
+  if a.AssignmentType == "" {
+    switch loc.SlotRole {
+    case constants.SlotRoleReserve:
+      a.AssignmentType = constants.AssignmentReserve
+    case constants.SlotRoleOverflow:
+      a.AssignmentType = constants.AssignmentOverflow
+    default:
+      primaries, err := s.assignRepo.ListAssignments(repos.SlotAssignmentFilter{
+        WarehouseID:    *loc.WarehouseID,
+        ItemID:         *a.ItemID,
+        AssignmentType: constants.AssignmentPrimary,
+        OpenOnly:       true,
+      })
+      if err != nil {
+        return nil, fmt.Errorf("Failed to list primary slot assignments: %w", err)
+      }
+      a.AssignmentType = constants.AssignmentPrimary
+      for _, p := range primaries {
+        if *p.LocationID != loc.ID {
+          a.AssignmentType = constants.AssignmentOverflow
+        }
+      }
+    }
+  }
+  return &a, nil
+}
+
+func (s *lSvc) ReleaseItem(locationID, itemID uuid.UUID, at time.Time, source string, closedByID *uuid.UUID) error {
+  if locationID == uuid.Nil {
+    return fmt.Errorf("Invalid LocationID")
+  }
+  if itemID == uuid.Nil {
+    return fmt.Errorf("Invalid ItemID")
+  }
+  return s.assignRepo.Close(locationID, itemID, at, source, closedByID)
+}
+
+func (s *lSvc) ListAssignments(f repos.SlotAssignmentFilter) ([]*models.SlotAssignment, error) {
+  if f.WarehouseID == uuid.Nil && f.CompanyID == uuid.Nil {
+    return nil, fmt.Errorf("Invalid WarehouseID")
+  }
+  return s.assignRepo.ListAssignments(f)
+}
+
 func (s *lSvc) LinkToTransactionFile(locationID, fileID uuid.UUID) error {
   if locationID == uuid.Nil {
     return fmt.Errorf("Invalid LocationID")
@@ -0,0 +1,397 @@
+package services
+
+import (
+  "fmt"
+  "math"
+  "sort"
+  "strings"
+  "time"
+
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+// ProductivityQuery scopes a throughput report over the lines of the given
+// transaction type categories (default pick). An operator's working time is
+// the gaps between their consecutive lines; gaps over IdleMinutes are breaks
+// and count as no time at all. Files without times of day give no time.
+type ProductivityQuery struct {
+  CompanyID        uuid.UUID
+  WarehouseID      uuid.UUID
+  StartDate        time.Time
+  EndDate          time.Time
+  TransactionTypes []string
+  Operator         string
+  IdleMinutes      int
+}
+
+// ProductivityRow is the throughput of one operator, zone, hour of day (UTC)
+// or transaction type. Each gap is booked to the line that ends it.
+// StandardHours is the time the labor standards allow for the timed lines
+// they cover and Performance that time against the time actually taken;
+// it is nil when no standard applies.
+type ProductivityRow struct {
+  Key              string   `json:"key"`
+  Lines            int64    `json:"lines"`
+  Units            int64    `json:"units"`
+  Operators        int      `json:"operators"`
+  ActiveHours      float64  `json:"active_hours"`
+  LinesPerHour     float64  `json:"lines_per_hour"`
+  UnitsPerHour     float64  `json:"units_per_hour"`
+  StandardHours    float64  `json:"standard_hours"`
+  Performance      *float64 `json:"performance"`
+  MedianGapSeconds float64  `json:"median_gap_seconds"`
+  P90GapSeconds    float64  `json:"p90_gap_seconds"`
+}
+
+// GapDistribution is the spread of time between consecutive lines of an
+// operator, overall or for one transaction type. WithinStandardShare is the
+// share of gaps no longer than the standard time of their line.
+type GapDistribution struct {
+  TransactionType     string           `json:"transaction_type"` // empty for all types
+  Gaps                int64            `json:"gaps"`
+  Breaks              int64            `json:"breaks"`
+  AvgSeconds          float64          `json:"avg_seconds"`
+  MedianSeconds       float64          `json:"median_seconds"`
+  P90Seconds          float64          `json:"p90_seconds"`
+  WithinStandardShare *float64         `json:"within_standard_share"`
+  Buckets             []*ProfileBucket `json:"buckets"`
+}
+
+type ProductivityReport struct {
+  WarehouseID       uuid.UUID               `json:"warehouse_id"`
+  StartDate         time.Time               `json:"start_date"`
+  EndDate           time.Time               `json:"end_date"`
+  TransactionTypes  []string                `json:"transaction_types"`
+  IdleMinutes       int                     `json:"idle_minutes"`
+  Totals            *ProductivityRow        `json:"totals"`
+  UnattributedLines int64                   `json:"unattributed_lines"` // lines without an operator
+  Standards         []*models.LaborStandard `json:"standards"`
+  ByOperator        []*ProductivityRow      `json:"by_operator"`
+  ByZone            []*ProductivityRow      `json:"by_zone"`
+  ByHour            []*ProductivityRow      `json:"by_hour"`
+  ByType            []*ProductivityRow      `json:"by_type"`
+  Gaps              []*GapDistribution      `json:"gaps"` // all types first, then per type
+}
+
+type ProductivitySvc interface {
+  // Report measures throughput per operator, zone, hour of day and
+  // transaction type against the warehouse's labor standards.
+  Report(q ProductivityQuery) (*ProductivityReport, error)
+  ListStandards(warehouseID uuid.UUID) ([]*models.LaborStandard, error)
+  // SetStandard creates or replaces the standard of std's transaction type.
+  SetStandard(std models.LaborStandard) (*models.LaborStandard, error)
+  DeleteStandard(warehouseID uuid.UUID, transactionType string) error
+}
+
+type productivitySvc struct {
+  repo            repos.LaborStandardRepo
+  arepo           repos.AnalyticsRepo
+  zrepo           repos.ZRepo
+}
+
+func NewProductivitySvc(repo repos.LaborStandardRepo, arepo repos.AnalyticsRepo, zrepo repos.ZRepo) ProductivitySvc {
+  return &productivitySvc{repo: repo, arepo: arepo, zrepo: zrepo}
+}
+
+const (
+  defaultIdleMinutes = 30
+  maxIdleMinutes     = 24 * 60
+  unzonedKey         = "unzoned"
+)
+
+var gapBuckets = []profileRange{
+  {"<15s", 0, 14}, {"15-30s", 15, 29}, {"30-60s", 30, 59}, {"1-2m", 60, 119},
+  {"2-5m", 120, 299}, {"5-10m", 300, 599}, {"10m+", 600, math.MaxInt64},
+}
+
+func (s *productivitySvc) Report(q ProductivityQuery) (*ProductivityReport, error) {
+  if q.WarehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  if q.IdleMinutes == 0 {
+    q.IdleMinutes = defaultIdleMinutes
+  }
+  if q.IdleMinutes < 1 || q.IdleMinutes > maxIdleMinutes {
+    return nil, fmt.Errorf("idle minutes must be between 1 and %d", maxIdleMinutes)
+  }
+  start, end, err := analyticsWindow(q.StartDate, q.EndDate)
+  if err != nil {
+    return nil, err
+  }
+  types, err := normalizeTypeFilter(q.TransactionTypes)
+  if err != nil {
+    return nil, err
+  }
+  f := repos.AnalyticsFilter{CompanyID: q.CompanyID, WarehouseID: q.WarehouseID, StartDate: start, EndDate: end}
+  if f.RawTypes, err = rawTypesFor(s.arepo, f, types); err != nil {
+    return nil, err
+  }
+  standards, err := s.repo.ListByWarehouse(q.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+  zones, err := s.zrepo.ListByWarehouse(q.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+  byCategory := make(map[string]*models.LaborStandard, len(standards))
+  for _, std := range standards {
+    byCategory[std.TransactionType] = std
+  }
+
+  var lines []repos.OperatorLine
+  if len(f.RawTypes) > 0 {
+    if lines, err = s.arepo.OperatorLines(f); err != nil {
+      return nil, err
+    }
+  }
+
+  idle := int64(q.IdleMinutes) * 60
+  total := newThroughput()
+  byOperator := make(map[string]*throughput)
+  byZone := make(map[string]*throughput)
+  byHour := make(map[string]*throughput)
+  byType := make(map[string]*throughput)
+  zoneOf := make(map[string]string)
+  var unattributed int64
+  var prev *repos.OperatorLine
+  for i := range lines {
+    l := &lines[i]
+    if l.CompletedBy == "" {
+      unattributed++
+      continue
+    }
+    if q.Operator != "" && !strings.EqualFold(l.CompletedBy, q.Operator) {
+      continue
+    }
+    gap := int64(-1)
+    if prev != nil && prev.CompletedBy == l.CompletedBy {
+      gap = int64(l.CompletedDate.Sub(prev.CompletedDate).Seconds())
+    }
+    prev = l
+
+    category := constants.NormalizeTransactionType(l.TransactionType)
+    stdSeconds := 0.0
+    if std, ok := byCategory[category]; ok {
+      stdSeconds = std.SecondsPerLine + std.SecondsPerUnit*float64(l.Units)
+    }
+    zone, ok := zoneOf[l.LocationPath]
+    if !ok {
+      zone = zoneForPath(zones, l.LocationPath)
+      zoneOf[l.LocationPath] = zone
+    }
+    hour := fmt.Sprintf("%02d", l.CompletedDate.UTC().Hour())
+    for _, t := range []*throughput{
+      total,
+      throughputFor(byOperator, l.CompletedBy),
+      throughputFor(byZone, zone),
+      throughputFor(byHour, hour),
+      throughputFor(byType, category),
+    } {
+      t.add(l.CompletedBy, l.Units, gap, idle, stdSeconds)
+    }
+  }
+
+  report := &ProductivityReport{
+    WarehouseID:       q.WarehouseID,
+    StartDate:         start,
+    EndDate:           end.AddDate(0, 0, -1),
+    TransactionTypes:  types,
+    IdleMinutes:       q.IdleMinutes,
+    Totals:            total.row(""),
+    UnattributedLines: unattributed,
+    Standards:         standards,
+    ByOperator:        throughputRows(byOperator),
+    ByZone:            throughputRows(byZone),
+    ByType:            throughputRows(byType),
+    Gaps:              []*GapDistribution{total.distribution("")},
+  }
+  for h := 0; h < 24; h++ {
+    key := fmt.Sprintf("%02d", h)
+    t, ok := byHour[key]
+    if !ok {
+      t = newThroughput()
+    }
+    report.ByHour = append(report.ByHour, t.row(key))
+  }
+  for _, row := range report.ByType {
+    report.Gaps = append(report.Gaps, byType[row.Key].distribution(row.Key))
+  }
+  return report, nil
+}
+
+func (s *productivitySvc) ListStandards(warehouseID uuid.UUID) ([]*models.LaborStandard, error) {
+  if warehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  return s.repo.ListByWarehouse(warehouseID)
+}
+
+func (s *productivitySvc) SetStandard(std models.LaborStandard) (*models.LaborStandard, error) {
+  if std.WarehouseID == nil || *std.WarehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  std.TransactionType = strings.ToLower(strings.TrimSpace(std.TransactionType))
+  if !constants.TransactionTypes[std.TransactionType] {
+    return nil, fmt.Errorf("invalid transaction type '%s'", std.TransactionType)
+  }
+  if std.SecondsPerLine < 0 || std.SecondsPerUnit < 0 {
+    return nil, fmt.Errorf("standard times must not be negative")
+  }
+  if std.SecondsPerLine == 0 && std.SecondsPerUnit == 0 {
+    return nil, fmt.Errorf("seconds per line or seconds per unit is required")
+  }
+  std.ID = uuid.Nil
+  if err := s.repo.Upsert(&std); err != nil {
+    return nil, err
+  }
+  return &std, nil
+}
+
+func (s *productivitySvc) DeleteStandard(warehouseID uuid.UUID, transactionType string) error {
+  if warehouseID == uuid.Nil {
+    return fmt.Errorf("invalid warehouseID")
+  }
+  return s.repo.Delete(warehouseID, strings.ToLower(strings.TrimSpace(transactionType)))
+}
+
+// throughput accumulates the lines and timed gaps of one report row.
+type throughput struct {
+  lines, units int64
+  operators    map[string]bool
+  gaps         []int64
+  breaks       int64
+  activeSec    float64
+  coveredSec   float64 // time taken by lines that have a standard
+  standardSec  float64
+  withinStd    int64
+  standardGaps int64
+}
+
+func newThroughput() *throughput {
+  return &throughput{operators: make(map[string]bool)}
+}
+
+func throughputFor(m map[string]*throughput, key string) *throughput {
+  t, ok := m[key]
+  if !ok {
+    t = newThroughput()
+    m[key] = t
+  }
+  return t
+}
+
+// add books a line; gap is the seconds since the operator's previous line,
+// negative for their first one, and stdSeconds the line's standard time.
+func (t *throughput) add(operator string, units, gap, idle int64, stdSeconds float64) {
+  t.lines++
+  t.units += units
+  t.operators[operator] = true
+  if gap < 0 {
+    return
+  }
+  if gap > idle {
+    t.breaks++
+    return
+  }
+  t.gaps = append(t.gaps, gap)
+  t.activeSec += float64(gap)
+  if stdSeconds > 0 {
+    t.coveredSec += float64(gap)
+    t.standardSec += stdSeconds
+    t.standardGaps++
+    if float64(gap) <= stdSeconds {
+      t.withinStd++
+    }
+  }
+}
+
+func (t *throughput) row(key string) *ProductivityRow {
+  r := &ProductivityRow{
+    Key:           key,
+    Lines:         t.lines,
+    Units:         t.units,
+    Operators:     len(t.operators),
+    ActiveHours:   t.activeSec / 3600,
+    StandardHours: t.standardSec / 3600,
+  }
+  if t.activeSec > 0 {
+    r.LinesPerHour = float64(t.lines) / r.ActiveHours
+    r.UnitsPerHour = float64(t.units) / r.ActiveHours
+  }
+  if t.coveredSec > 0 {
+    p := t.standardSec / t.coveredSec
+    r.Performance = &p
+  }
+  sort.Slice(t.gaps, func(i, j int) bool { return t.gaps[i] < t.gaps[j] })
+  r.MedianGapSeconds = quantile(t.gaps, 0.5)
+  r.P90GapSeconds = quantile(t.gaps, 0.9)
+  return r
+}
+
+func (t *throughput) distribution(transactionType string) *GapDistribution {
+  sort.Slice(t.gaps, func(i, j int) bool { return t.gaps[i] < t.gaps[j] })
+  d := &GapDistribution{
+    TransactionType: transactionType,
+    Gaps:            int64(len(t.gaps)),
+    Breaks:          t.breaks,
+    MedianSeconds:   quantile(t.gaps, 0.5),
+    P90Seconds:      quantile(t.gaps, 0.9),
+  }
+  if len(t.gaps) > 0 {
+    d.AvgSeconds = t.activeSec / float64(len(t.gaps))
+  }
+  if t.standardGaps > 0 {
+    share := float64(t.withinStd) / float64(t.standardGaps)
+    d.WithinStandardShare = &share
+  }
+  counts := make([]int64, len(gapBuckets))
+  for _, g := range t.gaps {
+    counts[bucketOf(gapBuckets, g)]++
+  }
+  d.Buckets = profileBuckets(gapBuckets, counts, d.Gaps)
+  return d
+}
+
+// throughputRows turns the accumulated rows into report rows, busiest first.
+func throughputRows(m map[string]*throughput) []*ProductivityRow {
+  rows := make([]*ProductivityRow, 0, len(m))
+  for key, t := range m {
+    rows = append(rows, t.row(key))
+  }
+  sort.Slice(rows, func(i, j int) bool {
+    if rows[i].Lines != rows[j].Lines {
+      return rows[i].Lines > rows[j].Lines
+    }
+    return rows[i].Key < rows[j].Key
+  })
+  return rows
+}
+
+// zoneForPath names the most specific zone covering path.
+func zoneForPath(zones []*models.Zone, path string) string {
+  name, depth := unzonedKey, -1
+  for _, z := range zones {
+    if pathInSubtree(path, z.PathPrefix) && len(z.PathPrefix) > depth {
+      name, depth = z.Name, len(z.PathPrefix)
+    }
+  }
+  return name
+}
+
+// quantile interpolates the q-th quantile of sorted values, 0 when empty.
+func quantile(sorted []int64, q float64) float64 {
+  if len(sorted) == 0 {
+    return 0
+  }
+  pos := q * float64(len(sorted)-1)
+  lo := int(pos)
+  if lo+1 >= len(sorted) {
+    return float64(sorted[lo])
+  }
+  frac := pos - float64(lo)
+  return float64(sorted[lo]) + frac*float64(sorted[lo+1]-sorted[lo])
+}
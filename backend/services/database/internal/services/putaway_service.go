@@ -0,0 +1,375 @@
+package services
+
+import (
+  "fmt"
+  "math"
+  "sort"
+  "strings"
+
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+// PutawayQuery asks where Quantity units of an item received into a
+// warehouse should go. ExcludeLocationID, typically the receiving dock of
+// the receipt, is never suggested.
+type PutawayQuery struct {
+  CompanyID         uuid.UUID
+  WarehouseID       uuid.UUID
+  ItemID            uuid.UUID
+  Quantity          int64
+  ExcludeLocationID uuid.UUID
+  Limit             int
+}
+
+// PutawayCandidate is one ranked location. OnHand is estimated from the
+// activity at the location, inbound types adding and picks removing units;
+// Capacity and Room are nil when slot or item dimensions are unknown.
+// SuggestedQuantity splits the receipt over the candidates in rank order, a
+// candidate of unknown room taking all that is left.
+type PutawayCandidate struct {
+  Rank              int       `json:"rank"`
+  LocationID        uuid.UUID `json:"location_id"`
+  LocationPath      string    `json:"location_path"`
+  SlotRole          string    `json:"slot_role"`
+  Kind              string    `json:"kind"` // see constants.PutawayPickFace etc.
+  Zone              string    `json:"zone"`
+  Capacity          *int64    `json:"capacity"`
+  OnHand            int64     `json:"on_hand"`
+  Room              *int64    `json:"room"`
+  DistanceM         *float64  `json:"distance_m"` // to the nearest pick face of the item
+  SuggestedQuantity int64     `json:"suggested_quantity"`
+  Score             float64   `json:"score"`
+  Reasons           []string  `json:"reasons"`
+}
+
+type PutawaySuggestion struct {
+  WarehouseID uuid.UUID           `json:"warehouse_id"`
+  ItemID      uuid.UUID           `json:"item_id"`
+  ItemName    string              `json:"item_name"`
+  Quantity    int64               `json:"quantity"`
+  Unplaced    int64               `json:"unplaced"` // units the listed candidates have no room for
+  PickFaces   []string            `json:"pick_faces"`
+  Candidates  []*PutawayCandidate `json:"candidates"`
+  Excluded    map[string]int      `json:"excluded"` // skipped locations per constants.PutawaySkip* reason
+}
+
+type PutawaySvc interface {
+  Suggest(q PutawayQuery) (*PutawaySuggestion, error)
+}
+
+type putawaySvc struct {
+  arepo           repos.AnalyticsRepo
+  lrepo           repos.LRepo
+  irepo           repos.IRepo
+  zrepo           repos.ZRepo
+}
+
+func NewPutawaySvc(arepo repos.AnalyticsRepo, lrepo repos.LRepo, irepo repos.IRepo, zrepo repos.ZRepo) PutawaySvc {
+  return &putawaySvc{arepo: arepo, lrepo: lrepo, irepo: irepo, zrepo: zrepo}
+}
+
+const (
+  defaultPutawayLimit = 10
+  maxPutawayLimit     = 100
+)
+
+// Score parts: the kind of candidate dominates, then being close to the pick
+// face and taking the whole receipt.
+var putawayKindScores = map[string]float64{
+  constants.PutawayPickFace:     2,
+  constants.PutawayItemReserve:  1,
+  constants.PutawayEmptyReserve: 0.5,
+}
+
+const (
+  putawayProximityWeight = 0.3
+  putawayFitsAllWeight   = 0.2
+  putawayOverflowPenalty = 0.05 // reserve before overflow, all else equal
+)
+
+func (s *putawaySvc) Suggest(q PutawayQuery) (*PutawaySuggestion, error) {
+  if q.WarehouseID == uuid.Nil || q.ItemID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouse or item")
+  }
+  if q.Quantity <= 0 {
+    return nil, fmt.Errorf("quantity must be positive")
+  }
+  if q.Limit < 0 {
+    return nil, fmt.Errorf("limit cannot be negative")
+  }
+  if q.Limit == 0 {
+    q.Limit = defaultPutawayLimit
+  }
+  q.Limit = min(q.Limit, maxPutawayLimit)
+  item, err := s.irepo.GetByID(q.ItemID)
+  if err != nil {
+    return nil, err
+  }
+  if item.CompanyID == nil || *item.CompanyID != q.CompanyID {
+    return nil, fmt.Errorf("item does not belong to company")
+  }
+  locs, err := s.lrepo.ListLocations(repos.LocationFilter{WarehouseID: q.WarehouseID})
+  if err != nil {
+    return nil, fmt.Errorf("failed to load locations: %w", err)
+  }
+  links, err := s.lrepo.ListItemLinks(q.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+  zones, err := s.zrepo.ListByWarehouse(q.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+  onHand, err := s.estimateOnHand(q.CompanyID, q.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+
+  linked := make(map[uuid.UUID]map[uuid.UUID]bool) // location -> items
+  for _, l := range links {
+    if linked[l.LocationID] == nil {
+      linked[l.LocationID] = make(map[uuid.UUID]bool)
+    }
+    linked[l.LocationID][l.ItemID] = true
+  }
+  var pickFaces []*models.Location
+  for _, loc := range locs {
+    if linked[loc.ID][item.ID] && loc.ID != q.ExcludeLocationID &&
+      loc.Status == constants.LocationStatusActive && (loc.SlotRole == "" || loc.SlotRole == constants.SlotRolePick) {
+      pickFaces = append(pickFaces, loc)
+    }
+  }
+
+  out := &PutawaySuggestion{
+    WarehouseID: q.WarehouseID,
+    ItemID:      item.ID,
+    ItemName:    item.Name,
+    Quantity:    q.Quantity,
+    PickFaces:   []string{},
+    Candidates:  []*PutawayCandidate{},
+    Excluded:    make(map[string]int),
+  }
+  for _, pf := range pickFaces {
+    out.PickFaces = append(out.PickFaces, pf.LocationPath)
+  }
+  sort.Strings(out.PickFaces)
+
+  var candidates []*PutawayCandidate
+  for _, loc := range locs {
+    if loc.ID == q.ExcludeLocationID {
+      continue
+    }
+    holdsItem := linked[loc.ID][item.ID] || onHand[loc.ID][item.ID] > 0
+    others := 0
+    for itemID := range linked[loc.ID] {
+      if itemID != item.ID {
+        others++
+      }
+    }
+    for itemID, n := range onHand[loc.ID] {
+      if itemID != item.ID && n > 0 && !linked[loc.ID][itemID] {
+        others++
+      }
+    }
+    pickRole := loc.SlotRole == "" || loc.SlotRole == constants.SlotRolePick
+    var kind string
+    switch {
+    case holdsItem && pickRole:
+      kind = constants.PutawayPickFace
+    case holdsItem:
+      kind = constants.PutawayItemReserve
+    case pickRole:
+      continue // an empty pick slot is a slotting decision, not a putaway one
+    case others > 0:
+      out.Excluded[constants.PutawaySkipOccupied]++
+      continue
+    default:
+      kind = constants.PutawayEmptyReserve
+    }
+    if loc.Status != constants.LocationStatusActive {
+      out.Excluded[constants.PutawaySkipInactive]++
+      continue
+    }
+    if !slotFits(loc, item) {
+      out.Excluded[constants.PutawaySkipNoFit]++
+      continue
+    }
+    zone := zoneForPath(zones, loc.LocationPath)
+    compatible := true
+    for _, z := range zones {
+      if pathInSubtree(loc.LocationPath, z.PathPrefix) && len(zoneRuleFailures(z, loc, item)) > 0 {
+        compatible = false
+      }
+    }
+    if !compatible {
+      out.Excluded[constants.PutawaySkipZoneRule]++
+      continue
+    }
+
+    c := &PutawayCandidate{
+      LocationID:   loc.ID,
+      LocationPath: loc.LocationPath,
+      SlotRole:     loc.SlotRole,
+      Kind:         kind,
+      Zone:         zone,
+      OnHand:       onHand[loc.ID][item.ID],
+    }
+    switch kind {
+    case constants.PutawayPickFace:
+      c.Reasons = append(c.Reasons, "pick face of the item")
+    case constants.PutawayItemReserve:
+      c.Reasons = append(c.Reasons, fmt.Sprintf("%s slot already holding the item", loc.SlotRole))
+    default:
+      c.Reasons = append(c.Reasons, fmt.Sprintf("empty %s slot", loc.SlotRole))
+    }
+    if capacity, ok := slotCapacity(loc, item); others > 0 {
+      c.Reasons = append(c.Reasons, fmt.Sprintf("room unknown: shared with %d other items", others))
+    } else if ok {
+      room := capacity - c.OnHand
+      if room <= 0 {
+        out.Excluded[constants.PutawaySkipFull]++
+        continue
+      }
+      c.Capacity, c.Room = &capacity, &room
+      if room >= q.Quantity {
+        c.Reasons = append(c.Reasons, fmt.Sprintf("room for all %d units (capacity %d, about %d on hand)", q.Quantity, capacity, c.OnHand))
+      } else {
+        c.Reasons = append(c.Reasons, fmt.Sprintf("room for %d of %d units (capacity %d, about %d on hand)", room, q.Quantity, capacity, c.OnHand))
+      }
+    } else {
+      c.Reasons = append(c.Reasons, "capacity unknown: slot or item dimensions missing")
+    }
+    if zone != unzonedKey {
+      c.Reasons = append(c.Reasons, fmt.Sprintf("meets the rules of zone %s", zone))
+    }
+
+    c.Score = putawayKindScores[kind]
+    if c.Room != nil && *c.Room >= q.Quantity {
+      c.Score += putawayFitsAllWeight
+    }
+    if loc.SlotRole == constants.SlotRoleOverflow {
+      c.Score -= putawayOverflowPenalty
+    }
+    if kind != constants.PutawayPickFace {
+      proximity, reason := pickFaceProximity(loc, pickFaces, &c.DistanceM)
+      c.Score += putawayProximityWeight * proximity
+      c.Reasons = append(c.Reasons, reason)
+    } else {
+      c.Score += putawayProximityWeight
+    }
+    candidates = append(candidates, c)
+  }
+
+  sort.SliceStable(candidates, func(i, j int) bool {
+    if candidates[i].Score != candidates[j].Score {
+      return candidates[i].Score > candidates[j].Score
+    }
+    return candidates[i].LocationPath < candidates[j].LocationPath
+  })
+  if len(candidates) > q.Limit {
+    candidates = candidates[:q.Limit]
+  }
+  remaining := q.Quantity
+  for i, c := range candidates {
+    c.Rank = i + 1
+    if remaining == 0 {
+      continue
+    }
+    take := remaining
+    if c.Room != nil {
+      take = min(take, *c.Room)
+    }
+    c.SuggestedQuantity = take
+    remaining -= take
+  }
+  out.Unplaced = remaining
+  out.Candidates = append(out.Candidates, candidates...)
+  return out, nil
+}
+
+// estimateOnHand nets every item's inbound against its picked units per
+// location over all rolled up activity.
+func (s *putawaySvc) estimateOnHand(companyID, warehouseID uuid.UUID) (map[uuid.UUID]map[uuid.UUID]int64, error) {
+  rows, err := s.arepo.LocationItemUnits(repos.AnalyticsFilter{CompanyID: companyID, WarehouseID: warehouseID})
+  if err != nil {
+    return nil, err
+  }
+  return netOnHand(rows), nil
+}
+
+// netOnHand maps location -> item -> inbound less picked units, never below
+// zero. Types that are neither leave the estimate alone.
+func netOnHand(rows []repos.LocationItemUnits) map[uuid.UUID]map[uuid.UUID]int64 {
+  out := make(map[uuid.UUID]map[uuid.UUID]int64)
+  for _, r := range rows {
+    category := constants.NormalizeTransactionType(r.TransactionType)
+    var delta int64
+    switch {
+    case constants.PutawayInboundTypes[category]:
+      delta = r.Units
+    case constants.PutawayOutboundTypes[category]:
+      delta = -r.Units
+    default:
+      continue
+    }
+    if out[r.LocationID] == nil {
+      out[r.LocationID] = make(map[uuid.UUID]int64)
+    }
+    out[r.LocationID][r.ItemID] += delta
+  }
+  for _, items := range out {
+    for itemID, n := range items {
+      items[itemID] = max(n, 0)
+    }
+  }
+  return out
+}
+
+// pickFaceProximity rates 0..1 how close loc is to the nearest of the item's
+// pick faces: by rectilinear distance when both have coordinates, else by a
+// shared aisle, else by the share of leading path segments in common. It sets
+// *distance when measured and returns the reason to show.
+func pickFaceProximity(loc *models.Location, pickFaces []*models.Location, distance **float64) (float64, string) {
+  if len(pickFaces) == 0 {
+    return 0, "item has no pick face to be near"
+  }
+  best := math.Inf(1)
+  var nearest *models.Location
+  hasCoord := loc.CoordX != 0 || loc.CoordY != 0
+  for _, pf := range pickFaces {
+    if !hasCoord || (pf.CoordX == 0 && pf.CoordY == 0) {
+      continue
+    }
+    if d := math.Abs(loc.CoordX-pf.CoordX) + math.Abs(loc.CoordY-pf.CoordY); d < best {
+      best, nearest = d, pf
+    }
+  }
+  if nearest != nil {
+    *distance = &best
+    return 1 / (1 + best/20), fmt.Sprintf("%.1f m from pick face %s", best, nearest.LocationPath)
+  }
+  for _, pf := range pickFaces {
+    if loc.Aisle != "" && strings.EqualFold(loc.Aisle, pf.Aisle) {
+      return 0.6, fmt.Sprintf("same aisle as pick face %s", pf.LocationPath)
+    }
+  }
+  segments := strings.Split(loc.LocationPath, "/")
+  bestShare, nearest := 0.0, pickFaces[0]
+  for _, pf := range pickFaces {
+    other := strings.Split(pf.LocationPath, "/")
+    common := 0
+    for common < len(segments) && common < len(other) && segments[common] == other[common] {
+      common++
+    }
+    if share := float64(common) / float64(max(len(segments), len(other))); share > bestShare {
+      bestShare, nearest = share, pf
+    }
+  }
+  if bestShare == 0 {
+    return 0, "no measurable proximity to a pick face"
+  }
+  return 0.5 * bestShare, fmt.Sprintf("shares a path prefix with pick face %s", nearest.LocationPath)
+}
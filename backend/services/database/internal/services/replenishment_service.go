@@ -0,0 +1,461 @@
+package services
+
+import (
+  "fmt"
+  "math"
+  "sort"
+  "time"
+
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+// ReplenishmentSlot is the state of one configured pick face. OnHand comes
+// from the transaction stream when inbound units were ever recorded at the
+// slot, else it is projected: the level after the last completed task (or
+// the max level when the setting was made) less the picks of the days after.
+type ReplenishmentSlot struct {
+  LocationID    uuid.UUID  `json:"location_id"`
+  LocationPath  string     `json:"location_path"`
+  ItemID        uuid.UUID  `json:"item_id"`
+  ItemName      string     `json:"item_name"`
+  Method        string     `json:"method"`
+  OnHand        int64      `json:"on_hand"`
+  OnHandSource  string     `json:"on_hand_source"`
+  MinUnits      int64      `json:"min_units"`
+  MaxUnits      int64      `json:"max_units"`
+  Capacity      *int64     `json:"capacity"`
+  AvgDailyUnits float64    `json:"avg_daily_units"` // picks at the slot over the last replenishmentDemandDays
+  DaysOfSupply  *float64   `json:"days_of_supply"`
+  Below         bool       `json:"below_min"`
+  OpenTaskID    *uuid.UUID `json:"open_task_id"`
+  Note          string     `json:"note,omitempty"`
+}
+
+type ReplenishmentRun struct {
+  WarehouseID uuid.UUID                   `json:"warehouse_id"`
+  EvaluatedAt time.Time                   `json:"evaluated_at"`
+  BelowMin    int                         `json:"below_min"`
+  Slots       []*ReplenishmentSlot        `json:"slots"`
+  Created     []*models.ReplenishmentTask `json:"created"`
+}
+
+type ReplenishmentSvc interface {
+  ListSettings(warehouseID uuid.UUID) ([]*models.ReplenishmentSetting, error)
+  SetSetting(st *models.ReplenishmentSetting) error
+  DeleteSetting(warehouseID, locationID, itemID uuid.UUID) error
+  // Evaluate estimates every configured pick face of the warehouse. With
+  // createTasks it raises a task for each face below its min level that has
+  // no open one.
+  Evaluate(companyID, warehouseID uuid.UUID, createTasks bool) (*ReplenishmentRun, error)
+  // EvaluateFace evaluates and raises tasks for the one pick face of the item
+  // at the location, reading only that item's activity.
+  EvaluateFace(companyID, warehouseID, locationID, itemID uuid.UUID) (*ReplenishmentRun, error)
+  ListTasks(f repos.ReplenishmentTaskFilter) ([]*models.ReplenishmentTask, error)
+  GetTask(taskID uuid.UUID) (*models.ReplenishmentTask, error)
+  CompleteTask(taskID, completedByID uuid.UUID) error
+  CancelTask(taskID uuid.UUID) error
+}
+
+type replenishmentSvc struct {
+  repo            repos.ReplenishmentRepo
+  arepo           repos.AnalyticsRepo
+  lrepo           repos.LRepo
+  sarepo          repos.SlotAssignmentRepo
+}
+
+func NewReplenishmentSvc(repo repos.ReplenishmentRepo, arepo repos.AnalyticsRepo, lrepo repos.LRepo, sarepo repos.SlotAssignmentRepo) ReplenishmentSvc {
+  return &replenishmentSvc{repo: repo, arepo: arepo, lrepo: lrepo, sarepo: sarepo}
+}
+
+// replenishmentDemandDays is the pick history days of supply are measured on.
+const replenishmentDemandDays = 28
+
+func (s *replenishmentSvc) ListSettings(warehouseID uuid.UUID) ([]*models.ReplenishmentSetting, error) {
+  if warehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  return s.repo.ListSettings(warehouseID)
+}
+
+func (s *replenishmentSvc) SetSetting(st *models.ReplenishmentSetting) error {
+  if st.CompanyID == nil || st.WarehouseID == nil || st.LocationID == nil || st.ItemID == nil {
+    return fmt.Errorf("invalid warehouse, location or item")
+  }
+  switch st.Method {
+  case constants.ReplenishMinMax:
+    if st.MinUnits <= 0 || st.MaxUnits < 0 {
+      return fmt.Errorf("min units must be positive and max units cannot be negative")
+    }
+    if st.MaxUnits > 0 && st.MaxUnits <= st.MinUnits {
+      return fmt.Errorf("max units must exceed min units")
+    }
+    st.MinDays, st.MaxDays = 0, 0
+  case constants.ReplenishDaysOfSupply:
+    if st.MinDays <= 0 || st.MaxDays <= st.MinDays {
+      return fmt.Errorf("max days must exceed a positive min days")
+    }
+    st.MinUnits, st.MaxUnits = 0, 0
+  default:
+    return fmt.Errorf("invalid replenishment method '%s'", st.Method)
+  }
+  open, err := s.sarepo.ListAssignments(repos.SlotAssignmentFilter{WarehouseID: *st.WarehouseID, LocationID: *st.LocationID, ItemID: *st.ItemID, OpenOnly: true})
+  if err != nil {
+    return err
+  }
+  if len(open) == 0 {
+    return fmt.Errorf("item is not slotted to the location")
+  }
+  if open[0].AssignmentType == constants.AssignmentReserve {
+    return fmt.Errorf("replenishment settings apply to pick faces, not reserve slots")
+  }
+  return s.repo.UpsertSetting(st)
+}
+
+func (s *replenishmentSvc) DeleteSetting(warehouseID, locationID, itemID uuid.UUID) error {
+  if warehouseID == uuid.Nil || locationID == uuid.Nil || itemID == uuid.Nil {
+    return fmt.Errorf("invalid warehouse, location or item")
+  }
+  return s.repo.DeleteSetting(warehouseID, locationID, itemID)
+}
+
+// replenishmentPair keys a pick face by location and item.
+type replenishmentPair [2]uuid.UUID
+
+func (s *replenishmentSvc) Evaluate(companyID, warehouseID uuid.UUID, createTasks bool) (*ReplenishmentRun, error) {
+  if warehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  return s.evaluate(companyID, warehouseID, nil, createTasks)
+}
+
+func (s *replenishmentSvc) EvaluateFace(companyID, warehouseID, locationID, itemID uuid.UUID) (*ReplenishmentRun, error) {
+  if warehouseID == uuid.Nil || locationID == uuid.Nil || itemID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouse, location or item")
+  }
+  return s.evaluate(companyID, warehouseID, &replenishmentPair{locationID, itemID}, true)
+}
+
+// evaluate estimates the warehouse's configured pick faces, or only face
+// when it is set.
+func (s *replenishmentSvc) evaluate(companyID, warehouseID uuid.UUID, face *replenishmentPair, createTasks bool) (*ReplenishmentRun, error) {
+  now := time.Now().UTC()
+  today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+  run := &ReplenishmentRun{WarehouseID: warehouseID, EvaluatedAt: now, Slots: []*ReplenishmentSlot{}, Created: []*models.ReplenishmentTask{}}
+  settings, err := s.repo.ListSettings(warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  f := repos.AnalyticsFilter{CompanyID: companyID, WarehouseID: warehouseID}
+  if face != nil {
+    kept := settings[:0]
+    for _, st := range settings {
+      if *st.LocationID == face[0] && *st.ItemID == face[1] {
+        kept = append(kept, st)
+      }
+    }
+    settings, f.ItemID = kept, face[1]
+  }
+  if len(settings) == 0 {
+    return run, nil
+  }
+
+  flows, err := s.arepo.LocationItemUnits(f)
+  if err != nil {
+    return nil, err
+  }
+  stock := netOnHand(flows)
+  lastInbound := make(map[replenishmentPair]time.Time)
+  for _, r := range flows {
+    if constants.PutawayInboundTypes[constants.NormalizeTransactionType(r.TransactionType)] {
+      key := replenishmentPair{r.LocationID, r.ItemID}
+      if r.LastDay.After(lastInbound[key]) {
+        lastInbound[key] = r.LastDay
+      }
+    }
+  }
+  done, err := s.repo.LatestDone(warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  lastDone := make(map[replenishmentPair]*models.ReplenishmentTask, len(done))
+  for _, t := range done {
+    lastDone[replenishmentPair{*t.ToLocationID, *t.ItemID}] = t
+  }
+  open, err := s.repo.ListTasks(repos.ReplenishmentTaskFilter{WarehouseID: warehouseID, ItemID: f.ItemID, Status: constants.ReplenishTaskOpen})
+  if err != nil {
+    return nil, err
+  }
+  openTask := make(map[replenishmentPair]uuid.UUID, len(open))
+  for _, t := range open {
+    openTask[replenishmentPair{*t.ToLocationID, *t.ItemID}] = t.ID
+  }
+
+  // Picks per face and day, back to the earliest projection baseline.
+  demandStart := today.AddDate(0, 0, -replenishmentDemandDays)
+  from := demandStart
+  for _, st := range settings {
+    if st.CreatedAt.Before(from) {
+      from = st.CreatedAt.UTC()
+    }
+  }
+  picked := make(map[replenishmentPair]map[time.Time]int64)
+  pf := f
+  pf.StartDate, pf.EndDate = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC), today.AddDate(0, 0, 1)
+  if pf.RawTypes, err = rawTypesFor(s.arepo, pf, []string{constants.TransactionTypePick}); err != nil {
+    return nil, err
+  }
+  if len(pf.RawTypes) > 0 {
+    days, err := s.arepo.LocationItemDays(pf)
+    if err != nil {
+      return nil, err
+    }
+    for _, d := range days {
+      key := replenishmentPair{d.LocationID, d.ItemID}
+      if picked[key] == nil {
+        picked[key] = make(map[time.Time]int64)
+      }
+      picked[key][d.Day.UTC()] += d.Units
+    }
+  }
+  pickedAfter := func(key replenishmentPair, at time.Time) int64 {
+    day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+    var n int64
+    for d, units := range picked[key] {
+      if d.After(day) {
+        n += units
+      }
+    }
+    return n
+  }
+
+  reserves, err := s.reserveSlots(warehouseID, f.ItemID)
+  if err != nil {
+    return nil, err
+  }
+
+  for _, st := range settings {
+    if st.Location == nil || st.Item == nil {
+      continue
+    }
+    key := replenishmentPair{st.Location.ID, st.Item.ID}
+    slot := &ReplenishmentSlot{
+      LocationID:   st.Location.ID,
+      LocationPath: st.Location.LocationPath,
+      ItemID:       st.Item.ID,
+      ItemName:     st.Item.Name,
+      Method:       st.Method,
+    }
+    if id, ok := openTask[key]; ok {
+      slot.OpenTaskID = &id
+    }
+    var recent int64
+    for d, units := range picked[key] {
+      if d.After(demandStart) {
+        recent += units
+      }
+    }
+    slot.AvgDailyUnits = float64(recent) / replenishmentDemandDays
+    capacity, capacityKnown := slotCapacity(st.Location, st.Item)
+    if capacityKnown {
+      slot.Capacity = &capacity
+    }
+
+    switch st.Method {
+    case constants.ReplenishMinMax:
+      slot.MinUnits, slot.MaxUnits = st.MinUnits, st.MaxUnits
+      if slot.MaxUnits == 0 && capacityKnown {
+        slot.MaxUnits = capacity
+      }
+    case constants.ReplenishDaysOfSupply:
+      slot.MinUnits = int64(math.Ceil(st.MinDays * slot.AvgDailyUnits))
+      slot.MaxUnits = int64(math.Ceil(st.MaxDays * slot.AvgDailyUnits))
+      if capacityKnown && slot.MaxUnits > capacity {
+        slot.MaxUnits = capacity
+        slot.MinUnits = min(slot.MinUnits, capacity)
+      }
+    }
+
+    last := lastDone[key]
+    if inbound, ok := lastInbound[key]; ok {
+      slot.OnHandSource = constants.OnHandTransactions
+      slot.OnHand = stock[key[0]][key[1]]
+      // a task completed after the last recorded inbound is not in the stream yet
+      if last != nil && last.CompletedAt != nil && last.CompletedAt.After(inbound.AddDate(0, 0, 1)) {
+        slot.OnHand += last.Quantity
+      }
+    } else {
+      slot.OnHandSource = constants.OnHandProjected
+      baseline, level := st.CreatedAt, slot.MaxUnits
+      if last != nil && last.CreatedAt.After(st.CreatedAt) {
+        baseline, level = last.CreatedAt, last.OnHand+last.Quantity
+      }
+      slot.OnHand = max(level-pickedAfter(key, baseline), 0)
+    }
+    if slot.AvgDailyUnits > 0 {
+      dos := float64(slot.OnHand) / slot.AvgDailyUnits
+      slot.DaysOfSupply = &dos
+    }
+
+    switch {
+    case slot.MaxUnits == 0 && st.Method == constants.ReplenishMinMax:
+      slot.Note = "max level unknown: set max units or the slot and item dimensions"
+    case slot.OnHand < slot.MinUnits:
+      slot.Below = true
+      run.BelowMin++
+    }
+    run.Slots = append(run.Slots, slot)
+    if !slot.Below || slot.OpenTaskID != nil || !createTasks {
+      continue
+    }
+    quantity := slot.MaxUnits - slot.OnHand
+    if quantity <= 0 {
+      continue
+    }
+    task := &models.ReplenishmentTask{
+      CompanyID:    st.CompanyID,
+      WarehouseID:  st.WarehouseID,
+      ItemID:       &slot.ItemID,
+      ToLocationID: &slot.LocationID,
+      Quantity:     quantity,
+      OnHand:       slot.OnHand,
+      OnHandSource: slot.OnHandSource,
+      MinUnits:     slot.MinUnits,
+      MaxUnits:     slot.MaxUnits,
+      DaysOfSupply: slot.DaysOfSupply,
+      Status:       constants.ReplenishTaskOpen,
+    }
+    if reserve := bestReserve(reserves[slot.ItemID], stock, slot.ItemID, slot.LocationID); reserve != nil {
+      task.FromLocationID = &reserve.ID
+      if held := stock[reserve.ID][slot.ItemID]; held < quantity {
+        task.Note = fmt.Sprintf("reserve %s holds about %d units", reserve.LocationPath, held)
+      }
+    } else {
+      task.Note = "no reserve slot of the item"
+    }
+    run.Created = append(run.Created, task)
+  }
+  // a concurrent run may have raised the same task since open was read
+  if run.Created, err = s.repo.CreateTasks(run.Created); err != nil {
+    return nil, err
+  }
+  for _, t := range run.Created {
+    for _, slot := range run.Slots {
+      if slot.LocationID == *t.ToLocationID && slot.ItemID == *t.ItemID {
+        slot.OpenTaskID = &t.ID
+      }
+    }
+  }
+  sort.SliceStable(run.Slots, func(i, j int) bool {
+    if run.Slots[i].Below != run.Slots[j].Below {
+      return run.Slots[i].Below
+    }
+    return run.Slots[i].LocationPath < run.Slots[j].LocationPath
+  })
+  return run, nil
+}
+
+// reserveSlots lists per item the active slots it is reserved in: open
+// reserve assignments, and linked locations whose role is reserve or
+// overflow. A non-nil itemID limits it to that item.
+func (s *replenishmentSvc) reserveSlots(warehouseID, itemID uuid.UUID) (map[uuid.UUID][]*models.Location, error) {
+  links, err := s.lrepo.ListItemLinks(warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  byID := make(map[uuid.UUID]*models.Location)
+  if itemID == uuid.Nil {
+    locs, err := s.lrepo.ListLocations(repos.LocationFilter{WarehouseID: warehouseID, Status: constants.LocationStatusActive})
+    if err != nil {
+      return nil, fmt.Errorf("failed to load locations: %w", err)
+    }
+    for _, loc := range locs {
+      byID[loc.ID] = loc
+    }
+  } else {
+    kept := links[:0]
+    for _, l := range links {
+      if l.ItemID != itemID {
+        continue
+      }
+      loc, err := s.lrepo.GetByID(l.LocationID)
+      if err != nil {
+        return nil, fmt.Errorf("failed to load locations: %w", err)
+      }
+      if loc.Status == constants.LocationStatusActive {
+        byID[loc.ID] = loc
+      }
+      kept = append(kept, l)
+    }
+    links = kept
+  }
+  assignments, err := s.sarepo.ListAssignments(repos.SlotAssignmentFilter{WarehouseID: warehouseID, ItemID: itemID, AssignmentType: constants.AssignmentReserve, OpenOnly: true})
+  if err != nil {
+    return nil, err
+  }
+  reserved := make(map[replenishmentPair]bool, len(assignments))
+  for _, a := range assignments {
+    reserved[replenishmentPair{*a.LocationID, *a.ItemID}] = true
+  }
+  out := make(map[uuid.UUID][]*models.Location)
+  for _, l := range links {
+    loc, ok := byID[l.LocationID]
+    if !ok {
+      continue
+    }
+    if reserved[replenishmentPair{l.LocationID, l.ItemID}] || loc.SlotRole == constants.SlotRoleReserve || loc.SlotRole == constants.SlotRoleOverflow {
+      out[l.ItemID] = append(out[l.ItemID], loc)
+    }
+  }
+  return out, nil
+}
+
+// bestReserve is the reserve slot other than the pick face estimated to hold
+// most of the item, by path on a tie; nil without any.
+func bestReserve(slots []*models.Location, stock map[uuid.UUID]map[uuid.UUID]int64, itemID, pickFaceID uuid.UUID) *models.Location {
+  var best *models.Location
+  for _, loc := range slots {
+    if loc.ID == pickFaceID {
+      continue
+    }
+    if best == nil {
+      best = loc
+      continue
+    }
+    held, bestHeld := stock[loc.ID][itemID], stock[best.ID][itemID]
+    if held > bestHeld || (held == bestHeld && loc.LocationPath < best.LocationPath) {
+      best = loc
+    }
+  }
+  return best
+}
+
+func (s *replenishmentSvc) ListTasks(f repos.ReplenishmentTaskFilter) ([]*models.ReplenishmentTask, error) {
+  if f.Status != "" && !constants.ReplenishTaskStatuses[f.Status] {
+    return nil, fmt.Errorf("invalid replenishment task status '%s'", f.Status)
+  }
+  return s.repo.ListTasks(f)
+}
+
+func (s *replenishmentSvc) GetTask(taskID uuid.UUID) (*models.ReplenishmentTask, error) {
+  if taskID == uuid.Nil {
+    return nil, fmt.Errorf("invalid taskID")
+  }
+  return s.repo.GetTask(taskID)
+}
+
+func (s *replenishmentSvc) CompleteTask(taskID, completedByID uuid.UUID) error {
+  if taskID == uuid.Nil {
+    return fmt.Errorf("invalid taskID")
+  }
+  return s.repo.UpdateTaskStatus(taskID, constants.ReplenishTaskDone, &completedByID)
+}
+
+func (s *replenishmentSvc) CancelTask(taskID uuid.UUID) error {
+  if taskID == uuid.Nil {
+    return fmt.Errorf("invalid taskID")
+  }
+  return s.repo.UpdateTaskStatus(taskID, constants.ReplenishTaskCancelled, nil)
+}
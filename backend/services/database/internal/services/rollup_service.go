@@ -0,0 +1,211 @@
+package services
+
+import (
+  "fmt"
+  "time"
+
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+// HeatmapQuery selects the window, transaction type categories (default
+// picks) and grouping of a heatmap. Depth 0 gives one cell per location,
+// otherwise one per location path prefix of Depth segments (e.g. 1 = aisle,
+// 2 = bay, 3 = level for aisle/bay/level/position paths).
+type HeatmapQuery struct {
+  CompanyID        uuid.UUID
+  WarehouseID      uuid.UUID
+  StartDate        time.Time
+  EndDate          time.Time
+  TransactionTypes []string
+  Depth            int
+}
+
+// HeatCell is one location or hierarchy node. Intensity is its lines against
+// the busiest cell, 0..1. Locations carry their coordinates in X/Y, nodes the
+// bounding box of their located slots; both are omitted when unknown.
+type HeatCell struct {
+  Node          string     `json:"node"`
+  LocationID    *uuid.UUID `json:"location_id,omitempty"`
+  Locations     int64      `json:"locations"`
+  Lines         int64      `json:"lines"`
+  Units         int64      `json:"units"`
+  Items         int64      `json:"items"`
+  Intensity     float64    `json:"intensity"`
+  X             *float64   `json:"x,omitempty"`
+  Y             *float64   `json:"y,omitempty"`
+  LevelHeightCm *float64   `json:"level_height_cm,omitempty"`
+  MinX          *float64   `json:"min_x,omitempty"`
+  MaxX          *float64   `json:"max_x,omitempty"`
+  MinY          *float64   `json:"min_y,omitempty"`
+  MaxY          *float64   `json:"max_y,omitempty"`
+}
+
+type Heatmap struct {
+  WarehouseID      uuid.UUID   `json:"warehouse_id"`
+  StartDate        time.Time   `json:"start_date"`
+  EndDate          time.Time   `json:"end_date"`
+  TransactionTypes []string    `json:"transaction_types"`
+  Depth            int         `json:"depth"`
+  MaxLines         int64       `json:"max_lines"`
+  MaxUnits         int64       `json:"max_units"`
+  Cells            []*HeatCell `json:"cells"`
+}
+
+type RollupSvc interface {
+  // Refresh rebuilds a warehouse's daily activity rollups for firstDay..lastDay.
+  Refresh(warehouseID uuid.UUID, firstDay, lastDay time.Time) error
+  // RefreshFile rebuilds the rollups covering an imported file's records.
+  RefreshFile(fileID uuid.UUID) error
+  // Rebuild builds a warehouse's rollups again from all of its records,
+  // rebuildChunkDays at a time. progress gets chunks done and total.
+  Rebuild(warehouseID uuid.UUID, progress func(done, total int)) (*repos.RollupStatus, error)
+  Status(warehouseID uuid.UUID) (*repos.RollupStatus, error)
+  // Heatmap reports activity per location or hierarchy node from the rollups.
+  Heatmap(q HeatmapQuery) (*Heatmap, error)
+}
+
+type rollupSvc struct {
+  repo            repos.RollupRepo
+}
+
+func NewRollupSvc(repo repos.RollupRepo) RollupSvc {
+  return &rollupSvc{repo: repo}
+}
+
+const (
+  maxHeatmapDepth  = 10
+  rebuildChunkDays = 31
+)
+
+func (s *rollupSvc) Refresh(warehouseID uuid.UUID, firstDay, lastDay time.Time) error {
+  if warehouseID == uuid.Nil {
+    return fmt.Errorf("invalid warehouseID")
+  }
+  if lastDay.Before(firstDay) {
+    return fmt.Errorf("first day must not be after last day")
+  }
+  return s.repo.Refresh(warehouseID, firstDay, lastDay)
+}
+
+func (s *rollupSvc) RefreshFile(fileID uuid.UUID) error {
+  if fileID == uuid.Nil {
+    return fmt.Errorf("invalid fileID")
+  }
+  return s.repo.RefreshFile(fileID)
+}
+
+// Rebuild spans every day with records or rollups, so rollups of days whose
+// records are gone are dropped as well. Each chunk is replaced by Refresh
+// under the warehouse's rollup lock; the warehouse is never left without them.
+func (s *rollupSvc) Rebuild(warehouseID uuid.UUID, progress func(done, total int)) (*repos.RollupStatus, error) {
+  st, err := s.Status(warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  first, last, ok := rebuildSpan(st)
+  if !ok {
+    return st, nil
+  }
+  total := int(last.Sub(first).Hours()/24)/rebuildChunkDays + 1
+  progress(0, total)
+  for done := 0; done < total; done++ {
+    from := first.AddDate(0, 0, done*rebuildChunkDays)
+    to := from.AddDate(0, 0, rebuildChunkDays-1)
+    if to.After(last) {
+      to = last
+    }
+    if err := s.repo.Refresh(warehouseID, from, to); err != nil {
+      return nil, err
+    }
+    progress(done+1, total)
+  }
+  return s.repo.Status(warehouseID)
+}
+
+// rebuildSpan is the first and last whole UTC day covered by the warehouse's
+// records or rollups; ok is false when it has neither.
+func rebuildSpan(st *repos.RollupStatus) (first, last time.Time, ok bool) {
+  for _, d := range []*time.Time{st.FirstDay, st.RecordFirstDate} {
+    if d != nil && (!ok || d.Before(first)) {
+      first, ok = *d, true
+    }
+  }
+  for _, d := range []*time.Time{st.LastDay, st.RecordLastDate} {
+    if d != nil && d.After(last) {
+      last = *d
+    }
+  }
+  if !ok {
+    return first, last, false
+  }
+  first, last = first.UTC(), last.UTC()
+  first = time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, time.UTC)
+  last = time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, time.UTC)
+  return first, last, true
+}
+
+func (s *rollupSvc) Status(warehouseID uuid.UUID) (*repos.RollupStatus, error) {
+  if warehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  return s.repo.Status(warehouseID)
+}
+
+func (s *rollupSvc) Heatmap(q HeatmapQuery) (*Heatmap, error) {
+  if q.WarehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  if q.Depth < 0 || q.Depth > maxHeatmapDepth {
+    return nil, fmt.Errorf("depth must be between 0 and %d", maxHeatmapDepth)
+  }
+  start, end, err := analyticsWindow(q.StartDate, q.EndDate)
+  if err != nil {
+    return nil, err
+  }
+  types, err := normalizeTypeFilter(q.TransactionTypes)
+  if err != nil {
+    return nil, err
+  }
+  f := repos.AnalyticsFilter{CompanyID: q.CompanyID, WarehouseID: q.WarehouseID, StartDate: start, EndDate: end}
+  if f.RawTypes, err = rawTypesFor(s.repo, f, types); err != nil {
+    return nil, err
+  }
+  nodes, err := s.repo.LocationHeat(f, q.Depth)
+  if err != nil {
+    return nil, err
+  }
+
+  hm := &Heatmap{
+    WarehouseID:      q.WarehouseID,
+    StartDate:        start,
+    EndDate:          end.AddDate(0, 0, -1),
+    TransactionTypes: types,
+    Depth:            q.Depth,
+    Cells:            make([]*HeatCell, 0, len(nodes)),
+  }
+  for _, n := range nodes {
+    cell := &HeatCell{
+      Node:       n.Node,
+      LocationID: n.LocationID,
+      Locations:  n.Locations,
+      Lines:      n.Lines,
+      Units:      n.Units,
+      Items:      n.Items,
+    }
+    if n.LocationID != nil {
+      cell.X, cell.Y, cell.LevelHeightCm = n.MinX, n.MinY, n.LevelHeightCm
+    } else {
+      cell.MinX, cell.MaxX, cell.MinY, cell.MaxY = n.MinX, n.MaxX, n.MinY, n.MaxY
+    }
+    hm.MaxLines = max(hm.MaxLines, n.Lines)
+    hm.MaxUnits = max(hm.MaxUnits, n.Units)
+    hm.Cells = append(hm.Cells, cell)
+  }
+  if hm.MaxLines > 0 {
+    for _, c := range hm.Cells {
+      c.Intensity = float64(c.Lines) / float64(hm.MaxLines)
+    }
+  }
+  return hm, nil
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+  "encoding/json"
+  "fmt"
+  "strings"
+
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+// ScenarioParams names a slotting strategy and the plan parameters it runs.
+type ScenarioParams struct {
+  CompanyID   uuid.UUID
+  WarehouseID uuid.UUID
+  CreatedByID uuid.UUID
+  Name        string
+  Description string
+  Plan        SlotPlanParams
+}
+
+// ScenarioKPI is one KPI across compared scenarios, Values in the order of
+// ScenarioComparison.Scenarios. Better tells whether lower or higher wins.
+type ScenarioKPI struct {
+  Key            string    `json:"key"`
+  Better         string    `json:"better"`
+  Values         []float64 `json:"values"`
+  BestScenarioID uuid.UUID `json:"best_scenario_id"`
+}
+
+type ScenarioComparison struct {
+  Scenarios []*models.SlottingScenario `json:"scenarios"`
+  KPIs      []*ScenarioKPI             `json:"kpis"`
+}
+
+type ScenarioSvc interface {
+  // Create runs the scenario's slot plan, scores it and stores both.
+  Create(p ScenarioParams) (*models.SlottingScenario, error)
+  GetByID(scenarioID uuid.UUID) (*models.SlottingScenario, error)
+  // Delete removes the scenario together with its slot plan.
+  Delete(scenarioID uuid.UUID) error
+  List(f repos.ScenarioFilter) ([]*models.SlottingScenario, error)
+  // Clone reruns a scenario under a new name, with plan when given and the
+  // source's parameters otherwise.
+  Clone(scenarioID, createdByID uuid.UUID, name, description string, plan *SlotPlanParams) (*models.SlottingScenario, error)
+  // Compare lines up the KPIs of scenarios of one warehouse.
+  Compare(scenarioIDs []uuid.UUID) (*ScenarioComparison, error)
+}
+
+type scenarioSvc struct {
+  repo            repos.ScenarioRepo
+  slsvc           SlottingSvc
+  asvc            AnalyticsSvc
+}
+
+func NewScenarioSvc(repo repos.ScenarioRepo, slsvc SlottingSvc, asvc AnalyticsSvc) ScenarioSvc {
+  return &scenarioSvc{repo: repo, slsvc: slsvc, asvc: asvc}
+}
+
+const maxComparedScenarios = 10
+
+func (s *scenarioSvc) Create(p ScenarioParams) (*models.SlottingScenario, error) {
+  return s.create(p, nil)
+}
+
+func (s *scenarioSvc) create(p ScenarioParams, clonedFromID *uuid.UUID) (*models.SlottingScenario, error) {
+  if p.CompanyID == uuid.Nil || p.WarehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouse")
+  }
+  p.Name = strings.TrimSpace(p.Name)
+  if p.Name == "" {
+    return nil, fmt.Errorf("scenario name is required")
+  }
+  p.Plan.CompanyID, p.Plan.WarehouseID, p.Plan.CreatedByID = p.CompanyID, p.WarehouseID, p.CreatedByID
+  plan, err := s.slsvc.Recommend(p.Plan)
+  if err != nil {
+    return nil, err
+  }
+  scenario := &models.SlottingScenario{
+    CompanyID:        &p.CompanyID,
+    WarehouseID:      &p.WarehouseID,
+    Name:             p.Name,
+    Description:      p.Description,
+    Params:           plan.Params,
+    PlanID:           &plan.ID,
+    ClonedFromID:     clonedFromID,
+    ItemCount:        plan.ItemCount,
+    CurrentTravelM:   plan.CurrentTravelM,
+    TravelM:          plan.PlanTravelM,
+    CurrentErgoScore: plan.CurrentErgoScore,
+    ErgoScore:        plan.PlanErgoScore,
+    UnassignedCount:  plan.UnassignedCount,
+  }
+  if p.CreatedByID != uuid.Nil {
+    scenario.CreatedByID = &p.CreatedByID
+  }
+  // the plan only exists for the scenario, so it goes when the scenario fails
+  moves, err := s.slsvc.MoveList(plan.ID, MoveListParams{})
+  if err == nil {
+    scenario.MovesRequired = moves.MoveCount
+    scenario.MoveHours = moves.TotalCostSec / 3600
+    scenario.CapacityViolations, err = s.capacityViolations(plan)
+  }
+  if err == nil {
+    err = s.repo.Create(scenario)
+  }
+  if err != nil {
+    _ = s.slsvc.DeletePlan(plan.ID)
+    return nil, err
+  }
+  return scenario, nil
+}
+
+// capacityViolations counts plan entries whose target pick face holds fewer
+// units than the item's peak day in the plan window.
+func (s *scenarioSvc) capacityViolations(plan *models.SlotPlan) (int, error) {
+  velocity, err := s.asvc.ItemVelocity(VelocityQuery{CompanyID: derefUUID(plan.CompanyID), WarehouseID: derefUUID(plan.WarehouseID), StartDate: plan.StartDate, EndDate: plan.EndDate})
+  if err != nil {
+    return 0, err
+  }
+  peak := make(map[uuid.UUID]int64, len(velocity.Rows))
+  for _, v := range velocity.Rows {
+    peak[v.ItemID] = v.PeakDailyUnits
+  }
+  entries, err := s.slsvc.ListEntries(repos.SlotPlanEntryFilter{PlanID: plan.ID})
+  if err != nil {
+    return 0, err
+  }
+  violations := 0
+  for _, e := range entries {
+    if e.Item == nil || e.ToLocation == nil || peak[e.Item.ID] == 0 {
+      continue
+    }
+    if capacity, known := slotCapacity(e.ToLocation, e.Item); known && capacity < peak[e.Item.ID] {
+      violations++
+    }
+  }
+  return violations, nil
+}
+
+func (s *scenarioSvc) GetByID(scenarioID uuid.UUID) (*models.SlottingScenario, error) {
+  if scenarioID == uuid.Nil {
+    return nil, fmt.Errorf("invalid scenarioID")
+  }
+  return s.repo.GetByID(scenarioID)
+}
+
+func (s *scenarioSvc) Delete(scenarioID uuid.UUID) error {
+  scenario, err := s.GetByID(scenarioID)
+  if err != nil {
+    return err
+  }
+  if err := s.repo.Delete(scenario.ID); err != nil {
+    return err
+  }
+  if scenario.PlanID != nil {
+    return s.slsvc.DeletePlan(*scenario.PlanID)
+  }
+  return nil
+}
+
+func (s *scenarioSvc) List(f repos.ScenarioFilter) ([]*models.SlottingScenario, error) {
+  return s.repo.ListScenarios(f)
+}
+
+func (s *scenarioSvc) Clone(scenarioID, createdByID uuid.UUID, name, description string, plan *SlotPlanParams) (*models.SlottingScenario, error) {
+  src, err := s.GetByID(scenarioID)
+  if err != nil {
+    return nil, err
+  }
+  p := ScenarioParams{
+    CompanyID:   derefUUID(src.CompanyID),
+    WarehouseID: derefUUID(src.WarehouseID),
+    CreatedByID: createdByID,
+    Name:        name,
+    Description: description,
+  }
+  if strings.TrimSpace(p.Name) == "" {
+    p.Name = src.Name + " (copy)"
+  }
+  if p.Description == "" {
+    p.Description = src.Description
+  }
+  if plan != nil {
+    p.Plan = *plan
+  } else if err := json.Unmarshal(src.Params, &p.Plan); err != nil {
+    return nil, fmt.Errorf("failed to decode scenario params: %w", err)
+  }
+  return s.create(p, &src.ID)
+}
+
+func (s *scenarioSvc) Compare(scenarioIDs []uuid.UUID) (*ScenarioComparison, error) {
+  if len(scenarioIDs) < 2 {
+    return nil, fmt.Errorf("at least two scenarios are needed to compare")
+  }
+  if len(scenarioIDs) > maxComparedScenarios {
+    return nil, fmt.Errorf("cannot compare more than %d scenarios", maxComparedScenarios)
+  }
+  cmp := &ScenarioComparison{}
+  for _, id := range scenarioIDs {
+    scenario, err := s.GetByID(id)
+    if err != nil {
+      return nil, err
+    }
+    if len(cmp.Scenarios) > 0 && derefUUID(scenario.WarehouseID) != derefUUID(cmp.Scenarios[0].WarehouseID) {
+      return nil, fmt.Errorf("scenarios must belong to the same warehouse")
+    }
+    cmp.Scenarios = append(cmp.Scenarios, scenario)
+  }
+
+  kpis := []struct {
+    key    string
+    better string
+    value  func(sc *models.SlottingScenario) float64
+  }{
+    {"travel_m", "lower", func(sc *models.SlottingScenario) float64 { return sc.TravelM }},
+    {"travel_saving_pct", "higher", func(sc *models.SlottingScenario) float64 {
+      if sc.CurrentTravelM == 0 {
+        return 0
+      }
+      return (sc.CurrentTravelM - sc.TravelM) / sc.CurrentTravelM * 100
+    }},
+    {"ergo_score", "higher", func(sc *models.SlottingScenario) float64 { return sc.ErgoScore }},
+    {"moves_required", "lower", func(sc *models.SlottingScenario) float64 { return float64(sc.MovesRequired) }},
+    {"move_hours", "lower", func(sc *models.SlottingScenario) float64 { return sc.MoveHours }},
+    {"capacity_violations", "lower", func(sc *models.SlottingScenario) float64 { return float64(sc.CapacityViolations) }},
+    {"unassigned", "lower", func(sc *models.SlottingScenario) float64 { return float64(sc.UnassignedCount) }},
+  }
+  for _, k := range kpis {
+    row := &ScenarioKPI{Key: k.key, Better: k.better}
+    best := -1
+    for i, sc := range cmp.Scenarios {
+      v := k.value(sc)
+      row.Values = append(row.Values, v)
+      if best < 0 || (k.better == "lower" && v < row.Values[best]) || (k.better == "higher" && v > row.Values[best]) {
+        best = i
+      }
+    }
+    row.BestScenarioID = cmp.Scenarios[best].ID
+    cmp.KPIs = append(cmp.KPIs, row)
+  }
+  return cmp, nil
+}
@@ -0,0 +1,531 @@
+package services
+
+import (
+  "fmt"
+  "math"
+  "sort"
+  "time"
+
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+// ReplayParams selects the history to replay and how pickers are timed.
+// Without dates the plan's own velocity window is used.
+type ReplayParams struct {
+  StartDate       time.Time
+  EndDate         time.Time
+  Routing         string
+  WalkSpeedMps    float64
+  SecondsPerLine  float64
+  SecondsPerOrder float64
+}
+
+// AisleLoad counts order visits to an aisle; the peak hour is the clock hour
+// with the most distinct orders in the aisle, a proxy for picker congestion.
+type AisleLoad struct {
+  Aisle          string    `json:"aisle"`
+  Visits         int       `json:"visits"`
+  PeakHour       time.Time `json:"peak_hour"`
+  PeakHourVisits int       `json:"peak_hour_visits"`
+}
+
+type ReplayScenario struct {
+  Name               string       `json:"name"`
+  Orders             int          `json:"orders"`
+  Lines              int64        `json:"lines"`
+  UnroutedLines      int64        `json:"unrouted_lines"` // item unslotted or slot without coordinates
+  TravelM            float64      `json:"travel_m"`
+  AvgTravelPerOrderM float64      `json:"avg_travel_per_order_m"`
+  PickHours          float64      `json:"pick_hours"`
+  LinesPerHour       float64      `json:"lines_per_hour"`
+  Hotspots           []*AisleLoad `json:"hotspots"`
+}
+
+type OrderReplay struct {
+  OrderName       string    `json:"order_name"`
+  CompletedAt     time.Time `json:"completed_at"`
+  Lines           int       `json:"lines"`
+  CurrentTravelM  float64   `json:"current_travel_m"`
+  ProposedTravelM float64   `json:"proposed_travel_m"`
+}
+
+type ReplayReport struct {
+  PlanID           uuid.UUID       `json:"plan_id"`
+  WarehouseID      uuid.UUID       `json:"warehouse_id"`
+  StartDate        time.Time       `json:"start_date"`
+  EndDate          time.Time       `json:"end_date"`
+  Routing          string          `json:"routing"`
+  Current          *ReplayScenario `json:"current"`
+  Proposed         *ReplayScenario `json:"proposed"`
+  TravelSavedM     float64         `json:"travel_saved_m"`
+  TravelSavedPct   float64         `json:"travel_saved_pct"`
+  LinesPerHourGain float64         `json:"lines_per_hour_gain"`
+  Orders           []*OrderReplay  `json:"-"` // per order detail, exported only
+}
+
+type SimulationSvc interface {
+  // ReplayPlan routes historical pick orders through the current slot map
+  // and the plan's proposed one.
+  ReplayPlan(planID uuid.UUID, p ReplayParams) (*ReplayReport, error)
+}
+
+type simulationSvc struct {
+  repo            repos.AnalyticsRepo
+  sprepo          repos.SlotPlanRepo
+  wrepo           repos.WRepo
+  lrepo           repos.LRepo
+}
+
+func NewSimulationSvc(repo repos.AnalyticsRepo, sprepo repos.SlotPlanRepo, wrepo repos.WRepo, lrepo repos.LRepo) SimulationSvc {
+  return &simulationSvc{repo: repo, sprepo: sprepo, wrepo: wrepo, lrepo: lrepo}
+}
+
+const (
+  defaultSecondsPerLine  = 10
+  defaultSecondsPerOrder = 30
+  replayHotspotCount     = 10
+  // orders with more distinct stops than this are routed heuristically
+  optimalRoutingMaxStops = 10
+)
+
+func (s *simulationSvc) ReplayPlan(planID uuid.UUID, p ReplayParams) (*ReplayReport, error) {
+  if planID == uuid.Nil {
+    return nil, fmt.Errorf("invalid planID")
+  }
+  if p.Routing == "" {
+    p.Routing = constants.RoutingSShape
+  }
+  if !constants.RoutingPolicies[p.Routing] {
+    return nil, fmt.Errorf("invalid routing '%s'", p.Routing)
+  }
+  if p.WalkSpeedMps < 0 || p.SecondsPerLine < 0 || p.SecondsPerOrder < 0 {
+    return nil, fmt.Errorf("replay parameters cannot be negative")
+  }
+  if p.WalkSpeedMps == 0 {
+    p.WalkSpeedMps = defaultWalkSpeedMps
+  }
+  if p.SecondsPerLine == 0 {
+    p.SecondsPerLine = defaultSecondsPerLine
+  }
+  if p.SecondsPerOrder == 0 {
+    p.SecondsPerOrder = defaultSecondsPerOrder
+  }
+
+  plan, err := s.sprepo.GetByID(planID)
+  if err != nil {
+    return nil, err
+  }
+  wh, err := s.wrepo.GetByID(*plan.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+  if p.StartDate.IsZero() && p.EndDate.IsZero() {
+    p.StartDate, p.EndDate = plan.StartDate, plan.EndDate
+  }
+  start, end, err := analyticsWindow(p.StartDate, p.EndDate)
+  if err != nil {
+    return nil, err
+  }
+
+  locs, err := s.lrepo.ListLocations(repos.LocationFilter{WarehouseID: wh.ID})
+  if err != nil {
+    return nil, fmt.Errorf("failed to load locations: %w", err)
+  }
+  layout := newPickLayout(wh, locs)
+  current, proposed, err := s.slotMaps(plan)
+  if err != nil {
+    return nil, err
+  }
+
+  f := repos.AnalyticsFilter{CompanyID: *plan.CompanyID, WarehouseID: wh.ID, StartDate: start, EndDate: end}
+  if f.RawTypes, err = rawTypesFor(s.repo, f, []string{constants.TransactionTypePick}); err != nil {
+    return nil, err
+  }
+  var lines []repos.PickLine
+  if len(f.RawTypes) > 0 {
+    if lines, err = s.repo.PickLines(f); err != nil {
+      return nil, err
+    }
+  }
+
+  report := &ReplayReport{
+    PlanID:      plan.ID,
+    WarehouseID: wh.ID,
+    StartDate:   start,
+    EndDate:     end.AddDate(0, 0, -1),
+    Routing:     p.Routing,
+  }
+  cur := newReplayTally("current")
+  prop := newReplayTally("proposed")
+  for _, o := range replayOrders(lines) {
+    row := &OrderReplay{OrderName: o.name, CompletedAt: o.at, Lines: len(o.items)}
+    row.CurrentTravelM = cur.add(layout, o, current, p)
+    row.ProposedTravelM = prop.add(layout, o, proposed, p)
+    report.Orders = append(report.Orders, row)
+  }
+  report.Current = cur.scenario()
+  report.Proposed = prop.scenario()
+  report.TravelSavedM = report.Current.TravelM - report.Proposed.TravelM
+  if report.Current.TravelM > 0 {
+    report.TravelSavedPct = 100 * report.TravelSavedM / report.Current.TravelM
+  }
+  report.LinesPerHourGain = report.Proposed.LinesPerHour - report.Current.LinesPerHour
+  return report, nil
+}
+
+// slotMaps returns the location each item is picked from now and under the
+// plan. Items outside the plan keep their current slot in both.
+func (s *simulationSvc) slotMaps(plan *models.SlotPlan) (map[uuid.UUID]uuid.UUID, map[uuid.UUID]uuid.UUID, error) {
+  links, err := s.lrepo.ListItemLinks(*plan.WarehouseID)
+  if err != nil {
+    return nil, nil, err
+  }
+  current := make(map[uuid.UUID]uuid.UUID)
+  for _, l := range links {
+    if _, ok := current[l.ItemID]; !ok {
+      current[l.ItemID] = l.LocationID
+    }
+  }
+  entries, err := s.sprepo.ListEntries(repos.SlotPlanEntryFilter{PlanID: plan.ID})
+  if err != nil {
+    return nil, nil, err
+  }
+  proposed := make(map[uuid.UUID]uuid.UUID, len(current))
+  for _, e := range entries {
+    if e.FromLocationID != nil {
+      current[*e.ItemID] = *e.FromLocationID
+    }
+  }
+  for itemID, locID := range current {
+    proposed[itemID] = locID
+  }
+  for _, e := range entries {
+    if e.ToLocationID != nil {
+      proposed[*e.ItemID] = *e.ToLocationID
+    }
+  }
+  return current, proposed, nil
+}
+
+type replayOrder struct {
+  name  string
+  at    time.Time
+  items []uuid.UUID
+}
+
+// replayOrders groups lines by order name; lines without one are their own order.
+func replayOrders(lines []repos.PickLine) []*replayOrder {
+  var orders []*replayOrder
+  var last *replayOrder
+  for _, l := range lines {
+    if last == nil || l.OrderName == "" || l.OrderName != last.name {
+      last = &replayOrder{name: l.OrderName, at: l.CompletedDate}
+      orders = append(orders, last)
+    }
+    last.items = append(last.items, l.ItemID)
+  }
+  return orders
+}
+
+type replayTally struct {
+  s      *ReplayScenario
+  visits map[string]int
+  hourly map[string]map[time.Time]int
+}
+
+func newReplayTally(name string) *replayTally {
+  return &replayTally{
+    s:      &ReplayScenario{Name: name, Hotspots: []*AisleLoad{}},
+    visits: make(map[string]int),
+    hourly: make(map[string]map[time.Time]int),
+  }
+}
+
+// add routes one order under slots and returns its travel.
+func (t *replayTally) add(layout *pickLayout, o *replayOrder, slots map[uuid.UUID]uuid.UUID, p ReplayParams) float64 {
+  var stops []pickPoint
+  seen := make(map[uuid.UUID]bool)
+  aisles := make(map[string]bool)
+  var routed int64
+  for _, itemID := range o.items {
+    pt, ok := layout.points[slots[itemID]]
+    if !ok {
+      t.s.UnroutedLines++
+      continue
+    }
+    routed++
+    aisles[pt.aisle] = true
+    if !seen[slots[itemID]] {
+      seen[slots[itemID]] = true
+      stops = append(stops, pt)
+    }
+  }
+  if routed == 0 {
+    return 0
+  }
+  travel := layout.route(stops, p.Routing)
+  t.s.Orders++
+  t.s.Lines += routed
+  t.s.TravelM += travel
+  t.s.PickHours += (travel/p.WalkSpeedMps + float64(routed)*p.SecondsPerLine + p.SecondsPerOrder) / 3600
+  hour := o.at.UTC().Truncate(time.Hour)
+  for a := range aisles {
+    t.visits[a]++
+    if t.hourly[a] == nil {
+      t.hourly[a] = make(map[time.Time]int)
+    }
+    t.hourly[a][hour]++
+  }
+  return travel
+}
+
+func (t *replayTally) scenario() *ReplayScenario {
+  if t.s.Orders > 0 {
+    t.s.AvgTravelPerOrderM = t.s.TravelM / float64(t.s.Orders)
+  }
+  if t.s.PickHours > 0 {
+    t.s.LinesPerHour = float64(t.s.Lines) / t.s.PickHours
+  }
+  for a, v := range t.visits {
+    load := &AisleLoad{Aisle: a, Visits: v}
+    for h, n := range t.hourly[a] {
+      if n > load.PeakHourVisits || (n == load.PeakHourVisits && h.Before(load.PeakHour)) {
+        load.PeakHour, load.PeakHourVisits = h, n
+      }
+    }
+    t.s.Hotspots = append(t.s.Hotspots, load)
+  }
+  sort.Slice(t.s.Hotspots, func(i, j int) bool {
+    a, b := t.s.Hotspots[i], t.s.Hotspots[j]
+    if a.PeakHourVisits != b.PeakHourVisits {
+      return a.PeakHourVisits > b.PeakHourVisits
+    }
+    if a.Visits != b.Visits {
+      return a.Visits > b.Visits
+    }
+    return a.Aisle < b.Aisle
+  })
+  if len(t.s.Hotspots) > replayHotspotCount {
+    t.s.Hotspots = t.s.Hotspots[:replayHotspotCount]
+  }
+  return t.s
+}
+
+// pickPoint is a slot's position in the aisle graph: the aisle's centre line
+// x and the distance y from the front cross aisle.
+type pickPoint struct {
+  aisle string
+  x     float64
+  y     float64
+}
+
+// pickLayout models the warehouse as one block of parallel aisles running
+// along y, joined by a front cross aisle at the lowest y and a back cross
+// aisle at the highest. The depot sits in front of the front cross aisle.
+type pickLayout struct {
+  points   map[uuid.UUID]pickPoint
+  length   float64 // aisle length between the cross aisles
+  depotX   float64
+  depotOff float64 // depot distance to the front cross aisle
+}
+
+func newPickLayout(wh *models.Warehouse, locs []*models.Location) *pickLayout {
+  l := &pickLayout{points: make(map[uuid.UUID]pickPoint), depotX: wh.DepotX}
+  sumX := make(map[string]float64)
+  count := make(map[string]int)
+  front, back := math.Inf(1), math.Inf(-1)
+  var located []*models.Location
+  for _, loc := range locs {
+    if _, ok := depotDistance(wh, loc); !ok {
+      continue
+    }
+    located = append(located, loc)
+    a := aisleKey(loc)
+    sumX[a] += loc.CoordX
+    count[a]++
+    front = math.Min(front, loc.CoordY)
+    back = math.Max(back, loc.CoordY)
+  }
+  if len(located) == 0 {
+    return l
+  }
+  l.length = back - front
+  l.depotOff = math.Abs(wh.DepotY - front)
+  for _, loc := range located {
+    a := aisleKey(loc)
+    l.points[loc.ID] = pickPoint{aisle: a, x: sumX[a] / float64(count[a]), y: loc.CoordY - front}
+  }
+  return l
+}
+
+// aisleKey falls back to the x coordinate for slots without an aisle.
+func aisleKey(loc *models.Location) string {
+  if loc.Aisle != "" {
+    return loc.Aisle
+  }
+  return fmt.Sprintf("x=%.1f", loc.CoordX)
+}
+
+// route is the depot-to-depot travel to visit stops under a routing policy.
+func (l *pickLayout) route(stops []pickPoint, policy string) float64 {
+  if len(stops) == 0 {
+    return 0
+  }
+  byAisle := make(map[float64][]float64)
+  minX, maxX := l.depotX, l.depotX
+  for _, p := range stops {
+    byAisle[p.x] = append(byAisle[p.x], p.y)
+    minX, maxX = math.Min(minX, p.x), math.Max(maxX, p.x)
+  }
+  xs := make([]float64, 0, len(byAisle))
+  for x, ys := range byAisle {
+    sort.Float64s(ys)
+    xs = append(xs, x)
+  }
+  sort.Float64s(xs)
+  base := 2*l.depotOff + 2*(maxX-minX)
+
+  returnRoute := base
+  for _, x := range xs {
+    ys := byAisle[x]
+    returnRoute += 2 * ys[len(ys)-1]
+  }
+  sShape := base + float64(len(xs))*l.length
+  if len(xs)%2 == 1 {
+    ys := byAisle[xs[len(xs)-1]]
+    sShape += 2*ys[len(ys)-1] - l.length
+  }
+  largestGap := returnRoute
+  if len(xs) > 1 {
+    largestGap = base + 2*l.length
+    for _, x := range xs[1 : len(xs)-1] {
+      ys := byAisle[x]
+      gap := math.Max(ys[0], l.length-ys[len(ys)-1])
+      for i := 1; i < len(ys); i++ {
+        gap = math.Max(gap, ys[i]-ys[i-1])
+      }
+      largestGap += 2 * (l.length - gap)
+    }
+  }
+
+  switch policy {
+  case constants.RoutingReturn:
+    return returnRoute
+  case constants.RoutingLargestGap:
+    return largestGap
+  case constants.RoutingOptimal:
+    best := math.Min(returnRoute, math.Min(sShape, largestGap))
+    return math.Min(best, l.shortestTour(stops))
+  default:
+    return sShape
+  }
+}
+
+// distance is the shortest aisle graph path between two slots: along the
+// aisle when they share it, otherwise round the nearer cross aisle.
+func (l *pickLayout) distance(a, b pickPoint) float64 {
+  if a.x == b.x {
+    return math.Abs(a.y - b.y)
+  }
+  return math.Abs(a.x-b.x) + math.Min(a.y+b.y, 2*l.length-a.y-b.y)
+}
+
+func (l *pickLayout) fromDepot(p pickPoint) float64 {
+  return l.depotOff + math.Abs(p.x-l.depotX) + p.y
+}
+
+// shortestTour solves the tour exactly for small orders and falls back to a
+// nearest neighbour tour improved by 2-opt for larger ones.
+func (l *pickLayout) shortestTour(stops []pickPoint) float64 {
+  n := len(stops)
+  d := make([][]float64, n)
+  for i := range stops {
+    d[i] = make([]float64, n)
+    for j := range stops {
+      d[i][j] = l.distance(stops[i], stops[j])
+    }
+  }
+  if n <= optimalRoutingMaxStops {
+    // Held-Karp over subsets of stops, ending at stop j
+    full := 1 << n
+    dp := make([][]float64, full)
+    for mask := range dp {
+      dp[mask] = make([]float64, n)
+      for j := range dp[mask] {
+        dp[mask][j] = math.Inf(1)
+      }
+    }
+    for j := range stops {
+      dp[1<<j][j] = l.fromDepot(stops[j])
+    }
+    for mask := 1; mask < full; mask++ {
+      for j := 0; j < n; j++ {
+        if mask&(1<<j) == 0 || math.IsInf(dp[mask][j], 1) {
+          continue
+        }
+        for k := 0; k < n; k++ {
+          if mask&(1<<k) != 0 {
+            continue
+          }
+          next := mask | 1<<k
+          dp[next][k] = math.Min(dp[next][k], dp[mask][j]+d[j][k])
+        }
+      }
+    }
+    best := math.Inf(1)
+    for j := range stops {
+      best = math.Min(best, dp[full-1][j]+l.fromDepot(stops[j]))
+    }
+    return best
+  }
+
+  tour := make([]int, 0, n)
+  used := make([]bool, n)
+  for len(tour) < n {
+    next, nextDist := -1, math.Inf(1)
+    for k := range stops {
+      if used[k] {
+        continue
+      }
+      dist := l.fromDepot(stops[k])
+      if len(tour) > 0 {
+        dist = d[tour[len(tour)-1]][k]
+      }
+      if dist < nextDist {
+        next, nextDist = k, dist
+      }
+    }
+    used[next] = true
+    tour = append(tour, next)
+  }
+  leg := func(i, j int) float64 {
+    switch {
+    case i < 0:
+      return l.fromDepot(stops[tour[j]])
+    case j >= n:
+      return l.fromDepot(stops[tour[i]])
+    }
+    return d[tour[i]][tour[j]]
+  }
+  for improved := true; improved; {
+    improved = false
+    for i := 0; i < n-1; i++ {
+      for j := i + 1; j < n; j++ {
+        if leg(i-1, j)+leg(i, j+1) < leg(i-1, i)+leg(j, j+1)-1e-9 {
+          for a, b := i, j; a < b; a, b = a+1, b-1 {
+            tour[a], tour[b] = tour[b], tour[a]
+          }
+          improved = true
+        }
+      }
+    }
+  }
+  total := leg(-1, 0)
+  for i := 0; i < n; i++ {
+    total += leg(i, i+1)
+  }
+  return total
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+  "math"
+  "testing"
+
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+)
+
+func TestPickLayoutRoute(t *testing.T) {
+  // three aisles 10 apart and 20 long, depot 5 in front of the first
+  layout := &pickLayout{length: 20, depotX: 0, depotOff: 5}
+  at := func(x, y float64) pickPoint { return pickPoint{x: x, y: y} }
+  tests := []struct {
+    name  string
+    stops []pickPoint
+    want  map[string]float64
+  }{
+    {
+      name: "no stops",
+      want: map[string]float64{constants.RoutingSShape: 0, constants.RoutingReturn: 0, constants.RoutingLargestGap: 0, constants.RoutingOptimal: 0},
+    },
+    {
+      // every policy walks in to y 4 and back
+      name:  "one stop",
+      stops: []pickPoint{at(0, 4)},
+      want:  map[string]float64{constants.RoutingSShape: 18, constants.RoutingReturn: 18, constants.RoutingLargestGap: 18, constants.RoutingOptimal: 18},
+    },
+    {
+      // picks near the front: return dips into each aisle, s-shape walks the
+      // first two in full, largest gap crosses the back
+      name:  "shallow picks",
+      stops: []pickPoint{at(0, 2), at(10, 10), at(20, 2)},
+      want:  map[string]float64{constants.RoutingSShape: 94, constants.RoutingReturn: 78, constants.RoutingLargestGap: 110, constants.RoutingOptimal: 78},
+    },
+    {
+      // picks near the back: largest gap skips the middle of the middle aisle
+      name:  "deep picks",
+      stops: []pickPoint{at(0, 18), at(10, 1), at(10, 19), at(20, 18)},
+      want:  map[string]float64{constants.RoutingSShape: 126, constants.RoutingReturn: 160, constants.RoutingLargestGap: 94, constants.RoutingOptimal: 94},
+    },
+    {
+      // an even number of aisles lets s-shape come back out the front
+      name:  "even aisles",
+      stops: []pickPoint{at(0, 2), at(10, 18)},
+      want:  map[string]float64{constants.RoutingSShape: 70, constants.RoutingReturn: 70, constants.RoutingLargestGap: 70, constants.RoutingOptimal: 70},
+    },
+  }
+  for _, tt := range tests {
+    for policy, want := range tt.want {
+      t.Run(tt.name+"/"+policy, func(t *testing.T) {
+        if got := layout.route(tt.stops, policy); math.Abs(got-want) > 1e-9 {
+          t.Errorf("route = %v, want %v", got, want)
+        }
+      })
+    }
+  }
+}
+
+func TestPickLayoutRouteOptimal(t *testing.T) {
+  // the exact tour never loses to a heuristic and the default policy is s-shape
+  layout := &pickLayout{length: 20, depotX: 5, depotOff: 3}
+  stops := []pickPoint{{x: 0, y: 3}, {x: 0, y: 15}, {x: 10, y: 7}, {x: 20, y: 12}, {x: 30, y: 1}, {x: 30, y: 19}}
+  optimal := layout.route(stops, constants.RoutingOptimal)
+  for _, policy := range []string{constants.RoutingSShape, constants.RoutingReturn, constants.RoutingLargestGap} {
+    if got := layout.route(stops, policy); got < optimal {
+      t.Errorf("%s route %v is shorter than optimal %v", policy, got, optimal)
+    }
+  }
+  if got, want := layout.route(stops, ""), layout.route(stops, constants.RoutingSShape); got != want {
+    t.Errorf("default route = %v, want s-shape %v", got, want)
+  }
+}
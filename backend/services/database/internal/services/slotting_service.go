@@ -0,0 +1,1209 @@
+package services
+
+import (
+  "encoding/json"
+  "fmt"
+  "math"
+  "sort"
+  "time"
+
+  "github.com/google/uuid"
+  "gorm.io/datatypes"
+
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+// MoveListParams caps and prices a move list. SecondsPerMove is the labour
+// to relocate one item's stock; WalkSpeedMps converts saved travel to time.
+type MoveListParams struct {
+  MaxMoves       int     `json:"max_moves"`
+  SecondsPerMove float64 `json:"seconds_per_move"`
+  WalkSpeedMps   float64 `json:"walk_speed_mps"`
+}
+
+// PlannedMove is one item move of a group. Moves are done in Sequence order;
+// a ViaTemporary item is staged in a spare slot before the group starts and
+// put away last. TravelSavedM and ErgoGain are over the plan window; the
+// benefit is the walking time saved per day.
+type PlannedMove struct {
+  ItemID           uuid.UUID `json:"item_id"`
+  ItemName         string    `json:"item_name"`
+  FromLocationID   uuid.UUID `json:"from_location_id"`
+  FromPath         string    `json:"from_path"`
+  ToLocationID     uuid.UUID `json:"to_location_id"`
+  ToPath           string    `json:"to_path"`
+  Sequence         int       `json:"sequence"`
+  ViaTemporary     bool      `json:"via_temporary"`
+  Lines            int64     `json:"lines"`
+  TravelSavedM     float64   `json:"travel_saved_m"`
+  ErgoGain         float64   `json:"ergo_gain"`
+  BenefitSecPerDay float64   `json:"benefit_sec_per_day"`
+}
+
+// MoveGroup is a set of moves that only make sense together. PaybackDays is
+// nil when the group saves no travel.
+type MoveGroup struct {
+  Rank             int            `json:"rank"`
+  Kind             string         `json:"kind"`
+  Moves            []*PlannedMove `json:"moves"`
+  CostSec          float64        `json:"cost_sec"`
+  BenefitSecPerDay float64        `json:"benefit_sec_per_day"`
+  ErgoGain         float64        `json:"ergo_gain"`
+  PaybackDays      *float64       `json:"payback_days"`
+  Note             string         `json:"note,omitempty"`
+}
+
+type MoveList struct {
+  PlanID                uuid.UUID    `json:"plan_id"`
+  Groups                []*MoveGroup `json:"groups"`
+  MoveCount             int          `json:"move_count"`
+  TotalCostSec          float64      `json:"total_cost_sec"`
+  TotalBenefitSecPerDay float64      `json:"total_benefit_sec_per_day"`
+  DeferredGroups        int          `json:"deferred_groups"` // left out by MaxMoves
+  StaleEntries          int          `json:"stale_entries"`   // plan moves the current links no longer match
+}
+
+const (
+  defaultSecondsPerMove = 240
+  defaultWalkSpeedMps   = 1.0
+)
+
+// SizingQuery scopes a pick-face sizing report. Demand is pick units over
+// [StartDate, EndDate]; items below MinDays of supply are under-slotted and
+// above MaxDays over-slotted (defaults: half and twice TargetDays). With
+// ForecastRunID demand is the run's forecast over its horizon instead.
+type SizingQuery struct {
+  CompanyID     uuid.UUID
+  WarehouseID   uuid.UUID
+  StartDate     time.Time
+  EndDate       time.Time
+  TargetDays    float64
+  MinDays       float64
+  MaxDays       float64
+  Status        string
+  SortField     string
+  SortDir       string
+  Limit         int
+  Offset        int
+  ForecastRunID uuid.UUID
+}
+
+// SlotProfile is a slot size in use among the warehouse's active pick slots.
+type SlotProfile struct {
+  SlotType    string  `json:"slot_type"`
+  WidthCm     float64 `json:"width_cm"`
+  DepthCm     float64 `json:"depth_cm"`
+  HeightCm    float64 `json:"height_cm"`
+  MaxWeightKg float64 `json:"max_weight_kg"`
+  Slots       int     `json:"slots"`
+}
+
+// ItemSizing compares an item's pick-face capacity, summed over its active
+// pick slots, with its demand. Days of supply are nil without demand or
+// without a known capacity.
+type ItemSizing struct {
+  ItemID                  uuid.UUID `json:"item_id"`
+  ItemName                string    `json:"item_name"`
+  Slots                   int       `json:"slots"`
+  SlotType                string    `json:"slot_type"`
+  CapacityUnits           int64     `json:"capacity_units"`
+  CapacityKnown           bool      `json:"capacity_known"`
+  AvgDailyUnits           float64   `json:"avg_daily_units"`
+  PeakDailyUnits          int64     `json:"peak_daily_units"`
+  DaysOfSupply            *float64  `json:"days_of_supply"`
+  PeakDaysOfSupply        *float64  `json:"peak_days_of_supply"`
+  Status                  string    `json:"status"`
+  TargetUnits             int64     `json:"target_units"`
+  RecommendedSlotType     string    `json:"recommended_slot_type"`
+  RecommendedWidthCm      float64   `json:"recommended_width_cm"`
+  RecommendedDepthCm      float64   `json:"recommended_depth_cm"`
+  RecommendedHeightCm     float64   `json:"recommended_height_cm"`
+  RecommendedSlots        int       `json:"recommended_slots"`
+  RecommendedCapacity     int64     `json:"recommended_capacity"`
+  RecommendedDaysOfSupply *float64  `json:"recommended_days_of_supply"`
+}
+
+type SizingReport struct {
+  WarehouseID uuid.UUID      `json:"warehouse_id"`
+  StartDate   time.Time      `json:"start_date"`
+  EndDate     time.Time      `json:"end_date"`
+  Days        int            `json:"days"`
+  TargetDays  float64        `json:"target_days"`
+  MinDays     float64        `json:"min_days"`
+  MaxDays     float64        `json:"max_days"`
+  Profiles    []*SlotProfile `json:"profiles"`
+  Counts      map[string]int `json:"counts"` // items per status, before the status filter
+  TotalItems  int            `json:"total_items"`
+  Rows        []*ItemSizing  `json:"rows"`
+}
+
+const defaultTargetDaysOfSupply = 5
+
+// SlotPlanParams drives a recommendation. Velocity comes from pick lines in
+// [StartDate, EndDate]; TravelWeight and ErgoWeight balance how much a slot's
+// distance from the depot and its height count towards its desirability.
+// AffinityWeight adds closeness to the slots already given to items of the
+// same co-pick cluster. Pinned items keep the slots they are linked to;
+// slots in ExcludeZoneIDs are left out as targets, so their items move out.
+type SlotPlanParams struct {
+  CompanyID      uuid.UUID   `json:"-"`
+  WarehouseID    uuid.UUID   `json:"-"`
+  CreatedByID    uuid.UUID   `json:"-"`
+  ZoneID         uuid.UUID   `json:"zone_id"`
+  StartDate      time.Time   `json:"start_date"`
+  EndDate        time.Time   `json:"end_date"`
+  TravelWeight   float64     `json:"travel_weight"`
+  ErgoWeight     float64     `json:"ergo_weight"`
+  AffinityWeight float64     `json:"affinity_weight"`
+  PinnedItemIDs  []uuid.UUID `json:"pinned_item_ids"`
+  ExcludeZoneIDs []uuid.UUID `json:"exclude_zone_ids"`
+}
+
+type SlottingSvc interface {
+  // Recommend builds and stores a slot plan for the warehouse or zone in p.
+  Recommend(p SlotPlanParams) (*models.SlotPlan, error)
+  GetPlanByID(planID uuid.UUID) (*models.SlotPlan, error)
+  DeletePlan(planID uuid.UUID) error
+  ListPlans(f repos.SlotPlanFilter) ([]*models.SlotPlan, error)
+  ListEntries(f repos.SlotPlanEntryFilter) ([]*models.SlotPlanEntry, error)
+  // MoveList turns the plan's moves that still apply into ranked groups of
+  // swaps, chains and moves to empty slots, capped at p.MaxMoves item moves.
+  MoveList(planID uuid.UUID, p MoveListParams) (*MoveList, error)
+  // AcceptMoveList stores the move list as open move tasks.
+  AcceptMoveList(planID, createdByID uuid.UUID, p MoveListParams) ([]*models.MoveTask, error)
+  GetMoveTask(taskID uuid.UUID) (*models.MoveTask, error)
+  ListMoveTasks(f repos.MoveTaskFilter) ([]*models.MoveTask, error)
+  // CompleteMoveTask confirms the open task, moving the item to moved, once
+  // the earlier tasks of its group are done.
+  CompleteMoveTask(task *models.MoveTask, moved *models.SlotAssignment, completedByID uuid.UUID) error
+  CancelMoveTask(taskID uuid.UUID) error
+  // PickFaceSizing compares pick slot capacity with demand per item and
+  // recommends a slot size for the target days of supply.
+  PickFaceSizing(q SizingQuery) (*SizingReport, error)
+}
+
+type slottingSvc struct {
+  repo            repos.SlotPlanRepo
+  mtrepo          repos.MoveTaskRepo
+  wrepo           repos.WRepo
+  lrepo           repos.LRepo
+  irepo           repos.IRepo
+  zrepo           repos.ZRepo
+  asvc            AnalyticsSvc
+  fsvc            ForecastSvc
+  ergsvc          ErgonomicSvc
+}
+
+func NewSlottingSvc(repo repos.SlotPlanRepo, mtrepo repos.MoveTaskRepo, wrepo repos.WRepo, lrepo repos.LRepo, irepo repos.IRepo, zrepo repos.ZRepo, asvc AnalyticsSvc, fsvc ForecastSvc, ergsvc ErgonomicSvc) SlottingSvc {
+  return &slottingSvc{repo: repo, mtrepo: mtrepo, wrepo: wrepo, lrepo: lrepo, irepo: irepo, zrepo: zrepo, asvc: asvc, fsvc: fsvc, ergsvc: ergsvc}
+}
+
+func (s *slottingSvc) Recommend(p SlotPlanParams) (*models.SlotPlan, error) {
+  if p.CompanyID == uuid.Nil || p.WarehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouse")
+  }
+  if p.TravelWeight < 0 || p.ErgoWeight < 0 || p.AffinityWeight < 0 {
+    return nil, fmt.Errorf("weights cannot be negative")
+  }
+  if p.TravelWeight == 0 && p.ErgoWeight == 0 {
+    p.TravelWeight, p.ErgoWeight = 1, 1
+  }
+  wh, err := s.wrepo.GetByID(p.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+  zones, err := s.zrepo.ListByWarehouse(wh.ID)
+  if err != nil {
+    return nil, err
+  }
+  prefix := ""
+  if p.ZoneID != uuid.Nil {
+    var scope *models.Zone
+    for _, z := range zones {
+      if z.ID == p.ZoneID {
+        scope = z
+      }
+    }
+    if scope == nil {
+      return nil, fmt.Errorf("zone does not belong to warehouse")
+    }
+    prefix = scope.PathPrefix
+  }
+  excluded := make(map[uuid.UUID]bool, len(p.ExcludeZoneIDs))
+  for _, id := range p.ExcludeZoneIDs {
+    excluded[id] = true
+  }
+  for _, z := range zones {
+    delete(excluded, z.ID)
+  }
+  if len(excluded) > 0 {
+    return nil, fmt.Errorf("excluded zone does not belong to warehouse")
+  }
+
+  locs, err := s.lrepo.ListLocations(repos.LocationFilter{WarehouseID: wh.ID, PathPrefix: prefix})
+  if err != nil {
+    return nil, fmt.Errorf("failed to load locations: %w", err)
+  }
+  ergo, err := s.ergsvc.GetModel(wh.ID)
+  if err != nil {
+    return nil, err
+  }
+  layout := newSlotLayout(wh, locs, zones, ergo, p.TravelWeight, p.ErgoWeight)
+  layout.exclude(p.ExcludeZoneIDs)
+
+  links, err := s.lrepo.ListItemLinks(wh.ID)
+  if err != nil {
+    return nil, err
+  }
+  current := make(map[uuid.UUID][]*slotCandidate)
+  var itemIDs []uuid.UUID
+  for _, l := range links {
+    c, ok := layout.byID[l.LocationID]
+    if !ok {
+      continue
+    }
+    if _, seen := current[l.ItemID]; !seen {
+      itemIDs = append(itemIDs, l.ItemID)
+    }
+    current[l.ItemID] = append(current[l.ItemID], c)
+  }
+  if len(itemIDs) == 0 {
+    return nil, fmt.Errorf("no items are slotted in scope")
+  }
+  items, err := s.irepo.ListItems(repos.ItemFilter{IDs: itemIDs})
+  if err != nil {
+    return nil, fmt.Errorf("failed to load items: %w", err)
+  }
+
+  velocity, err := s.asvc.ItemVelocity(VelocityQuery{CompanyID: p.CompanyID, WarehouseID: wh.ID, StartDate: p.StartDate, EndDate: p.EndDate})
+  if err != nil {
+    return nil, err
+  }
+  lines := make(map[uuid.UUID]int64, len(velocity.Rows))
+  for _, v := range velocity.Rows {
+    lines[v.ItemID] = v.Lines
+  }
+  sort.SliceStable(items, func(i, j int) bool {
+    if lines[items[i].ID] != lines[items[j].ID] {
+      return lines[items[i].ID] > lines[items[j].ID]
+    }
+    return items[i].Name < items[j].Name
+  })
+
+  partners := make(map[uuid.UUID][]uuid.UUID)
+  if p.AffinityWeight > 0 {
+    aff, err := s.asvc.ItemAffinity(AffinityQuery{CompanyID: p.CompanyID, WarehouseID: wh.ID, StartDate: velocity.StartDate, EndDate: velocity.EndDate, Limit: 1})
+    if err != nil {
+      return nil, err
+    }
+    for _, c := range aff.Clusters {
+      for _, a := range c.Items {
+        for _, b := range c.Items {
+          if a.ItemID != b.ItemID {
+            partners[a.ItemID] = append(partners[a.ItemID], b.ItemID)
+          }
+        }
+      }
+    }
+  }
+  placed := make(map[uuid.UUID]*slotCandidate)
+
+  pinned := make(map[uuid.UUID]bool, len(p.PinnedItemIDs))
+  for _, id := range p.PinnedItemIDs {
+    pinned[id] = true
+  }
+  for _, it := range items {
+    if pinned[it.ID] {
+      for _, c := range current[it.ID] {
+        c.used = true
+      }
+      placed[it.ID] = bestCandidate(current[it.ID])
+    }
+  }
+
+  plan := models.SlotPlan{
+    CompanyID:   &p.CompanyID,
+    WarehouseID: &wh.ID,
+    StartDate:   velocity.StartDate,
+    EndDate:     velocity.EndDate,
+    ItemCount:   len(items),
+  }
+  if p.ZoneID != uuid.Nil {
+    plan.ZoneID = &p.ZoneID
+  }
+  if p.CreatedByID != uuid.Nil {
+    plan.CreatedByID = &p.CreatedByID
+  }
+  p.StartDate, p.EndDate = velocity.StartDate, velocity.EndDate
+  raw, err := json.Marshal(p)
+  if err != nil {
+    return nil, fmt.Errorf("failed to encode plan params: %w", err)
+  }
+  plan.Params = datatypes.JSON(raw)
+
+  var totalLines float64
+  entries := make([]*models.SlotPlanEntry, 0, len(items))
+  for _, it := range items {
+    itemID := it.ID
+    from := bestCandidate(current[it.ID])
+    e := &models.SlotPlanEntry{ItemID: &itemID, Lines: lines[it.ID], Pinned: pinned[it.ID]}
+    var to *slotCandidate
+    if e.Pinned {
+      to = from
+      e.Note = "pinned"
+    } else {
+      var near []*slotCandidate
+      for _, id := range partners[it.ID] {
+        if c, ok := placed[id]; ok {
+          near = append(near, c)
+        }
+      }
+      to = layout.takeNear(it, current[it.ID], near, p.TravelWeight+p.ErgoWeight, p.AffinityWeight)
+      if to == nil {
+        e.Note = "no compatible slot available"
+        plan.UnassignedCount++
+      } else {
+        for _, c := range current[it.ID] {
+          if c == to {
+            from = to
+          }
+        }
+      }
+    }
+    e.FromLocationID, e.FromScore = &from.loc.ID, from.score
+    if to != nil {
+      placed[it.ID] = to
+      e.ToLocationID, e.ToScore = &to.loc.ID, to.score
+      if to != from {
+        plan.MoveCount++
+      }
+    } else {
+      to = from
+    }
+
+    w := float64(e.Lines)
+    totalLines += w
+    plan.CurrentTravelM += w * 2 * layout.travelOf(from)
+    plan.PlanTravelM += w * 2 * layout.travelOf(to)
+    plan.CurrentErgoScore += w * from.ergo
+    plan.PlanErgoScore += w * to.ergo
+    entries = append(entries, e)
+  }
+  if totalLines > 0 {
+    plan.CurrentErgoScore /= totalLines
+    plan.PlanErgoScore /= totalLines
+  }
+
+  created, err := s.repo.Create(plan, entries)
+  if err != nil {
+    return nil, err
+  }
+  created.Entries = entries
+  return created, nil
+}
+
+func (s *slottingSvc) GetPlanByID(planID uuid.UUID) (*models.SlotPlan, error) {
+  if planID == uuid.Nil {
+    return nil, fmt.Errorf("invalid planID")
+  }
+  return s.repo.GetByID(planID)
+}
+
+func (s *slottingSvc) DeletePlan(planID uuid.UUID) error {
+  if planID == uuid.Nil {
+    return fmt.Errorf("invalid planID")
+  }
+  return s.repo.Delete(planID)
+}
+
+func (s *slottingSvc) ListPlans(f repos.SlotPlanFilter) ([]*models.SlotPlan, error) {
+  return s.repo.ListSlotPlans(f)
+}
+
+func (s *slottingSvc) ListEntries(f repos.SlotPlanEntryFilter) ([]*models.SlotPlanEntry, error) {
+  if f.PlanID == uuid.Nil {
+    return nil, fmt.Errorf("invalid planID")
+  }
+  return s.repo.ListEntries(f)
+}
+
+func (s *slottingSvc) MoveList(planID uuid.UUID, p MoveListParams) (*MoveList, error) {
+  if planID == uuid.Nil {
+    return nil, fmt.Errorf("invalid planID")
+  }
+  if p.MaxMoves < 0 || p.SecondsPerMove < 0 || p.WalkSpeedMps < 0 {
+    return nil, fmt.Errorf("move list parameters cannot be negative")
+  }
+  if p.SecondsPerMove == 0 {
+    p.SecondsPerMove = defaultSecondsPerMove
+  }
+  if p.WalkSpeedMps == 0 {
+    p.WalkSpeedMps = defaultWalkSpeedMps
+  }
+  plan, err := s.repo.GetByID(planID)
+  if err != nil {
+    return nil, err
+  }
+  wh, err := s.wrepo.GetByID(*plan.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+  entries, err := s.repo.ListEntries(repos.SlotPlanEntryFilter{PlanID: plan.ID, MovesOnly: true})
+  if err != nil {
+    return nil, err
+  }
+  locs, err := s.lrepo.ListLocations(repos.LocationFilter{WarehouseID: wh.ID})
+  if err != nil {
+    return nil, fmt.Errorf("failed to load locations: %w", err)
+  }
+  ergo, err := s.ergsvc.GetModel(wh.ID)
+  if err != nil {
+    return nil, err
+  }
+  layout := newSlotLayout(wh, locs, nil, ergo, 1, 1)
+  links, err := s.lrepo.ListItemLinks(wh.ID)
+  if err != nil {
+    return nil, err
+  }
+  linked := make(map[[2]uuid.UUID]bool, len(links))
+  occupants := make(map[uuid.UUID][]uuid.UUID)
+  for _, l := range links {
+    linked[[2]uuid.UUID{l.ItemID, l.LocationID}] = true
+    occupants[l.LocationID] = append(occupants[l.LocationID], l.ItemID)
+  }
+
+  list := &MoveList{PlanID: plan.ID, Groups: []*MoveGroup{}}
+  var moves []*PlannedMove
+  for _, e := range entries {
+    from, okFrom := layout.byID[derefUUID(e.FromLocationID)]
+    to, okTo := layout.byID[derefUUID(e.ToLocationID)]
+    if !okFrom || !okTo || !linked[[2]uuid.UUID{*e.ItemID, from.loc.ID}] || linked[[2]uuid.UUID{*e.ItemID, to.loc.ID}] {
+      list.StaleEntries++
+      continue
+    }
+    m := &PlannedMove{
+      ItemID:         *e.ItemID,
+      FromLocationID: from.loc.ID,
+      FromPath:       from.loc.LocationPath,
+      ToLocationID:   to.loc.ID,
+      ToPath:         to.loc.LocationPath,
+      Lines:          e.Lines,
+      TravelSavedM:   float64(e.Lines) * 2 * (layout.travelOf(from) - layout.travelOf(to)),
+      ErgoGain:       float64(e.Lines) * (to.ergo - from.ergo),
+    }
+    if e.Item != nil {
+      m.ItemName = e.Item.Name
+    }
+    moves = append(moves, m)
+  }
+
+  days := plan.EndDate.Sub(plan.StartDate).Hours()/24 + 1
+  groups := groupMoves(moves, occupants)
+  for _, g := range groups {
+    handlings := len(g.Moves)
+    for _, m := range g.Moves {
+      if m.ViaTemporary {
+        handlings++
+      }
+      if days > 0 {
+        m.BenefitSecPerDay = m.TravelSavedM / p.WalkSpeedMps / days
+      }
+      g.BenefitSecPerDay += m.BenefitSecPerDay
+      g.ErgoGain += m.ErgoGain
+    }
+    g.CostSec = float64(handlings) * p.SecondsPerMove
+    if g.BenefitSecPerDay > 0 {
+      payback := g.CostSec / g.BenefitSecPerDay
+      g.PaybackDays = &payback
+    }
+  }
+  sort.SliceStable(groups, func(i, j int) bool {
+    a, b := groups[i], groups[j]
+    if (a.PaybackDays == nil) != (b.PaybackDays == nil) {
+      return a.PaybackDays != nil
+    }
+    if a.PaybackDays != nil && *a.PaybackDays != *b.PaybackDays {
+      return *a.PaybackDays < *b.PaybackDays
+    }
+    return a.ErgoGain > b.ErgoGain
+  })
+
+  for _, g := range groups {
+    if p.MaxMoves > 0 && list.MoveCount+len(g.Moves) > p.MaxMoves {
+      list.DeferredGroups++
+      continue
+    }
+    g.Rank = len(list.Groups) + 1
+    list.Groups = append(list.Groups, g)
+    list.MoveCount += len(g.Moves)
+    list.TotalCostSec += g.CostSec
+    list.TotalBenefitSecPerDay += g.BenefitSecPerDay
+  }
+  return list, nil
+}
+
+func (s *slottingSvc) AcceptMoveList(planID, createdByID uuid.UUID, p MoveListParams) ([]*models.MoveTask, error) {
+  open, err := s.mtrepo.ListMoveTasks(repos.MoveTaskFilter{PlanID: planID, Status: constants.MoveTaskStatusOpen})
+  if err != nil {
+    return nil, err
+  }
+  if len(open) > 0 {
+    return nil, fmt.Errorf("slot plan already has %d open move tasks", len(open))
+  }
+  list, err := s.MoveList(planID, p)
+  if err != nil {
+    return nil, err
+  }
+  plan, err := s.repo.GetByID(planID)
+  if err != nil {
+    return nil, err
+  }
+  var tasks []*models.MoveTask
+  for _, g := range list.Groups {
+    for _, m := range g.Moves {
+      itemID, fromID, toID := m.ItemID, m.FromLocationID, m.ToLocationID
+      t := &models.MoveTask{
+        CompanyID:        plan.CompanyID,
+        WarehouseID:      plan.WarehouseID,
+        PlanID:           &plan.ID,
+        ItemID:           &itemID,
+        FromLocationID:   &fromID,
+        ToLocationID:     &toID,
+        GroupNo:          g.Rank,
+        Kind:             g.Kind,
+        Sequence:         m.Sequence,
+        ViaTemporary:     m.ViaTemporary,
+        Status:           constants.MoveTaskStatusOpen,
+        EstimatedCostSec: g.CostSec / float64(len(g.Moves)),
+        BenefitSecPerDay: m.BenefitSecPerDay,
+      }
+      if createdByID != uuid.Nil {
+        t.CreatedByID = &createdByID
+      }
+      tasks = append(tasks, t)
+    }
+  }
+  if err := s.mtrepo.CreateTasks(tasks); err != nil {
+    return nil, err
+  }
+  return tasks, nil
+}
+
+func (s *slottingSvc) GetMoveTask(taskID uuid.UUID) (*models.MoveTask, error) {
+  if taskID == uuid.Nil {
+    return nil, fmt.Errorf("invalid taskID")
+  }
+  return s.mtrepo.GetByID(taskID)
+}
+
+func (s *slottingSvc) ListMoveTasks(f repos.MoveTaskFilter) ([]*models.MoveTask, error) {
+  if f.Status != "" && !constants.MoveTaskStatuses[f.Status] {
+    return nil, fmt.Errorf("invalid move task status '%s'", f.Status)
+  }
+  return s.mtrepo.ListMoveTasks(f)
+}
+
+func (s *slottingSvc) CompleteMoveTask(task *models.MoveTask, moved *models.SlotAssignment, completedByID uuid.UUID) error {
+  if task == nil || task.ID == uuid.Nil {
+    return fmt.Errorf("invalid taskID")
+  }
+  return s.mtrepo.Complete(task, moved, completedByID)
+}
+
+func (s *slottingSvc) CancelMoveTask(taskID uuid.UUID) error {
+  if taskID == uuid.Nil {
+    return fmt.Errorf("invalid taskID")
+  }
+  return s.mtrepo.UpdateStatus(taskID, constants.MoveTaskStatusCancelled, nil)
+}
+
+// groupMoves links each move to the moves that vacate its target slot and
+// splits them into connected groups. Within a group a move is sequenced after
+// the moves it waits on; a cycle is broken by staging one item aside.
+// occupants lists the items currently linked to each location.
+func groupMoves(moves []*PlannedMove, occupants map[uuid.UUID][]uuid.UUID) []*MoveGroup {
+  leaving := make(map[uuid.UUID][]int) // location -> moves out of it
+  for i, m := range moves {
+    leaving[m.FromLocationID] = append(leaving[m.FromLocationID], i)
+  }
+  waitsOn := make([][]int, len(moves))
+  waitedBy := make([][]int, len(moves))
+  parent := make([]int, len(moves))
+  for i := range parent {
+    parent[i] = i
+  }
+  var find func(int) int
+  find = func(i int) int {
+    if parent[i] != i {
+      parent[i] = find(parent[i])
+    }
+    return parent[i]
+  }
+  for i, m := range moves {
+    for _, j := range leaving[m.ToLocationID] {
+      waitsOn[i] = append(waitsOn[i], j)
+      waitedBy[j] = append(waitedBy[j], i)
+      parent[find(i)] = find(j)
+    }
+  }
+
+  members := make(map[int][]int)
+  var roots []int
+  for i := range moves {
+    r := find(i)
+    if _, ok := members[r]; !ok {
+      roots = append(roots, r)
+    }
+    members[r] = append(members[r], i)
+  }
+
+  groups := make([]*MoveGroup, 0, len(roots))
+  for _, r := range roots {
+    idx := members[r]
+    g := &MoveGroup{Kind: constants.MoveKindChain}
+    switch {
+    case len(idx) == 1:
+      g.Kind = constants.MoveKindMove
+    case len(idx) == 2 && len(waitsOn[idx[0]]) == 1 && len(waitsOn[idx[1]]) == 1:
+      g.Kind = constants.MoveKindSwap
+    }
+
+    pending := make(map[int]int, len(idx))
+    for _, i := range idx {
+      pending[i] = len(waitsOn[i])
+    }
+    done := make(map[int]bool, len(idx))
+    var staged []int
+    release := func(i int) {
+      done[i] = true
+      for _, k := range waitedBy[i] {
+        pending[k]--
+      }
+    }
+    for len(done) < len(idx) {
+      progressed := false
+      for _, i := range idx {
+        if !done[i] && pending[i] == 0 {
+          g.Moves = append(g.Moves, moves[i])
+          release(i)
+          progressed = true
+        }
+      }
+      if progressed {
+        continue
+      }
+      // every remaining move waits on another: stage the lowest line item aside
+      var pick = -1
+      for _, i := range idx {
+        if !done[i] && (pick < 0 || moves[i].Lines < moves[pick].Lines) {
+          pick = i
+        }
+      }
+      moves[pick].ViaTemporary = true
+      staged = append(staged, pick)
+      release(pick)
+    }
+    for _, i := range staged {
+      g.Moves = append(g.Moves, moves[i])
+    }
+    for n, m := range g.Moves {
+      m.Sequence = n + 1
+    }
+
+    staying := 0
+    for _, i := range idx {
+      m := moves[i]
+      for _, itemID := range occupants[m.ToLocationID] {
+        moving := false
+        for _, j := range leaving[m.ToLocationID] {
+          if moves[j].ItemID == itemID {
+            moving = true
+          }
+        }
+        if !moving {
+          staying++
+        }
+      }
+    }
+    if staying > 0 {
+      g.Note = fmt.Sprintf("%d item(s) stay linked to a target slot", staying)
+    }
+    groups = append(groups, g)
+  }
+  return groups
+}
+
+func derefUUID(id *uuid.UUID) uuid.UUID {
+  if id == nil {
+    return uuid.Nil
+  }
+  return *id
+}
+
+func (s *slottingSvc) PickFaceSizing(q SizingQuery) (*SizingReport, error) {
+  if q.WarehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  if q.TargetDays < 0 || q.MinDays < 0 || q.MaxDays < 0 {
+    return nil, fmt.Errorf("days of supply cannot be negative")
+  }
+  if q.TargetDays == 0 {
+    q.TargetDays = defaultTargetDaysOfSupply
+  }
+  if q.MinDays == 0 {
+    q.MinDays = q.TargetDays / 2
+  }
+  if q.MaxDays == 0 {
+    q.MaxDays = q.TargetDays * 2
+  }
+  if q.MinDays > q.TargetDays || q.MaxDays < q.TargetDays {
+    return nil, fmt.Errorf("target days of supply must lie between min and max days")
+  }
+  if q.Status != "" && !constants.SizingStatuses[q.Status] {
+    return nil, fmt.Errorf("invalid sizing status '%s'", q.Status)
+  }
+  wh, err := s.wrepo.GetByID(q.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+  locs, err := s.lrepo.ListLocations(repos.LocationFilter{WarehouseID: wh.ID, Status: constants.LocationStatusActive})
+  if err != nil {
+    return nil, fmt.Errorf("failed to load locations: %w", err)
+  }
+  picks := make(map[uuid.UUID]*models.Location)
+  profiles := make(map[SlotProfile]*SlotProfile)
+  for _, loc := range locs {
+    if loc.SlotRole != "" && loc.SlotRole != constants.SlotRolePick {
+      continue
+    }
+    picks[loc.ID] = loc
+    if loc.SlotWidthCm <= 0 || loc.SlotDepthCm <= 0 || loc.SlotHeightCm <= 0 {
+      continue
+    }
+    key := SlotProfile{SlotType: loc.SlotType, WidthCm: loc.SlotWidthCm, DepthCm: loc.SlotDepthCm, HeightCm: loc.SlotHeightCm, MaxWeightKg: loc.MaxWeightKg}
+    p, ok := profiles[key]
+    if !ok {
+      copied := key
+      p = &copied
+      profiles[key] = p
+    }
+    p.Slots++
+  }
+
+  var velocity *VelocityReport
+  if q.ForecastRunID != uuid.Nil {
+    velocity, err = s.fsvc.ForecastVelocity(q.ForecastRunID)
+    if err == nil && velocity.WarehouseID != wh.ID {
+      err = fmt.Errorf("forecast run belongs to another warehouse")
+    }
+  } else {
+    velocity, err = s.asvc.ItemVelocity(VelocityQuery{CompanyID: q.CompanyID, WarehouseID: wh.ID, StartDate: q.StartDate, EndDate: q.EndDate})
+  }
+  if err != nil {
+    return nil, err
+  }
+  report := &SizingReport{
+    WarehouseID: wh.ID,
+    StartDate:   velocity.StartDate,
+    EndDate:     velocity.EndDate,
+    Days:        velocity.Days,
+    TargetDays:  q.TargetDays,
+    MinDays:     q.MinDays,
+    MaxDays:     q.MaxDays,
+    Profiles:    []*SlotProfile{},
+    Counts:      make(map[string]int),
+    Rows:        []*ItemSizing{},
+  }
+  for _, p := range profiles {
+    report.Profiles = append(report.Profiles, p)
+  }
+  sort.Slice(report.Profiles, func(i, j int) bool {
+    a, b := report.Profiles[i], report.Profiles[j]
+    if va, vb := a.WidthCm*a.DepthCm*a.HeightCm, b.WidthCm*b.DepthCm*b.HeightCm; va != vb {
+      return va < vb
+    }
+    return a.SlotType < b.SlotType
+  })
+
+  demand := make(map[uuid.UUID]*ItemVelocity, len(velocity.Rows))
+  var itemIDs []uuid.UUID
+  for _, v := range velocity.Rows {
+    demand[v.ItemID] = v
+    itemIDs = append(itemIDs, v.ItemID)
+  }
+  links, err := s.lrepo.ListItemLinks(wh.ID)
+  if err != nil {
+    return nil, err
+  }
+  slots := make(map[uuid.UUID][]*models.Location)
+  for _, l := range links {
+    loc, ok := picks[l.LocationID]
+    if !ok {
+      continue
+    }
+    if _, seen := slots[l.ItemID]; !seen && demand[l.ItemID] == nil {
+      itemIDs = append(itemIDs, l.ItemID)
+    }
+    slots[l.ItemID] = append(slots[l.ItemID], loc)
+  }
+  if len(itemIDs) == 0 {
+    return report, nil
+  }
+  items, err := s.irepo.ListItems(repos.ItemFilter{IDs: itemIDs})
+  if err != nil {
+    return nil, fmt.Errorf("failed to load items: %w", err)
+  }
+
+  for _, it := range items {
+    row := &ItemSizing{ItemID: it.ID, ItemName: it.Name, Slots: len(slots[it.ID]), CapacityKnown: true}
+    if v := demand[it.ID]; v != nil {
+      row.AvgDailyUnits, row.PeakDailyUnits = v.AvgDailyUnits, v.PeakDailyUnits
+    }
+    for _, loc := range slots[it.ID] {
+      if row.SlotType == "" {
+        row.SlotType = loc.SlotType
+      }
+      units, ok := slotCapacity(loc, it)
+      row.CapacityUnits += units
+      row.CapacityKnown = row.CapacityKnown && ok
+    }
+    switch {
+    case row.Slots == 0:
+      row.CapacityKnown = false
+      row.Status = constants.SizingUnslotted
+    case !row.CapacityKnown:
+      row.Status = constants.SizingUnknown
+    case row.AvgDailyUnits == 0:
+      row.Status = constants.SizingOver
+    default:
+      dos := float64(row.CapacityUnits) / row.AvgDailyUnits
+      row.DaysOfSupply = &dos
+      switch {
+      case dos < q.MinDays:
+        row.Status = constants.SizingUnder
+      case dos > q.MaxDays:
+        row.Status = constants.SizingOver
+      default:
+        row.Status = constants.SizingOK
+      }
+    }
+    if row.CapacityKnown && row.PeakDailyUnits > 0 {
+      peak := float64(row.CapacityUnits) / float64(row.PeakDailyUnits)
+      row.PeakDaysOfSupply = &peak
+    }
+    recommendSlotSize(row, it, report.Profiles, q.TargetDays)
+    report.Counts[row.Status]++
+    if q.Status == "" || q.Status == row.Status {
+      report.Rows = append(report.Rows, row)
+    }
+  }
+
+  sortSizing(report.Rows, q.SortField, q.SortDir)
+  report.TotalItems = len(report.Rows)
+  report.Rows = paginate(report.Rows, q.Limit, q.Offset)
+  return report, nil
+}
+
+// recommendSlotSize picks the smallest slot profile in use that holds the
+// target stock in one slot, or else as few of the largest as needed. The
+// target is TargetDays of average demand but never less than a peak day.
+func recommendSlotSize(row *ItemSizing, item *models.Item, profiles []*SlotProfile, targetDays float64) {
+  if row.AvgDailyUnits == 0 {
+    return
+  }
+  row.TargetUnits = int64(math.Ceil(math.Max(row.AvgDailyUnits*targetDays, float64(row.PeakDailyUnits))))
+  var best *SlotProfile
+  var bestCap int64
+  for _, p := range profiles {
+    units, ok := slotCapacity(&models.Location{SlotWidthCm: p.WidthCm, SlotDepthCm: p.DepthCm, SlotHeightCm: p.HeightCm, MaxWeightKg: p.MaxWeightKg}, item)
+    if !ok || units == 0 {
+      continue
+    }
+    if units >= row.TargetUnits {
+      best, bestCap = p, units
+      break
+    }
+    if units > bestCap {
+      best, bestCap = p, units
+    }
+  }
+  if best == nil {
+    return
+  }
+  row.RecommendedSlotType = best.SlotType
+  row.RecommendedWidthCm, row.RecommendedDepthCm, row.RecommendedHeightCm = best.WidthCm, best.DepthCm, best.HeightCm
+  row.RecommendedSlots = int(math.Ceil(float64(row.TargetUnits) / float64(bestCap)))
+  row.RecommendedCapacity = bestCap * int64(row.RecommendedSlots)
+  dos := float64(row.RecommendedCapacity) / row.AvgDailyUnits
+  row.RecommendedDaysOfSupply = &dos
+}
+
+func sortSizing(rows []*ItemSizing, field, dir string) {
+  dosOf := func(r *ItemSizing) float64 {
+    if r.DaysOfSupply == nil {
+      return math.Inf(1)
+    }
+    return *r.DaysOfSupply
+  }
+  less := func(a, b *ItemSizing) bool { return dosOf(a) < dosOf(b) }
+  switch field {
+  case "avg_daily_units":
+    less = func(a, b *ItemSizing) bool { return a.AvgDailyUnits < b.AvgDailyUnits }
+  case "peak_daily_units":
+    less = func(a, b *ItemSizing) bool { return a.PeakDailyUnits < b.PeakDailyUnits }
+  case "capacity_units":
+    less = func(a, b *ItemSizing) bool { return a.CapacityUnits < b.CapacityUnits }
+  case "item_name":
+    less = func(a, b *ItemSizing) bool { return a.ItemName < b.ItemName }
+  }
+  // most urgent first unless asked otherwise: days of supply ascending
+  desc := dir == "desc" || dir == "DESC"
+  sort.Slice(rows, func(i, j int) bool { return rows[i].ItemName < rows[j].ItemName })
+  sort.SliceStable(rows, func(i, j int) bool {
+    if desc {
+      return less(rows[j], rows[i])
+    }
+    return less(rows[i], rows[j])
+  })
+}
+
+// slotCandidate is a location with its precomputed desirability.
+type slotCandidate struct {
+  loc      *models.Location
+  zones    []*models.Zone
+  travel   float64 // one-way metres from the depot
+  hasCoord bool
+  ergo     float64
+  score    float64
+  pickable bool // active pick slot the plan may assign to
+  used     bool
+}
+
+// slotLayout rates every location in scope once. Pick candidates are kept
+// best-first so assignment is a scan for the first free compatible slot.
+type slotLayout struct {
+  byID      map[uuid.UUID]*slotCandidate
+  picks     []*slotCandidate
+  avgTravel float64
+  span      float64 // width plus depth of the located slots
+}
+
+func newSlotLayout(wh *models.Warehouse, locs []*models.Location, zones []*models.Zone, ergo *models.ErgonomicModel, travelWeight, ergoWeight float64) *slotLayout {
+  l := &slotLayout{byID: make(map[uuid.UUID]*slotCandidate, len(locs))}
+  maxTravel, sumTravel, withCoord := 0.0, 0.0, 0
+  minX, maxX, minY, maxY := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+  for _, loc := range locs {
+    c := &slotCandidate{loc: loc}
+    c.ergo, _ = ergonomicScore(ergo, loc)
+    c.travel, c.hasCoord = depotDistance(wh, loc)
+    if c.hasCoord {
+      maxTravel = math.Max(maxTravel, c.travel)
+      sumTravel += c.travel
+      withCoord++
+      minX, maxX = math.Min(minX, loc.CoordX), math.Max(maxX, loc.CoordX)
+      minY, maxY = math.Min(minY, loc.CoordY), math.Max(maxY, loc.CoordY)
+    }
+    for _, z := range zones {
+      if pathInSubtree(loc.LocationPath, z.PathPrefix) {
+        c.zones = append(c.zones, z)
+      }
+    }
+    c.pickable = loc.Status == constants.LocationStatusActive &&
+      (loc.SlotRole == "" || loc.SlotRole == constants.SlotRolePick)
+    l.byID[loc.ID] = c
+  }
+  if withCoord > 0 {
+    l.avgTravel = sumTravel / float64(withCoord)
+    l.span = (maxX - minX) + (maxY - minY)
+  }
+  for _, c := range l.byID {
+    travelScore := 0.5
+    if c.hasCoord && maxTravel > 0 {
+      travelScore = 1 - c.travel/maxTravel
+    }
+    c.score = (travelWeight*travelScore + ergoWeight*c.ergo) / (travelWeight + ergoWeight)
+    if c.pickable {
+      l.picks = append(l.picks, c)
+    }
+  }
+  sort.SliceStable(l.picks, func(i, j int) bool {
+    if l.picks[i].score != l.picks[j].score {
+      return l.picks[i].score > l.picks[j].score
+    }
+    return l.picks[i].loc.LocationPath < l.picks[j].loc.LocationPath
+  })
+  return l
+}
+
+// exclude takes the slots in any of the zones out of assignment.
+func (l *slotLayout) exclude(zoneIDs []uuid.UUID) {
+  if len(zoneIDs) == 0 {
+    return
+  }
+  ids := make(map[uuid.UUID]bool, len(zoneIDs))
+  for _, id := range zoneIDs {
+    ids[id] = true
+  }
+  picks := l.picks[:0]
+  for _, c := range l.picks {
+    for _, z := range c.zones {
+      if ids[z.ID] {
+        c.pickable = false
+      }
+    }
+    if c.pickable {
+      picks = append(picks, c)
+    }
+  }
+  l.picks = picks
+}
+
+// take reserves the best free pick slot item fits in and is allowed in. On a
+// tie the item's current slot wins, which avoids pointless moves.
+func (l *slotLayout) take(item *models.Item, current []*slotCandidate) *slotCandidate {
+  for _, c := range l.picks {
+    if c.used || !l.compatible(c, item) {
+      continue
+    }
+    for _, cur := range current {
+      if cur != c && !cur.used && cur.pickable && cur.score == c.score && l.compatible(cur, item) {
+        c = cur
+        break
+      }
+    }
+    c.used = true
+    return c
+  }
+  return nil
+}
+
+// takeNear is take with a proximity objective: slots close to the partner
+// slots in near gain up to affinityWeight on top of the slot score, which
+// carries baseWeight. Without partners it is plain take.
+func (l *slotLayout) takeNear(item *models.Item, current, near []*slotCandidate, baseWeight, affinityWeight float64) *slotCandidate {
+  if len(near) == 0 || affinityWeight == 0 || l.span == 0 {
+    return l.take(item, current)
+  }
+  var best *slotCandidate
+  bestValue := -1.0
+  for _, c := range l.picks {
+    if c.used || !l.compatible(c, item) {
+      continue
+    }
+    proximity := 0.0
+    for _, n := range near {
+      if c.hasCoord && n.hasCoord {
+        d := math.Abs(c.loc.CoordX-n.loc.CoordX) + math.Abs(c.loc.CoordY-n.loc.CoordY)
+        proximity += math.Max(0, 1-d/l.span)
+      }
+    }
+    proximity /= float64(len(near))
+    value := (baseWeight*c.score + affinityWeight*proximity) / (baseWeight + affinityWeight)
+    if value > bestValue || (value == bestValue && isCurrent(c, current)) {
+      best, bestValue = c, value
+    }
+  }
+  if best != nil {
+    best.used = true
+  }
+  return best
+}
+
+func isCurrent(c *slotCandidate, current []*slotCandidate) bool {
+  for _, cur := range current {
+    if cur == c {
+      return true
+    }
+  }
+  return false
+}
+
+func (l *slotLayout) compatible(c *slotCandidate, item *models.Item) bool {
+  if !slotFits(c.loc, item) {
+    return false
+  }
+  for _, z := range c.zones {
+    if len(zoneRuleFailures(z, c.loc, item)) > 0 {
+      return false
+    }
+  }
+  return true
+}
+
+// travelOf falls back to the average distance for slots without coordinates.
+func (l *slotLayout) travelOf(c *slotCandidate) float64 {
+  if c.hasCoord {
+    return c.travel
+  }
+  return l.avgTravel
+}
+
+func bestCandidate(cs []*slotCandidate) *slotCandidate {
+  var best *slotCandidate
+  for _, c := range cs {
+    if best == nil || c.score > best.score {
+      best = c
+    }
+  }
+  return best
+}
+
+// depotDistance is the rectilinear distance from the warehouse depot; false
+// when the location has no coordinates.
+func depotDistance(wh *models.Warehouse, loc *models.Location) (float64, bool) {
+  if loc.CoordX == 0 && loc.CoordY == 0 {
+    return 0, false
+  }
+  return math.Abs(loc.CoordX-wh.DepotX) + math.Abs(loc.CoordY-wh.DepotY), true
+}
+
+// slotFits checks the item against the slot in any orientation and against
+// the slot weight limit. Unknown dimensions or limits never block.
+func slotFits(loc *models.Location, item *models.Item) bool {
+  if loc.MaxWeightKg > 0 && item.UnitWeightKg > loc.MaxWeightKg {
+    return false
+  }
+  slot := sortedDims(loc.SlotWidthCm, loc.SlotDepthCm, loc.SlotHeightCm)
+  it := sortedDims(item.LengthCm, item.WidthCm, item.HeightCm)
+  if slot[0] <= 0 || it[0] <= 0 {
+    return true
+  }
+  for i := range slot {
+    if it[i] > slot[i] {
+      return false
+    }
+  }
+  return true
+}
+
+func sortedDims(a, b, c float64) [3]float64 {
+  d := [3]float64{a, b, c}
+  sort.Float64s(d[:])
+  return d
+}
+
+// slotCapacity is how many units fit in the slot, stacked in a grid in the
+// best of the six orientations and capped by the slot weight limit. False
+// when slot or item dimensions are unknown.
+func slotCapacity(loc *models.Location, item *models.Item) (int64, bool) {
+  slot := [3]float64{loc.SlotWidthCm, loc.SlotDepthCm, loc.SlotHeightCm}
+  if slot[0] <= 0 || slot[1] <= 0 || slot[2] <= 0 || item.LengthCm <= 0 || item.WidthCm <= 0 || item.HeightCm <= 0 {
+    return 0, false
+  }
+  l, w, h := item.LengthCm, item.WidthCm, item.HeightCm
+  var best int64
+  for _, o := range [6][3]float64{{l, w, h}, {l, h, w}, {w, l, h}, {w, h, l}, {h, l, w}, {h, w, l}} {
+    n := int64(math.Floor(slot[0]/o[0])) * int64(math.Floor(slot[1]/o[1])) * int64(math.Floor(slot[2]/o[2]))
+    if n > best {
+      best = n
+    }
+  }
+  if loc.MaxWeightKg > 0 && item.UnitWeightKg > 0 {
+    if byWeight := int64(math.Floor(loc.MaxWeightKg / item.UnitWeightKg)); byWeight < best {
+      best = byWeight
+    }
+  }
+  return best, true
+}
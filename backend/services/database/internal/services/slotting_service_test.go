@@ -0,0 +1,182 @@
+package services
+
+import (
+  "strconv"
+  "strings"
+  "testing"
+
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+)
+
+func TestGroupMoves(t *testing.T) {
+  ids := make(map[string]uuid.UUID)
+  id := func(name string) uuid.UUID {
+    if _, ok := ids[name]; !ok {
+      ids[name] = uuid.New()
+    }
+    return ids[name]
+  }
+  // a move is "item from->to lines", e.g. "X L1->L2 5"
+  type groupWant struct {
+    kind   string
+    order  string // items in sequence
+    staged string // items moved via a temporary slot
+    note   string
+  }
+  tests := []struct {
+    name      string
+    moves     []string
+    occupants map[string][]string
+    want      []groupWant
+  }{
+    {
+      name:  "single move",
+      moves: []string{"X L1->L2 5"},
+      want:  []groupWant{{kind: constants.MoveKindMove, order: "X"}},
+    },
+    {
+      name:  "independent moves form their own groups",
+      moves: []string{"X L1->L2 5", "Y L3->L4 5"},
+      want:  []groupWant{{kind: constants.MoveKindMove, order: "X"}, {kind: constants.MoveKindMove, order: "Y"}},
+    },
+    {
+      name:  "swap stages the slower item",
+      moves: []string{"X L1->L2 5", "Y L2->L1 10"},
+      want:  []groupWant{{kind: constants.MoveKindSwap, order: "Y X", staged: "X"}},
+    },
+    {
+      name:  "chain empties the target first",
+      moves: []string{"X L1->L2 5", "Y L2->L3 5"},
+      want:  []groupWant{{kind: constants.MoveKindChain, order: "Y X"}},
+    },
+    {
+      name:  "long chain runs back to front",
+      moves: []string{"X L1->L2 5", "Y L2->L3 5", "Z L3->L4 5"},
+      want:  []groupWant{{kind: constants.MoveKindChain, order: "Z Y X"}},
+    },
+    {
+      name:  "cycle is broken at its slowest item",
+      moves: []string{"X L1->L2 3", "Y L2->L3 2", "Z L3->L1 1"},
+      want:  []groupWant{{kind: constants.MoveKindChain, order: "Y X Z", staged: "Z"}},
+    },
+    {
+      name:      "item staying in the target is noted",
+      moves:     []string{"X L1->L2 5"},
+      occupants: map[string][]string{"L2": {"W"}},
+      want:      []groupWant{{kind: constants.MoveKindMove, order: "X", note: "1 item(s) stay linked to a target slot"}},
+    },
+    {
+      name:      "item leaving the target is not noted",
+      moves:     []string{"X L1->L2 5", "Y L2->L3 5"},
+      occupants: map[string][]string{"L2": {"Y"}},
+      want:      []groupWant{{kind: constants.MoveKindChain, order: "Y X"}},
+    },
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      var moves []*PlannedMove
+      names := make(map[*PlannedMove]string)
+      for _, spec := range tt.moves {
+        parts := strings.Fields(spec)
+        item := parts[0]
+        from, to, _ := strings.Cut(parts[1], "->")
+        lines, _ := strconv.ParseInt(parts[2], 10, 64)
+        m := &PlannedMove{ItemID: id(item), ItemName: item, FromLocationID: id(from), ToLocationID: id(to), Lines: lines}
+        moves = append(moves, m)
+        names[m] = item
+      }
+      occupants := make(map[uuid.UUID][]uuid.UUID)
+      for loc, items := range tt.occupants {
+        for _, item := range items {
+          occupants[id(loc)] = append(occupants[id(loc)], id(item))
+        }
+      }
+
+      groups := groupMoves(moves, occupants)
+      if len(groups) != len(tt.want) {
+        t.Fatalf("got %d groups, want %d", len(groups), len(tt.want))
+      }
+      for i, g := range groups {
+        want := tt.want[i]
+        var order, staged []string
+        for n, m := range g.Moves {
+          order = append(order, names[m])
+          if m.ViaTemporary {
+            staged = append(staged, names[m])
+          }
+          if m.Sequence != n+1 {
+            t.Errorf("group %d: %s has sequence %d, want %d", i, names[m], m.Sequence, n+1)
+          }
+        }
+        if g.Kind != want.kind {
+          t.Errorf("group %d: kind %q, want %q", i, g.Kind, want.kind)
+        }
+        if got := strings.Join(order, " "); got != want.order {
+          t.Errorf("group %d: order %q, want %q", i, got, want.order)
+        }
+        if got := strings.Join(staged, " "); got != want.staged {
+          t.Errorf("group %d: staged %q, want %q", i, got, want.staged)
+        }
+        if g.Note != want.note {
+          t.Errorf("group %d: note %q, want %q", i, g.Note, want.note)
+        }
+      }
+    })
+  }
+}
+
+func TestSlotFits(t *testing.T) {
+  slot := &models.Location{SlotWidthCm: 40, SlotDepthCm: 60, SlotHeightCm: 30, MaxWeightKg: 20}
+  tests := []struct {
+    name string
+    loc  *models.Location
+    item *models.Item
+    want bool
+  }{
+    {name: "fits as is", loc: slot, item: &models.Item{LengthCm: 50, WidthCm: 30, HeightCm: 20, UnitWeightKg: 5}, want: true},
+    {name: "fits turned", loc: slot, item: &models.Item{LengthCm: 25, WidthCm: 55, HeightCm: 35}, want: true},
+    {name: "too long every way", loc: slot, item: &models.Item{LengthCm: 65, WidthCm: 10, HeightCm: 10}, want: false},
+    {name: "two sides too big", loc: slot, item: &models.Item{LengthCm: 45, WidthCm: 45, HeightCm: 10}, want: false},
+    {name: "too heavy", loc: slot, item: &models.Item{LengthCm: 10, WidthCm: 10, HeightCm: 10, UnitWeightKg: 25}, want: false},
+    {name: "unknown item size", loc: slot, item: &models.Item{UnitWeightKg: 5}, want: true},
+    {name: "unknown slot size", loc: &models.Location{}, item: &models.Item{LengthCm: 500, WidthCm: 500, HeightCm: 500}, want: true},
+    {name: "no weight limit", loc: &models.Location{SlotWidthCm: 40, SlotDepthCm: 60, SlotHeightCm: 30}, item: &models.Item{LengthCm: 10, WidthCm: 10, HeightCm: 10, UnitWeightKg: 500}, want: true},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      if got := slotFits(tt.loc, tt.item); got != tt.want {
+        t.Errorf("slotFits = %v, want %v", got, tt.want)
+      }
+    })
+  }
+}
+
+func TestSlotCapacity(t *testing.T) {
+  slot := &models.Location{SlotWidthCm: 40, SlotDepthCm: 60, SlotHeightCm: 30}
+  tests := []struct {
+    name      string
+    loc       *models.Location
+    item      *models.Item
+    want      int64
+    wantKnown bool
+  }{
+    {name: "exact grid", loc: slot, item: &models.Item{LengthCm: 20, WidthCm: 20, HeightCm: 10}, want: 2 * 3 * 3, wantKnown: true},
+    // standing up it does not fit; on its side 35 across the width and 10 along the depth gives 1*6*1
+    {name: "best orientation", loc: slot, item: &models.Item{LengthCm: 10, WidthCm: 25, HeightCm: 35}, want: 6, wantKnown: true},
+    {name: "turned to fit", loc: slot, item: &models.Item{LengthCm: 55, WidthCm: 10, HeightCm: 10}, want: 4 * 1 * 3, wantKnown: true},
+    {name: "does not fit", loc: slot, item: &models.Item{LengthCm: 70, WidthCm: 10, HeightCm: 10}, want: 0, wantKnown: true},
+    {name: "capped by weight", loc: &models.Location{SlotWidthCm: 40, SlotDepthCm: 60, SlotHeightCm: 30, MaxWeightKg: 10}, item: &models.Item{LengthCm: 20, WidthCm: 20, HeightCm: 10, UnitWeightKg: 3}, want: 3, wantKnown: true},
+    {name: "unknown slot size", loc: &models.Location{SlotWidthCm: 40, SlotDepthCm: 60}, item: &models.Item{LengthCm: 20, WidthCm: 20, HeightCm: 10}},
+    {name: "unknown item size", loc: slot, item: &models.Item{LengthCm: 20, WidthCm: 20}},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      got, known := slotCapacity(tt.loc, tt.item)
+      if got != tt.want || known != tt.wantKnown {
+        t.Errorf("slotCapacity = %d, %v, want %d, %v", got, known, tt.want, tt.wantKnown)
+      }
+    })
+  }
+}
@@ -0,0 +1,417 @@
+package services
+
+import (
+  "fmt"
+  "math"
+  "sort"
+  "time"
+
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+// SlowStockQuery sets the thresholds for slotted items. An item is dead
+// without a pick in the DeadDays up to AsOf; it is slow when its picks in the
+// last WindowDays fell by DropPct or more against the WindowDays before,
+// provided the prior window had at least MinPriorLines. Thresholds left zero
+// come from the warehouse's setting.
+type SlowStockQuery struct {
+  CompanyID     uuid.UUID
+  WarehouseID   uuid.UUID
+  AsOf          time.Time
+  DeadDays      int
+  WindowDays    int
+  DropPct       float64
+  MinPriorLines int64
+  Status        string
+  SortField     string
+  SortDir       string
+  Limit         int
+  Offset        int
+}
+
+type SlowStockSlot struct {
+  LocationID   uuid.UUID `json:"location_id"`
+  LocationPath string    `json:"location_path"`
+  GoldenZone   bool      `json:"golden_zone"`
+  CubeM3       float64   `json:"cube_m3"`
+}
+
+type SlowStockItem struct {
+  ItemID        uuid.UUID        `json:"item_id"`
+  ItemName      string           `json:"item_name"`
+  Status        string           `json:"status"`
+  LastPickAt    *time.Time       `json:"last_pick_at"`
+  DaysSincePick *int             `json:"days_since_pick"` // nil when never picked
+  RecentLines   int64            `json:"recent_lines"`
+  PriorLines    int64            `json:"prior_lines"`
+  DropPct       *float64         `json:"drop_pct"`
+  Slots         []*SlowStockSlot `json:"slots"`
+  CubeM3        float64          `json:"cube_m3"`
+  GoldenCubeM3  float64          `json:"golden_cube_m3"` // prime space the item holds
+  FlaggedAt     *time.Time       `json:"flagged_at"`     // since the last scan flagged it
+}
+
+type SlowStockReport struct {
+  WarehouseID       uuid.UUID        `json:"warehouse_id"`
+  AsOf              time.Time        `json:"as_of"`
+  DeadDays          int              `json:"dead_days"`
+  WindowDays        int              `json:"window_days"`
+  DropPct           float64          `json:"drop_pct"`
+  Counts            map[string]int   `json:"counts"`
+  TotalCubeM3       float64          `json:"total_cube_m3"`
+  TotalGoldenCubeM3 float64          `json:"total_golden_cube_m3"`
+  TotalItems        int              `json:"total_items"`
+  Rows              []*SlowStockItem `json:"rows"`
+}
+
+// StockFlagChange is an item crossing a threshold between two scans. Status
+// is empty when the item is no longer dead or slow.
+type StockFlagChange struct {
+  ItemID     uuid.UUID `json:"item_id"`
+  ItemName   string    `json:"item_name"`
+  FromStatus string    `json:"from_status"`
+  Status     string    `json:"status"`
+}
+
+type SlowStockSvc interface {
+  // Report lists dead and slow slotted items without touching stored flags.
+  Report(q SlowStockQuery) (*SlowStockReport, error)
+  // Scan builds the report and brings the stored flags in line with it,
+  // returning the items that crossed a threshold since the last scan.
+  Scan(q SlowStockQuery) (*SlowStockReport, []*StockFlagChange, error)
+  // GetSetting returns the warehouse's thresholds, the defaults (with a nil
+  // ID) when it has none.
+  GetSetting(warehouseID uuid.UUID) (*models.SlowStockSetting, error)
+  SetSetting(st *models.SlowStockSetting) error
+  // ResetSetting drops the warehouse's thresholds, returning it to the defaults.
+  ResetSetting(warehouseID uuid.UUID) error
+}
+
+type slowStockSvc struct {
+  repo            repos.StockFlagRepo
+  arepo           repos.AnalyticsRepo
+  lrepo           repos.LRepo
+  irepo           repos.IRepo
+}
+
+func NewSlowStockSvc(repo repos.StockFlagRepo, arepo repos.AnalyticsRepo, lrepo repos.LRepo, irepo repos.IRepo) SlowStockSvc {
+  return &slowStockSvc{repo: repo, arepo: arepo, lrepo: lrepo, irepo: irepo}
+}
+
+const (
+  defaultDeadDays          = 60
+  defaultSlowWindowDays    = 30
+  defaultSlowDropPct       = 50
+  defaultSlowMinPriorLines = 5
+)
+
+func (s *slowStockSvc) Report(q SlowStockQuery) (*SlowStockReport, error) {
+  report, err := s.build(&q)
+  if err != nil {
+    return nil, err
+  }
+  flags, err := s.repo.ListByWarehouse(q.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+  byItem := make(map[uuid.UUID]*models.StockFlag, len(flags))
+  for _, f := range flags {
+    byItem[*f.ItemID] = f
+  }
+  for _, row := range report.Rows {
+    if f, ok := byItem[row.ItemID]; ok && f.Status == row.Status {
+      at := f.FlaggedAt
+      row.FlaggedAt = &at
+    }
+  }
+  finishSlowStock(report, q)
+  return report, nil
+}
+
+func (s *slowStockSvc) Scan(q SlowStockQuery) (*SlowStockReport, []*StockFlagChange, error) {
+  report, err := s.build(&q)
+  if err != nil {
+    return nil, nil, err
+  }
+  flags, err := s.repo.ListByWarehouse(q.WarehouseID)
+  if err != nil {
+    return nil, nil, err
+  }
+  byItem := make(map[uuid.UUID]*models.StockFlag, len(flags))
+  for _, f := range flags {
+    byItem[*f.ItemID] = f
+  }
+
+  changes := []*StockFlagChange{}
+  for _, row := range report.Rows {
+    f, ok := byItem[row.ItemID]
+    delete(byItem, row.ItemID)
+    if ok && f.Status == row.Status {
+      at := f.FlaggedAt
+      row.FlaggedAt = &at
+      continue
+    }
+    itemID := row.ItemID
+    flag := &models.StockFlag{CompanyID: &q.CompanyID, WarehouseID: &q.WarehouseID, ItemID: &itemID, Status: row.Status}
+    if err := s.repo.Upsert(flag); err != nil {
+      return nil, nil, err
+    }
+    change := &StockFlagChange{ItemID: row.ItemID, ItemName: row.ItemName, Status: row.Status}
+    if ok {
+      change.FromStatus = f.Status
+    }
+    changes = append(changes, change)
+    row.FlaggedAt = &flag.FlaggedAt
+  }
+  for itemID, f := range byItem {
+    if err := s.repo.Delete(f.ID); err != nil {
+      return nil, nil, fmt.Errorf("failed to clear stock flag: %w", err)
+    }
+    changes = append(changes, &StockFlagChange{ItemID: itemID, FromStatus: f.Status})
+  }
+  finishSlowStock(report, q)
+  return report, changes, nil
+}
+
+func (s *slowStockSvc) GetSetting(warehouseID uuid.UUID) (*models.SlowStockSetting, error) {
+  if warehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  st, err := s.repo.GetSetting(warehouseID)
+  if err != nil {
+    return nil, err
+  }
+  if st == nil {
+    return &models.SlowStockSetting{
+      WarehouseID:   &warehouseID,
+      DeadDays:      defaultDeadDays,
+      WindowDays:    defaultSlowWindowDays,
+      DropPct:       defaultSlowDropPct,
+      MinPriorLines: defaultSlowMinPriorLines,
+    }, nil
+  }
+  return st, nil
+}
+
+func (s *slowStockSvc) SetSetting(st *models.SlowStockSetting) error {
+  if st.CompanyID == nil || st.WarehouseID == nil {
+    return fmt.Errorf("invalid warehouse")
+  }
+  if st.DeadDays <= 0 || st.WindowDays <= 0 || st.MinPriorLines <= 0 {
+    return fmt.Errorf("dead days, window days and min prior lines must be positive")
+  }
+  if st.DropPct <= 0 || st.DropPct > 100 {
+    return fmt.Errorf("drop percentage must be above 0 and at most 100")
+  }
+  return s.repo.UpsertSetting(st)
+}
+
+func (s *slowStockSvc) ResetSetting(warehouseID uuid.UUID) error {
+  if warehouseID == uuid.Nil {
+    return fmt.Errorf("invalid warehouseID")
+  }
+  return s.repo.DeleteSetting(warehouseID)
+}
+
+// build fills in the warehouse's thresholds on q and returns every dead or slow slotted item,
+// unfiltered and unsorted.
+func (s *slowStockSvc) build(q *SlowStockQuery) (*SlowStockReport, error) {
+  if q.WarehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  if q.DeadDays < 0 || q.WindowDays < 0 || q.DropPct < 0 || q.MinPriorLines < 0 {
+    return nil, fmt.Errorf("slow stock thresholds cannot be negative")
+  }
+  if q.DropPct > 100 {
+    return nil, fmt.Errorf("drop percentage cannot exceed 100")
+  }
+  if q.Status != "" && !constants.StockFlagStatuses[q.Status] {
+    return nil, fmt.Errorf("invalid stock status '%s'", q.Status)
+  }
+  st, err := s.GetSetting(q.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+  if q.DeadDays == 0 {
+    q.DeadDays = st.DeadDays
+  }
+  if q.WindowDays == 0 {
+    q.WindowDays = st.WindowDays
+  }
+  if q.DropPct == 0 {
+    q.DropPct = st.DropPct
+  }
+  if q.MinPriorLines == 0 {
+    q.MinPriorLines = st.MinPriorLines
+  }
+  _, end, err := analyticsWindow(time.Time{}, q.AsOf)
+  if err != nil {
+    return nil, err
+  }
+
+  report := &SlowStockReport{
+    WarehouseID: q.WarehouseID,
+    AsOf:        end.AddDate(0, 0, -1),
+    DeadDays:    q.DeadDays,
+    WindowDays:  q.WindowDays,
+    DropPct:     q.DropPct,
+    Counts:      make(map[string]int),
+    Rows:        []*SlowStockItem{},
+  }
+  links, err := s.lrepo.ListItemLinks(q.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+  if len(links) == 0 {
+    return report, nil
+  }
+  locs, err := s.lrepo.ListLocations(repos.LocationFilter{WarehouseID: q.WarehouseID})
+  if err != nil {
+    return nil, fmt.Errorf("failed to load locations: %w", err)
+  }
+  locByID := make(map[uuid.UUID]*models.Location, len(locs))
+  for _, loc := range locs {
+    locByID[loc.ID] = loc
+  }
+  slots := make(map[uuid.UUID][]*models.Location)
+  var itemIDs []uuid.UUID
+  for _, l := range links {
+    loc, ok := locByID[l.LocationID]
+    if !ok {
+      continue
+    }
+    if _, seen := slots[l.ItemID]; !seen {
+      itemIDs = append(itemIDs, l.ItemID)
+    }
+    slots[l.ItemID] = append(slots[l.ItemID], loc)
+  }
+
+  f := repos.AnalyticsFilter{CompanyID: q.CompanyID, WarehouseID: q.WarehouseID, EndDate: end}
+  if f.RawTypes, err = rawTypesFor(s.arepo, f, []string{constants.TransactionTypePick}); err != nil {
+    return nil, err
+  }
+  last := make(map[uuid.UUID]time.Time)
+  recent := make(map[uuid.UUID]int64)
+  prior := make(map[uuid.UUID]int64)
+  if len(f.RawTypes) > 0 {
+    all, err := s.arepo.ItemLastActivity(f)
+    if err != nil {
+      return nil, err
+    }
+    for _, a := range all {
+      last[a.ItemID] = a.LastDate
+    }
+    recentStart := end.AddDate(0, 0, -q.WindowDays)
+    for _, w := range []struct {
+      start, end time.Time
+      lines      map[uuid.UUID]int64
+    }{
+      {recentStart, end, recent},
+      {recentStart.AddDate(0, 0, -q.WindowDays), recentStart, prior},
+    } {
+      wf := f
+      wf.StartDate, wf.EndDate = w.start, w.end
+      rows, err := s.arepo.ItemLastActivity(wf)
+      if err != nil {
+        return nil, err
+      }
+      for _, a := range rows {
+        w.lines[a.ItemID] = a.Lines
+      }
+    }
+  }
+
+  items, err := s.irepo.ListItems(repos.ItemFilter{IDs: itemIDs})
+  if err != nil {
+    return nil, fmt.Errorf("failed to load items: %w", err)
+  }
+  deadBefore := end.AddDate(0, 0, -q.DeadDays)
+  for _, it := range items {
+    row := &SlowStockItem{ItemID: it.ID, ItemName: it.Name, RecentLines: recent[it.ID], PriorLines: prior[it.ID]}
+    if at, ok := last[it.ID]; ok {
+      row.LastPickAt = &at
+      days := int(end.Sub(at).Hours() / 24)
+      row.DaysSincePick = &days
+    }
+    if row.PriorLines > 0 {
+      drop := 100 * float64(row.PriorLines-row.RecentLines) / float64(row.PriorLines)
+      row.DropPct = &drop
+    }
+    switch {
+    case row.LastPickAt == nil || row.LastPickAt.Before(deadBefore):
+      row.Status = constants.StockFlagDead
+    case row.PriorLines >= q.MinPriorLines && row.DropPct != nil && *row.DropPct >= q.DropPct:
+      row.Status = constants.StockFlagSlow
+    default:
+      continue
+    }
+    for _, loc := range slots[it.ID] {
+      slot := &SlowStockSlot{
+        LocationID:   loc.ID,
+        LocationPath: loc.LocationPath,
+        GoldenZone:   inGoldenZone(loc),
+        CubeM3:       loc.SlotWidthCm * loc.SlotDepthCm * loc.SlotHeightCm / 1e6,
+      }
+      row.Slots = append(row.Slots, slot)
+      row.CubeM3 += slot.CubeM3
+      if slot.GoldenZone {
+        row.GoldenCubeM3 += slot.CubeM3
+      }
+    }
+    report.Rows = append(report.Rows, row)
+  }
+  return report, nil
+}
+
+// finishSlowStock totals the report, then applies the status filter, sorting
+// and pagination of q.
+func finishSlowStock(report *SlowStockReport, q SlowStockQuery) {
+  rows := report.Rows[:0]
+  for _, row := range report.Rows {
+    report.Counts[row.Status]++
+    report.TotalCubeM3 += row.CubeM3
+    report.TotalGoldenCubeM3 += row.GoldenCubeM3
+    if q.Status == "" || q.Status == row.Status {
+      rows = append(rows, row)
+    }
+  }
+  less := func(a, b *SlowStockItem) bool { return a.GoldenCubeM3 < b.GoldenCubeM3 }
+  switch q.SortField {
+  case "cube_m3":
+    less = func(a, b *SlowStockItem) bool { return a.CubeM3 < b.CubeM3 }
+  case "days_since_pick":
+    daysOf := func(r *SlowStockItem) int {
+      if r.DaysSincePick == nil {
+        return math.MaxInt
+      }
+      return *r.DaysSincePick
+    }
+    less = func(a, b *SlowStockItem) bool { return daysOf(a) < daysOf(b) }
+  case "recent_lines":
+    less = func(a, b *SlowStockItem) bool { return a.RecentLines < b.RecentLines }
+  case "item_name":
+    less = func(a, b *SlowStockItem) bool { return a.ItemName < b.ItemName }
+  }
+  asc := q.SortDir == "asc" || q.SortDir == "ASC"
+  sort.Slice(rows, func(i, j int) bool { return rows[i].ItemName < rows[j].ItemName })
+  sort.SliceStable(rows, func(i, j int) bool {
+    if asc {
+      return less(rows[i], rows[j])
+    }
+    return less(rows[j], rows[i])
+  })
+  report.TotalItems = len(rows)
+  report.Rows = paginate(rows, q.Limit, q.Offset)
+}
+
+// inGoldenZone tells whether the slot floor is between waist and shoulder
+// height, judged by the Level=2 name path segment when the height is unknown.
+func inGoldenZone(loc *models.Location) bool {
+  if loc.LevelHeightCm > 0 {
+    return loc.LevelHeightCm >= constants.GoldenZoneMinCm && loc.LevelHeightCm <= constants.GoldenZoneMaxCm
+  }
+  return locationLevel(loc) == 2
+}
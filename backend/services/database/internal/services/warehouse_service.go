@@ -2,6 +2,8 @@ package services
 
 import (
   "fmt"
+  "time"
+
   "github.com/google/uuid"
   "github.com/yungbote/slotter/backend/services/database/internal/models"
   "github.com/yungbote/slotter/backend/services/database/internal/repos"
@@ -11,6 +13,9 @@ type WSvc interface {
   //GENERAL CRUD
   CreateWarehouse(warehouse models.Warehouse) (*models.Warehouse, error)
   UpdateWarehouseName(warehouseID uuid.UUID, newName string) error
+  UpdateDepot(warehouseID uuid.UUID, x, y float64) error
+  // UpdateGoLiveDate sets the day from which records are expected; nil clears it.
+  UpdateGoLiveDate(warehouseID uuid.UUID, goLive *time.Time) error
   GetWarehouseByID(warehouseID uuid.UUID) (*models.Warehouse, error)
   DeleteWarehouse(warehouseID uuid.UUID) error
 
@@ -18,7 +23,7 @@ type WSvc interface {
   UnlinkFromItem(warehouseID, itemID uuid.UUID) error
 
   ListWarehouses(f repos.WarehouseFilter) ([]*models.Warehouse, error)
-
+  CloneWarehouse(sourceID uuid.UUID, target models.Warehouse, includeItems bool, progress func(done, total int)) (*repos.WarehouseCloneResult, error)
 }
 
 type wSvc struct {
@@ -50,6 +55,24 @@ func (s *wSvc) UpdateWarehouseName(warehouseID uuid.UUID, newName string) error
   return s.repo.UpdateName(warehouseID, newName)
 }
 
+func (s *wSvc) UpdateDepot(warehouseID uuid.UUID, x, y float64) error {
+  if warehouseID == uuid.Nil {
+    return fmt.Errorf("Invalid warehouseID")
+  }
+  return s.repo.UpdateDepot(warehouseID, x, y)
+}
+
+func (s *wSvc) UpdateGoLiveDate(warehouseID uuid.UUID, goLive *time.Time) error {
+  if warehouseID == uuid.Nil {
+    return fmt.Errorf("Invalid warehouseID")
+  }
+  if goLive != nil {
+    day := time.Date(goLive.Year(), goLive.Month(), goLive.Day(), 0, 0, 0, 0, time.UTC)
+    goLive = &day
+  }
+  return s.repo.UpdateGoLiveDate(warehouseID, goLive)
+}
+
 func (s *wSvc) UpdateWarehouseAvatarURL(warehouseID uuid.UUID, newAvatarURL string) error {
   if warehouseID == uuid.Nil || newAvatarURL == "" {
     return fmt.Errorf("Invalid input to update warehouse avatar url")
@@ -102,3 +125,20 @@ func (s *wSvc) ListWarehouses(f repos.WarehouseFilter) ([]*models.Warehouse, err
   }
   return warehouses, nil
 }
+
+func (s *wSvc) CloneWarehouse(sourceID uuid.UUID, target models.Warehouse, includeItems bool, progress func(done, total int)) (*repos.WarehouseCloneResult, error) {
+  if sourceID == uuid.Nil {
+    return nil, fmt.Errorf("invalid source warehouseID")
+  }
+  if target.Name == "" {
+    return nil, fmt.Errorf("warehouse name is empty")
+  }
+  if target.CompanyID == nil || *target.CompanyID == uuid.Nil {
+    return nil, fmt.Errorf("warehouse must have a valid company ID")
+  }
+  res, err := s.repo.Clone(sourceID, target, includeItems, progress)
+  if err != nil {
+    return nil, fmt.Errorf("failed to clone warehouse: %w", err)
+  }
+  return res, nil
+}
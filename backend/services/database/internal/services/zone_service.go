@@ -0,0 +1,206 @@
+package services
+
+import (
+  "fmt"
+  "strconv"
+  "strings"
+
+  "github.com/google/uuid"
+  "github.com/yungbote/slotter/backend/services/database/internal/constants"
+  "github.com/yungbote/slotter/backend/services/database/internal/models"
+  "github.com/yungbote/slotter/backend/services/database/internal/repos"
+)
+
+type ZSvc interface {
+  CreateZone(zone models.Zone) (*models.Zone, error)
+  UpdateZone(zone models.Zone) error
+  GetZoneByID(zoneID uuid.UUID) (*models.Zone, error)
+  DeleteZone(zoneID uuid.UUID) error
+  ListZones(warehouseID uuid.UUID) ([]*models.Zone, error)
+  ZonesForLocation(location *models.Location) ([]*models.Zone, error)
+  // CheckLink evaluates every zone covering location against item, stores the
+  // failures and resolves violations for this pair that no longer apply.
+  CheckLink(location *models.Location, item *models.Item, source string, fileID *uuid.UUID) ([]*models.ZoneViolation, error)
+  ListViolations(f repos.ZoneViolationFilter) ([]*models.ZoneViolation, error)
+}
+
+type zSvc struct {
+  repo            repos.ZRepo
+  vrepo           repos.ZVRepo
+}
+
+func NewZSvc(repo repos.ZRepo, vrepo repos.ZVRepo) ZSvc {
+  return &zSvc{repo: repo, vrepo: vrepo}
+}
+
+func (s *zSvc) CreateZone(zone models.Zone) (*models.Zone, error) {
+  if zone.WarehouseID == nil || zone.CompanyID == nil {
+    return nil, fmt.Errorf("zone warehouse and company are required")
+  }
+  if err := validateZone(&zone); err != nil {
+    return nil, err
+  }
+  created, err := s.repo.Create(zone)
+  if err != nil {
+    return nil, fmt.Errorf("failed to create zone: %w", err)
+  }
+  return created, nil
+}
+
+func (s *zSvc) UpdateZone(zone models.Zone) error {
+  if zone.ID == uuid.Nil {
+    return fmt.Errorf("invalid zoneID")
+  }
+  if err := validateZone(&zone); err != nil {
+    return err
+  }
+  if err := s.repo.Update(zone); err != nil {
+    return fmt.Errorf("failed to update zone: %w", err)
+  }
+  return nil
+}
+
+func (s *zSvc) GetZoneByID(zoneID uuid.UUID) (*models.Zone, error) {
+  if zoneID == uuid.Nil {
+    return nil, fmt.Errorf("invalid zoneID")
+  }
+  return s.repo.GetByID(zoneID)
+}
+
+func (s *zSvc) DeleteZone(zoneID uuid.UUID) error {
+  if zoneID == uuid.Nil {
+    return fmt.Errorf("invalid zoneID")
+  }
+  return s.repo.Delete(zoneID)
+}
+
+func (s *zSvc) ListZones(warehouseID uuid.UUID) ([]*models.Zone, error) {
+  if warehouseID == uuid.Nil {
+    return nil, fmt.Errorf("invalid warehouseID")
+  }
+  return s.repo.ListByWarehouse(warehouseID)
+}
+
+func (s *zSvc) ZonesForLocation(location *models.Location) ([]*models.Zone, error) {
+  if location == nil || location.WarehouseID == nil {
+    return nil, fmt.Errorf("location missing warehouse reference")
+  }
+  zones, err := s.repo.ListByWarehouse(*location.WarehouseID)
+  if err != nil {
+    return nil, err
+  }
+  var out []*models.Zone
+  for _, z := range zones {
+    if pathInSubtree(location.LocationPath, z.PathPrefix) {
+      out = append(out, z)
+    }
+  }
+  return out, nil
+}
+
+func (s *zSvc) CheckLink(location *models.Location, item *models.Item, source string, fileID *uuid.UUID) ([]*models.ZoneViolation, error) {
+  if location == nil || item == nil {
+    return nil, fmt.Errorf("location and item are required")
+  }
+  zones, err := s.ZonesForLocation(location)
+  if err != nil {
+    return nil, err
+  }
+  var found []*models.ZoneViolation
+  var keep []string
+  for _, z := range zones {
+    for rule, detail := range zoneRuleFailures(z, location, item) {
+      v := models.ZoneViolation{
+        CompanyID:         z.CompanyID,
+        WarehouseID:       location.WarehouseID,
+        ZoneID:            &z.ID,
+        LocationID:        &location.ID,
+        ItemID:            &item.ID,
+        Rule:              rule,
+        Detail:            detail,
+        Source:            source,
+        TransactionFileID: fileID,
+      }
+      if err := s.vrepo.Upsert(v); err != nil {
+        return nil, err
+      }
+      found = append(found, &v)
+      keep = append(keep, z.ID.String()+":"+rule)
+    }
+  }
+  if err := s.vrepo.ResolveLink(location.ID, item.ID, keep); err != nil {
+    return nil, err
+  }
+  return found, nil
+}
+
+func (s *zSvc) ListViolations(f repos.ZoneViolationFilter) ([]*models.ZoneViolation, error) {
+  return s.vrepo.ListViolations(f)
+}
+
+func validateZone(zone *models.Zone) error {
+  if zone.Name == "" {
+    return fmt.Errorf("zone name is required")
+  }
+  zone.PathPrefix = strings.Trim(zone.PathPrefix, "/")
+  if zone.PathPrefix == "" {
+    return fmt.Errorf("zone path prefix is required")
+  }
+  if zone.TemperatureClass != "" && !constants.TemperatureClasses[zone.TemperatureClass] {
+    return fmt.Errorf("invalid temperature class '%s'", zone.TemperatureClass)
+  }
+  if zone.MaxUnitWeightKg < 0 || zone.MaxItemCubeCm3 < 0 || zone.WeightLimitFromLevel < 0 {
+    return fmt.Errorf("zone limits cannot be negative")
+  }
+  return nil
+}
+
+// zoneRuleFailures returns rule -> human readable detail for every constraint
+// of zone that item breaks when stored in location.
+func zoneRuleFailures(zone *models.Zone, location *models.Location, item *models.Item) map[string]string {
+  out := make(map[string]string)
+  itemTemp := item.TemperatureClass
+  if itemTemp == "" {
+    itemTemp = constants.TemperatureClassAmbient
+  }
+  if zone.TemperatureClass != "" && zone.TemperatureClass != itemTemp {
+    out[constants.ZoneRuleTemperature] = fmt.Sprintf("item is %s, zone is %s", itemTemp, zone.TemperatureClass)
+  }
+  if item.Hazmat && !zone.HazmatAllowed {
+    out[constants.ZoneRuleHazmat] = "hazmat item in a zone that does not allow hazmat"
+  }
+  if zone.MaxUnitWeightKg > 0 && item.UnitWeightKg > zone.MaxUnitWeightKg {
+    level := locationLevel(location)
+    if zone.WeightLimitFromLevel == 0 || level >= zone.WeightLimitFromLevel {
+      out[constants.ZoneRuleUnitWeight] = fmt.Sprintf("unit weight %.2f kg exceeds %.2f kg", item.UnitWeightKg, zone.MaxUnitWeightKg)
+    }
+  }
+  if cube := itemCubeCm3(item); zone.MaxItemCubeCm3 > 0 && cube > zone.MaxItemCubeCm3 {
+    out[constants.ZoneRuleItemCube] = fmt.Sprintf("item cube %.0f cm3 exceeds %.0f cm3", cube, zone.MaxItemCubeCm3)
+  }
+  return out
+}
+
+func itemCubeCm3(item *models.Item) float64 {
+  return item.LengthCm * item.WidthCm * item.HeightCm
+}
+
+// locationLevel reads the "Level=<n>" segment the parser puts in the name path.
+// Locations without one report level 0, which only level-agnostic limits hit.
+func locationLevel(location *models.Location) int {
+  for _, part := range strings.Split(location.LocationNamePath, "|") {
+    kv := strings.SplitN(part, "=", 2)
+    if len(kv) == 2 && strings.EqualFold(strings.TrimSpace(kv[0]), "level") {
+      if n, err := strconv.Atoi(strings.TrimSpace(kv[1])); err == nil {
+        return n
+      }
+    }
+  }
+  return 0
+}
+
+// pathInSubtree reports whether path is prefix itself or a descendant of it.
+func pathInSubtree(path, prefix string) bool {
+  prefix = strings.Trim(prefix, "/")
+  return path == prefix || strings.HasPrefix(path, prefix+"/")
+}